@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// runApply 执行 drilling apply 子命令，使主机和隧道与配置文档一致
+// 指定 --server 时通过运行中的服务的API应用，否则直接修改本地数据库
+func runApply(args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	file := flags.String("f", "", "path to the YAML document, - reads from stdin")
+	dryRun := flags.Bool("dry-run", false, "only print the planned changes")
+	prune := flags.Bool("prune", false, "delete hosts and tunnels that are not in the document")
+	server := flags.String("server", "", "URL of a running drilling server, e.g. https://localhost:8080")
+	token := flags.String("token", os.Getenv("DRILLING_TOKEN"), "API token used with --server (default $DRILLING_TOKEN)")
+	insecure := flags.Bool("insecure", false, "skip TLS certificate verification when using --server")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("missing -f, usage: drilling apply -f tunnels.yaml [--dry-run] [--prune]")
+	}
+
+	var data []byte
+	var err error
+	if *file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", *file, err)
+	}
+
+	doc, err := service.ParseApplyDocument(data)
+	if err != nil {
+		return err
+	}
+	// 凭据引用在本地解析，服务端只接收解析后的值
+	if err := doc.ResolveSecrets(); err != nil {
+		return err
+	}
+
+	var result *service.ApplyResult
+	if *server != "" {
+		result, err = applyRemote(doc, *server, *token, *insecure, *dryRun, *prune)
+	} else {
+		result, err = applyLocal(doc, *dryRun, *prune)
+	}
+	if result != nil {
+		printApplyResult(result)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *server == "" && !result.DryRun && result.Summary.Update > 0 {
+		fmt.Println("Note: a running server picks up updated tunnels after they are restarted, use --server to apply through it")
+	}
+	return nil
+}
+
+// applyLocal 直接修改配置的数据库，新建的资源在服务下次启动时归属第一个管理员
+func applyLocal(doc *service.ApplyDocument, dryRun, prune bool) (*service.ApplyResult, error) {
+	local, err := openLocalServices()
+	if err != nil {
+		return nil, err
+	}
+	defer local.close()
+
+	applyService := service.NewApplyService(local.hostService, local.tunnelService)
+	result, err := applyService.Apply(doc, service.ApplyOptions{DryRun: dryRun, Prune: prune})
+	if result != nil {
+		for _, change := range result.Changes {
+			if change.Applied {
+				local.recordAudit(change.Action, change.ResourceType, change.ID, change.Name, change.Changes)
+			}
+		}
+	}
+	return result, err
+}
+
+// applyRemote 将解析凭据后的配置文档提交到运行中的服务
+func applyRemote(doc *service.ApplyDocument, server, token string, insecure, dryRun, prune bool) (*service.ApplyResult, error) {
+	body, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("dry_run", fmt.Sprint(dryRun))
+	query.Set("prune", fmt.Sprint(prune))
+	endpoint := strings.TrimRight(server, "/") + "/api/v1/apply?" + query.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/yaml")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	if insecure {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error   string               `json:"error"`
+			Details string               `json:"details"`
+			Result  *service.ApplyResult `json:"result"`
+		}
+		if err := json.Unmarshal(data, &failure); err != nil || failure.Error == "" {
+			return nil, fmt.Errorf("server returned %s", resp.Status)
+		}
+		if failure.Details != "" {
+			return failure.Result, fmt.Errorf("%s: %s", failure.Error, failure.Details)
+		}
+		return failure.Result, errors.New(failure.Error)
+	}
+
+	var result service.ApplyResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid response from server: %v", err)
+	}
+	return &result, nil
+}
+
+// printApplyResult 输出变更计划，+ 新建、~ 修改、- 删除
+func printApplyResult(result *service.ApplyResult) {
+	symbols := map[string]string{
+		service.ApplyActionCreate: "+",
+		service.ApplyActionUpdate: "~",
+		service.ApplyActionDelete: "-",
+	}
+
+	for _, change := range result.Changes {
+		symbol, ok := symbols[change.Action]
+		if !ok {
+			continue
+		}
+		fmt.Printf("%s %s %s\n", symbol, change.ResourceType, change.Name)
+		if change.Action != service.ApplyActionUpdate {
+			continue
+		}
+
+		fields := make([]string, 0, len(change.Changes))
+		for field := range change.Changes {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fmt.Printf("    %s: %v -> %v\n", field, change.Changes[field].Before, change.Changes[field].After)
+		}
+	}
+
+	format := "Applied: %d created, %d updated, %d deleted, %d unchanged\n"
+	if result.DryRun {
+		format = "Plan: %d to create, %d to update, %d to delete, %d unchanged\n"
+	}
+	fmt.Printf(format, result.Summary.Create, result.Summary.Update, result.Summary.Delete, result.Summary.Unchanged)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/KodaTao/drilling/internal/sshconfig"
+)
+
+// runImportSSHConfig 执行 drilling import-ssh-config 子命令，从 OpenSSH 配置导入主机和隧道
+// 在本机运行，可以读取 Include 的文件和私钥文件
+func runImportSSHConfig(args []string) error {
+	flags := flag.NewFlagSet("import-ssh-config", flag.ContinueOnError)
+	file := flags.String("f", "~/.ssh/config", "path to the OpenSSH client config")
+	dryRun := flags.Bool("dry-run", false, "only print what would be imported")
+	hosts := flags.String("hosts", "", "comma-separated host aliases to import, all when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := sshconfig.ParseFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", *file, err)
+	}
+
+	local, err := openLocalServices()
+	if err != nil {
+		return err
+	}
+	defer local.close()
+
+	importService := service.NewSSHConfigImportService(local.hostService, local.tunnelService)
+	opts := service.SSHImportOptions{ReadIdentityFiles: true}
+	if *hosts != "" {
+		opts.Aliases = strings.Split(*hosts, ",")
+	}
+
+	var plan *service.SSHImportPlan
+	if *dryRun {
+		plan, err = importService.Plan(cfg, opts)
+	} else {
+		plan, err = importService.Import(cfg, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !*dryRun {
+		for _, host := range plan.Hosts {
+			if host.ID == 0 || host.Action != service.SSHImportCreate {
+				continue
+			}
+			local.recordAudit(models.AuditActionCreate, models.ResourceTypeHost, host.ID, host.Alias, nil)
+			for _, tunnel := range host.Tunnels {
+				if tunnel.ID != 0 {
+					local.recordAudit(models.AuditActionCreate, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, nil)
+				}
+			}
+		}
+	}
+
+	printSSHImportPlan(plan, *dryRun)
+	return nil
+}
+
+// printSSHImportPlan 输出导入计划或结果，+ 导入、! 无法导入、= 已存在
+func printSSHImportPlan(plan *service.SSHImportPlan, dryRun bool) {
+	symbols := map[string]string{
+		service.SSHImportCreate:      "+",
+		service.SSHImportExists:      "=",
+		service.SSHImportUnsupported: "!",
+		service.SSHImportFailed:      "!",
+	}
+
+	for _, warning := range plan.Warnings {
+		fmt.Println("warning:", warning)
+	}
+	for _, host := range plan.Hosts {
+		target := fmt.Sprintf("%s@%s:%d", host.Username, host.Hostname, host.Port)
+		if host.JumpHost != "" {
+			target += " via " + host.JumpHost
+		}
+		fmt.Printf("%s host %s (%s) [%s]\n", symbols[host.Action], host.Alias, target, host.Action)
+		for _, issue := range host.Issues {
+			fmt.Printf("    - %s\n", issue)
+		}
+		for _, tunnel := range host.Tunnels {
+			fmt.Printf("  %s tunnel %s: %s [%s]\n", symbols[tunnel.Action], tunnel.Name, tunnel.Source, tunnel.Action)
+			for _, issue := range tunnel.Issues {
+				fmt.Printf("      - %s\n", issue)
+			}
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Plan: %d hosts to import, %d skipped\n", len(plan.Hosts)-plan.Skipped, plan.Skipped)
+	} else {
+		fmt.Printf("Imported %d hosts, %d skipped\n", plan.Created, plan.Skipped)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/KodaTao/drilling/internal/config"
+	"github.com/KodaTao/drilling/internal/database"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+	"github.com/KodaTao/drilling/internal/service"
+	"gorm.io/gorm/logger"
+)
+
+// localServices 子命令直接操作本地数据库时使用的服务
+type localServices struct {
+	hostService   service.HostService
+	tunnelService service.TunnelService
+	auditService  service.AuditService
+	close         func()
+}
+
+// openLocalServices 按服务的配置打开本地数据库并创建服务，使用完毕后需要调用 close
+func openLocalServices() (*localServices, error) {
+	cfg := config.Load()
+
+	db, err := database.Init(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+	// 子命令只输出执行结果，不输出SQL日志
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	closeDB := func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	if err := database.Migrate(db); err != nil {
+		closeDB()
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	auditService, err := service.NewAuditService(repository.NewAuditRepository(db), cfg.Audit.File)
+	if err != nil {
+		closeDB()
+		return nil, fmt.Errorf("failed to initialize audit log: %v", err)
+	}
+
+	hostService := service.NewHostService(repository.NewHostRepository(db), encryptionKey(cfg), cfg.SSH.KeyDir)
+	trafficService := service.NewTrafficService(repository.NewTrafficRepository(db))
+	sessionService := service.NewSessionService(repository.NewSessionRepository(db))
+	return &localServices{
+		hostService:   hostService,
+		tunnelService: service.NewTunnelService(repository.NewTunnelRepository(db), hostService, trafficService, sessionService),
+		auditService:  auditService,
+		close: func() {
+			auditService.Close()
+			closeDB()
+		},
+	}, nil
+}
+
+// recordAudit 记录子命令执行的修改，来源标记为 cli
+func (l *localServices) recordAudit(action, resourceType string, resourceID uint, resourceName string, changes models.AuditChanges) {
+	l.auditService.Record(&models.AuditEvent{
+		SourceIP:     "cli",
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ResourceName: resourceName,
+		Changes:      changes,
+	})
+}
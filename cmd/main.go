@@ -1,17 +1,29 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/KodaTao/drilling/internal/api"
 	"github.com/KodaTao/drilling/internal/config"
 	"github.com/KodaTao/drilling/internal/database"
 	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
 	"github.com/KodaTao/drilling/internal/repository"
 	"github.com/KodaTao/drilling/internal/service"
+	"github.com/KodaTao/drilling/internal/tlsutil"
 	"github.com/KodaTao/drilling/web"
 	"github.com/gin-gonic/gin"
 )
@@ -32,7 +44,131 @@ func isStaticFile(path string) bool {
 	return false
 }
 
+// normalizeBasePath 规范化路径前缀，返回以 / 开头且不以 / 结尾的前缀，未配置时返回空字符串
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// withBasePath 去除请求路径中的前缀后交给路由处理，反向代理已去除前缀的请求原样处理
+func withBasePath(handler http.Handler, basePath string) http.Handler {
+	if basePath == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.Path
+		if path == basePath || strings.HasPrefix(path, basePath+"/") {
+			req.URL.Path = strings.TrimPrefix(path, basePath)
+			if req.URL.Path == "" {
+				req.URL.Path = "/"
+			}
+			req.URL.RawPath = ""
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// injectBaseHref 在 index.html 中插入 <base> 标签，使前端资源和API请求使用正确的路径前缀
+func injectBaseHref(html []byte, basePath string) []byte {
+	tag := fmt.Sprintf(`<head><base href="%s/">`, basePath)
+	return []byte(strings.Replace(string(html), "<head>", tag, 1))
+}
+
+// parseDuration 解析配置中的时间间隔，为空或格式错误时返回默认值
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration %q, using %s", value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// setupTLS 根据配置加载服务端证书，必要时生成自签名证书，并配置客户端证书校验
+func setupTLS(cfg config.TLSConfig, serverHost string) (*tls.Config, *tlsutil.CertReloader, error) {
+	if cfg.SelfSigned {
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		extra := []string{serverHost}
+		if hostname, err := os.Hostname(); err == nil {
+			extra = append(extra, hostname)
+		}
+		for _, host := range extra {
+			if host != "" && host != "0.0.0.0" && host != "::" && !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+
+		generated, err := tlsutil.EnsureSelfSigned(cfg.CertFile, cfg.KeyFile, hosts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if generated {
+			log.Printf("Generated self-signed TLS certificate %s for %s", cfg.CertFile, strings.Join(hosts, ", "))
+		}
+	}
+
+	reloader, err := tlsutil.NewCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pool, err := tlsutil.LoadCertPool(cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsConfig.ClientCAs = pool
+
+		switch cfg.ClientAuth {
+		case "", "optional":
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		case "require":
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, nil, fmt.Errorf("invalid client_auth: %s", cfg.ClientAuth)
+		}
+	}
+
+	return tlsConfig, reloader, nil
+}
+
+// subcommands 命令行子命令，不带子命令时启动服务
+var subcommands = map[string]func(args []string) error{
+	"apply":             runApply,
+	"import-ssh-config": runImportSSHConfig,
+}
+
+// encryptionKey 获取主机凭据的加密密钥，未配置时使用默认密钥
+func encryptionKey(cfg *config.Config) string {
+	if cfg.Security.EncryptKey == "" {
+		return "default-encryption-key-change-in-production"
+	}
+	return cfg.Security.EncryptKey
+}
+
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// 初始化日志
 	middleware.InitLogger()
 
@@ -58,12 +194,13 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
-	// 重启后重置所有隧道状态为未启动状态
-	log.Println("Resetting all tunnel status to inactive on startup...")
-	if err := db.Exec("UPDATE tunnels SET status = ? WHERE status = ?", "inactive", "active").Error; err != nil {
+	// 重启后重置上次未正常关闭时残留的运行状态，自动启动的隧道稍后会重新启动
+	log.Println("Resetting stale tunnel status to inactive on startup...")
+	if err := db.Exec("UPDATE tunnels SET status = ? WHERE status IN ?", models.TunnelStatusInactive,
+		[]string{models.TunnelStatusActive, models.TunnelStatusStandby, models.TunnelStatusConnected, models.TunnelStatusDegraded}).Error; err != nil {
 		log.Printf("Warning: Failed to reset tunnel status: %v", err)
 	} else {
-		log.Println("All tunnel status reset to inactive successfully")
+		log.Println("Stale tunnel status reset to inactive successfully")
 	}
 
 	// 设置 Gin 模式
@@ -76,10 +213,16 @@ func main() {
 	// 创建 Gin 实例
 	r := gin.New()
 
+	// 只信任配置的反向代理转发的客户端IP，日志和审计记录依赖真实的客户端IP
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	basePath := normalizeBasePath(cfg.Server.BasePath)
+
 	// 使用中间件
 	r.Use(middleware.Logger())
 	r.Use(gin.Recovery())
-	r.Use(middleware.CORSMiddleware())
+	r.Use(middleware.CORSMiddleware(cfg.Server.CORS.AllowedOrigins))
 
 	// 健康检查接口
 	r.GET("/health", func(c *gin.Context) {
@@ -92,23 +235,114 @@ func main() {
 	// 初始化仓库层
 	hostRepo := repository.NewHostRepository(db)
 	tunnelRepo := repository.NewTunnelRepository(db)
+	trafficRepo := repository.NewTrafficRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	shareRepo := repository.NewShareRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	exportProfileRepo := repository.NewExportProfileRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	tunnelGroupRepo := repository.NewTunnelGroupRepository(db)
 
 	// 初始化服务层
-	encryptKey := cfg.Security.EncryptKey
-	if encryptKey == "" {
-		encryptKey = "default-encryption-key-change-in-production"
+	hostService := service.NewHostService(hostRepo, encryptionKey(cfg), cfg.SSH.KeyDir)
+	trafficService := service.NewTrafficService(trafficRepo)
+	sessionService := service.NewSessionService(sessionRepo)
+	tunnelService := service.NewTunnelService(tunnelRepo, hostService, trafficService, sessionService)
+	tunnelGroupService := service.NewTunnelGroupService(tunnelGroupRepo, tunnelRepo, tunnelService)
+	clashExportService := service.NewClashExportService(tunnelRepo, hostRepo, tunnelService)
+	proxyExportService := service.NewProxyExportService(tunnelRepo, hostRepo, tunnelService, service.DefaultProxyExporters()...)
+	sshExportService := service.NewSSHExportService(tunnelRepo, hostRepo)
+	exportProfileService := service.NewExportProfileService(exportProfileRepo, subscriptionRepo)
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, exportProfileRepo, tunnelRepo, proxyExportService, trafficService)
+	authService := service.NewAuthService(userRepo, parseDuration(cfg.Auth.SessionTTL, 24*time.Hour))
+	tokenService := service.NewTokenService(tokenRepo)
+	accessService := service.NewAccessService(hostRepo, tunnelRepo, shareRepo, userRepo)
+	auditService, err := service.NewAuditService(auditRepo, cfg.Audit.File)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit log: %v", err)
+	}
+	defer auditService.Close()
+	applyService := service.NewApplyService(hostService, tunnelService)
+	backupService := service.NewBackupService(hostService, tunnelService, tunnelGroupService)
+	sshConfigImportService := service.NewSSHConfigImportService(hostService, tunnelService)
+
+	if err := authService.EnsureAdminUser(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword); err != nil {
+		log.Fatalf("Failed to initialize users: %v", err)
+	}
+	if err := accessService.ClaimUnownedResources(); err != nil {
+		log.Fatalf("Failed to assign unowned resources: %v", err)
+	}
+
+	retention := cfg.Logging.Retention
+	logRetentionService := service.NewLogRetentionService(tunnelRepo, sessionRepo, parseDuration(retention.MaxAge, 0), retention.MaxRowsPerTunnel, parseDuration(retention.PruneInterval, time.Hour))
+
+	// 收到退出信号时取消上下文
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// 后台任务：流量统计写入及配额检查、连接日志清理、按计划启停隧道
+	ctx, cancel := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	background.Add(3)
+	go func() {
+		defer background.Done()
+		tunnelService.MonitorQuotas(ctx)
+	}()
+	go func() {
+		defer background.Done()
+		logRetentionService.Run(ctx)
+	}()
+	go func() {
+		defer background.Done()
+		tunnelService.RunScheduler(ctx)
+	}()
+
+	// 自动启动隧道
+	if cfg.AutoStart.Enabled {
+		go func() {
+			opts := service.AutoStartOptions{
+				Concurrency: cfg.AutoStart.Concurrency,
+				Retries:     cfg.AutoStart.Retries,
+				RetryDelay:  parseDuration(cfg.AutoStart.RetryDelay, 5*time.Second),
+			}
+			if err := tunnelService.RunAutoStart(signalCtx, opts); err != nil {
+				log.Printf("Failed to start auto tunnels: %v", err)
+			}
+		}()
 	}
-	hostService := service.NewHostService(hostRepo, encryptKey)
-	tunnelService := service.NewTunnelService(tunnelRepo, hostService)
-	clashExportService := service.NewClashExportService(tunnelRepo, hostRepo)
 
 	// 初始化API处理器
-	hostHandler := api.NewHostHandler(hostService)
-	tunnelHandler := api.NewTunnelHandler(tunnelService)
-	exportHandler := api.NewExportHandler(clashExportService)
+	hostHandler := api.NewHostHandler(hostService, accessService, auditService)
+	tunnelHandler := api.NewTunnelHandler(tunnelService, accessService, auditService)
+	tunnelGroupHandler := api.NewTunnelGroupHandler(tunnelGroupService, accessService, auditService)
+	exportHandler := api.NewExportHandler(clashExportService, proxyExportService, sshExportService, exportProfileService, auditService)
+	exportProfileHandler := api.NewExportProfileHandler(exportProfileService, auditService)
+	subscriptionHandler := api.NewSubscriptionHandler(subscriptionService, auditService, basePath, cfg.Server.TrustedProxies)
+	sessionHandler := api.NewSessionHandler(sessionService, accessService)
+	// 启用HTTPS时会话Cookie始终只通过HTTPS发送
+	authHandler := api.NewAuthHandler(authService, accessService, cfg.Auth.CookieSecure || cfg.Server.TLS.Enabled)
+	tokenHandler := api.NewTokenHandler(tokenService)
+	shareHandler := api.NewShareHandler(accessService, auditService)
+	auditHandler := api.NewAuditHandler(auditService)
+	applyHandler := api.NewApplyHandler(applyService, auditService)
+	backupHandler := api.NewBackupHandler(backupService, auditService)
+	sshConfigHandler := api.NewSSHConfigHandler(sshConfigImportService, accessService, auditService)
+
+	// 无需登录的API
+	publicV1 := r.Group("/api/v1")
+	authHandler.RegisterPublicRoutes(publicV1)
+	// 订阅地址中的令牌即凭据，代理客户端无需登录即可拉取
+	subscriptionHandler.RegisterPublicRoutes(publicV1)
 
-	// API 路由组
+	// API 路由组，启用认证时需要登录
 	apiV1 := r.Group("/api/v1")
+	if cfg.Auth.Enabled {
+		apiV1.Use(middleware.AuthMiddleware(authService, tokenService))
+	} else {
+		log.Println("Warning: authentication is disabled, the management API is open to anyone who can reach it")
+	}
 	{
 		// 系统状态
 		apiV1.GET("/status", func(c *gin.Context) {
@@ -119,18 +353,55 @@ func main() {
 			})
 		})
 
+		// 注册认证及用户管理路由
+		if cfg.Auth.Enabled {
+			authHandler.RegisterRoutes(apiV1)
+			tokenHandler.RegisterRoutes(apiV1)
+			shareHandler.RegisterRoutes(apiV1)
+		}
+
 		// 注册主机管理路由
 		hostHandler.RegisterRoutes(apiV1)
 
 		// 注册隧道管理路由
 		tunnelHandler.RegisterRoutes(apiV1)
 
+		// 注册隧道组路由
+		tunnelGroupHandler.RegisterRoutes(apiV1)
+
+		// 注册连接会话路由
+		sessionHandler.RegisterRoutes(apiV1)
+
+		// 注册审计记录路由
+		auditHandler.RegisterRoutes(apiV1)
+
+		// 注册声明式配置路由
+		applyHandler.RegisterRoutes(apiV1)
+
+		// 注册备份与恢复路由
+		backupHandler.RegisterRoutes(apiV1)
+
+		// 注册SSH配置导入路由
+		sshConfigHandler.RegisterRoutes(apiV1)
+
+		// 注册导出模板路由
+		exportProfileHandler.RegisterRoutes(apiV1)
+
+		// 注册订阅管理路由
+		subscriptionHandler.RegisterRoutes(apiV1)
+
 		// 注册导出路由
-		exportGroup := apiV1.Group("/export")
+		// 导出的配置包含所有隧道，只允许管理员访问
+		exportGroup := apiV1.Group("/export", middleware.RequireScope(models.ScopeExportRead), middleware.RequireRole(models.UserRoleAdmin))
 		{
 			exportGroup.GET("/clash", exportHandler.ExportClashConfig)
 			exportGroup.GET("/clash/preview", exportHandler.GetClashConfigPreview)
 			exportGroup.GET("/socks5/status", exportHandler.GetSocks5TunnelsStatus)
+			exportGroup.GET("/ssh-config", exportHandler.ExportSSHConfig)
+			exportGroup.GET("/ssh-commands", exportHandler.ExportSSHCommands)
+			exportGroup.GET("/autossh", exportHandler.ExportAutosshUnits)
+			exportGroup.GET("/:format", exportHandler.ExportProxyConfig)
+			exportGroup.POST("/:format", middleware.RequireScope(models.ScopeTunnelsControl), exportHandler.ExportProxyConfig)
 		}
 	}
 
@@ -184,11 +455,67 @@ func main() {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load frontend"})
 			return
 		}
-		c.Data(http.StatusOK, "text/html", data)
+		c.Data(http.StatusOK, "text/html", injectBaseHref(data, basePath))
 	})
 
 	// 启动服务器
 	address := cfg.Server.Host + ":" + cfg.Server.Port
-	log.Printf("Starting Drilling SSH Tunnel Manager on %s", address)
-	log.Fatal(r.Run(address))
+	srv := &http.Server{
+		Addr:    address,
+		Handler: withBasePath(r, basePath),
+	}
+
+	if cfg.Server.TLS.Enabled {
+		tlsConfig, reloader, err := setupTLS(cfg.Server.TLS, cfg.Server.Host)
+		if err != nil {
+			log.Fatalf("Failed to configure TLS: %v", err)
+		}
+		srv.TLSConfig = tlsConfig
+
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			reloader.Watch(ctx, parseDuration(cfg.Server.TLS.ReloadInterval, 30*time.Second))
+		}()
+	} else if cfg.Server.Host != "127.0.0.1" && cfg.Server.Host != "localhost" {
+		log.Printf("Warning: serving plain HTTP on %s, credentials are sent unencrypted; consider enabling server.tls", cfg.Server.Host)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			log.Printf("Starting Drilling SSH Tunnel Manager on https://%s%s/", address, basePath)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Starting Drilling SSH Tunnel Manager on http://%s%s/", address, basePath)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case <-signalCtx.Done():
+		log.Println("Shutdown signal received")
+	case err := <-serverErr:
+		log.Printf("Server error: %v", err)
+	}
+	stopSignals()
+
+	// 优雅关闭：停止HTTP服务，等待隧道连接结束，最后写入流量统计
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), parseDuration(cfg.Server.ShutdownTimeout, 30*time.Second))
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+	if err := tunnelService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Tunnel shutdown did not complete cleanly: %v", err)
+	}
+
+	cancel()
+	background.Wait()
+	log.Println("Drilling SSH Tunnel Manager stopped")
 }
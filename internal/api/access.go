@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// authorizeHost 检查当前用户对主机的权限，失败时写入错误响应并返回false
+func authorizeHost(c *gin.Context, accessService service.AccessService, hostID uint, permission string) (*models.Host, bool) {
+	host, err := accessService.AuthorizeHost(middleware.CurrentUser(c), hostID, permission)
+	if err != nil {
+		respondAccessError(c, err, "Host not found")
+		return nil, false
+	}
+	return host, true
+}
+
+// authorizeTunnel 检查当前用户对隧道的权限，失败时写入错误响应并返回false
+func authorizeTunnel(c *gin.Context, accessService service.AccessService, tunnelID uint, permission string) (*models.Tunnel, bool) {
+	tunnel, err := accessService.AuthorizeTunnel(middleware.CurrentUser(c), tunnelID, permission)
+	if err != nil {
+		respondAccessError(c, err, "Tunnel not found")
+		return nil, false
+	}
+	return tunnel, true
+}
+
+// requireCreate 检查当前用户是否可以创建资源，失败时写入错误响应并返回false
+func requireCreate(c *gin.Context, accessService service.AccessService) bool {
+	if !accessService.CanCreate(middleware.CurrentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Permission denied",
+		})
+		return false
+	}
+	return true
+}
+
+// currentUserID 获取当前用户ID，未启用认证时返回0
+func currentUserID(c *gin.Context) uint {
+	if user := middleware.CurrentUser(c); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// isAdmin 检查当前用户是否为管理员，未启用认证时视为管理员
+func isAdmin(c *gin.Context) bool {
+	user := middleware.CurrentUser(c)
+	return user == nil || user.Role == models.UserRoleAdmin
+}
+
+// respondAccessError 返回访问控制错误：无权查看时返回404，避免泄露资源是否存在；权限不足时返回403
+func respondAccessError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Permission denied",
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": notFound,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to check permissions",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxApplyDocumentSize 配置文档的最大大小
+const maxApplyDocumentSize = 4 << 20
+
+// ApplyHandler 声明式配置处理器
+type ApplyHandler struct {
+	applyService service.ApplyService
+	auditService service.AuditService
+}
+
+// NewApplyHandler 创建声明式配置处理器实例
+func NewApplyHandler(applyService service.ApplyService, auditService service.AuditService) *ApplyHandler {
+	return &ApplyHandler{
+		applyService: applyService,
+		auditService: auditService,
+	}
+}
+
+// Apply 应用YAML或JSON格式的配置文档
+// 查询参数 dry_run=true 只返回变更计划，prune=true 删除文档中没有的主机和隧道
+// 凭据只能直接填写值，环境变量和文件引用需要由 drilling apply 命令在本地解析
+func (h *ApplyHandler) Apply(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxApplyDocumentSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	doc, err := service.ParseApplyDocument(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid apply document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	opts := service.ApplyOptions{
+		DryRun:  c.Query("dry_run") == "true",
+		Prune:   c.Query("prune") == "true",
+		OwnerID: currentUserID(c),
+	}
+
+	result, err := h.applyService.Apply(doc, opts)
+	if result != nil && !opts.DryRun {
+		h.recordChanges(c, result)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if result == nil {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to apply configuration",
+			"details": err.Error(),
+			"result":  result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// recordChanges 为已执行的变更记录审计事件，执行失败时只记录已生效的部分
+func (h *ApplyHandler) recordChanges(c *gin.Context, result *service.ApplyResult) {
+	for _, change := range result.Changes {
+		if !change.Applied {
+			continue
+		}
+		recordAudit(c, h.auditService, change.Action, change.ResourceType, change.ID, change.Name, change.Changes)
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *ApplyHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/apply",
+		middleware.RequireScope(models.ScopeHostsWrite),
+		middleware.RequireScope(models.ScopeTunnelsWrite),
+		middleware.RequireRole(models.UserRoleAdmin),
+		h.Apply,
+	)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 审计记录处理器
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler 创建审计记录处理器实例
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// QueryEvents 查询审计记录
+// 支持的查询参数：user、action、resource_type、resource_id、since/until（RFC3339）、cursor、limit
+func (h *AuditHandler) QueryEvents(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	events, nextCursor, err := h.auditService.QueryEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get audit events",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      events,
+		"count":       len(events),
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseAuditFilter 从查询参数解析审计记录过滤条件
+func parseAuditFilter(c *gin.Context) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{
+		Username:     c.Query("user"),
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		Limit:        100, // 默认限制
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			filter.Limit = parsedLimit
+		}
+	}
+	if filter.Limit > maxConnectionLogLimit {
+		filter.Limit = maxConnectionLogLimit
+	}
+
+	if resourceID := c.Query("resource_id"); resourceID != "" {
+		id, err := strconv.ParseUint(resourceID, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid resource_id: %v", err)
+		}
+		filter.ResourceID = uint(id)
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since: %v", err)
+		}
+		filter.Since = &t
+	}
+
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return filter, fmt.Errorf("invalid until: %v", err)
+		}
+		filter.Until = &t
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		beforeID, err := strconv.ParseUint(cursor, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor: %v", err)
+		}
+		filter.BeforeID = uint(beforeID)
+	}
+
+	return filter, nil
+}
+
+// recordAudit 记录当前请求执行的管理操作，操作者和来源IP从请求上下文获取
+func recordAudit(c *gin.Context, auditService service.AuditService, action, resourceType string, resourceID uint, resourceName string, changes models.AuditChanges) {
+	event := &models.AuditEvent{
+		SourceIP:     c.ClientIP(),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ResourceName: resourceName,
+		Changes:      changes,
+	}
+	if user := middleware.CurrentUser(c); user != nil {
+		event.UserID = user.ID
+		event.Username = user.Username
+	}
+	if token := middleware.CurrentToken(c); token != nil {
+		event.TokenPrefix = token.Prefix
+	}
+	auditService.Record(event)
+}
+
+// RegisterRoutes 注册路由
+func (h *AuditHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/audit", middleware.RequireScope(models.ScopeAuditRead), middleware.RequireRole(models.UserRoleAdmin), h.QueryEvents)
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler 认证及用户管理处理器
+type AuthHandler struct {
+	authService   service.AuthService
+	accessService service.AccessService
+	cookieSecure  bool
+}
+
+// NewAuthHandler 创建认证处理器实例
+func NewAuthHandler(authService service.AuthService, accessService service.AccessService, cookieSecure bool) *AuthHandler {
+	return &AuthHandler{
+		authService:   authService,
+		accessService: accessService,
+		cookieSecure:  cookieSecure,
+	}
+}
+
+// LoginRequest 登录请求
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// CreateUserRequest 创建用户请求
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin operator viewer"`
+}
+
+// UpdateUserRequest 修改用户请求，角色为空时保持不变
+type UpdateUserRequest struct {
+	Role     string `json:"role" binding:"omitempty,oneof=admin operator viewer"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Login 登录并设置会话Cookie
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.authService.Login(req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid username or password",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to log in",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	maxAge := int(time.Until(result.ExpiresAt).Seconds())
+	h.setCookie(c, middleware.SessionCookieName, result.Token, maxAge, true)
+	h.setCookie(c, middleware.CSRFCookieName, result.CSRFToken, maxAge, false)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Logged in successfully",
+		"user":       result.User,
+		"csrf_token": result.CSRFToken,
+		"expires_at": result.ExpiresAt,
+	})
+}
+
+// Logout 退出登录并清除会话Cookie
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if token, err := c.Cookie(middleware.SessionCookieName); err == nil {
+		if err := h.authService.Logout(token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to log out",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	h.setCookie(c, middleware.SessionCookieName, "", -1, true)
+	h.setCookie(c, middleware.CSRFCookieName, "", -1, false)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// GetCurrentUser 获取当前登录用户
+func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
+	session := middleware.CurrentSession(c)
+	c.JSON(http.StatusOK, gin.H{
+		"user":       middleware.CurrentUser(c),
+		"csrf_token": session.CSRFToken,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+// ChangePassword 修改当前用户的密码，修改后需要重新登录
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if err := h.authService.ChangePassword(user.ID, req.OldPassword, req.NewPassword); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to change password",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.setCookie(c, middleware.SessionCookieName, "", -1, true)
+	h.setCookie(c, middleware.CSRFCookieName, "", -1, false)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed successfully, please log in again",
+	})
+}
+
+// GetAllUsers 获取所有用户
+func (h *AuthHandler) GetAllUsers(c *gin.Context) {
+	users, err := h.authService.GetAllUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get users",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+		"count": len(users),
+	})
+}
+
+// CreateUser 创建用户
+func (h *AuthHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.authService.CreateUser(req.Username, req.Password, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to create user",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "User created successfully",
+		"user":    user,
+	})
+}
+
+// UpdateUser 修改用户角色和禁用状态，不能修改自己
+func (h *AuthHandler) UpdateUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if middleware.CurrentUser(c).ID == uint(id) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Cannot change the role or status of the current user",
+		})
+		return
+	}
+
+	user, err := h.authService.UpdateUser(uint(id), req.Role, req.Disabled)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to update user",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User updated successfully",
+		"user":    user,
+	})
+}
+
+// DeleteUser 删除用户，不能删除自己，被删除用户拥有的主机和隧道转移给当前用户
+func (h *AuthHandler) DeleteUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	if middleware.CurrentUser(c).ID == uint(id) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Cannot delete the current user",
+		})
+		return
+	}
+
+	if err := h.accessService.TransferOwnership(uint(id), middleware.CurrentUser(c).ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to transfer resources",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.DeleteUser(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to delete user",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deleted successfully",
+	})
+}
+
+// setCookie 设置认证相关Cookie，maxAge 为负数时删除Cookie
+func (h *AuthHandler) setCookie(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, "/", "", h.cookieSecure, httpOnly)
+}
+
+// RegisterPublicRoutes 注册无需登录的路由
+func (h *AuthHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.POST("/auth/login", h.Login)
+}
+
+// RegisterRoutes 注册需要登录的路由
+func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
+	// 用户和认证管理只允许通过登录会话操作
+	auth := router.Group("/auth", middleware.RequireSession())
+	{
+		auth.POST("/logout", h.Logout)
+		auth.GET("/me", h.GetCurrentUser)
+		auth.PUT("/password", h.ChangePassword)
+	}
+
+	users := router.Group("/users", middleware.RequireSession(), middleware.RequireRole(models.UserRoleAdmin))
+	{
+		users.GET("", h.GetAllUsers)
+		users.POST("", h.CreateUser)
+		users.PUT("/:id", h.UpdateUser)
+		users.DELETE("/:id", h.DeleteUser)
+	}
+}
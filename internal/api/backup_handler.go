@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// BackupHandler 备份与恢复处理器
+type BackupHandler struct {
+	backupService service.BackupService
+	auditService  service.AuditService
+}
+
+// NewBackupHandler 创建备份与恢复处理器实例
+func NewBackupHandler(backupService service.BackupService, auditService service.AuditService) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+		auditService:  auditService,
+	}
+}
+
+// ExportBackupRequest 导出备份请求
+type ExportBackupRequest struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// ImportBackupRequest 恢复备份请求
+type ImportBackupRequest struct {
+	Passphrase string                 `json:"passphrase" binding:"required"`
+	Strategy   string                 `json:"strategy"` // skip, overwrite, rename，默认 skip
+	Archive    *service.BackupArchive `json:"archive" binding:"required"`
+}
+
+// ExportBackup 导出所有主机和隧道的备份文件，凭据使用请求中的口令加密
+func (h *BackupHandler) ExportBackup(c *gin.Context) {
+	var req ExportBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	archive, err := h.backupService.Export(req.Passphrase)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to export backup",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to export backup",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionExport, models.ResourceTypeExport, 0, "backup", nil)
+
+	filename := fmt.Sprintf("drilling-backup-%s.json", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// ImportBackup 恢复备份，名称冲突时按 strategy 处理
+func (h *BackupHandler) ImportBackup(c *gin.Context) {
+	var req ImportBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.Strategy == "" {
+		req.Strategy = service.BackupConflictSkip
+	}
+
+	result, err := h.backupService.Import(req.Archive, req.Passphrase, req.Strategy, currentUserID(c))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrBackupPassphrase) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to import backup",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.recordImport(c, models.ResourceTypeHost, result.Hosts)
+	h.recordImport(c, models.ResourceTypeTunnel, result.Tunnels)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// recordImport 为导入时创建或覆盖的资源记录审计事件
+func (h *BackupHandler) recordImport(c *gin.Context, resourceType string, items []service.BackupImportItem) {
+	for _, item := range items {
+		switch item.Action {
+		case service.BackupImportCreated, service.BackupImportRenamed:
+			recordAudit(c, h.auditService, models.AuditActionCreate, resourceType, item.ID, item.Name, nil)
+		case service.BackupImportUpdated:
+			recordAudit(c, h.auditService, models.AuditActionUpdate, resourceType, item.ID, item.Name, nil)
+		}
+	}
+}
+
+// RegisterRoutes 注册路由
+// 备份包含所有主机的凭据，只允许管理员访问
+func (h *BackupHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/export/backup",
+		middleware.RequireScope(models.ScopeExportRead),
+		middleware.RequireRole(models.UserRoleAdmin),
+		h.ExportBackup,
+	)
+	router.POST("/import/backup",
+		middleware.RequireScope(models.ScopeHostsWrite),
+		middleware.RequireScope(models.ScopeTunnelsWrite),
+		middleware.RequireRole(models.UserRoleAdmin),
+		h.ImportBackup,
+	)
+}
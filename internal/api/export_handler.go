@@ -1,10 +1,14 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/KodaTao/drilling/internal/models"
 	"github.com/KodaTao/drilling/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -12,31 +16,116 @@ import (
 // ExportHandler 导出处理器
 type ExportHandler struct {
 	clashExportService service.ClashExportService
+	proxyExportService service.ProxyExportService
+	sshExportService   service.SSHExportService
+	profileService     service.ExportProfileService
+	auditService       service.AuditService
 }
 
 // NewExportHandler 创建导出处理器实例
-func NewExportHandler(clashExportService service.ClashExportService) *ExportHandler {
+func NewExportHandler(clashExportService service.ClashExportService, proxyExportService service.ProxyExportService, sshExportService service.SSHExportService, profileService service.ExportProfileService, auditService service.AuditService) *ExportHandler {
 	return &ExportHandler{
 		clashExportService: clashExportService,
+		proxyExportService: proxyExportService,
+		sshExportService:   sshExportService,
+		profileService:     profileService,
+		auditService:       auditService,
 	}
 }
 
+// exportProfile 读取 profile 查询参数对应的导出模板，未指定时返回 nil 使用默认模板
+func (h *ExportHandler) exportProfile(c *gin.Context) (*models.ExportProfile, bool) {
+	ref := strings.TrimSpace(c.Query("profile"))
+	if ref == "" {
+		return nil, true
+	}
+	profile, err := h.profileService.ResolveProfile(ref)
+	if err != nil {
+		if errors.Is(err, service.ErrExportProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Export profile not found",
+				"details": err.Error(),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get export profile",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return profile, true
+}
+
+// exportOptions 读取导出选项查询参数，导出模板中的同名设置在导出时合并，失败时写入错误响应
+// 只有 POST 导出会按需启动隧道，GET 导出可能由跨站链接触发，不启动任何隧道
+func exportOptions(c *gin.Context) (service.ExportOptions, bool) {
+	selector, ok := parseSelector(c)
+	if !ok {
+		return service.ExportOptions{}, false
+	}
+	opts := service.ExportOptions{
+		IncludeInactive: c.Query("include_inactive") == "true",
+		Selector:        selector,
+	}
+	if c.Request.Method == http.MethodPost {
+		opts.StartOnDemand = true
+		return opts, true
+	}
+	if c.Query("start_on_demand") == "true" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid export request",
+			"details": "starting tunnels on demand requires POST",
+		})
+		return service.ExportOptions{}, false
+	}
+	opts.NoStart = true
+	return opts, true
+}
+
+// recordExportStarts 为导出时按需启动的每个隧道记录启动审计
+func recordExportStarts(c *gin.Context, auditService service.AuditService, nodes []service.ProxyNode) {
+	for _, node := range nodes {
+		recordAudit(c, auditService, models.AuditActionStart, models.ResourceTypeTunnel, node.TunnelID, node.TunnelName, nil)
+	}
+}
+
+// exportName 返回审计日志中的导出名称，使用模板时附带模板名称
+func exportName(format string, profile *models.ExportProfile) string {
+	if profile == nil {
+		return format
+	}
+	return format + ":" + profile.Name
+}
+
 // ExportClashConfig 导出Clash配置
 // @Summary 导出Clash配置
-// @Description 将所有活跃的SOCKS5隧道导出为Clash配置文件
+// @Description 将所有活跃的SOCKS5隧道导出为Clash配置文件，可以通过 profile 参数指定导出模板
 // @Tags export
 // @Accept json
 // @Produce application/x-yaml
+// @Param profile query string false "导出模板名称或ID"
+// @Param include_inactive query bool false "同时导出未运行的SOCKS5隧道"
+// @Param selector query string false "标签选择器，例如 env=prod,team in (a,b)"
 // @Success 200 {string} string "Clash配置YAML文件内容"
 // @Failure 404 {object} gin.H "没有找到活跃的SOCKS5隧道"
 // @Failure 500 {object} gin.H "生成配置失败"
 // @Router /api/v1/export/clash [get]
 func (h *ExportHandler) ExportClashConfig(c *gin.Context) {
+	profile, ok := h.exportProfile(c)
+	if !ok {
+		return
+	}
+	opts, ok := exportOptions(c)
+	if !ok {
+		return
+	}
+
 	// 生成Clash配置
-	yamlData, err := h.clashExportService.ExportClashConfigYAML()
+	yamlData, err := h.clashExportService.ExportClashConfigYAML(profile, opts)
 	if err != nil {
 		// 检查是否是因为没有活跃隧道
-		if err.Error() == "no active SOCKS5 tunnels found" {
+		if errors.Is(err, service.ErrNoActiveSocks5Tunnels) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "No active SOCKS5 tunnels found",
 				"message": "Please start at least one SOCKS5 tunnel before exporting Clash configuration",
@@ -53,6 +142,8 @@ func (h *ExportHandler) ExportClashConfig(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionExport, models.ResourceTypeExport, 0, exportName("clash", profile), nil)
+
 	// 设置响应头，使浏览器下载文件
 	filename := fmt.Sprintf("clash-config-%s.yaml", time.Now().Format("20060102-150405"))
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
@@ -69,16 +160,29 @@ func (h *ExportHandler) ExportClashConfig(c *gin.Context) {
 // @Tags export
 // @Accept json
 // @Produce json
+// @Param profile query string false "导出模板名称或ID"
+// @Param include_inactive query bool false "同时预览未运行的SOCKS5隧道"
+// @Param selector query string false "标签选择器，例如 env=prod,team in (a,b)"
 // @Success 200 {object} service.ClashConfig "Clash配置预览"
 // @Failure 404 {object} gin.H "没有找到活跃的SOCKS5隧道"
 // @Failure 500 {object} gin.H "生成配置失败"
 // @Router /api/v1/export/clash/preview [get]
 func (h *ExportHandler) GetClashConfigPreview(c *gin.Context) {
+	profile, ok := h.exportProfile(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := exportOptions(c)
+	if !ok {
+		return
+	}
+
 	// 生成Clash配置
-	config, err := h.clashExportService.GenerateClashConfig()
+	config, err := h.clashExportService.GenerateClashConfig(profile, opts)
 	if err != nil {
 		// 检查是否是因为没有活跃隧道
-		if err.Error() == "no active SOCKS5 tunnels found" {
+		if errors.Is(err, service.ErrNoActiveSocks5Tunnels) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "No active SOCKS5 tunnels found",
 				"message": "Please start at least one SOCKS5 tunnel before generating Clash configuration",
@@ -95,8 +199,8 @@ func (h *ExportHandler) GetClashConfigPreview(c *gin.Context) {
 		return
 	}
 
-	// 获取活跃隧道信息
-	tunnels, err := h.clashExportService.GetActiveSocks5Tunnels()
+	// 获取隧道信息
+	tunnels, err := h.clashExportService.GetSocks5Tunnels(opts.IncludeInactive)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get tunnel information",
@@ -116,16 +220,17 @@ func (h *ExportHandler) GetClashConfigPreview(c *gin.Context) {
 
 // GetSocks5TunnelsStatus 获取SOCKS5隧道状态
 // @Summary 获取SOCKS5隧道状态
-// @Description 获取所有SOCKS5隧道的状态信息，用于判断是否可以导出配置
+// @Description 获取SOCKS5隧道的实际运行状态，用于判断是否可以导出配置，include_inactive 为true时同时返回未运行的隧道
 // @Tags export
 // @Accept json
 // @Produce json
+// @Param include_inactive query bool false "同时返回未运行的SOCKS5隧道"
 // @Success 200 {object} gin.H "SOCKS5隧道状态信息"
 // @Failure 500 {object} gin.H "获取状态失败"
 // @Router /api/v1/export/socks5/status [get]
 func (h *ExportHandler) GetSocks5TunnelsStatus(c *gin.Context) {
-	// 获取活跃的SOCKS5隧道
-	activeTunnels, err := h.clashExportService.GetActiveSocks5Tunnels()
+	// 获取SOCKS5隧道，状态以实际运行状态为准
+	tunnels, err := h.clashExportService.GetSocks5Tunnels(c.Query("include_inactive") == "true")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get SOCKS5 tunnel status",
@@ -135,8 +240,12 @@ func (h *ExportHandler) GetSocks5TunnelsStatus(c *gin.Context) {
 	}
 
 	// 构建状态信息
-	tunnelStatus := make([]gin.H, 0, len(activeTunnels))
-	for _, tunnel := range activeTunnels {
+	activeCount := 0
+	tunnelStatus := make([]gin.H, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		if models.IsRunningStatus(tunnel.Status) {
+			activeCount++
+		}
 		tunnelStatus = append(tunnelStatus, gin.H{
 			"id":            tunnel.ID,
 			"name":          tunnel.Name,
@@ -150,9 +259,167 @@ func (h *ExportHandler) GetSocks5TunnelsStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":        "SOCKS5 tunnel status retrieved successfully",
-		"active_count":   len(activeTunnels),
-		"can_export":     len(activeTunnels) > 0,
+		"active_count":   activeCount,
+		"can_export":     len(tunnels) > 0,
 		"tunnels":        tunnelStatus,
 		"last_check":     time.Now().Format("2006-01-02 15:04:05"),
 	})
-}
\ No newline at end of file
+}
+
+// ExportProxyConfig 导出代理客户端配置
+// @Summary 导出代理客户端配置
+// @Description 将所有活跃的SOCKS5隧道导出为指定代理客户端的配置，支持 clash、sing-box、xray、surge 和 pac
+// @Description 使用 POST 时按需启动未运行的隧道，首个客户端连接时才建立SSH连接
+// @Tags export
+// @Produce plain
+// @Param format path string true "导出格式"
+// @Param profile query string false "导出模板名称或ID"
+// @Param include_inactive query bool false "同时导出未运行的SOCKS5隧道"
+// @Param selector query string false "标签选择器，例如 env=prod,team in (a,b)"
+// @Success 200 {string} string "配置文件内容"
+// @Failure 404 {object} gin.H "不支持的格式或没有找到活跃的SOCKS5隧道"
+// @Router /api/v1/export/{format} [get]
+// @Router /api/v1/export/{format} [post]
+func (h *ExportHandler) ExportProxyConfig(c *gin.Context) {
+	format := c.Param("format")
+	profile, ok := h.exportProfile(c)
+	if !ok {
+		return
+	}
+	opts, ok := exportOptions(c)
+	if !ok {
+		return
+	}
+	export, err := h.proxyExportService.Export(format, profile, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownExportFormat):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Unknown export format",
+				"details": err.Error(),
+				"formats": h.proxyExportService.Formats(),
+			})
+		case errors.Is(err, service.ErrNoActiveSocks5Tunnels):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "No active SOCKS5 tunnels found",
+				"message": "Please start at least one SOCKS5 tunnel before exporting",
+				"details": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to generate configuration",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	recordExportStarts(c, h.auditService, export.Started)
+	recordAudit(c, h.auditService, models.AuditActionExport, models.ResourceTypeExport, 0, exportName(format, profile), nil)
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", export.Filename))
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
+
+// ExportSSHConfig 导出OpenSSH客户端配置
+// @Summary 导出OpenSSH配置
+// @Description 将选中的主机和隧道导出为 ~/.ssh/config 片段，包含 LocalForward、RemoteForward、DynamicForward 和 ProxyJump
+// @Tags export
+// @Produce plain
+// @Param hosts query string false "主机ID列表，逗号分隔"
+// @Param tunnels query string false "隧道ID列表，逗号分隔"
+// @Param selector query string false "标签选择器，只导出标签匹配的隧道"
+// @Success 200 {string} string "OpenSSH配置"
+// @Router /api/v1/export/ssh-config [get]
+func (h *ExportHandler) ExportSSHConfig(c *gin.Context) {
+	h.exportSSH(c, "ssh-config", "ssh_config", h.sshExportService.ExportSSHConfig)
+}
+
+// ExportSSHCommands 导出ssh命令
+// @Summary 导出ssh命令
+// @Description 将选中的隧道导出为等价的 ssh -L/-R/-D 命令，每个主机一条命令
+// @Tags export
+// @Produce plain
+// @Param hosts query string false "主机ID列表，逗号分隔"
+// @Param tunnels query string false "隧道ID列表，逗号分隔"
+// @Param selector query string false "标签选择器，只导出标签匹配的隧道"
+// @Success 200 {string} string "ssh命令"
+// @Router /api/v1/export/ssh-commands [get]
+func (h *ExportHandler) ExportSSHCommands(c *gin.Context) {
+	h.exportSSH(c, "ssh-commands", "drilling-tunnels.sh", h.sshExportService.ExportSSHCommands)
+}
+
+// ExportAutosshUnits 导出autossh systemd单元
+// @Summary 导出autossh systemd单元
+// @Description 将选中的隧道导出为运行 autossh 的 systemd 单元，每个主机一个单元
+// @Tags export
+// @Produce plain
+// @Param hosts query string false "主机ID列表，逗号分隔"
+// @Param tunnels query string false "隧道ID列表，逗号分隔"
+// @Param selector query string false "标签选择器，只导出标签匹配的隧道"
+// @Success 200 {string} string "systemd单元"
+// @Router /api/v1/export/autossh [get]
+func (h *ExportHandler) ExportAutosshUnits(c *gin.Context) {
+	h.exportSSH(c, "autossh", "drilling-autossh.service", h.sshExportService.ExportAutosshUnits)
+}
+
+// exportSSH 解析选择的主机和隧道，生成导出内容并作为文本文件返回
+func (h *ExportHandler) exportSSH(c *gin.Context, format, filename string, generate func(service.ExportSelection) ([]byte, error)) {
+	var selection service.ExportSelection
+	var err error
+	if selection.HostIDs, err = parseIDList(c.Query("hosts")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid host IDs",
+			"details": err.Error(),
+		})
+		return
+	}
+	if selection.TunnelIDs, err = parseIDList(c.Query("tunnels")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid tunnel IDs",
+			"details": err.Error(),
+		})
+		return
+	}
+	var ok bool
+	if selection.Selector, ok = parseSelector(c); !ok {
+		return
+	}
+
+	data, err := generate(selection)
+	if err != nil {
+		if errors.Is(err, service.ErrNoTunnelsToExport) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "No tunnels found to export",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate export",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionExport, models.ResourceTypeExport, 0, format, nil)
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// parseIDList 解析逗号分隔的ID列表，为空时返回nil
+func parseIDList(value string) ([]uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", part)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
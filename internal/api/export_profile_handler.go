@@ -0,0 +1,182 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportProfileHandler 导出模板处理器
+type ExportProfileHandler struct {
+	profileService service.ExportProfileService
+	auditService   service.AuditService
+}
+
+// NewExportProfileHandler 创建导出模板处理器实例
+func NewExportProfileHandler(profileService service.ExportProfileService, auditService service.AuditService) *ExportProfileHandler {
+	return &ExportProfileHandler{
+		profileService: profileService,
+		auditService:   auditService,
+	}
+}
+
+// GetProfiles 获取所有导出模板
+func (h *ExportProfileHandler) GetProfiles(c *gin.Context) {
+	profiles, err := h.profileService.GetAllProfiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get export profiles",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profiles": profiles,
+		"count":    len(profiles),
+	})
+}
+
+// GetProfile 获取导出模板详情
+func (h *ExportProfileHandler) GetProfile(c *gin.Context) {
+	profile, ok := h.loadProfile(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile": profile,
+	})
+}
+
+// CreateProfile 创建导出模板
+func (h *ExportProfileHandler) CreateProfile(c *gin.Context) {
+	var profile models.ExportProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	profile.ID = 0
+	if err := h.profileService.CreateProfile(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to create export profile",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeExportProfile, profile.ID, profile.Name, service.DiffFields(nil, &profile))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Export profile created successfully",
+		"profile": profile,
+	})
+}
+
+// UpdateProfile 更新导出模板
+func (h *ExportProfileHandler) UpdateProfile(c *gin.Context) {
+	existing, ok := h.loadProfile(c)
+	if !ok {
+		return
+	}
+
+	var profile models.ExportProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	profile.ID = existing.ID
+	if err := h.profileService.UpdateProfile(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to update export profile",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionUpdate, models.ResourceTypeExportProfile, profile.ID, profile.Name, service.DiffFields(existing, &profile))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Export profile updated successfully",
+		"profile": profile,
+	})
+}
+
+// DeleteProfile 删除导出模板
+func (h *ExportProfileHandler) DeleteProfile(c *gin.Context) {
+	existing, ok := h.loadProfile(c)
+	if !ok {
+		return
+	}
+
+	if err := h.profileService.DeleteProfile(existing.ID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrExportProfileInUse) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to delete export profile",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionDelete, models.ResourceTypeExportProfile, existing.ID, existing.Name, service.DiffFields(existing, nil))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Export profile deleted successfully",
+	})
+}
+
+// loadProfile 读取路径参数中的导出模板，失败时写入错误响应
+func (h *ExportProfileHandler) loadProfile(c *gin.Context) (*models.ExportProfile, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid export profile ID",
+		})
+		return nil, false
+	}
+
+	profile, err := h.profileService.GetProfile(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrExportProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Export profile not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get export profile",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return profile, true
+}
+
+// RegisterRoutes 注册路由
+// 导出模板决定导出哪些隧道，与导出接口一样只允许管理员访问
+func (h *ExportProfileHandler) RegisterRoutes(router *gin.RouterGroup) {
+	profiles := router.Group("/export-profiles", middleware.RequireRole(models.UserRoleAdmin))
+	{
+		profiles.GET("", middleware.RequireScope(models.ScopeExportRead), h.GetProfiles)
+		profiles.GET("/:id", middleware.RequireScope(models.ScopeExportRead), h.GetProfile)
+		profiles.POST("", middleware.RequireScope(models.ScopeExportWrite), h.CreateProfile)
+		profiles.PUT("/:id", middleware.RequireScope(models.ScopeExportWrite), h.UpdateProfile)
+		profiles.DELETE("/:id", middleware.RequireScope(models.ScopeExportWrite), h.DeleteProfile)
+	}
+}
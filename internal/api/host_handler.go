@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/KodaTao/drilling/internal/middleware"
 	"github.com/KodaTao/drilling/internal/models"
 	"github.com/KodaTao/drilling/internal/service"
 	"github.com/gin-gonic/gin"
@@ -11,13 +12,17 @@ import (
 
 // HostHandler 主机处理器
 type HostHandler struct {
-	hostService service.HostService
+	hostService   service.HostService
+	accessService service.AccessService
+	auditService  service.AuditService
 }
 
 // NewHostHandler 创建主机处理器实例
-func NewHostHandler(hostService service.HostService) *HostHandler {
+func NewHostHandler(hostService service.HostService, accessService service.AccessService, auditService service.AuditService) *HostHandler {
 	return &HostHandler{
-		hostService: hostService,
+		hostService:   hostService,
+		accessService: accessService,
+		auditService:  auditService,
 	}
 }
 
@@ -32,6 +37,15 @@ func (h *HostHandler) CreateHost(c *gin.Context) {
 		return
 	}
 
+	if !requireCreate(c, h.accessService) {
+		return
+	}
+	if !authorizeJumpHost(c, h.accessService, &host, 0) {
+		return
+	}
+	host.OwnerID = currentUserID(c)
+	submitted := submittedSecrets(&host)
+
 	if err := h.hostService.CreateHost(&host); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create host",
@@ -40,9 +54,13 @@ func (h *HostHandler) CreateHost(c *gin.Context) {
 		return
 	}
 
+	changes := service.DiffFields(nil, &host)
+	markSecretChanges(changes, submitted)
+	recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeHost, host.ID, host.Name, changes)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Host created successfully",
-		"host":    host,
+		"host":    models.NewHostResponse(&host),
 	})
 }
 
@@ -57,6 +75,10 @@ func (h *HostHandler) GetHost(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorizeHost(c, h.accessService, uint(id), models.PermissionView); !ok {
+		return
+	}
+
 	host, err := h.hostService.GetHost(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -66,19 +88,18 @@ func (h *HostHandler) GetHost(c *gin.Context) {
 		return
 	}
 
-	// 清除敏感信息用于返回
-	hostResponse := *host
-	hostResponse.Password = ""
-	hostResponse.PrivateKey = ""
-	hostResponse.Passphrase = ""
-
 	c.JSON(http.StatusOK, gin.H{
-		"host": hostResponse,
+		"host": models.NewHostResponse(host),
 	})
 }
 
 // GetAllHosts 获取所有主机
 func (h *HostHandler) GetAllHosts(c *gin.Context) {
+	selector, ok := parseSelector(c)
+	if !ok {
+		return
+	}
+
 	hosts, err := h.hostService.GetAllHosts()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -88,14 +109,19 @@ func (h *HostHandler) GetAllHosts(c *gin.Context) {
 		return
 	}
 
-	// 清除敏感信息用于返回
-	var hostsResponse []models.Host
-	for _, host := range hosts {
-		hostResponse := host
-		hostResponse.Password = ""
-		hostResponse.PrivateKey = ""
-		hostResponse.Passphrase = ""
-		hostsResponse = append(hostsResponse, hostResponse)
+	scope, err := h.accessService.NewScope(middleware.CurrentUser(c))
+	if err != nil {
+		respondAccessError(c, err, "Host not found")
+		return
+	}
+
+	// 过滤掉当前用户无权查看和标签不匹配的主机，返回不包含凭据的响应
+	hostsResponse := make([]models.HostResponse, 0, len(hosts))
+	for i := range hosts {
+		if !scope.CanViewHost(&hosts[i]) || !selector.Matches(hosts[i].Labels) {
+			continue
+		}
+		hostsResponse = append(hostsResponse, models.NewHostResponse(&hosts[i]))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -115,6 +141,11 @@ func (h *HostHandler) UpdateHost(c *gin.Context) {
 		return
 	}
 
+	existing, ok := authorizeHost(c, h.accessService, uint(id), models.PermissionEdit)
+	if !ok {
+		return
+	}
+
 	var host models.Host
 	if err := c.ShouldBindJSON(&host); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -125,6 +156,15 @@ func (h *HostHandler) UpdateHost(c *gin.Context) {
 	}
 
 	host.ID = uint(id)
+	if !authorizeJumpHost(c, h.accessService, &host, existing.JumpHostID) {
+		return
+	}
+	submitted := submittedSecrets(&host)
+
+	// 只有管理员可以转移所有者
+	if host.OwnerID == 0 || !isAdmin(c) {
+		host.OwnerID = existing.OwnerID
+	}
 
 	if err := h.hostService.UpdateHost(&host); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -134,9 +174,13 @@ func (h *HostHandler) UpdateHost(c *gin.Context) {
 		return
 	}
 
+	changes := service.DiffFields(existing, &host)
+	markSecretChanges(changes, submitted)
+	recordAudit(c, h.auditService, models.AuditActionUpdate, models.ResourceTypeHost, host.ID, host.Name, changes)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Host updated successfully",
-		"host":    host,
+		"host":    models.NewHostResponse(&host),
 	})
 }
 
@@ -151,6 +195,11 @@ func (h *HostHandler) DeleteHost(c *gin.Context) {
 		return
 	}
 
+	existing, ok := authorizeHost(c, h.accessService, uint(id), models.PermissionEdit)
+	if !ok {
+		return
+	}
+
 	if err := h.hostService.DeleteHost(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to delete host",
@@ -159,6 +208,8 @@ func (h *HostHandler) DeleteHost(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionDelete, models.ResourceTypeHost, existing.ID, existing.Name, service.DiffFields(existing, nil))
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Host deleted successfully",
 	})
@@ -175,6 +226,10 @@ func (h *HostHandler) TestConnection(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorizeHost(c, h.accessService, uint(id), models.PermissionControl); !ok {
+		return
+	}
+
 	if err := h.hostService.TestConnection(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -201,6 +256,10 @@ func (h *HostHandler) CheckStatus(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorizeHost(c, h.accessService, uint(id), models.PermissionControl); !ok {
+		return
+	}
+
 	if err := h.hostService.CheckHostStatus(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -227,16 +286,47 @@ func (h *HostHandler) CheckStatus(c *gin.Context) {
 	})
 }
 
+// submittedSecrets 记录请求中提交了哪些凭据，需要在加密和合并原有值之前调用
+func submittedSecrets(host *models.Host) []string {
+	var fields []string
+	if host.Password != "" {
+		fields = append(fields, "password")
+	}
+	if host.PrivateKey != "" {
+		fields = append(fields, "private_key")
+	}
+	if host.Passphrase != "" {
+		fields = append(fields, "passphrase")
+	}
+	return fields
+}
+
+// markSecretChanges 在审计记录中标记被修改的凭据，不记录凭据内容
+func markSecretChanges(changes models.AuditChanges, fields []string) {
+	for _, field := range fields {
+		changes[field] = models.FieldChange{Before: models.AuditRedacted, After: models.AuditRedacted}
+	}
+}
+
+// authorizeJumpHost 经由跳板机连接会使用跳板机的凭据，设置或更换跳板机时要求对跳板机有控制权限
+func authorizeJumpHost(c *gin.Context, accessService service.AccessService, host *models.Host, previousJumpHostID uint) bool {
+	if host.JumpHostID == 0 || host.JumpHostID == previousJumpHostID {
+		return true
+	}
+	_, ok := authorizeHost(c, accessService, host.JumpHostID, models.PermissionControl)
+	return ok
+}
+
 // RegisterRoutes 注册路由
 func (h *HostHandler) RegisterRoutes(router *gin.RouterGroup) {
 	hosts := router.Group("/hosts")
 	{
-		hosts.POST("", h.CreateHost)
-		hosts.GET("", h.GetAllHosts)
-		hosts.GET("/:id", h.GetHost)
-		hosts.PUT("/:id", h.UpdateHost)
-		hosts.DELETE("/:id", h.DeleteHost)
-		hosts.POST("/:id/test", h.TestConnection)
-		hosts.POST("/:id/status", h.CheckStatus)
+		hosts.POST("", middleware.RequireScope(models.ScopeHostsWrite), h.CreateHost)
+		hosts.GET("", middleware.RequireScope(models.ScopeHostsRead), h.GetAllHosts)
+		hosts.GET("/:id", middleware.RequireScope(models.ScopeHostsRead), h.GetHost)
+		hosts.PUT("/:id", middleware.RequireScope(models.ScopeHostsWrite), h.UpdateHost)
+		hosts.DELETE("/:id", middleware.RequireScope(models.ScopeHostsWrite), h.DeleteHost)
+		hosts.POST("/:id/test", middleware.RequireScope(models.ScopeHostsRead), h.TestConnection)
+		hosts.POST("/:id/status", middleware.RequireScope(models.ScopeHostsRead), h.CheckStatus)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/KodaTao/drilling/internal/labels"
+	"github.com/gin-gonic/gin"
+)
+
+// parseSelector 读取 selector 查询参数中的标签选择器，格式错误时写入错误响应并返回false
+func parseSelector(c *gin.Context) (labels.Selector, bool) {
+	selector, err := labels.Parse(c.Query("selector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid label selector",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return selector, true
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler 连接会话处理器
+type SessionHandler struct {
+	sessionService service.SessionService
+	accessService  service.AccessService
+}
+
+// NewSessionHandler 创建连接会话处理器实例
+func NewSessionHandler(sessionService service.SessionService, accessService service.AccessService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		accessService:  accessService,
+	}
+}
+
+// QuerySessions 查询连接会话
+// 支持的查询参数：tunnel_id、client、target、since/until（RFC3339）、cursor、limit
+func (h *SessionHandler) QuerySessions(c *gin.Context) {
+	filter, err := parseSessionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if tunnelIDStr := c.Query("tunnel_id"); tunnelIDStr != "" {
+		tunnelID, err := strconv.ParseUint(tunnelIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid tunnel ID",
+			})
+			return
+		}
+		if _, ok := authorizeTunnel(c, h.accessService, uint(tunnelID), models.PermissionView); !ok {
+			return
+		}
+		filter.TunnelID = uint(tunnelID)
+	} else {
+		// 非管理员只能查询自己可以查看的隧道
+		filter.TunnelIDs, err = h.accessService.VisibleTunnelIDs(middleware.CurrentUser(c))
+		if err != nil {
+			respondAccessError(c, err, "Tunnel not found")
+			return
+		}
+	}
+
+	h.respondSessions(c, filter)
+}
+
+// GetTunnelSessions 查询指定隧道的连接会话
+func (h *SessionHandler) GetTunnelSessions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tunnel ID",
+		})
+		return
+	}
+
+	if _, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionView); !ok {
+		return
+	}
+
+	filter, err := parseSessionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+	filter.TunnelID = uint(id)
+
+	h.respondSessions(c, filter)
+}
+
+// GetSession 获取单个连接会话
+func (h *SessionHandler) GetSession(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid session ID",
+		})
+		return
+	}
+
+	session, err := h.sessionService.GetSession(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Session not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if _, ok := authorizeTunnel(c, h.accessService, session.TunnelID, models.PermissionView); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+	})
+}
+
+// respondSessions 执行会话查询并返回分页结果
+func (h *SessionHandler) respondSessions(c *gin.Context, filter repository.SessionFilter) {
+	sessions, nextCursor, err := h.sessionService.QuerySessions(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get sessions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":    sessions,
+		"count":       len(sessions),
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseSessionFilter 从查询参数解析连接会话过滤条件
+func parseSessionFilter(c *gin.Context) (repository.SessionFilter, error) {
+	filter := repository.SessionFilter{
+		ClientAddr: c.Query("client"),
+		TargetAddr: c.Query("target"),
+		Limit:      100, // 默认限制
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			filter.Limit = parsedLimit
+		}
+	}
+	if filter.Limit > maxConnectionLogLimit {
+		filter.Limit = maxConnectionLogLimit
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since: %v", err)
+		}
+		filter.Since = &t
+	}
+
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return filter, fmt.Errorf("invalid until: %v", err)
+		}
+		filter.Until = &t
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		beforeID, err := strconv.ParseUint(cursor, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor: %v", err)
+		}
+		filter.BeforeID = uint(beforeID)
+	}
+
+	return filter, nil
+}
+
+// RegisterRoutes 注册路由
+func (h *SessionHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/sessions", middleware.RequireScope(models.ScopeLogsRead), h.QuerySessions)
+	router.GET("/sessions/:id", middleware.RequireScope(models.ScopeLogsRead), h.GetSession)
+	router.GET("/tunnels/:id/sessions", middleware.RequireScope(models.ScopeLogsRead), h.GetTunnelSessions)
+}
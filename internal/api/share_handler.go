@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ShareHandler 主机和隧道共享处理器
+type ShareHandler struct {
+	accessService service.AccessService
+	auditService  service.AuditService
+}
+
+// NewShareHandler 创建共享处理器实例
+func NewShareHandler(accessService service.AccessService, auditService service.AuditService) *ShareHandler {
+	return &ShareHandler{
+		accessService: accessService,
+		auditService:  auditService,
+	}
+}
+
+// ShareRequest 共享资源请求
+type ShareRequest struct {
+	Username   string `json:"username" binding:"required"`
+	Permission string `json:"permission" binding:"required,oneof=view control edit"`
+}
+
+// GetShares 获取资源的共享列表
+func (h *ShareHandler) GetShares(resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := h.authorizeResource(c, resourceType)
+		if !ok {
+			return
+		}
+
+		shares, err := h.accessService.GetShares(resourceType, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to get shares",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"shares": shares,
+			"count":  len(shares),
+		})
+	}
+}
+
+// CreateShare 将资源共享给其他用户，已共享时更新权限
+func (h *ShareHandler) CreateShare(resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := h.authorizeResource(c, resourceType)
+		if !ok {
+			return
+		}
+
+		var req ShareRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		share, err := h.accessService.ShareResource(resourceType, id, req.Username, req.Permission)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to share resource",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		recordAudit(c, h.auditService, models.AuditActionShare, resourceType, id, "", models.AuditChanges{
+			req.Username: {After: req.Permission},
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Resource shared successfully",
+			"share":   share,
+		})
+	}
+}
+
+// DeleteShare 取消资源对指定用户的共享
+func (h *ShareHandler) DeleteShare(resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := h.authorizeResource(c, resourceType)
+		if !ok {
+			return
+		}
+
+		userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid user ID",
+			})
+			return
+		}
+
+		if err := h.accessService.RemoveShare(resourceType, id, uint(userID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to remove share",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		recordAudit(c, h.auditService, models.AuditActionUnshare, resourceType, id, "", models.AuditChanges{
+			"user_id": {Before: userID},
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Share removed successfully",
+		})
+	}
+}
+
+// authorizeResource 解析资源ID并检查当前用户是否可以管理其共享（需要编辑权限）
+func (h *ShareHandler) authorizeResource(c *gin.Context, resourceType string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid " + resourceType + " ID",
+		})
+		return 0, false
+	}
+
+	if resourceType == models.ResourceTypeHost {
+		_, ok := authorizeHost(c, h.accessService, uint(id), models.PermissionEdit)
+		return uint(id), ok
+	}
+	_, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionEdit)
+	return uint(id), ok
+}
+
+// RegisterRoutes 注册路由
+func (h *ShareHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/hosts/:id/shares", middleware.RequireScope(models.ScopeHostsRead), h.GetShares(models.ResourceTypeHost))
+	router.POST("/hosts/:id/shares", middleware.RequireScope(models.ScopeHostsWrite), h.CreateShare(models.ResourceTypeHost))
+	router.DELETE("/hosts/:id/shares/:userId", middleware.RequireScope(models.ScopeHostsWrite), h.DeleteShare(models.ResourceTypeHost))
+
+	router.GET("/tunnels/:id/shares", middleware.RequireScope(models.ScopeTunnelsRead), h.GetShares(models.ResourceTypeTunnel))
+	router.POST("/tunnels/:id/shares", middleware.RequireScope(models.ScopeTunnelsWrite), h.CreateShare(models.ResourceTypeTunnel))
+	router.DELETE("/tunnels/:id/shares/:userId", middleware.RequireScope(models.ScopeTunnelsWrite), h.DeleteShare(models.ResourceTypeTunnel))
+}
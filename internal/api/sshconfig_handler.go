@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/KodaTao/drilling/internal/sshconfig"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSSHConfigSize 上传的SSH配置文件的最大大小
+const maxSSHConfigSize = 1 << 20
+
+// SSHConfigHandler SSH配置导入处理器
+type SSHConfigHandler struct {
+	importService service.SSHConfigImportService
+	accessService service.AccessService
+	auditService  service.AuditService
+}
+
+// NewSSHConfigHandler 创建SSH配置导入处理器实例
+func NewSSHConfigHandler(importService service.SSHConfigImportService, accessService service.AccessService, auditService service.AuditService) *SSHConfigHandler {
+	return &SSHConfigHandler{
+		importService: importService,
+		accessService: accessService,
+		auditService:  auditService,
+	}
+}
+
+// ImportSSHConfig 从上传的 OpenSSH 客户端配置导入主机和隧道
+// 查询参数 dry_run=true 只返回导入计划，hosts=a,b 只导入指定的主机别名
+// 服务端无法读取客户端的私钥文件，没有设置 IdentityFile 或 IdentityFile 不在 ssh.key_dir 目录内的主机无法导入，读取私钥内容需要使用 drilling import-ssh-config 命令
+func (h *SSHConfigHandler) ImportSSHConfig(c *gin.Context) {
+	if !requireCreate(c, h.accessService) {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSSHConfigSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	cfg, err := sshconfig.Parse(bytes.NewReader(data), "ssh_config")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid SSH config",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	opts := service.SSHImportOptions{
+		OwnerID: currentUserID(c),
+		CanUseJumpHost: func(id uint) bool {
+			_, err := h.accessService.AuthorizeHost(user, id, models.PermissionControl)
+			return err == nil
+		},
+	}
+	if hosts := c.Query("hosts"); hosts != "" {
+		opts.Aliases = strings.Split(hosts, ",")
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	var plan *service.SSHImportPlan
+	if dryRun {
+		plan, err = h.importService.Plan(cfg, opts)
+	} else {
+		plan, err = h.importService.Import(cfg, opts)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to import SSH config",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !dryRun {
+		h.recordImport(c, plan)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"plan":    plan,
+	})
+}
+
+// recordImport 为导入时创建的主机和隧道记录审计事件
+func (h *SSHConfigHandler) recordImport(c *gin.Context, plan *service.SSHImportPlan) {
+	for _, host := range plan.Hosts {
+		if host.Action != service.SSHImportCreate || host.ID == 0 {
+			continue
+		}
+		recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeHost, host.ID, host.Alias, nil)
+		for _, tunnel := range host.Tunnels {
+			if tunnel.ID != 0 {
+				recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, nil)
+			}
+		}
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *SSHConfigHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/import/ssh-config",
+		middleware.RequireScope(models.ScopeHostsWrite),
+		middleware.RequireScope(models.ScopeTunnelsWrite),
+		h.ImportSSHConfig,
+	)
+}
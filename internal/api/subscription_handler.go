@@ -0,0 +1,269 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SubscriptionHandler 订阅处理器
+type SubscriptionHandler struct {
+	subscriptionService service.SubscriptionService
+	auditService        service.AuditService
+	basePath            string       // 反向代理子路径前缀，生成订阅地址时使用
+	trustedProxies      []*net.IPNet // 可信反向代理，只信任来自这些地址的 X-Forwarded-Proto
+}
+
+// NewSubscriptionHandler 创建订阅处理器实例
+// basePath 为规范化后的路径前缀，trustedProxies 为可信反向代理的IP或CIDR
+func NewSubscriptionHandler(subscriptionService service.SubscriptionService, auditService service.AuditService, basePath string, trustedProxies []string) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		subscriptionService: subscriptionService,
+		auditService:        auditService,
+		basePath:            basePath,
+		trustedProxies:      parseTrustedProxies(trustedProxies),
+	}
+}
+
+// parseTrustedProxies 解析可信反向代理的IP或CIDR，单个IP视为只包含该地址的网段
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				continue
+			}
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(proxy); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+// CreateSubscription 创建订阅，返回只显示一次的订阅地址
+func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
+	var req service.SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	subscription, token, err := h.subscriptionService.CreateSubscription(currentUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to create subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeSubscription, subscription.ID, subscription.Name, service.DiffFields(nil, subscription))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Subscription created successfully, the URL will not be shown again",
+		"token":        token,
+		"url":          h.subscriptionURL(c, token),
+		"subscription": subscription,
+	})
+}
+
+// GetSubscriptions 获取所有订阅
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	subscriptions, err := h.subscriptionService.GetSubscriptions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get subscriptions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"count":         len(subscriptions),
+	})
+}
+
+// RevokeSubscription 吊销订阅
+func (h *SubscriptionHandler) RevokeSubscription(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid subscription ID",
+		})
+		return
+	}
+
+	subscription, err := h.subscriptionService.GetSubscription(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Subscription not found",
+		})
+		return
+	}
+
+	if err := h.subscriptionService.RevokeSubscription(subscription.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionDelete, models.ResourceTypeSubscription, subscription.ID, subscription.Name, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subscription revoked successfully",
+	})
+}
+
+// GetFetches 获取订阅的拉取记录
+func (h *SubscriptionHandler) GetFetches(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid subscription ID",
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	fetches, err := h.subscriptionService.GetFetches(uint(id), limit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to get subscription fetches",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"fetches": fetches,
+		"count":   len(fetches),
+	})
+}
+
+// Subscribe 代理客户端拉取订阅，令牌即凭据，不需要登录
+// 响应头 subscription-userinfo 携带本月流量和到期时间，profile-update-interval 是建议的自动更新间隔
+func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
+	subscription, err := h.subscriptionService.Authenticate(c.Param("token"))
+	if subscription == nil {
+		// 无效的令牌不写入拉取记录，避免被用来刷表
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Subscription not found",
+		})
+		return
+	}
+
+	fetch := &models.SubscriptionFetch{
+		SubscriptionID: subscription.ID,
+		SourceIP:       c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	if err != nil {
+		fetch.StatusCode = http.StatusGone
+		fetch.Error = err.Error()
+		h.subscriptionService.RecordFetch(fetch)
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "Subscription is no longer available",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	content, err := h.subscriptionService.Render(subscription)
+	if err != nil {
+		fetch.StatusCode = http.StatusInternalServerError
+		if errors.Is(err, service.ErrNoActiveSocks5Tunnels) || errors.Is(err, service.ErrExportProfileNotFound) {
+			fetch.StatusCode = http.StatusNotFound
+		}
+		fetch.Error = err.Error()
+		h.subscriptionService.RecordFetch(fetch)
+		c.JSON(fetch.StatusCode, gin.H{
+			"error":   "Failed to generate subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	fetch.StatusCode = http.StatusOK
+	fetch.Bytes = len(content.Export.Data)
+	h.subscriptionService.RecordFetch(fetch)
+	// 导出模板设置了按需启动时，拉取订阅会启动未运行的隧道
+	recordExportStarts(c, h.auditService, content.Export.Started)
+
+	// 客户端通常以文件名作为配置名称
+	filename := subscription.Name + path.Ext(content.Export.Filename)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(filename)))
+	c.Header("Subscription-Userinfo", content.UserInfo())
+	c.Header("Profile-Update-Interval", strconv.Itoa(subscription.UpdateInterval))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, content.Export.ContentType, content.Export.Data)
+}
+
+// subscriptionURL 根据当前请求的地址和路径前缀生成订阅地址
+// 只有来自可信反向代理的请求才使用 X-Forwarded-Proto 判断协议
+func (h *SubscriptionHandler) subscriptionURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || (h.fromTrustedProxy(c) && c.GetHeader("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s/api/v1/subscribe/%s", scheme, c.Request.Host, h.basePath, token)
+}
+
+// fromTrustedProxy 检查请求是否直接来自可信反向代理
+func (h *SubscriptionHandler) fromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range h.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterRoutes 注册订阅管理路由
+// 订阅可以导出所有隧道，只允许管理员管理
+func (h *SubscriptionHandler) RegisterRoutes(router *gin.RouterGroup) {
+	subscriptions := router.Group("/subscriptions", middleware.RequireRole(models.UserRoleAdmin))
+	{
+		subscriptions.GET("", middleware.RequireScope(models.ScopeExportRead), h.GetSubscriptions)
+		subscriptions.GET("/:id/fetches", middleware.RequireScope(models.ScopeExportRead), h.GetFetches)
+		subscriptions.POST("", middleware.RequireScope(models.ScopeExportWrite), h.CreateSubscription)
+		subscriptions.DELETE("/:id", middleware.RequireScope(models.ScopeExportWrite), h.RevokeSubscription)
+	}
+}
+
+// RegisterPublicRoutes 注册无需登录的订阅拉取路由
+func (h *SubscriptionHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("/subscribe/:token", h.Subscribe)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenHandler API令牌处理器
+type TokenHandler struct {
+	tokenService service.TokenService
+}
+
+// NewTokenHandler 创建API令牌处理器实例
+func NewTokenHandler(tokenService service.TokenService) *TokenHandler {
+	return &TokenHandler{
+		tokenService: tokenService,
+	}
+}
+
+// CreateTokenRequest 创建API令牌请求
+type CreateTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"` // 有效天数，0表示永不过期
+}
+
+// CreateToken 为当前用户创建API令牌
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	apiToken, plain, err := h.tokenService.CreateToken(middleware.CurrentUser(c).ID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to create token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Token created successfully, it will not be shown again",
+		"token":   plain,
+		"info":    apiToken,
+	})
+}
+
+// GetTokens 获取当前用户的API令牌
+func (h *TokenHandler) GetTokens(c *gin.Context) {
+	tokens, err := h.tokenService.GetUserTokens(middleware.CurrentUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get tokens",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"count":  len(tokens),
+	})
+}
+
+// RevokeToken 吊销当前用户的API令牌
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid token ID",
+		})
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(middleware.CurrentUser(c).ID, uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Failed to revoke token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Token revoked successfully",
+	})
+}
+
+// GetScopes 获取所有可用的权限范围
+func (h *TokenHandler) GetScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"scopes": models.AllScopes,
+	})
+}
+
+// RegisterRoutes 注册路由
+func (h *TokenHandler) RegisterRoutes(router *gin.RouterGroup) {
+	// 令牌管理只允许通过登录会话操作，避免令牌自我复制
+	tokens := router.Group("/tokens", middleware.RequireSession())
+	{
+		tokens.GET("", h.GetTokens)
+		tokens.POST("", h.CreateToken)
+		tokens.GET("/scopes", h.GetScopes)
+		tokens.DELETE("/:id", h.RevokeToken)
+	}
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KodaTao/drilling/internal/middleware"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TunnelGroupHandler 隧道组处理器
+type TunnelGroupHandler struct {
+	groupService  service.TunnelGroupService
+	accessService service.AccessService
+	auditService  service.AuditService
+}
+
+// NewTunnelGroupHandler 创建隧道组处理器实例
+func NewTunnelGroupHandler(groupService service.TunnelGroupService, accessService service.AccessService, auditService service.AuditService) *TunnelGroupHandler {
+	return &TunnelGroupHandler{
+		groupService:  groupService,
+		accessService: accessService,
+		auditService:  auditService,
+	}
+}
+
+// GetGroups 获取当前用户的隧道组，管理员可以看到所有隧道组
+func (h *TunnelGroupHandler) GetGroups(c *gin.Context) {
+	groups, err := h.groupService.GetAllGroups()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get tunnel groups",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	visible := make([]models.TunnelGroup, 0, len(groups))
+	for _, group := range groups {
+		if canAccessGroup(c, &group) {
+			visible = append(visible, group)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups": visible,
+		"count":  len(visible),
+	})
+}
+
+// GetGroup 获取隧道组详情及启动顺序
+func (h *TunnelGroupHandler) GetGroup(c *gin.Context) {
+	group, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.groupService.PlanGroup(group.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"group":      group,
+			"plan_error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group":       group,
+		"start_order": tunnelIDs(plan),
+	})
+}
+
+// CreateGroup 创建隧道组
+func (h *TunnelGroupHandler) CreateGroup(c *gin.Context) {
+	var req service.TunnelGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !requireCreate(c, h.accessService) || !h.authorizeMembers(c, req.TunnelIDs) {
+		return
+	}
+
+	group, err := h.groupService.CreateGroup(currentUserID(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to create tunnel group",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeTunnelGroup, group.ID, group.Name, service.DiffFields(nil, group))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tunnel group created successfully",
+		"group":   group,
+	})
+}
+
+// UpdateGroup 更新隧道组
+func (h *TunnelGroupHandler) UpdateGroup(c *gin.Context) {
+	existing, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+
+	var req service.TunnelGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !h.authorizeMembers(c, req.TunnelIDs) {
+		return
+	}
+
+	group, err := h.groupService.UpdateGroup(existing.ID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to update tunnel group",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionUpdate, models.ResourceTypeTunnelGroup, group.ID, group.Name, service.DiffFields(existing, group))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tunnel group updated successfully",
+		"group":   group,
+	})
+}
+
+// DeleteGroup 删除隧道组，组内的隧道保留
+func (h *TunnelGroupHandler) DeleteGroup(c *gin.Context) {
+	existing, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.DeleteGroup(existing.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete tunnel group",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionDelete, models.ResourceTypeTunnelGroup, existing.ID, existing.Name, service.DiffFields(existing, nil))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tunnel group deleted successfully",
+	})
+}
+
+// StartGroup 按依赖顺序启动隧道组，失败时回滚本次启动的隧道
+func (h *TunnelGroupHandler) StartGroup(c *gin.Context) {
+	group, ok := h.loadGroup(c)
+	if !ok || !h.authorizePlan(c, group) {
+		return
+	}
+
+	result, err := h.groupService.StartGroup(group.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to start tunnel group",
+			"details": err.Error(),
+			"result":  result,
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionStart, models.ResourceTypeTunnelGroup, group.ID, group.Name, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tunnel group started successfully",
+		"result":  result,
+	})
+}
+
+// StopGroup 按依赖的相反顺序停止隧道组
+func (h *TunnelGroupHandler) StopGroup(c *gin.Context) {
+	group, ok := h.loadGroup(c)
+	if !ok || !h.authorizePlan(c, group) {
+		return
+	}
+
+	result, err := h.groupService.StopGroup(group.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to stop tunnel group",
+			"details": err.Error(),
+			"result":  result,
+		})
+		return
+	}
+
+	recordAudit(c, h.auditService, models.AuditActionStop, models.ResourceTypeTunnelGroup, group.ID, group.Name, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tunnel group stopped successfully",
+		"result":  result,
+	})
+}
+
+// loadGroup 读取路径参数中的隧道组，只有创建者和管理员可以访问，失败时写入错误响应
+func (h *TunnelGroupHandler) loadGroup(c *gin.Context) (*models.TunnelGroup, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tunnel group ID",
+		})
+		return nil, false
+	}
+
+	group, err := h.groupService.GetGroup(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrTunnelGroupNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Tunnel group not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get tunnel group",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+
+	// 无权访问时返回404，避免泄露隧道组是否存在
+	if !canAccessGroup(c, group) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Tunnel group not found",
+		})
+		return nil, false
+	}
+	return group, true
+}
+
+// authorizeMembers 检查当前用户可以查看加入隧道组的隧道
+func (h *TunnelGroupHandler) authorizeMembers(c *gin.Context, ids []uint) bool {
+	for _, id := range ids {
+		if _, ok := authorizeTunnel(c, h.accessService, id, models.PermissionView); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// authorizePlan 检查当前用户可以控制启动隧道组时涉及的所有隧道，包括组外的依赖隧道
+func (h *TunnelGroupHandler) authorizePlan(c *gin.Context, group *models.TunnelGroup) bool {
+	plan, err := h.groupService.PlanGroup(group.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid tunnel dependencies",
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	for _, tunnel := range plan {
+		if _, ok := authorizeTunnel(c, h.accessService, tunnel.ID, models.PermissionControl); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// canAccessGroup 检查当前用户是否为隧道组的创建者或管理员
+func canAccessGroup(c *gin.Context, group *models.TunnelGroup) bool {
+	return isAdmin(c) || group.OwnerID == currentUserID(c)
+}
+
+// tunnelIDs 返回隧道的ID列表
+func tunnelIDs(tunnels []models.Tunnel) []uint {
+	ids := make([]uint, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		ids = append(ids, tunnel.ID)
+	}
+	return ids
+}
+
+// RegisterRoutes 注册路由
+func (h *TunnelGroupHandler) RegisterRoutes(router *gin.RouterGroup) {
+	groups := router.Group("/tunnel-groups")
+	{
+		groups.GET("", middleware.RequireScope(models.ScopeTunnelsRead), h.GetGroups)
+		groups.GET("/:id", middleware.RequireScope(models.ScopeTunnelsRead), h.GetGroup)
+		groups.POST("", middleware.RequireScope(models.ScopeTunnelsWrite), h.CreateGroup)
+		groups.PUT("/:id", middleware.RequireScope(models.ScopeTunnelsWrite), h.UpdateGroup)
+		groups.DELETE("/:id", middleware.RequireScope(models.ScopeTunnelsWrite), h.DeleteGroup)
+		groups.POST("/:id/start", middleware.RequireScope(models.ScopeTunnelsControl), h.StartGroup)
+		groups.POST("/:id/stop", middleware.RequireScope(models.ScopeTunnelsControl), h.StopGroup)
+	}
+}
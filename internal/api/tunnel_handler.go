@@ -1,23 +1,36 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/KodaTao/drilling/internal/labels"
+	"github.com/KodaTao/drilling/internal/middleware"
 	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
 	"github.com/KodaTao/drilling/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
+// maxConnectionLogLimit 单次查询连接日志的最大条数
+const maxConnectionLogLimit = 1000
+
 // TunnelHandler 隧道处理器
 type TunnelHandler struct {
 	tunnelService service.TunnelService
+	accessService service.AccessService
+	auditService  service.AuditService
 }
 
 // NewTunnelHandler 创建隧道处理器实例
-func NewTunnelHandler(tunnelService service.TunnelService) *TunnelHandler {
+func NewTunnelHandler(tunnelService service.TunnelService, accessService service.AccessService, auditService service.AuditService) *TunnelHandler {
 	return &TunnelHandler{
 		tunnelService: tunnelService,
+		accessService: accessService,
+		auditService:  auditService,
 	}
 }
 
@@ -32,6 +45,18 @@ func (h *TunnelHandler) CreateTunnel(c *gin.Context) {
 		return
 	}
 
+	// 创建隧道需要可以使用所属主机
+	if !requireCreate(c, h.accessService) {
+		return
+	}
+	if _, ok := authorizeHost(c, h.accessService, tunnel.HostID, models.PermissionControl); !ok {
+		return
+	}
+	if !h.authorizeDependencies(c, tunnel.DependsOn, nil) {
+		return
+	}
+	tunnel.OwnerID = currentUserID(c)
+
 	if err := h.tunnelService.CreateTunnel(&tunnel); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create tunnel",
@@ -40,6 +65,8 @@ func (h *TunnelHandler) CreateTunnel(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, service.DiffFields(nil, &tunnel))
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Tunnel created successfully",
 		"tunnel":  tunnel,
@@ -57,22 +84,29 @@ func (h *TunnelHandler) GetTunnel(c *gin.Context) {
 		return
 	}
 
-	tunnel, err := h.tunnelService.GetTunnel(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Tunnel not found",
-			"details": err.Error(),
-		})
+	tunnel, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionView)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"tunnel": tunnel,
-	})
+	}
+	// 设置了启停计划的隧道同时返回下一次计划的启动或停止
+	if status, err := h.tunnelService.GetScheduleStatus(tunnel.ID); err == nil && status.Next != nil {
+		response["next_transition"] = status.Next
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetAllTunnels 获取所有隧道
 func (h *TunnelHandler) GetAllTunnels(c *gin.Context) {
+	selector, ok := parseSelector(c)
+	if !ok {
+		return
+	}
+
 	tunnels, err := h.tunnelService.GetAllTunnels()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -82,6 +116,13 @@ func (h *TunnelHandler) GetAllTunnels(c *gin.Context) {
 		return
 	}
 
+	tunnels, err = h.filterVisible(c, tunnels)
+	if err != nil {
+		respondAccessError(c, err, "Tunnel not found")
+		return
+	}
+	tunnels = filterLabeled(tunnels, selector)
+
 	c.JSON(http.StatusOK, gin.H{
 		"tunnels": tunnels,
 		"count":   len(tunnels),
@@ -99,6 +140,14 @@ func (h *TunnelHandler) GetTunnelsByHost(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorizeHost(c, h.accessService, uint(hostID), models.PermissionView); !ok {
+		return
+	}
+	selector, ok := parseSelector(c)
+	if !ok {
+		return
+	}
+
 	tunnels, err := h.tunnelService.GetTunnelsByHost(uint(hostID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -108,6 +157,13 @@ func (h *TunnelHandler) GetTunnelsByHost(c *gin.Context) {
 		return
 	}
 
+	tunnels, err = h.filterVisible(c, tunnels)
+	if err != nil {
+		respondAccessError(c, err, "Tunnel not found")
+		return
+	}
+	tunnels = filterLabeled(tunnels, selector)
+
 	c.JSON(http.StatusOK, gin.H{
 		"tunnels": tunnels,
 		"count":   len(tunnels),
@@ -125,6 +181,11 @@ func (h *TunnelHandler) UpdateTunnel(c *gin.Context) {
 		return
 	}
 
+	existing, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionEdit)
+	if !ok {
+		return
+	}
+
 	var tunnel models.Tunnel
 	if err := c.ShouldBindJSON(&tunnel); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -136,6 +197,22 @@ func (h *TunnelHandler) UpdateTunnel(c *gin.Context) {
 
 	tunnel.ID = uint(id)
 
+	// 更换主机时需要可以使用新主机
+	if tunnel.HostID != existing.HostID {
+		if _, ok := authorizeHost(c, h.accessService, tunnel.HostID, models.PermissionControl); !ok {
+			return
+		}
+	}
+
+	if !h.authorizeDependencies(c, tunnel.DependsOn, existing.DependsOn) {
+		return
+	}
+
+	// 只有管理员可以转移所有者
+	if tunnel.OwnerID == 0 || !isAdmin(c) {
+		tunnel.OwnerID = existing.OwnerID
+	}
+
 	if err := h.tunnelService.UpdateTunnel(&tunnel); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update tunnel",
@@ -144,6 +221,8 @@ func (h *TunnelHandler) UpdateTunnel(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionUpdate, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, service.DiffFields(existing, &tunnel))
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Tunnel updated successfully",
 		"tunnel":  tunnel,
@@ -161,6 +240,11 @@ func (h *TunnelHandler) DeleteTunnel(c *gin.Context) {
 		return
 	}
 
+	existing, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionEdit)
+	if !ok {
+		return
+	}
+
 	if err := h.tunnelService.DeleteTunnel(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to delete tunnel",
@@ -169,6 +253,8 @@ func (h *TunnelHandler) DeleteTunnel(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionDelete, models.ResourceTypeTunnel, existing.ID, existing.Name, service.DiffFields(existing, nil))
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Tunnel deleted successfully",
 	})
@@ -185,7 +271,18 @@ func (h *TunnelHandler) StartTunnel(c *gin.Context) {
 		return
 	}
 
-	if err := h.tunnelService.StartTunnel(uint(id)); err != nil {
+	tunnel, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionControl)
+	if !ok {
+		return
+	}
+
+	// on_demand=true 时只监听端口，首个客户端连接时才建立SSH连接
+	start := h.tunnelService.StartTunnel
+	if c.Query("on_demand") == "true" {
+		start = h.tunnelService.StartTunnelOnDemand
+	}
+
+	if err := start(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Failed to start tunnel",
@@ -194,6 +291,8 @@ func (h *TunnelHandler) StartTunnel(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionStart, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Tunnel started successfully",
@@ -211,6 +310,11 @@ func (h *TunnelHandler) StopTunnel(c *gin.Context) {
 		return
 	}
 
+	tunnel, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionControl)
+	if !ok {
+		return
+	}
+
 	if err := h.tunnelService.StopTunnel(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -220,6 +324,8 @@ func (h *TunnelHandler) StopTunnel(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionStop, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Tunnel stopped successfully",
@@ -237,6 +343,11 @@ func (h *TunnelHandler) RestartTunnel(c *gin.Context) {
 		return
 	}
 
+	tunnel, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionControl)
+	if !ok {
+		return
+	}
+
 	if err := h.tunnelService.RestartTunnel(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -246,6 +357,8 @@ func (h *TunnelHandler) RestartTunnel(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionRestart, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Tunnel restarted successfully",
@@ -263,6 +376,10 @@ func (h *TunnelHandler) GetTunnelStatus(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionView); !ok {
+		return
+	}
+
 	status, err := h.tunnelService.GetTunnelStatus(uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -278,6 +395,7 @@ func (h *TunnelHandler) GetTunnelStatus(c *gin.Context) {
 }
 
 // GetConnectionLogs 获取连接日志
+// 支持的查询参数：event_type（逗号分隔）、since/until（RFC3339）、client、q、cursor、limit
 func (h *TunnelHandler) GetConnectionLogs(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -288,27 +406,213 @@ func (h *TunnelHandler) GetConnectionLogs(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionView); !ok {
+		return
+	}
+
+	filter, err := parseConnectionLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+	filter.TunnelID = uint(id)
+
+	h.respondConnectionLogs(c, filter)
+}
+
+// QueryConnectionLogs 查询所有隧道的连接日志，可通过 tunnel_id 参数过滤
+func (h *TunnelHandler) QueryConnectionLogs(c *gin.Context) {
+	filter, err := parseConnectionLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if tunnelIDStr := c.Query("tunnel_id"); tunnelIDStr != "" {
+		tunnelID, err := strconv.ParseUint(tunnelIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid tunnel ID",
+			})
+			return
+		}
+		if _, ok := authorizeTunnel(c, h.accessService, uint(tunnelID), models.PermissionView); !ok {
+			return
+		}
+		filter.TunnelID = uint(tunnelID)
+	} else {
+		// 非管理员只能查询自己可以查看的隧道
+		filter.TunnelIDs, err = h.accessService.VisibleTunnelIDs(middleware.CurrentUser(c))
+		if err != nil {
+			respondAccessError(c, err, "Tunnel not found")
+			return
+		}
+	}
+
+	h.respondConnectionLogs(c, filter)
+}
+
+// respondConnectionLogs 执行日志查询并返回分页结果
+func (h *TunnelHandler) respondConnectionLogs(c *gin.Context, filter repository.ConnectionLogFilter) {
+	logs, nextCursor, err := h.tunnelService.QueryConnectionLogs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get connection logs",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":        logs,
+		"count":       len(logs),
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseConnectionLogFilter 从查询参数解析连接日志过滤条件
+func parseConnectionLogFilter(c *gin.Context) (repository.ConnectionLogFilter, error) {
+	filter := repository.ConnectionLogFilter{
+		ClientAddr: c.Query("client"),
+		Search:     c.Query("q"),
+		Limit:      100, // 默认限制
+	}
+
 	// 获取limit参数
-	limitStr := c.Query("limit")
-	limit := 100 // 默认限制
-	if limitStr != "" {
+	if limitStr := c.Query("limit"); limitStr != "" {
 		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
+			filter.Limit = parsedLimit
 		}
 	}
+	if filter.Limit > maxConnectionLogLimit {
+		filter.Limit = maxConnectionLogLimit
+	}
 
-	logs, err := h.tunnelService.GetConnectionLogs(uint(id), limit)
+	if eventTypes := c.Query("event_type"); eventTypes != "" {
+		for _, eventType := range strings.Split(eventTypes, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				filter.EventTypes = append(filter.EventTypes, eventType)
+			}
+		}
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since: %v", err)
+		}
+		filter.Since = &t
+	}
+
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return filter, fmt.Errorf("invalid until: %v", err)
+		}
+		filter.Until = &t
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		beforeID, err := strconv.ParseUint(cursor, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor: %v", err)
+		}
+		filter.BeforeID = uint(beforeID)
+	}
+
+	return filter, nil
+}
+
+// GetQuotaUsage 获取隧道流量配额使用情况
+func (h *TunnelHandler) GetQuotaUsage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tunnel ID",
+		})
+		return
+	}
+
+	if _, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionView); !ok {
+		return
+	}
+
+	usage, err := h.tunnelService.GetQuotaUsage(uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get connection logs",
+			"error":   "Failed to get quota usage",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quota": usage,
+	})
+}
+
+// GetScheduleStatus 获取隧道启停计划的状态和下一次计划的启动或停止
+func (h *TunnelHandler) GetScheduleStatus(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tunnel ID",
+		})
+		return
+	}
+
+	if _, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionView); !ok {
+		return
+	}
+
+	status, err := h.tunnelService.GetScheduleStatus(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get schedule status",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedule": status,
+	})
+}
+
+// GetHealthStatus 获取隧道健康检查的状态
+func (h *TunnelHandler) GetHealthStatus(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tunnel ID",
+		})
+		return
+	}
+
+	if _, ok := authorizeTunnel(c, h.accessService, uint(id), models.PermissionView); !ok {
+		return
+	}
+
+	status, err := h.tunnelService.GetHealthStatus(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get health status",
 			"details": err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"logs":  logs,
-		"count": len(logs),
+		"health": status,
 	})
 }
 
@@ -323,6 +627,8 @@ func (h *TunnelHandler) StartAutoTunnels(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionStart, models.ResourceTypeTunnel, 0, "auto_start", nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Auto tunnels started successfully",
@@ -340,12 +646,164 @@ func (h *TunnelHandler) StopAllTunnels(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionStop, models.ResourceTypeTunnel, 0, "all", nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "All tunnels stopped successfully",
 	})
 }
 
+// bulkResult 批量操作中单个隧道的结果
+type bulkResult struct {
+	TunnelID uint   `json:"tunnel_id"`
+	Name     string `json:"name"`
+	Success  bool   `json:"success"`
+	Skipped  bool   `json:"skipped,omitempty"` // 已在运行或已停止，没有执行操作
+	Error    string `json:"error,omitempty"`
+}
+
+// BulkStartTunnels 按标签选择器启动隧道，按依赖顺序启动，已在运行的隧道跳过
+func (h *TunnelHandler) BulkStartTunnels(c *gin.Context) {
+	h.bulkApply(c, models.AuditActionStart, models.PermissionControl, func(tunnels []models.Tunnel) ([]models.Tunnel, error) {
+		ids := make([]uint, 0, len(tunnels))
+		byID := make(map[uint]models.Tunnel, len(tunnels))
+		for _, tunnel := range tunnels {
+			ids = append(ids, tunnel.ID)
+			byID[tunnel.ID] = tunnel
+		}
+		// 存在循环依赖或依赖的隧道不存在时不启动任何隧道，按ID顺序启动会破坏依赖顺序
+		order, err := h.tunnelService.StartOrder(ids)
+		if err != nil {
+			return nil, err
+		}
+		// 只启动选中的隧道，依赖的隧道需要已在运行或同时被选中
+		ordered := make([]models.Tunnel, 0, len(tunnels))
+		for _, tunnel := range order {
+			if selected, ok := byID[tunnel.ID]; ok {
+				ordered = append(ordered, selected)
+			}
+		}
+		return ordered, nil
+	}, func(tunnel *models.Tunnel) (bool, error) {
+		if h.tunnelService.IsTunnelRunning(tunnel.ID) {
+			return false, nil
+		}
+		return true, h.tunnelService.StartTunnel(tunnel.ID)
+	})
+}
+
+// BulkStopTunnels 按标签选择器停止隧道
+func (h *TunnelHandler) BulkStopTunnels(c *gin.Context) {
+	h.bulkApply(c, models.AuditActionStop, models.PermissionControl, nil, func(tunnel *models.Tunnel) (bool, error) {
+		if !h.tunnelService.IsTunnelRunning(tunnel.ID) {
+			return false, nil
+		}
+		return true, h.tunnelService.StopTunnel(tunnel.ID)
+	})
+}
+
+// BulkDeleteTunnels 按标签选择器删除隧道
+func (h *TunnelHandler) BulkDeleteTunnels(c *gin.Context) {
+	h.bulkApply(c, models.AuditActionDelete, models.PermissionEdit, nil, func(tunnel *models.Tunnel) (bool, error) {
+		return true, h.tunnelService.DeleteTunnel(tunnel.ID)
+	})
+}
+
+// bulkApply 对标签匹配且当前用户可以查看的隧道逐个执行操作，权限不足或执行失败的隧道记为失败
+// 批量操作必须指定选择器，避免误操作所有隧道；order 为nil时按ID顺序执行，返回错误时不执行任何操作
+// apply 返回是否执行了操作
+func (h *TunnelHandler) bulkApply(c *gin.Context, action, permission string, order func([]models.Tunnel) ([]models.Tunnel, error), apply func(*models.Tunnel) (bool, error)) {
+	selector, ok := parseSelector(c)
+	if !ok {
+		return
+	}
+	if selector.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Label selector is required for bulk operations",
+		})
+		return
+	}
+
+	tunnels, err := h.tunnelService.GetAllTunnels()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve tunnels",
+			"details": err.Error(),
+		})
+		return
+	}
+	scope, err := h.accessService.NewScope(middleware.CurrentUser(c))
+	if err != nil {
+		respondAccessError(c, err, "Tunnel not found")
+		return
+	}
+
+	var matched []models.Tunnel
+	for _, tunnel := range tunnels {
+		if scope.CanViewTunnel(&tunnel) && selector.Matches(tunnel.Labels) {
+			matched = append(matched, tunnel)
+		}
+	}
+	if order != nil {
+		if matched, err = order(matched); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to order tunnels by dependencies",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	results := make([]bulkResult, 0, len(matched))
+	failed := 0
+	for i := range matched {
+		tunnel := &matched[i]
+		result := bulkResult{TunnelID: tunnel.ID, Name: tunnel.Name}
+		if !scope.HasTunnelPermission(tunnel, permission) {
+			result.Error = "permission denied"
+		} else if applied, err := apply(tunnel); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+			result.Skipped = !applied
+			if applied {
+				var changes models.AuditChanges
+				if action == models.AuditActionDelete {
+					changes = service.DiffFields(tunnel, nil)
+				}
+				recordAudit(c, h.auditService, action, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, changes)
+			}
+		}
+		if !result.Success {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"selector":  selector.String(),
+		"matched":   len(matched),
+		"succeeded": len(matched) - failed,
+		"failed":    failed,
+		"results":   results,
+	})
+}
+
+// filterLabeled 过滤出标签匹配选择器的隧道
+func filterLabeled(tunnels []models.Tunnel, selector labels.Selector) []models.Tunnel {
+	if selector.Empty() {
+		return tunnels
+	}
+	matched := make([]models.Tunnel, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		if selector.Matches(tunnel.Labels) {
+			matched = append(matched, tunnel)
+		}
+	}
+	return matched
+}
+
 // CreateMultipleLocalForwards 批量创建本地服务映射
 func (h *TunnelHandler) CreateMultipleLocalForwards(c *gin.Context) {
 	hostIDStr := c.Param("hostId")
@@ -357,6 +815,13 @@ func (h *TunnelHandler) CreateMultipleLocalForwards(c *gin.Context) {
 		return
 	}
 
+	if !requireCreate(c, h.accessService) {
+		return
+	}
+	if _, ok := authorizeHost(c, h.accessService, uint(hostID), models.PermissionControl); !ok {
+		return
+	}
+
 	var req struct {
 		Services []service.LocalServiceMapping `json:"services"`
 	}
@@ -375,7 +840,11 @@ func (h *TunnelHandler) CreateMultipleLocalForwards(c *gin.Context) {
 		return
 	}
 
-	tunnels, err := h.tunnelService.CreateMultipleLocalForwards(uint(hostID), req.Services)
+	tunnels, err := h.tunnelService.CreateMultipleLocalForwards(uint(hostID), currentUserID(c), req.Services)
+	// 部分失败时已创建的隧道同样需要记录
+	for i := range tunnels {
+		recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeTunnel, tunnels[i].ID, tunnels[i].Name, service.DiffFields(nil, &tunnels[i]))
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create tunnels",
@@ -431,6 +900,13 @@ func (h *TunnelHandler) CreateDynamicSOCKS5Tunnel(c *gin.Context) {
 		return
 	}
 
+	if !requireCreate(c, h.accessService) {
+		return
+	}
+	if _, ok := authorizeHost(c, h.accessService, uint(hostID), models.PermissionControl); !ok {
+		return
+	}
+
 	var req struct {
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
@@ -444,7 +920,7 @@ func (h *TunnelHandler) CreateDynamicSOCKS5Tunnel(c *gin.Context) {
 		return
 	}
 
-	tunnel, err := h.tunnelService.CreateDynamicSOCKS5Tunnel(uint(hostID), req.Name, req.Description, req.AutoStart)
+	tunnel, err := h.tunnelService.CreateDynamicSOCKS5Tunnel(uint(hostID), currentUserID(c), req.Name, req.Description, req.AutoStart)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create SOCKS5 tunnel",
@@ -453,6 +929,8 @@ func (h *TunnelHandler) CreateDynamicSOCKS5Tunnel(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, models.AuditActionCreate, models.ResourceTypeTunnel, tunnel.ID, tunnel.Name, service.DiffFields(nil, tunnel))
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "SOCKS5 tunnel created successfully",
 		"tunnel":  tunnel,
@@ -496,38 +974,82 @@ func (h *TunnelHandler) FindAvailablePort(c *gin.Context) {
 	})
 }
 
+// authorizeDependencies 检查当前用户可以查看新增的依赖隧道，已有的依赖不再检查
+func (h *TunnelHandler) authorizeDependencies(c *gin.Context, dependsOn, existing models.UintList) bool {
+	for _, dep := range dependsOn {
+		if existing.Contains(dep) {
+			continue
+		}
+		if _, ok := authorizeTunnel(c, h.accessService, dep, models.PermissionView); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// filterVisible 过滤出当前用户可以查看的隧道
+func (h *TunnelHandler) filterVisible(c *gin.Context, tunnels []models.Tunnel) ([]models.Tunnel, error) {
+	scope, err := h.accessService.NewScope(middleware.CurrentUser(c))
+	if err != nil {
+		return nil, err
+	}
+	if scope.Unrestricted() {
+		return tunnels, nil
+	}
+
+	visible := make([]models.Tunnel, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		if scope.CanViewTunnel(&tunnel) {
+			visible = append(visible, tunnel)
+		}
+	}
+	return visible, nil
+}
+
 // RegisterRoutes 注册路由
 func (h *TunnelHandler) RegisterRoutes(router *gin.RouterGroup) {
 	tunnels := router.Group("/tunnels")
 	{
-		tunnels.POST("", h.CreateTunnel)
-		tunnels.GET("", h.GetAllTunnels)
-		tunnels.GET("/:id", h.GetTunnel)
-		tunnels.PUT("/:id", h.UpdateTunnel)
-		tunnels.DELETE("/:id", h.DeleteTunnel)
-		tunnels.POST("/:id/start", h.StartTunnel)
-		tunnels.POST("/:id/stop", h.StopTunnel)
-		tunnels.POST("/:id/restart", h.RestartTunnel)
-		tunnels.GET("/:id/status", h.GetTunnelStatus)
-		tunnels.GET("/:id/logs", h.GetConnectionLogs)
+		tunnels.POST("", middleware.RequireScope(models.ScopeTunnelsWrite), h.CreateTunnel)
+		tunnels.GET("", middleware.RequireScope(models.ScopeTunnelsRead), h.GetAllTunnels)
+		tunnels.GET("/:id", middleware.RequireScope(models.ScopeTunnelsRead), h.GetTunnel)
+		tunnels.PUT("/:id", middleware.RequireScope(models.ScopeTunnelsWrite), h.UpdateTunnel)
+		tunnels.DELETE("/:id", middleware.RequireScope(models.ScopeTunnelsWrite), h.DeleteTunnel)
+		tunnels.POST("/:id/start", middleware.RequireScope(models.ScopeTunnelsControl), h.StartTunnel)
+		tunnels.POST("/:id/stop", middleware.RequireScope(models.ScopeTunnelsControl), h.StopTunnel)
+		tunnels.POST("/:id/restart", middleware.RequireScope(models.ScopeTunnelsControl), h.RestartTunnel)
+		tunnels.GET("/:id/status", middleware.RequireScope(models.ScopeTunnelsRead), h.GetTunnelStatus)
+		tunnels.GET("/:id/logs", middleware.RequireScope(models.ScopeLogsRead), h.GetConnectionLogs)
+		tunnels.GET("/:id/quota", middleware.RequireScope(models.ScopeTunnelsRead), h.GetQuotaUsage)
+		tunnels.GET("/:id/schedule", middleware.RequireScope(models.ScopeTunnelsRead), h.GetScheduleStatus)
+		tunnels.GET("/:id/health", middleware.RequireScope(models.ScopeTunnelsRead), h.GetHealthStatus)
+
+		// 按标签选择器批量操作，例如 ?selector=env=prod,team in (a,b)
+		tunnels.POST("/bulk/start", middleware.RequireScope(models.ScopeTunnelsControl), h.BulkStartTunnels)
+		tunnels.POST("/bulk/stop", middleware.RequireScope(models.ScopeTunnelsControl), h.BulkStopTunnels)
+		tunnels.POST("/bulk/delete", middleware.RequireScope(models.ScopeTunnelsWrite), h.BulkDeleteTunnels)
 	}
 
 	// 主机相关的隧道路由 - 使用不同的路径避免冲突
-	tunnels.GET("/by-host/:hostId", h.GetTunnelsByHost)
+	tunnels.GET("/by-host/:hostId", middleware.RequireScope(models.ScopeTunnelsRead), h.GetTunnelsByHost)
 
 	// 批量创建本地服务映射
-	tunnels.POST("/by-host/:hostId/multiple", h.CreateMultipleLocalForwards)
+	tunnels.POST("/by-host/:hostId/multiple", middleware.RequireScope(models.ScopeTunnelsWrite), h.CreateMultipleLocalForwards)
 
 	// 动态SOCKS5隧道
-	tunnels.POST("/by-host/:hostId/socks5", h.CreateDynamicSOCKS5Tunnel)
+	tunnels.POST("/by-host/:hostId/socks5", middleware.RequireScope(models.ScopeTunnelsWrite), h.CreateDynamicSOCKS5Tunnel)
 
 	// 端口管理
-	router.POST("/port/find-available", h.FindAvailablePort)
+	router.POST("/port/find-available", middleware.RequireScope(models.ScopeTunnelsRead), middleware.RequireRole(models.UserRoleAdmin, models.UserRoleOperator), h.FindAvailablePort)
 
 	// 服务健康检查
-	router.POST("/service/health-check", h.CheckServiceHealth)
+	router.POST("/service/health-check", middleware.RequireScope(models.ScopeTunnelsRead), middleware.RequireRole(models.UserRoleAdmin, models.UserRoleOperator), h.CheckServiceHealth)
+
+	// 连接日志查询
+	router.GET("/logs", middleware.RequireScope(models.ScopeLogsRead), h.QueryConnectionLogs)
 
 	// 全局操作
-	router.POST("/tunnels/auto-start", h.StartAutoTunnels)
-	router.POST("/tunnels/stop-all", h.StopAllTunnels)
-}
\ No newline at end of file
+	// 全局操作会影响所有用户的隧道，只允许管理员执行
+	router.POST("/tunnels/auto-start", middleware.RequireScope(models.ScopeTunnelsControl), middleware.RequireRole(models.UserRoleAdmin), h.StartAutoTunnels)
+	router.POST("/tunnels/stop-all", middleware.RequireScope(models.ScopeTunnelsControl), middleware.RequireRole(models.UserRoleAdmin), h.StopAllTunnels)
+}
@@ -9,18 +9,42 @@ import (
 
 // Config 应用配置结构
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	SSH      SSHConfig      `mapstructure:"ssh"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Security SecurityConfig `mapstructure:"security"`
-	Debug    bool           `mapstructure:"debug"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	SSH       SSHConfig       `mapstructure:"ssh"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	AutoStart AutoStartConfig `mapstructure:"auto_start"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Debug     bool            `mapstructure:"debug"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port string `mapstructure:"port"`
+	Host            string     `mapstructure:"host"`
+	Port            string     `mapstructure:"port"`
+	ShutdownTimeout string     `mapstructure:"shutdown_timeout"` // 优雅关闭时等待连接结束的最长时间
+	TLS             TLSConfig  `mapstructure:"tls"`
+	CORS            CORSConfig `mapstructure:"cors"`
+	TrustedProxies  []string   `mapstructure:"trusted_proxies"` // 可信反向代理的IP或CIDR，只信任来自这些地址的 X-Forwarded-For
+	BasePath        string     `mapstructure:"base_path"`       // 通过反向代理子路径访问时的路径前缀，例如 /drilling
+}
+
+// CORSConfig 跨域访问配置
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins"` // 允许携带凭据跨域访问API的来源，为空表示只允许同源访问
+}
+
+// TLSConfig 管理服务的HTTPS配置
+type TLSConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	CertFile       string `mapstructure:"cert_file"`
+	KeyFile        string `mapstructure:"key_file"`
+	SelfSigned     bool   `mapstructure:"self_signed"`     // 证书文件不存在时生成自签名证书
+	ReloadInterval string `mapstructure:"reload_interval"` // 检查证书文件是否更新的间隔
+	ClientCAFile   string `mapstructure:"client_ca_file"`  // 客户端证书CA，设置后启用双向TLS认证
+	ClientAuth     string `mapstructure:"client_auth"`     // optional：客户端可不提供证书；require：必须提供有效证书
 }
 
 // DatabaseConfig 数据库配置
@@ -33,12 +57,21 @@ type SSHConfig struct {
 	Timeout        string `mapstructure:"timeout"`
 	Keepalive      string `mapstructure:"keepalive"`
 	MaxConnections int    `mapstructure:"max_connections"`
+	KeyDir         string `mapstructure:"key_dir"` // 主机可以按 key_path 引用的私钥文件所在目录，为空时只能上传私钥内容
 }
 
 // LoggingConfig 日志配置
 type LoggingConfig struct {
-	Level string `mapstructure:"level"`
-	File  string `mapstructure:"file"`
+	Level     string             `mapstructure:"level"`
+	File      string             `mapstructure:"file"`
+	Retention LogRetentionConfig `mapstructure:"retention"`
+}
+
+// LogRetentionConfig 连接日志保留策略配置
+type LogRetentionConfig struct {
+	MaxAge           string `mapstructure:"max_age"`             // 日志最长保留时间，为空表示不按时间清理
+	MaxRowsPerTunnel int    `mapstructure:"max_rows_per_tunnel"` // 每个隧道最多保留的日志条数，0表示不限制
+	PruneInterval    string `mapstructure:"prune_interval"`      // 清理任务执行间隔
 }
 
 // SecurityConfig 安全配置
@@ -46,6 +79,28 @@ type SecurityConfig struct {
 	EncryptKey string `mapstructure:"encrypt_key"`
 }
 
+// AutoStartConfig 启动时自动启动隧道的配置
+type AutoStartConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Concurrency int    `mapstructure:"concurrency"` // 同时启动的隧道数
+	Retries     int    `mapstructure:"retries"`     // 启动失败后的重试次数
+	RetryDelay  string `mapstructure:"retry_delay"` // 两次重试之间的间隔
+}
+
+// AuthConfig 管理界面和API的认证配置
+type AuthConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	SessionTTL    string `mapstructure:"session_ttl"`    // 登录会话有效期，每次访问时顺延
+	CookieSecure  bool   `mapstructure:"cookie_secure"`  // 仅通过HTTPS发送会话Cookie
+	AdminUsername string `mapstructure:"admin_username"` // 初始管理员用户名，仅在没有任何用户时使用
+	AdminPassword string `mapstructure:"admin_password"` // 初始管理员密码，为空时随机生成并输出到日志
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	File string `mapstructure:"file"` // 审计记录同时以JSONL格式追加写入的文件，为空表示只保存到数据库
+}
+
 // Load 加载配置
 func Load() *Config {
 	// 设置默认配置
@@ -86,6 +141,17 @@ func setDefaults() {
 	// 服务器默认配置
 	viper.SetDefault("server.host", "127.0.0.1")
 	viper.SetDefault("server.port", "8080")
+	viper.SetDefault("server.shutdown_timeout", "30s")
+	viper.SetDefault("server.tls.enabled", false)
+	viper.SetDefault("server.tls.cert_file", "./certs/server.crt")
+	viper.SetDefault("server.tls.key_file", "./certs/server.key")
+	viper.SetDefault("server.tls.self_signed", false)
+	viper.SetDefault("server.tls.reload_interval", "30s")
+	viper.SetDefault("server.tls.client_ca_file", "")
+	viper.SetDefault("server.tls.client_auth", "optional")
+	viper.SetDefault("server.cors.allowed_origins", []string{"http://localhost:3000", "http://localhost:8080", "http://127.0.0.1:8080"})
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("server.base_path", "")
 
 	// 数据库默认配置
 	viper.SetDefault("database.path", "./drilling.db")
@@ -94,14 +160,34 @@ func setDefaults() {
 	viper.SetDefault("ssh.timeout", "30s")
 	viper.SetDefault("ssh.keepalive", "10s")
 	viper.SetDefault("ssh.max_connections", 100)
+	viper.SetDefault("ssh.key_dir", "")
 
 	// 日志默认配置
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.file", "./drilling.log")
+	viper.SetDefault("logging.retention.max_age", "720h")
+	viper.SetDefault("logging.retention.max_rows_per_tunnel", 10000)
+	viper.SetDefault("logging.retention.prune_interval", "1h")
 
 	// 安全配置
 	viper.SetDefault("security.encrypt_key", "default-encryption-key-change-in-production")
 
+	// 自动启动配置
+	viper.SetDefault("auto_start.enabled", true)
+	viper.SetDefault("auto_start.concurrency", 4)
+	viper.SetDefault("auto_start.retries", 3)
+	viper.SetDefault("auto_start.retry_delay", "5s")
+
+	// 认证配置
+	viper.SetDefault("auth.enabled", true)
+	viper.SetDefault("auth.session_ttl", "24h")
+	viper.SetDefault("auth.cookie_secure", false)
+	viper.SetDefault("auth.admin_username", "admin")
+	viper.SetDefault("auth.admin_password", "")
+
+	// 审计配置
+	viper.SetDefault("audit.file", "")
+
 	// 调试模式
 	viper.SetDefault("debug", false)
-}
\ No newline at end of file
+}
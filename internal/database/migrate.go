@@ -12,7 +12,7 @@ func Migrate(db *gorm.DB) error {
 	log.Println("Running database migrations...")
 
 	// 自动迁移数据库表结构
-	err := db.AutoMigrate(&models.Host{}, &models.Tunnel{}, &models.ConnectionLog{}, &models.TrafficStats{})
+	err := db.AutoMigrate(&models.Host{}, &models.Tunnel{}, &models.ConnectionLog{}, &models.TrafficStats{}, &models.ConnectionSession{}, &models.User{}, &models.UserSession{}, &models.APIToken{}, &models.ResourceShare{}, &models.AuditEvent{}, &models.ExportProfile{}, &models.Subscription{}, &models.SubscriptionFetch{}, &models.TunnelGroup{})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,188 @@
+package labels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 标签选择条件的运算符
+const (
+	OpEquals       = "="
+	OpNotEquals    = "!="
+	OpIn           = "in"
+	OpNotIn        = "notin"
+	OpExists       = "exists"
+	OpDoesNotExist = "!"
+)
+
+// Requirement 单个标签选择条件，例如 env=prod、team in (a,b)、!deprecated
+type Requirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// Matches 检查标签是否满足条件
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case OpExists:
+		return ok
+	case OpDoesNotExist:
+		return !ok
+	case OpEquals, OpIn:
+		return ok && contains(r.Values, value)
+	case OpNotEquals, OpNotIn:
+		// 与 Kubernetes 一致，没有该标签也视为不等于
+		return !ok || !contains(r.Values, value)
+	}
+	return false
+}
+
+// String 返回条件的文本形式
+func (r Requirement) String() string {
+	switch r.Operator {
+	case OpExists:
+		return r.Key
+	case OpDoesNotExist:
+		return "!" + r.Key
+	case OpIn, OpNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return r.Key + r.Operator + r.Values[0]
+}
+
+// Selector 标签选择器，所有条件都满足时匹配；没有条件的选择器匹配所有对象
+type Selector []Requirement
+
+// Matches 检查标签是否满足选择器的所有条件
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty 检查选择器是否没有任何条件
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+// String 返回选择器的文本形式
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse 解析 Kubernetes 风格的标签选择器，条件之间以逗号分隔：
+//
+//	env=prod        env==prod       env!=prod
+//	team in (a,b)   team notin (a,b)
+//	gpu             !deprecated
+//
+// 空字符串返回空选择器
+func Parse(selector string) (Selector, error) {
+	var result Selector
+	for _, part := range splitRequirements(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			if strings.TrimSpace(selector) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("invalid selector %q: empty requirement", selector)
+		}
+		r, err := parseRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// splitRequirements 按逗号拆分条件，忽略括号内的逗号
+func splitRequirements(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, ch := range selector {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+// parseRequirement 解析单个条件
+func parseRequirement(part string) (Requirement, error) {
+	if strings.HasPrefix(part, "!") && !strings.HasPrefix(part, "!=") {
+		key := strings.TrimSpace(part[1:])
+		return Requirement{Key: key, Operator: OpDoesNotExist}, ValidateKey(key)
+	}
+
+	// 集合条件：key in (a,b) / key notin (a,b)
+	if open := strings.Index(part, "("); open >= 0 {
+		if !strings.HasSuffix(part, ")") {
+			return Requirement{}, fmt.Errorf("missing ')' in %q", part)
+		}
+		fields := strings.Fields(part[:open])
+		if len(fields) != 2 || (fields[1] != OpIn && fields[1] != OpNotIn) {
+			return Requirement{}, fmt.Errorf("expected 'key in (...)' or 'key notin (...)', got %q", part)
+		}
+		r := Requirement{Key: fields[0], Operator: fields[1]}
+		if err := ValidateKey(r.Key); err != nil {
+			return Requirement{}, err
+		}
+		for _, value := range strings.Split(part[open+1:len(part)-1], ",") {
+			value = strings.TrimSpace(value)
+			if err := ValidateValue(value); err != nil {
+				return Requirement{}, err
+			}
+			r.Values = append(r.Values, value)
+		}
+		return r, nil
+	}
+
+	// 等值条件，先匹配较长的运算符
+	for _, op := range []string{"!=", "==", "="} {
+		if i := strings.Index(part, op); i >= 0 {
+			r := Requirement{Key: strings.TrimSpace(part[:i]), Operator: OpEquals}
+			if op == "!=" {
+				r.Operator = OpNotEquals
+			}
+			value := strings.TrimSpace(part[i+len(op):])
+			if err := ValidateKey(r.Key); err != nil {
+				return Requirement{}, err
+			}
+			if err := ValidateValue(value); err != nil {
+				return Requirement{}, err
+			}
+			r.Values = []string{value}
+			return r, nil
+		}
+	}
+
+	return Requirement{Key: part, Operator: OpExists}, ValidateKey(part)
+}
+
+// contains 检查列表是否包含指定值
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
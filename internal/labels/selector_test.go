@@ -0,0 +1,126 @@
+package labels
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitRequirements(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"", []string{""}},
+		{"env=prod", []string{"env=prod"}},
+		{"env=prod,team=data", []string{"env=prod", "team=data"}},
+		{"team in (a,b)", []string{"team in (a,b)"}},
+		{"env=prod,team in (a,b),!deprecated", []string{"env=prod", "team in (a,b)", "!deprecated"}},
+		{"team notin (a,b,c),tier in (x)", []string{"team notin (a,b,c)", "tier in (x)"}},
+		{"env=prod,", []string{"env=prod", ""}},
+	}
+	for _, tt := range tests {
+		if got := splitRequirements(tt.selector); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitRequirements(%q) = %q, want %q", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Selector
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"env=prod", Selector{{Key: "env", Operator: OpEquals, Values: []string{"prod"}}}},
+		{"env==prod", Selector{{Key: "env", Operator: OpEquals, Values: []string{"prod"}}}},
+		{"env != prod", Selector{{Key: "env", Operator: OpNotEquals, Values: []string{"prod"}}}},
+		{"gpu", Selector{{Key: "gpu", Operator: OpExists}}},
+		{"!deprecated", Selector{{Key: "deprecated", Operator: OpDoesNotExist}}},
+		{"team in (a, b)", Selector{{Key: "team", Operator: OpIn, Values: []string{"a", "b"}}}},
+		{"example.com/team notin (a,b),env=prod", Selector{
+			{Key: "example.com/team", Operator: OpNotIn, Values: []string{"a", "b"}},
+			{Key: "env", Operator: OpEquals, Values: []string{"prod"}},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.selector)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, selector := range []string{
+		"env=prod,",
+		",env=prod",
+		"env=prod,,team=data",
+		"team in (a,b",
+		"team (a,b)",
+		"team within (a,b)",
+		"team in (a,b c)",
+		"=prod",
+		"env=pr od",
+		"-env",
+		"!",
+	} {
+		if _, err := Parse(selector); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", selector)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "team": "data"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"region!=eu", true}, // 没有该标签也视为不等于
+		{"team in (data,web)", true},
+		{"team notin (data,web)", false},
+		{"region notin (eu)", true},
+		{"region in (eu)", false},
+		{"env", true},
+		{"!env", false},
+		{"!region", true},
+		{"env=prod,team in (web)", false},
+		{"env=prod,team in (web,data),!region", true},
+	}
+	for _, tt := range tests {
+		selector, err := Parse(tt.selector)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.selector, err)
+		}
+		if got := selector.Matches(labels); got != tt.want {
+			t.Errorf("%q.Matches(%v) = %v, want %v", tt.selector, labels, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorString(t *testing.T) {
+	for _, selector := range []string{
+		"env=prod",
+		"env!=prod",
+		"team in (a,b)",
+		"team notin (a)",
+		"gpu,!deprecated",
+	} {
+		parsed, err := Parse(selector)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", selector, err)
+		}
+		if got := parsed.String(); got != selector {
+			t.Errorf("Parse(%q).String() = %q", selector, got)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 认证相关的Cookie、请求头和上下文键
+const (
+	SessionCookieName = "drilling_session" // 会话令牌Cookie（HttpOnly）
+	CSRFCookieName    = "drilling_csrf"    // CSRF令牌Cookie，前端读取后放入请求头
+	CSRFHeaderName    = "X-CSRF-Token"
+
+	// ClientCertHeaderName 使用客户端证书认证的非只读请求必须携带的请求头
+	// 浏览器跨站请求无法在不经过CORS预检的情况下设置自定义请求头，以此防止CSRF
+	ClientCertHeaderName = "X-Requested-With"
+
+	contextUserKey    = "auth_user"
+	contextSessionKey = "auth_session"
+	contextTokenKey   = "auth_token"
+)
+
+// AuthMiddleware 依次校验 Authorization: Bearer 令牌、登录会话和双向TLS客户端证书
+// 使用会话的非只读请求还需要校验CSRF令牌
+func AuthMiddleware(authService service.AuthService, tokenService service.TokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if bearer, ok := bearerToken(c); ok {
+			user, apiToken, err := tokenService.Authenticate(bearer, c.ClientIP())
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error": "Invalid API token",
+				})
+				return
+			}
+
+			c.Set(contextUserKey, user)
+			c.Set(contextTokenKey, apiToken)
+			c.Next()
+			return
+		}
+
+		token, _ := c.Cookie(SessionCookieName)
+		user, session, err := authService.Authenticate(token)
+		if err != nil {
+			if commonName, ok := clientCertCommonName(c); ok {
+				authenticateClientCert(c, authService, commonName)
+				return
+			}
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Authentication required",
+			})
+			return
+		}
+
+		if !isSafeMethod(c.Request.Method) {
+			csrfToken := c.GetHeader(CSRFHeaderName)
+			if csrfToken == "" || subtle.ConstantTimeCompare([]byte(csrfToken), []byte(session.CSRFToken)) != 1 {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "Invalid CSRF token",
+				})
+				return
+			}
+		}
+
+		c.Set(contextUserKey, user)
+		c.Set(contextSessionKey, session)
+		c.Next()
+	}
+}
+
+// authenticateClientCert 以客户端证书CN对应的用户身份处理请求
+func authenticateClientCert(c *gin.Context, authService service.AuthService, commonName string) {
+	user, err := authService.AuthenticateClientCert(commonName)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Client certificate does not match an active user",
+		})
+		return
+	}
+
+	if !isSafeMethod(c.Request.Method) && c.GetHeader(ClientCertHeaderName) == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Missing " + ClientCertHeaderName + " header",
+		})
+		return
+	}
+
+	c.Set(contextUserKey, user)
+	c.Next()
+}
+
+// RequireScope 要求API令牌具有指定权限范围，使用登录会话的请求不受限制
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiToken := CurrentToken(c); apiToken != nil && !apiToken.Scopes.Contains(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Insufficient token scope",
+				"details": "required scope: " + scope,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole 要求当前用户具有指定角色之一，未启用认证时不做限制
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user != nil {
+			allowed := false
+			for _, role := range roles {
+				if user.Role == role {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "Permission denied",
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSession 要求使用登录会话访问，例如用户和令牌管理不允许通过API令牌操作
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentSession(c) == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "This endpoint requires an interactive login session",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser 获取当前请求的登录用户，未登录时返回nil
+func CurrentUser(c *gin.Context) *models.User {
+	if value, exists := c.Get(contextUserKey); exists {
+		if user, ok := value.(*models.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// CurrentSession 获取当前请求的登录会话，未登录时返回nil
+func CurrentSession(c *gin.Context) *models.UserSession {
+	if value, exists := c.Get(contextSessionKey); exists {
+		if session, ok := value.(*models.UserSession); ok {
+			return session
+		}
+	}
+	return nil
+}
+
+// CurrentToken 获取当前请求使用的API令牌，使用登录会话时返回nil
+func CurrentToken(c *gin.Context) *models.APIToken {
+	if value, exists := c.Get(contextTokenKey); exists {
+		if apiToken, ok := value.(*models.APIToken); ok {
+			return apiToken
+		}
+	}
+	return nil
+}
+
+// clientCertCommonName 获取已通过CA校验的客户端证书CN
+func clientCertCommonName(c *gin.Context) (string, bool) {
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	commonName := state.VerifiedChains[0][0].Subject.CommonName
+	return commonName, commonName != ""
+}
+
+// bearerToken 从 Authorization 请求头中获取Bearer令牌
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[7:]), true
+}
+
+// isSafeMethod 检查请求方法是否为只读方法
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -1,25 +1,40 @@
 package middleware
 
 import (
+	"log"
+	"slices"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware 配置 CORS 中间件
-func CORSMiddleware() gin.HandlerFunc {
+// CORSMiddleware 配置 CORS 中间件，allowedOrigins 为空时不允许跨域访问
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	if len(allowedOrigins) == 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
 	config := cors.DefaultConfig()
 
 	// 允许的源
-	config.AllowOrigins = []string{"http://localhost:3000", "http://localhost:8080", "http://127.0.0.1:8080"}
+	config.AllowOrigins = allowedOrigins
 
 	// 允许的方法
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
 
 	// 允许的头部
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-CSRF-Token", "X-Requested-With"}
 
-	// 允许凭据
+	// 允许凭据，允许任意来源时不能携带凭据，否则任何网站都可以以当前登录用户的身份调用API
 	config.AllowCredentials = true
+	if slices.Contains(allowedOrigins, "*") {
+		log.Println("Warning: CORS allows all origins, credentials will not be accepted for cross-origin requests")
+		config.AllowOrigins = nil
+		config.AllowAllOrigins = true
+		config.AllowCredentials = false
+	}
 
 	return cors.New(config)
 }
\ No newline at end of file
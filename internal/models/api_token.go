@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIToken 供自动化客户端使用的API令牌，令牌只保存哈希值
+type APIToken struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	Name       string         `json:"name" gorm:"not null"`
+	Prefix     string         `json:"prefix" gorm:"not null"`        // 令牌前缀，用于在列表中辨认令牌
+	TokenHash  string         `json:"-" gorm:"uniqueIndex;not null"` // 令牌的SHA-256哈希
+	Scopes     StringList     `json:"scopes" gorm:"type:text"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	LastUsedIP string         `json:"last_used_ip"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联的用户
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// TokenScope API令牌权限范围常量
+const (
+	ScopeHostsRead      = "hosts:read"
+	ScopeHostsWrite     = "hosts:write"
+	ScopeTunnelsRead    = "tunnels:read"
+	ScopeTunnelsWrite   = "tunnels:write"   // 创建、修改、删除隧道
+	ScopeTunnelsControl = "tunnels:control" // 启动、停止、重启隧道
+	ScopeLogsRead       = "logs:read"       // 连接日志和连接会话
+	ScopeExportRead     = "export:read"
+	ScopeExportWrite    = "export:write" // 导出模板管理，仅管理员可用
+	ScopeAuditRead      = "audit:read"   // 审计记录，仅管理员可用
+)
+
+// AllScopes 所有可用的权限范围
+var AllScopes = []string{
+	ScopeHostsRead,
+	ScopeHostsWrite,
+	ScopeTunnelsRead,
+	ScopeTunnelsWrite,
+	ScopeTunnelsControl,
+	ScopeLogsRead,
+	ScopeExportRead,
+	ScopeExportWrite,
+	ScopeAuditRead,
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEvent 管理操作审计记录
+type AuditEvent struct {
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	UserID       uint         `json:"user_id" gorm:"index"`                // 操作用户ID，未启用认证时为0
+	Username     string       `json:"username" gorm:"index"`               // 操作用户名，保留用户删除后的记录
+	TokenPrefix  string       `json:"token_prefix,omitempty"`              // 通过API令牌操作时的令牌前缀
+	SourceIP     string       `json:"source_ip"`                           // 请求来源IP
+	Action       string       `json:"action" gorm:"not null;index"`        // 操作类型，见 AuditAction 常量
+	ResourceType string       `json:"resource_type" gorm:"not null;index"` // host, tunnel, export
+	ResourceID   uint         `json:"resource_id" gorm:"index"`            // 资源ID，批量操作时为0
+	ResourceName string       `json:"resource_name"`                       // 资源名称，保留资源删除后的记录
+	Changes      AuditChanges `json:"changes,omitempty" gorm:"type:text"`  // 非敏感字段的修改前后值
+	CreatedAt    time.Time    `json:"created_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// FieldChange 单个字段的修改前后值，创建时 Before 为空，删除时 After 为空
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges 以JSON对象形式存储的字段修改记录
+type AuditChanges map[string]FieldChange
+
+// Value 实现 driver.Valuer 接口
+func (c AuditChanges) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(map[string]FieldChange(c))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (c *AuditChanges) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into AuditChanges", value)
+	}
+
+	if len(data) == 0 {
+		*c = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]FieldChange)(c))
+}
+
+// AuditAction 审计操作类型常量
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionStart   = "start"
+	AuditActionStop    = "stop"
+	AuditActionRestart = "restart"
+	AuditActionShare   = "share"
+	AuditActionUnshare = "unshare"
+	AuditActionExport  = "export"
+)
+
+// ResourceTypeExport 配置导出资源类型，用于审计记录
+const ResourceTypeExport = "export"
+
+// ResourceTypeExportProfile 导出模板资源类型，用于审计记录
+const ResourceTypeExportProfile = "export_profile"
+
+// ResourceTypeSubscription 订阅资源类型，用于审计记录
+const ResourceTypeSubscription = "subscription"
+
+// ResourceTypeTunnelGroup 隧道组资源类型，用于审计记录
+const ResourceTypeTunnelGroup = "tunnel_group"
+
+// AuditRedacted 敏感字段在审计记录中的占位值，只记录是否修改
+const AuditRedacted = "[redacted]"
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// ExportProfile Clash/mihomo 导出模板，定义基础设置、代理分组、规则和包含的隧道
+type ExportProfile struct {
+	ID              uint                `json:"id" gorm:"primaryKey"`
+	Name            string              `json:"name" gorm:"uniqueIndex;not null" binding:"required"`
+	Description     string              `json:"description"`
+	Mihomo          bool                `json:"mihomo" gorm:"default:false"` // 输出 mihomo 专用字段
+	Settings        ClashSettings       `json:"settings" gorm:"type:text"`
+	ProxyGroups     ExportProxyGroups   `json:"proxy_groups" gorm:"type:text"`         // 为空时使用默认的 Auto、Proxy、LoadBalance 分组
+	Rules           StringList          `json:"rules" gorm:"type:text"`                // 为空时使用默认规则，代理域名规则总是排在最前
+	RuleProviders   ExportRuleProviders `json:"rule_providers" gorm:"type:text"`       // 规则集，键为规则集名称，在规则中以 RULE-SET 引用
+	TunnelTags      StringList          `json:"tunnel_tags" gorm:"type:text"`          // 包含带有任一标签的隧道
+	TunnelIDs       UintList            `json:"tunnel_ids" gorm:"type:text"`           // 包含指定ID的隧道，与标签都为空时包含所有隧道
+	TunnelSelector  string              `json:"tunnel_selector"`                       // 标签选择器，只包含 key=value 标签匹配的隧道，例如 env=prod,team in (a,b)
+	IncludeInactive bool                `json:"include_inactive" gorm:"default:false"` // 同时导出未运行的SOCKS5隧道
+	StartOnDemand   bool                `json:"start_on_demand" gorm:"default:false"`  // 导出时按需启动未运行的隧道，首个客户端连接时才建立SSH连接
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ExportProfile) TableName() string {
+	return "export_profiles"
+}
+
+// ClashSettings Clash 基础设置，端口为0时不开启对应的代理端口
+type ClashSettings struct {
+	Port                int       `json:"port"`       // HTTP代理端口
+	SocksPort           int       `json:"socks_port"` // SOCKS5代理端口
+	MixedPort           int       `json:"mixed_port"` // HTTP和SOCKS5混合端口
+	AllowLan            bool      `json:"allow_lan"`
+	BindAddress         string    `json:"bind_address"`
+	Mode                string    `json:"mode"`      // rule, global, direct，默认 rule
+	LogLevel            string    `json:"log_level"` // silent, error, warning, info, debug，默认 info
+	IPv6                bool      `json:"ipv6"`
+	ExternalController  string    `json:"external_controller"`
+	Secret              string    `json:"secret"`
+	HealthCheckURL      string    `json:"health_check_url"`      // 默认分组的延迟测试地址
+	HealthCheckInterval int       `json:"health_check_interval"` // 默认分组的延迟测试间隔（秒）
+	DNS                 *ClashDNS `json:"dns"`                   // 为空时不输出 dns 段，使用客户端自身的设置
+
+	// 以下字段只在 mihomo 模板中输出
+	UnifiedDelay            bool              `json:"unified_delay"`
+	TCPConcurrent           bool              `json:"tcp_concurrent"`
+	FindProcessMode         string            `json:"find_process_mode"` // always, strict, off
+	GlobalClientFingerprint string            `json:"global_client_fingerprint"`
+	GeodataMode             bool              `json:"geodata_mode"`
+	GeoxURL                 map[string]string `json:"geox_url"` // geoip, geosite, mmdb 的下载地址
+}
+
+// Value 实现 driver.Valuer 接口
+func (s ClashSettings) Value() (driver.Value, error) {
+	return marshalJSONValue(s)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (s *ClashSettings) Scan(value interface{}) error {
+	return scanJSONValue(value, s, "ClashSettings")
+}
+
+// ClashDNS Clash DNS设置
+type ClashDNS struct {
+	Enable            bool     `json:"enable" yaml:"enable"`
+	Listen            string   `json:"listen,omitempty" yaml:"listen,omitempty"`
+	IPv6              bool     `json:"ipv6,omitempty" yaml:"ipv6,omitempty"`
+	DefaultNameserver []string `json:"default_nameserver,omitempty" yaml:"default-nameserver,omitempty"`
+	NameServer        []string `json:"nameserver" yaml:"nameserver"`
+	Fallback          []string `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	EnhancedMode      string   `json:"enhanced_mode,omitempty" yaml:"enhanced-mode,omitempty"` // fake-ip, redir-host
+	FakeIPRange       string   `json:"fake_ip_range,omitempty" yaml:"fake-ip-range,omitempty"`
+	UseHosts          bool     `json:"use_hosts,omitempty" yaml:"use-hosts,omitempty"`
+	FakeIPFilter      []string `json:"fake_ip_filter,omitempty" yaml:"fake-ip-filter,omitempty"`
+}
+
+// ExportProxyGroup 代理分组
+type ExportProxyGroup struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`          // select, url-test, fallback, load-balance
+	Proxies      []string `json:"proxies"`       // 其他分组、DIRECT、REJECT 或节点名称
+	IncludeNodes bool     `json:"include_nodes"` // 在 Proxies 之后加入导出的隧道节点
+	Filter       string   `json:"filter"`        // 只加入名称匹配此正则表达式的隧道节点
+	URL          string   `json:"url"`
+	Interval     int      `json:"interval"`
+	Tolerance    int      `json:"tolerance"`
+	Strategy     string   `json:"strategy"` // load-balance 的策略：consistent-hashing, round-robin
+	Hidden       bool     `json:"hidden"`   // mihomo：在面板中隐藏
+	Icon         string   `json:"icon"`     // mihomo：分组图标地址
+}
+
+// ExportProxyGroups 以JSON数组形式存储的代理分组
+type ExportProxyGroups []ExportProxyGroup
+
+// Value 实现 driver.Valuer 接口
+func (g ExportProxyGroups) Value() (driver.Value, error) {
+	if g == nil {
+		return "[]", nil
+	}
+	return marshalJSONValue([]ExportProxyGroup(g))
+}
+
+// Scan 实现 sql.Scanner 接口
+func (g *ExportProxyGroups) Scan(value interface{}) error {
+	return scanJSONValue(value, (*[]ExportProxyGroup)(g), "ExportProxyGroups")
+}
+
+// ExportRuleProvider 规则集
+type ExportRuleProvider struct {
+	Type     string `json:"type"`     // http, file
+	Behavior string `json:"behavior"` // domain, ipcidr, classical
+	URL      string `json:"url"`
+	Path     string `json:"path"`
+	Interval int    `json:"interval"`
+	Format   string `json:"format"` // mihomo：yaml, text, mrs
+}
+
+// ExportRuleProviders 以JSON对象形式存储的规则集
+type ExportRuleProviders map[string]ExportRuleProvider
+
+// Value 实现 driver.Valuer 接口
+func (p ExportRuleProviders) Value() (driver.Value, error) {
+	if p == nil {
+		return "{}", nil
+	}
+	return marshalJSONValue(map[string]ExportRuleProvider(p))
+}
+
+// Scan 实现 sql.Scanner 接口
+func (p *ExportRuleProviders) Scan(value interface{}) error {
+	return scanJSONValue(value, (*map[string]ExportRuleProvider)(p), "ExportRuleProviders")
+}
+
+// ProxyGroupType 代理分组类型常量
+const (
+	ProxyGroupSelect      = "select"
+	ProxyGroupURLTest     = "url-test"
+	ProxyGroupFallback    = "fallback"
+	ProxyGroupLoadBalance = "load-balance"
+)
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,22 +9,28 @@ import (
 
 // Host 主机模型
 type Host struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"uniqueIndex;not null" binding:"required"`
-	Hostname    string         `json:"hostname" gorm:"not null" binding:"required"`
-	Port        int            `json:"port" gorm:"default:22"`
-	Username    string         `json:"username" gorm:"not null" binding:"required"`
-	AuthType    string         `json:"auth_type" gorm:"not null" binding:"required,oneof=password key key_password"`
-	Password    string         `json:"password,omitempty" gorm:"type:text"`                    // 加密存储
-	PrivateKey  string         `json:"private_key,omitempty" gorm:"type:text"`                 // 私钥内容，加密存储
-	KeyPath     string         `json:"key_path,omitempty"`                                     // 私钥文件路径
-	Passphrase  string         `json:"passphrase,omitempty" gorm:"type:text"`                  // 私钥密码，加密存储
-	Description string         `json:"description"`                                            // 描述
-	Status      string         `json:"status" gorm:"default:inactive"`                        // active, inactive, error
-	LastCheck   *time.Time     `json:"last_check"`                                             // 最后检查时间
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Name           string         `json:"name" gorm:"uniqueIndex;not null" binding:"required"`
+	Hostname       string         `json:"hostname" gorm:"not null" binding:"required"`
+	Port           int            `json:"port" gorm:"default:22"`
+	Username       string         `json:"username" gorm:"not null" binding:"required"`
+	AuthType       string         `json:"auth_type" gorm:"not null" binding:"required,oneof=password key key_password"`
+	Password       string         `json:"password,omitempty" gorm:"type:text"`    // 加密存储
+	PrivateKey     string         `json:"private_key,omitempty" gorm:"type:text"` // 私钥内容，加密存储
+	KeyPath        string         `json:"key_path,omitempty"`                     // 私钥文件路径
+	Passphrase     string         `json:"passphrase,omitempty" gorm:"type:text"`  // 私钥密码，加密存储
+	KeyFingerprint string         `json:"-"`                                      // 私钥对应公钥的SHA256指纹，由服务端计算
+	Description    string         `json:"description"`                            // 描述
+	UploadLimit    int64          `json:"upload_limit" gorm:"default:0"`          // 主机所有隧道合计上行限速（字节/秒），0表示不限制
+	DownloadLimit  int64          `json:"download_limit" gorm:"default:0"`        // 主机所有隧道合计下行限速（字节/秒），0表示不限制
+	JumpHostID     uint           `json:"jump_host_id" gorm:"default:0;index"`    // 跳板机主机ID，0表示直接连接
+	Labels         Labels         `json:"labels" gorm:"type:text"`                // key=value 标签，列表可以按标签选择器筛选
+	Status         string         `json:"status" gorm:"default:inactive"`         // active, inactive, error
+	LastCheck      *time.Time     `json:"last_check"`                             // 最后检查时间
+	OwnerID        uint           `json:"owner_id" gorm:"index"`                  // 所有者用户ID
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联的隧道
 	Tunnels []Tunnel `json:"tunnels,omitempty" gorm:"foreignKey:HostID"`
@@ -34,6 +41,66 @@ func (Host) TableName() string {
 	return "hosts"
 }
 
+// HostResponse 返回给客户端的主机信息，不包含密码、私钥等凭据，只标记是否已设置
+type HostResponse struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	Hostname       string     `json:"hostname"`
+	Port           int        `json:"port"`
+	Username       string     `json:"username"`
+	AuthType       string     `json:"auth_type"`
+	HasPassword    bool       `json:"has_password"`
+	HasPrivateKey  bool       `json:"has_private_key"`
+	HasPassphrase  bool       `json:"has_passphrase"`
+	KeyFingerprint string     `json:"key_fingerprint,omitempty"`
+	KeyPath        string     `json:"key_path,omitempty"`
+	Description    string     `json:"description"`
+	UploadLimit    int64      `json:"upload_limit"`
+	DownloadLimit  int64      `json:"download_limit"`
+	JumpHostID     uint       `json:"jump_host_id"`
+	Labels         Labels     `json:"labels"`
+	Status         string     `json:"status"`
+	LastCheck      *time.Time `json:"last_check"`
+	OwnerID        uint       `json:"owner_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Tunnels        []Tunnel   `json:"tunnels,omitempty"`
+}
+
+// NewHostResponse 根据主机创建响应，凭据无论是否已解密都不会被包含
+func NewHostResponse(host *Host) HostResponse {
+	return HostResponse{
+		ID:             host.ID,
+		Name:           host.Name,
+		Hostname:       host.Hostname,
+		Port:           host.Port,
+		Username:       host.Username,
+		AuthType:       host.AuthType,
+		HasPassword:    host.Password != "",
+		HasPrivateKey:  host.PrivateKey != "",
+		HasPassphrase:  host.Passphrase != "",
+		KeyFingerprint: host.KeyFingerprint,
+		KeyPath:        host.KeyPath,
+		Description:    host.Description,
+		UploadLimit:    host.UploadLimit,
+		DownloadLimit:  host.DownloadLimit,
+		JumpHostID:     host.JumpHostID,
+		Labels:         host.Labels,
+		Status:         host.Status,
+		LastCheck:      host.LastCheck,
+		OwnerID:        host.OwnerID,
+		CreatedAt:      host.CreatedAt,
+		UpdatedAt:      host.UpdatedAt,
+		Tunnels:        host.Tunnels,
+	}
+}
+
+// MarshalJSON 序列化时始终使用 HostResponse，避免通过隧道等关联数据泄露主机凭据
+// 反序列化不受影响，请求中仍可以提交凭据
+func (h Host) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewHostResponse(&h))
+}
+
 // AuthTypes 认证类型常量
 const (
 	AuthTypePassword    = "password"
@@ -46,4 +113,4 @@ const (
 	HostStatusActive   = "active"
 	HostStatusInactive = "inactive"
 	HostStatusError    = "error"
-)
\ No newline at end of file
+)
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ConnectionSession 连接会话模型，每个经过隧道的连接在关闭时记录一条
+type ConnectionSession struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TunnelID    uint      `json:"tunnel_id" gorm:"not null;index"`
+	ClientAddr  string    `json:"client_addr" gorm:"index"` // 客户端地址
+	TargetAddr  string    `json:"target_addr" gorm:"index"` // 目标地址，SOCKS5隧道为客户端请求的目的地址
+	StartTime   time.Time `json:"start_time" gorm:"index"`
+	EndTime     time.Time `json:"end_time"`
+	Duration    int64     `json:"duration"`  // 持续时间（毫秒）
+	BytesIn     int64     `json:"bytes_in"`  // 入站字节数（写给客户端）
+	BytesOut    int64     `json:"bytes_out"` // 出站字节数（从客户端读取）
+	CloseReason string    `json:"close_reason"`
+
+	// 关联的隧道
+	Tunnel *Tunnel `json:"tunnel,omitempty" gorm:"foreignKey:TunnelID"`
+}
+
+// TableName 指定表名
+func (ConnectionSession) TableName() string {
+	return "connection_sessions"
+}
+
+// SessionCloseReason 连接会话关闭原因常量
+const (
+	SessionCloseClient  = "client closed"  // 客户端关闭连接
+	SessionCloseRemote  = "remote closed"  // 目标端关闭连接
+	SessionCloseStopped = "tunnel stopped" // 隧道停止
+	SessionCloseNormal  = "closed"         // 正常结束，无法区分关闭方
+)
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ResourceShare 主机或隧道共享给其他用户的权限
+type ResourceShare struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ResourceType string    `json:"resource_type" gorm:"not null;uniqueIndex:idx_share_resource_user"` // host, tunnel
+	ResourceID   uint      `json:"resource_id" gorm:"not null;uniqueIndex:idx_share_resource_user"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_share_resource_user;index"`
+	Permission   string    `json:"permission" gorm:"not null" binding:"required,oneof=view control edit"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// 被共享的用户
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// TableName 指定表名
+func (ResourceShare) TableName() string {
+	return "resource_shares"
+}
+
+// ResourceType 可共享的资源类型常量
+const (
+	ResourceTypeHost   = "host"
+	ResourceTypeTunnel = "tunnel"
+)
+
+// Permission 资源权限常量，权限从低到高依次包含
+const (
+	PermissionView    = "view"    // 查看资源、日志和状态
+	PermissionControl = "control" // 启动、停止隧道；通过主机创建隧道
+	PermissionEdit    = "edit"    // 修改、删除资源及管理共享
+)
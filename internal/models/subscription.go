@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Subscription 代理客户端订阅，客户端通过带令牌的订阅地址定期拉取最新配置，令牌只保存哈希值
+type Subscription struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Name           string     `json:"name" gorm:"not null"`
+	Format         string     `json:"format" gorm:"not null;default:clash"` // 导出格式，见 /export/{format}
+	ProfileID      *uint      `json:"profile_id" gorm:"index"`              // 导出模板，为空时使用默认模板
+	Prefix         string     `json:"prefix" gorm:"not null"`               // 令牌前缀，用于在列表中辨认订阅
+	TokenHash      string     `json:"-" gorm:"uniqueIndex;not null"`        // 令牌的SHA-256哈希
+	UpdateInterval int        `json:"update_interval" gorm:"default:24"`    // 建议客户端的自动更新间隔（小时）
+	ExpiresAt      *time.Time `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	LastFetchedAt  *time.Time `json:"last_fetched_at"`
+	FetchCount     int64      `json:"fetch_count" gorm:"default:0"`
+	CreatedBy      uint       `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// 关联的导出模板
+	Profile *ExportProfile `json:"profile,omitempty" gorm:"foreignKey:ProfileID"`
+}
+
+// TableName 指定表名
+func (Subscription) TableName() string {
+	return "subscriptions"
+}
+
+// SubscriptionFetch 订阅拉取记录，包括令牌无效以外的所有拉取
+type SubscriptionFetch struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;index"`
+	SourceIP       string    `json:"source_ip"`
+	UserAgent      string    `json:"user_agent"`
+	StatusCode     int       `json:"status_code"`
+	Error          string    `json:"error,omitempty"`
+	Bytes          int       `json:"bytes"`
+	FetchedAt      time.Time `json:"fetched_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (SubscriptionFetch) TableName() string {
+	return "subscription_fetches"
+}
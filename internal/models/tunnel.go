@@ -8,20 +8,50 @@ import (
 
 // Tunnel 隧道模型
 type Tunnel struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	HostID        uint           `json:"host_id" gorm:"not null" binding:"required"`
-	Name          string         `json:"name" gorm:"not null" binding:"required"`
-	Type          string         `json:"type" gorm:"not null" binding:"required,oneof=local_forward remote_forward dynamic"`
-	LocalAddress  string         `json:"local_address" gorm:"default:127.0.0.1"`
-	LocalPort     int            `json:"local_port" gorm:"not null" binding:"required"`
-	RemoteAddress string         `json:"remote_address"`
-	RemotePort    int            `json:"remote_port"`
-	Description   string         `json:"description"`
-	Status        string         `json:"status" gorm:"default:inactive"`
-	AutoStart     bool           `json:"auto_start" gorm:"default:false"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	HostID         uint           `json:"host_id" gorm:"not null" binding:"required"`
+	Name           string         `json:"name" gorm:"not null" binding:"required"`
+	Type           string         `json:"type" gorm:"not null" binding:"required,oneof=local_forward remote_forward dynamic"`
+	LocalAddress   string         `json:"local_address" gorm:"default:127.0.0.1"`
+	LocalPort      int            `json:"local_port" gorm:"not null" binding:"required"`
+	RemoteAddress  string         `json:"remote_address"`
+	RemotePort     int            `json:"remote_port"`
+	Description    string         `json:"description"`
+	Status         string         `json:"status" gorm:"default:inactive"`
+	AutoStart      bool           `json:"auto_start" gorm:"default:false"`
+	UploadLimit    int64          `json:"upload_limit" gorm:"default:0"`   // 上行限速（字节/秒），0表示不限制
+	DownloadLimit  int64          `json:"download_limit" gorm:"default:0"` // 下行限速（字节/秒），0表示不限制
+	QuotaBytes     int64          `json:"quota_bytes" gorm:"default:0"`    // 每个周期的流量配额（字节），0表示不限制
+	QuotaPeriod    string         `json:"quota_period" binding:"omitempty,oneof=daily monthly"`
+	StatusReason   string         `json:"status_reason"`                                          // 状态原因，例如配额耗尽被暂停
+	MaxConnections int            `json:"max_connections" gorm:"default:0"`                       // 最大并发连接数，0表示不限制
+	OverflowPolicy string         `json:"overflow_policy" binding:"omitempty,oneof=reject queue"` // 超过最大连接数时的处理策略
+	IdleTimeout    int            `json:"idle_timeout" gorm:"default:0"`                          // 连接空闲超时（秒），0表示不限制
+	MaxLifetime    int            `json:"max_lifetime" gorm:"default:0"`                          // 连接最长存活时间（秒），0表示不限制
+	ProxyDomains   StringList     `json:"proxy_domains" gorm:"type:text"`                         // 导出代理客户端配置时经由此动态隧道访问的域名，包含子域名
+	Tags           StringList     `json:"tags" gorm:"type:text"`                                  // 标签，导出模板可以按标签选择隧道
+	Lazy           bool           `json:"lazy" gorm:"default:false"`                              // 按需连接：启动时只监听端口，首个客户端连接时才建立SSH连接，仅支持本地转发和动态隧道
+	LazyTimeout    int            `json:"lazy_timeout" gorm:"default:0"`                          // 按需连接的隧道没有客户端连接后断开SSH连接的时间（秒），0表示默认300秒
+	DependsOn      UintList       `json:"depends_on" gorm:"type:text"`                            // 启动前需要先运行的隧道ID，按组启动和自动启动时按依赖顺序启动
+	Labels         Labels         `json:"labels" gorm:"type:text"`                                // key=value 标签，列表和批量操作可以按标签选择器筛选
+	StartCron      string         `json:"start_cron"`                                             // 按计划启动的 cron 表达式，例如 0 1 * * *
+	StopCron       string         `json:"stop_cron"`                                              // 按计划停止的 cron 表达式，例如 0 4 * * *
+	TimeWindows    StringList     `json:"time_windows" gorm:"type:text"`                          // 每周运行的时间段，例如 mon-fri 01:00-04:00，不能与 cron 表达式同时使用
+	Timezone       string         `json:"timezone"`                                               // 计划使用的 IANA 时区，为空时使用服务器本地时区
+	HealthCheck    string         `json:"health_check"`                                           // 健康检查方式：tcp、http 或 payload，为空表示不检查
+	HealthTarget   string         `json:"health_target"`                                          // 健康检查的目标地址 host:port，为空时使用本地转发的远程目标或远程转发的本地服务
+	HealthPath     string         `json:"health_path"`                                            // http 检查的请求路径，默认 /
+	HealthCode     int            `json:"health_code" gorm:"default:0"`                           // http 检查期望的状态码，0表示任意 2xx 或 3xx
+	HealthSend     string         `json:"health_send"`                                            // payload 检查连接后发送的内容
+	HealthExpect   string         `json:"health_expect"`                                          // payload 检查的响应或 http 检查的响应体需要包含的内容
+	HealthInterval int            `json:"health_interval" gorm:"default:0"`                       // 健康检查间隔（秒），0表示默认30秒
+	HealthTimeout  int            `json:"health_timeout" gorm:"default:0"`                        // 单次健康检查超时（秒），0表示默认5秒
+	HealthFailures int            `json:"health_failures" gorm:"default:0"`                       // 连续失败多少次后标记为 degraded，0表示默认3次
+	HealthRestart  bool           `json:"health_restart" gorm:"default:false"`                    // 标记为 degraded 时自动重启隧道
+	OwnerID        uint           `json:"owner_id" gorm:"index"`                                  // 所有者用户ID
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联的主机
 	Host *Host `json:"host,omitempty" gorm:"foreignKey:HostID"`
@@ -37,11 +67,12 @@ func (Tunnel) TableName() string {
 
 // ConnectionLog 连接日志模型
 type ConnectionLog struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	TunnelID  uint      `json:"tunnel_id" gorm:"not null"`
-	EventType string    `json:"event_type" gorm:"not null"` // connect, disconnect, error
-	Message   string    `json:"message" gorm:"type:text"`
-	Timestamp time.Time `json:"timestamp" gorm:"default:CURRENT_TIMESTAMP"`
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TunnelID   uint      `json:"tunnel_id" gorm:"not null;index"`
+	EventType  string    `json:"event_type" gorm:"not null;index"`   // connect, disconnect, error
+	ClientAddr string    `json:"client_addr,omitempty" gorm:"index"` // 客户端地址，仅连接相关事件记录
+	Message    string    `json:"message" gorm:"type:text"`
+	Timestamp  time.Time `json:"timestamp" gorm:"default:CURRENT_TIMESTAMP;index"`
 
 	// 关联的隧道
 	Tunnel *Tunnel `json:"tunnel,omitempty" gorm:"foreignKey:TunnelID"`
@@ -61,9 +92,37 @@ const (
 
 // TunnelStatus 隧道状态常量
 const (
-	TunnelStatusActive   = "active"
-	TunnelStatusInactive = "inactive"
-	TunnelStatusError    = "error"
+	TunnelStatusActive    = "active"
+	TunnelStatusInactive  = "inactive"
+	TunnelStatusError     = "error"
+	TunnelStatusSuspended = "suspended" // 流量配额耗尽被暂停
+	TunnelStatusStandby   = "standby"   // 按需连接的隧道正在监听端口，SSH未连接
+	TunnelStatusConnected = "connected" // 按需连接的隧道SSH已连接
+	TunnelStatusDegraded  = "degraded"  // 隧道在运行，但健康检查连续失败
+)
+
+// IsRunningStatus 检查状态是否表示隧道正在运行
+func IsRunningStatus(status string) bool {
+	return status == TunnelStatusActive || status == TunnelStatusStandby || status == TunnelStatusConnected || status == TunnelStatusDegraded
+}
+
+// HealthCheck 健康检查方式常量
+const (
+	HealthCheckTCP     = "tcp"     // 只检查能否建立TCP连接
+	HealthCheckHTTP    = "http"    // 发送 HTTP GET 请求并检查状态码
+	HealthCheckPayload = "payload" // 发送自定义内容并检查响应
+)
+
+// QuotaPeriod 流量配额周期常量
+const (
+	QuotaPeriodDaily   = "daily"
+	QuotaPeriodMonthly = "monthly"
+)
+
+// OverflowPolicy 超过最大连接数时的处理策略常量
+const (
+	OverflowPolicyReject = "reject" // 直接拒绝新连接
+	OverflowPolicyQueue  = "queue"  // 排队等待空闲槽位，队列已满时拒绝
 )
 
 // LogEventType 日志事件类型常量
@@ -73,16 +132,23 @@ const (
 	LogEventError      = "error"
 	LogEventStart      = "start"
 	LogEventStop       = "stop"
+	LogEventSuspend    = "suspend"
+	LogEventResume     = "resume"
+	LogEventReject     = "reject"
+	LogEventQueue      = "queue"
+	LogEventTimeout    = "timeout"
+	LogEventDegraded   = "degraded"
+	LogEventRecovered  = "recovered"
 )
 
 // TrafficStats 流量统计模型
 type TrafficStats struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	TunnelID    uint      `json:"tunnel_id" gorm:"not null;index"`
-	BytesIn     int64     `json:"bytes_in" gorm:"default:0"`     // 入站字节数
-	BytesOut    int64     `json:"bytes_out" gorm:"default:0"`    // 出站字节数
-	Connections int64     `json:"connections" gorm:"default:0"`  // 连接数
-	StartTime   time.Time `json:"start_time"`
+	BytesIn     int64     `json:"bytes_in" gorm:"default:0"`    // 入站字节数
+	BytesOut    int64     `json:"bytes_out" gorm:"default:0"`   // 出站字节数
+	Connections int64     `json:"connections" gorm:"default:0"` // 连接数
+	StartTime   time.Time `json:"start_time" gorm:"index"`      // 统计周期开始时间（按天统计）
 	EndTime     time.Time `json:"end_time"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -98,11 +164,11 @@ func (TrafficStats) TableName() string {
 
 // RealtimeTrafficStats 实时流量统计
 type RealtimeTrafficStats struct {
-	TunnelID         uint    `json:"tunnel_id"`
-	CurrentBytesIn   int64   `json:"current_bytes_in"`
-	CurrentBytesOut  int64   `json:"current_bytes_out"`
-	ActiveConnections int    `json:"active_connections"`
-	SpeedIn          float64 `json:"speed_in"`          // bytes/second
-	SpeedOut         float64 `json:"speed_out"`         // bytes/second
-	LastUpdateTime   time.Time `json:"last_update_time"`
-}
\ No newline at end of file
+	TunnelID          uint      `json:"tunnel_id"`
+	CurrentBytesIn    int64     `json:"current_bytes_in"`
+	CurrentBytesOut   int64     `json:"current_bytes_out"`
+	ActiveConnections int       `json:"active_connections"`
+	SpeedIn           float64   `json:"speed_in"`  // bytes/second
+	SpeedOut          float64   `json:"speed_out"` // bytes/second
+	LastUpdateTime    time.Time `json:"last_update_time"`
+}
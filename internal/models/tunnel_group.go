@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// TunnelGroup 隧道组，组内的隧道可以一起启动和停止
+type TunnelGroup struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description string    `json:"description"`
+	OwnerID     uint      `json:"owner_id" gorm:"index"` // 创建者用户ID
+	Tunnels     []Tunnel  `json:"tunnels" gorm:"many2many:tunnel_group_members"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (TunnelGroup) TableName() string {
+	return "tunnel_groups"
+}
+
+// TunnelIDs 返回组内隧道的ID
+func (g *TunnelGroup) TunnelIDs() []uint {
+	ids := make([]uint, 0, len(g.Tunnels))
+	for _, tunnel := range g.Tunnels {
+		ids = append(ids, tunnel.ID)
+	}
+	return ids
+}
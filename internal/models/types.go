@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList 以JSON数组形式存储的字符串列表
+type StringList []string
+
+// Value 实现 driver.Valuer 接口
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// Contains 检查列表是否包含指定值
+func (l StringList) Contains(value string) bool {
+	for _, item := range l {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// UintList 以JSON数组形式存储的ID列表
+type UintList []uint
+
+// Value 实现 driver.Valuer 接口
+func (l UintList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return marshalJSONValue([]uint(l))
+}
+
+// Scan 实现 sql.Scanner 接口
+func (l *UintList) Scan(value interface{}) error {
+	return scanJSONValue(value, (*[]uint)(l), "UintList")
+}
+
+// Contains 检查列表是否包含指定ID
+func (l UintList) Contains(id uint) bool {
+	for _, item := range l {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalJSONValue 将值序列化为JSON字符串，用于以JSON形式存储的字段
+func marshalJSONValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// scanJSONValue 将数据库中的JSON字符串反序列化到 dest，空值时保持零值
+func scanJSONValue(value interface{}, dest interface{}, name string) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into %s", value, name)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Labels 以JSON对象形式存储的 key=value 标签
+type Labels map[string]string
+
+// Value 实现 driver.Valuer 接口
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	return marshalJSONValue(map[string]string(l))
+}
+
+// Scan 实现 sql.Scanner 接口
+func (l *Labels) Scan(value interface{}) error {
+	*l = nil
+	if err := scanJSONValue(value, (*map[string]string)(l), "Labels"); err != nil {
+		return err
+	}
+	if len(*l) == 0 {
+		*l = nil
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User 管理界面和API的本地用户
+type User struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Username     string         `json:"username" gorm:"uniqueIndex;not null" binding:"required"`
+	PasswordHash string         `json:"-" gorm:"not null"` // bcrypt哈希
+	Role         string         `json:"role" gorm:"not null;default:viewer"`
+	Disabled     bool           `json:"disabled" gorm:"default:false"`
+	LastLoginAt  *time.Time     `json:"last_login_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (User) TableName() string {
+	return "users"
+}
+
+// UserRole 用户角色常量
+const (
+	UserRoleAdmin    = "admin"    // 管理所有资源和用户
+	UserRoleOperator = "operator" // 创建资源，管理自己拥有或被共享的资源
+	UserRoleViewer   = "viewer"   // 只能查看自己拥有或被共享的资源
+)
+
+// UserSession 用户登录会话，会话令牌只保存哈希值
+type UserSession struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TokenHash  string    `json:"-" gorm:"uniqueIndex;not null"` // 会话令牌的SHA-256哈希
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	CSRFToken  string    `json:"-" gorm:"not null"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// 关联的用户
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
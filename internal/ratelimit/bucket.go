@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bucket 令牌桶限速器，令牌单位为字节
+type Bucket struct {
+	mutex  sync.Mutex
+	rate   int64   // 每秒补充的令牌数，为0表示不限速
+	burst  int64   // 桶容量
+	tokens float64 // 当前令牌数，可以为负数表示欠账
+	last   time.Time
+}
+
+// NewBucket 创建令牌桶，rate<=0 时返回 nil 表示不限速
+func NewBucket(rate int64) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  rate,
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// NewAdjustableBucket 创建速率可以随时调整的令牌桶，rate<=0 时暂不限速，之后可以通过 SetRate 开始限速
+// 多个使用者共享的令牌桶应使用它创建，速率变化后所有使用者仍然共享同一个桶
+func NewAdjustableBucket(rate int64) *Bucket {
+	b := &Bucket{last: time.Now()}
+	b.SetRate(rate)
+	return b
+}
+
+// Rate 获取当前速率（字节/秒）
+func (b *Bucket) Rate() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.rate
+}
+
+// SetRate 调整速率，rate<=0 时取消限速
+func (b *Bucket) SetRate(rate int64) {
+	if b == nil {
+		return
+	}
+	if rate < 0 {
+		rate = 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	if b.rate == 0 {
+		// 从不限速开始限速时桶是满的
+		b.tokens = float64(rate)
+		b.last = now
+	} else {
+		b.refill(now)
+	}
+	b.rate = rate
+	b.burst = rate
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+}
+
+// WaitN 消耗 n 个令牌，令牌不足时阻塞直到补足或上下文取消
+func (b *Bucket) WaitN(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+
+	delay := b.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve 预留令牌并返回需要等待的时间
+func (b *Bucket) reserve(n int) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+	b.refill(time.Now())
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+}
+
+// refill 按经过的时间补充令牌
+func (b *Bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * float64(b.rate)
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+	}
+	b.last = now
+}
+
+// WaitAll 依次在多个令牌桶上等待，nil 桶会被忽略
+func WaitAll(ctx context.Context, n int, buckets ...*Bucket) error {
+	for _, bucket := range buckets {
+		if err := bucket.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditRepository 审计记录数据仓库接口
+type AuditRepository interface {
+	Create(event *models.AuditEvent) error
+	Query(filter AuditFilter) ([]models.AuditEvent, error)
+}
+
+// AuditFilter 审计记录查询条件
+type AuditFilter struct {
+	Username     string     // 操作用户名
+	Action       string     // 操作类型
+	ResourceType string     // 资源类型
+	ResourceID   uint       // 资源ID，为0表示不限制
+	Since        *time.Time // 时间下限（包含）
+	Until        *time.Time // 时间上限（不包含）
+	BeforeID     uint       // 分页游标，只返回ID小于该值的记录
+	Limit        int
+}
+
+// auditRepository 审计记录数据仓库实现
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository 创建审计记录数据仓库实例
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create 创建审计记录
+func (r *auditRepository) Create(event *models.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// Query 按条件查询审计记录，按ID倒序返回
+func (r *auditRepository) Query(filter AuditFilter) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	query := r.db.Model(&models.AuditEvent{})
+
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != 0 {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	err := query.Order("id DESC").Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// ExportProfileRepository 导出模板数据仓库接口
+type ExportProfileRepository interface {
+	Create(profile *models.ExportProfile) error
+	GetByID(id uint) (*models.ExportProfile, error)
+	GetByName(name string) (*models.ExportProfile, error)
+	GetAll() ([]models.ExportProfile, error)
+	Update(profile *models.ExportProfile) error
+	Delete(id uint) error
+}
+
+// exportProfileRepository 导出模板数据仓库实现
+type exportProfileRepository struct {
+	db *gorm.DB
+}
+
+// NewExportProfileRepository 创建导出模板数据仓库实例
+func NewExportProfileRepository(db *gorm.DB) ExportProfileRepository {
+	return &exportProfileRepository{db: db}
+}
+
+// Create 创建导出模板
+func (r *exportProfileRepository) Create(profile *models.ExportProfile) error {
+	return r.db.Create(profile).Error
+}
+
+// GetByID 根据ID获取导出模板
+func (r *exportProfileRepository) GetByID(id uint) (*models.ExportProfile, error) {
+	var profile models.ExportProfile
+	err := r.db.First(&profile, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetByName 根据名称获取导出模板
+func (r *exportProfileRepository) GetByName(name string) (*models.ExportProfile, error) {
+	var profile models.ExportProfile
+	err := r.db.Where("name = ?", name).First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetAll 获取所有导出模板
+func (r *exportProfileRepository) GetAll() ([]models.ExportProfile, error) {
+	var profiles []models.ExportProfile
+	err := r.db.Order("name").Find(&profiles).Error
+	return profiles, err
+}
+
+// Update 更新导出模板
+func (r *exportProfileRepository) Update(profile *models.ExportProfile) error {
+	return r.db.Save(profile).Error
+}
+
+// Delete 删除导出模板
+func (r *exportProfileRepository) Delete(id uint) error {
+	return r.db.Delete(&models.ExportProfile{}, id).Error
+}
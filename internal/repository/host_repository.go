@@ -16,6 +16,8 @@ type HostRepository interface {
 	Update(host *models.Host) error
 	Delete(id uint) error
 	UpdateStatus(id uint, status string) error
+	UpdateKeyFingerprint(id uint, fingerprint string) error
+	TransferOwner(fromOwnerID, toOwnerID uint) (int64, error)
 }
 
 // hostRepository 主机数据仓库实现
@@ -74,6 +76,15 @@ func (r *hostRepository) Delete(id uint) error {
 		return errors.New("cannot delete host with active tunnels")
 	}
 
+	// 检查是否被其他主机用作跳板机
+	r.db.Model(&models.Host{}).Where("jump_host_id = ?", id).Count(&count)
+	if count > 0 {
+		return errors.New("cannot delete host used as jump host by other hosts")
+	}
+
+	// 删除主机的共享
+	r.db.Where("resource_type = ? AND resource_id = ?", models.ResourceTypeHost, id).Delete(&models.ResourceShare{})
+
 	return r.db.Unscoped().Delete(&models.Host{}, id).Error
 }
 
@@ -81,3 +92,14 @@ func (r *hostRepository) Delete(id uint) error {
 func (r *hostRepository) UpdateStatus(id uint, status string) error {
 	return r.db.Model(&models.Host{}).Where("id = ?", id).Update("status", status).Error
 }
+
+// UpdateKeyFingerprint 更新主机私钥指纹
+func (r *hostRepository) UpdateKeyFingerprint(id uint, fingerprint string) error {
+	return r.db.Model(&models.Host{}).Where("id = ?", id).Update("key_fingerprint", fingerprint).Error
+}
+
+// TransferOwner 将一个用户拥有的主机转移给另一个用户，fromOwnerID 为0时认领无主的主机
+func (r *hostRepository) TransferOwner(fromOwnerID, toOwnerID uint) (int64, error) {
+	result := r.db.Model(&models.Host{}).Where("owner_id = ?", fromOwnerID).Update("owner_id", toOwnerID)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// SessionRepository 连接会话数据仓库接口
+type SessionRepository interface {
+	Create(session *models.ConnectionSession) error
+	GetByID(id uint) (*models.ConnectionSession, error)
+	Query(filter SessionFilter) ([]models.ConnectionSession, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// SessionFilter 连接会话查询条件
+type SessionFilter struct {
+	TunnelID   uint       // 为0表示所有隧道
+	TunnelIDs  []uint     // 限定在这些隧道内，为nil表示不限制
+	ClientAddr string     // 客户端地址，支持部分匹配
+	TargetAddr string     // 目标地址，支持部分匹配
+	Since      *time.Time // 会话开始时间下限（包含）
+	Until      *time.Time // 会话开始时间上限（不包含）
+	BeforeID   uint       // 分页游标，只返回ID小于该值的会话
+	Limit      int
+}
+
+// sessionRepository 连接会话数据仓库实现
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository 创建连接会话数据仓库实例
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Create 创建连接会话记录
+func (r *sessionRepository) Create(session *models.ConnectionSession) error {
+	return r.db.Create(session).Error
+}
+
+// GetByID 根据ID获取连接会话
+func (r *sessionRepository) GetByID(id uint) (*models.ConnectionSession, error) {
+	var session models.ConnectionSession
+	err := r.db.First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Query 按条件查询连接会话，按ID倒序返回
+func (r *sessionRepository) Query(filter SessionFilter) ([]models.ConnectionSession, error) {
+	var sessions []models.ConnectionSession
+	query := r.db.Model(&models.ConnectionSession{})
+
+	if filter.TunnelID != 0 {
+		query = query.Where("tunnel_id = ?", filter.TunnelID)
+	}
+	if filter.TunnelIDs != nil {
+		query = query.Where("tunnel_id IN ?", filter.TunnelIDs)
+	}
+	if filter.ClientAddr != "" {
+		query = query.Where("client_addr LIKE ?", "%"+filter.ClientAddr+"%")
+	}
+	if filter.TargetAddr != "" {
+		query = query.Where("target_addr LIKE ?", "%"+filter.TargetAddr+"%")
+	}
+	if filter.Since != nil {
+		query = query.Where("start_time >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("start_time < ?", *filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	err := query.Order("id DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// DeleteBefore 删除指定时间之前结束的连接会话，返回删除的条数
+func (r *sessionRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("end_time < ?", before).Delete(&models.ConnectionSession{})
+	return result.RowsAffected, result.Error
+}
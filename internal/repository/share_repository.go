@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ShareRepository 资源共享数据仓库接口
+type ShareRepository interface {
+	Upsert(share *models.ResourceShare) error
+	GetByResource(resourceType string, resourceID uint) ([]models.ResourceShare, error)
+	GetByUser(userID uint) ([]models.ResourceShare, error)
+	Delete(resourceType string, resourceID, userID uint) error
+	DeleteByResource(resourceType string, resourceID uint) error
+	DeleteByUser(userID uint) error
+}
+
+// shareRepository 资源共享数据仓库实现
+type shareRepository struct {
+	db *gorm.DB
+}
+
+// NewShareRepository 创建资源共享数据仓库实例
+func NewShareRepository(db *gorm.DB) ShareRepository {
+	return &shareRepository{db: db}
+}
+
+// Upsert 创建共享，已存在时更新权限
+func (r *shareRepository) Upsert(share *models.ResourceShare) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "resource_type"}, {Name: "resource_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"permission", "updated_at"}),
+	}).Create(share).Error
+}
+
+// GetByResource 获取资源的所有共享
+func (r *shareRepository) GetByResource(resourceType string, resourceID uint) ([]models.ResourceShare, error) {
+	var shares []models.ResourceShare
+	err := r.db.Preload("User").Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).Find(&shares).Error
+	return shares, err
+}
+
+// GetByUser 获取共享给用户的所有资源
+func (r *shareRepository) GetByUser(userID uint) ([]models.ResourceShare, error) {
+	var shares []models.ResourceShare
+	err := r.db.Where("user_id = ?", userID).Find(&shares).Error
+	return shares, err
+}
+
+// Delete 取消资源对某个用户的共享
+func (r *shareRepository) Delete(resourceType string, resourceID, userID uint) error {
+	return r.db.Where("resource_type = ? AND resource_id = ? AND user_id = ?", resourceType, resourceID, userID).Delete(&models.ResourceShare{}).Error
+}
+
+// DeleteByResource 删除资源的所有共享
+func (r *shareRepository) DeleteByResource(resourceType string, resourceID uint) error {
+	return r.db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).Delete(&models.ResourceShare{}).Error
+}
+
+// DeleteByUser 删除共享给用户的所有资源
+func (r *shareRepository) DeleteByUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.ResourceShare{}).Error
+}
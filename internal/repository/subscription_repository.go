@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// SubscriptionRepository 订阅数据仓库接口
+type SubscriptionRepository interface {
+	Create(subscription *models.Subscription) error
+	GetByID(id uint) (*models.Subscription, error)
+	GetByHash(tokenHash string) (*models.Subscription, error)
+	GetAll() ([]models.Subscription, error)
+	CountByProfileID(profileID uint) (int64, error)
+	Revoke(id uint, revokedAt time.Time) error
+	RecordFetch(fetch *models.SubscriptionFetch) error
+	GetFetches(subscriptionID uint, limit int) ([]models.SubscriptionFetch, error)
+}
+
+// subscriptionRepository 订阅数据仓库实现
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewSubscriptionRepository 创建订阅数据仓库实例
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{db: db}
+}
+
+// Create 创建订阅
+func (r *subscriptionRepository) Create(subscription *models.Subscription) error {
+	return r.db.Create(subscription).Error
+}
+
+// GetByID 根据ID获取订阅
+func (r *subscriptionRepository) GetByID(id uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := r.db.Preload("Profile").First(&subscription, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// GetByHash 根据令牌哈希获取订阅
+func (r *subscriptionRepository) GetByHash(tokenHash string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := r.db.Preload("Profile").Where("token_hash = ?", tokenHash).First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// GetAll 获取所有订阅，最新创建的在前
+func (r *subscriptionRepository) GetAll() ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.Preload("Profile").Order("created_at DESC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// CountByProfileID 统计使用导出模板的未吊销订阅数量
+func (r *subscriptionRepository) CountByProfileID(profileID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Subscription{}).
+		Where("profile_id = ? AND revoked_at IS NULL", profileID).
+		Count(&count).Error
+	return count, err
+}
+
+// Revoke 吊销订阅
+func (r *subscriptionRepository) Revoke(id uint, revokedAt time.Time) error {
+	return r.db.Model(&models.Subscription{}).Where("id = ?", id).Update("revoked_at", revokedAt).Error
+}
+
+// RecordFetch 写入拉取记录，成功的拉取同时更新订阅的拉取次数和最后拉取时间
+func (r *subscriptionRepository) RecordFetch(fetch *models.SubscriptionFetch) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(fetch).Error; err != nil {
+			return err
+		}
+		if fetch.Error != "" {
+			return nil
+		}
+		return tx.Model(&models.Subscription{}).Where("id = ?", fetch.SubscriptionID).Updates(map[string]interface{}{
+			"fetch_count":     gorm.Expr("fetch_count + 1"),
+			"last_fetched_at": fetch.FetchedAt,
+		}).Error
+	})
+}
+
+// GetFetches 获取订阅最近的拉取记录
+func (r *subscriptionRepository) GetFetches(subscriptionID uint, limit int) ([]models.SubscriptionFetch, error) {
+	var fetches []models.SubscriptionFetch
+	err := r.db.Where("subscription_id = ?", subscriptionID).
+		Order("fetched_at DESC").
+		Limit(limit).
+		Find(&fetches).Error
+	return fetches, err
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// TokenRepository API令牌数据仓库接口
+type TokenRepository interface {
+	Create(token *models.APIToken) error
+	GetByID(id uint) (*models.APIToken, error)
+	GetByHash(tokenHash string) (*models.APIToken, error)
+	GetByUserID(userID uint) ([]models.APIToken, error)
+	Delete(id uint) error
+	DeleteByUserID(userID uint) error
+	UpdateLastUsed(id uint, usedAt time.Time, ip string) error
+}
+
+// tokenRepository API令牌数据仓库实现
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository 创建API令牌数据仓库实例
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// Create 创建API令牌
+func (r *tokenRepository) Create(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByID 根据ID获取API令牌
+func (r *tokenRepository) GetByID(id uint) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.First(&token, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByHash 根据令牌哈希获取API令牌及其用户
+func (r *tokenRepository) GetByHash(tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Preload("User").Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByUserID 获取用户的所有API令牌
+func (r *tokenRepository) GetByUserID(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Delete 吊销API令牌
+func (r *tokenRepository) Delete(id uint) error {
+	return r.db.Delete(&models.APIToken{}, id).Error
+}
+
+// DeleteByUserID 吊销用户的所有API令牌
+func (r *tokenRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.APIToken{}).Error
+}
+
+// UpdateLastUsed 记录令牌最后使用的时间和来源地址
+func (r *tokenRepository) UpdateLastUsed(id uint, usedAt time.Time, ip string) error {
+	return r.db.Model(&models.APIToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": usedAt,
+		"last_used_ip": ip,
+	}).Error
+}
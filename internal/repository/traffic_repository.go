@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// TrafficRepository 流量统计数据仓库接口
+type TrafficRepository interface {
+	AddTraffic(tunnelID uint, startTime, endTime time.Time, bytesIn, bytesOut, connections int64) error
+	GetStats(tunnelID uint, startTime, endTime time.Time) ([]models.TrafficStats, error)
+	SumTraffic(tunnelID uint, startTime, endTime time.Time) (int64, int64, error)
+}
+
+// trafficRepository 流量统计数据仓库实现
+type trafficRepository struct {
+	db *gorm.DB
+}
+
+// NewTrafficRepository 创建流量统计数据仓库实例
+func NewTrafficRepository(db *gorm.DB) TrafficRepository {
+	return &trafficRepository{db: db}
+}
+
+// AddTraffic 将流量累加到指定时间段的统计记录中，记录不存在时创建
+func (r *trafficRepository) AddTraffic(tunnelID uint, startTime, endTime time.Time, bytesIn, bytesOut, connections int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TrafficStats{}).
+			Where("tunnel_id = ? AND start_time = ?", tunnelID, startTime).
+			Updates(map[string]interface{}{
+				"bytes_in":    gorm.Expr("bytes_in + ?", bytesIn),
+				"bytes_out":   gorm.Expr("bytes_out + ?", bytesOut),
+				"connections": gorm.Expr("connections + ?", connections),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+
+		return tx.Create(&models.TrafficStats{
+			TunnelID:    tunnelID,
+			BytesIn:     bytesIn,
+			BytesOut:    bytesOut,
+			Connections: connections,
+			StartTime:   startTime,
+			EndTime:     endTime,
+		}).Error
+	})
+}
+
+// GetStats 获取时间范围内的流量统计记录
+func (r *trafficRepository) GetStats(tunnelID uint, startTime, endTime time.Time) ([]models.TrafficStats, error) {
+	var stats []models.TrafficStats
+	err := r.db.Where("tunnel_id = ? AND start_time >= ? AND start_time < ?", tunnelID, startTime, endTime).
+		Order("start_time ASC").
+		Find(&stats).Error
+	return stats, err
+}
+
+// SumTraffic 汇总时间范围内的入站和出站字节数
+func (r *trafficRepository) SumTraffic(tunnelID uint, startTime, endTime time.Time) (int64, int64, error) {
+	var result struct {
+		BytesIn  int64
+		BytesOut int64
+	}
+	err := r.db.Model(&models.TrafficStats{}).
+		Select("COALESCE(SUM(bytes_in), 0) AS bytes_in, COALESCE(SUM(bytes_out), 0) AS bytes_out").
+		Where("tunnel_id = ? AND start_time >= ? AND start_time < ?", tunnelID, startTime, endTime).
+		Scan(&result).Error
+	return result.BytesIn, result.BytesOut, err
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// TunnelGroupRepository 隧道组数据仓库接口
+type TunnelGroupRepository interface {
+	Create(group *models.TunnelGroup) error
+	GetByID(id uint) (*models.TunnelGroup, error)
+	GetByName(name string) (*models.TunnelGroup, error)
+	GetAll() ([]models.TunnelGroup, error)
+	Update(group *models.TunnelGroup) error
+	Delete(id uint) error
+}
+
+// tunnelGroupRepository 隧道组数据仓库实现
+type tunnelGroupRepository struct {
+	db *gorm.DB
+}
+
+// NewTunnelGroupRepository 创建隧道组数据仓库实例
+func NewTunnelGroupRepository(db *gorm.DB) TunnelGroupRepository {
+	return &tunnelGroupRepository{db: db}
+}
+
+// Create 创建隧道组，只保存与隧道的关联，不修改隧道本身
+func (r *tunnelGroupRepository) Create(group *models.TunnelGroup) error {
+	return r.db.Omit("Tunnels.*").Create(group).Error
+}
+
+// GetByID 根据ID获取隧道组，包含组内的隧道
+func (r *tunnelGroupRepository) GetByID(id uint) (*models.TunnelGroup, error) {
+	var group models.TunnelGroup
+	err := r.db.Preload("Tunnels").First(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetByName 根据名称获取隧道组
+func (r *tunnelGroupRepository) GetByName(name string) (*models.TunnelGroup, error) {
+	var group models.TunnelGroup
+	err := r.db.Where("name = ?", name).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetAll 获取所有隧道组
+func (r *tunnelGroupRepository) GetAll() ([]models.TunnelGroup, error) {
+	var groups []models.TunnelGroup
+	err := r.db.Preload("Tunnels").Order("name").Find(&groups).Error
+	return groups, err
+}
+
+// Update 更新隧道组并替换组内的隧道
+func (r *tunnelGroupRepository) Update(group *models.TunnelGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM tunnel_group_members WHERE tunnel_group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Tunnels.*").Save(group).Error
+	})
+}
+
+// Delete 删除隧道组，组内的隧道保留
+func (r *tunnelGroupRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM tunnel_group_members WHERE tunnel_group_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.TunnelGroup{}, id).Error
+	})
+}
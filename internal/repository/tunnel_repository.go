@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/KodaTao/drilling/internal/models"
 	"gorm.io/gorm"
 )
@@ -15,9 +17,29 @@ type TunnelRepository interface {
 	Update(tunnel *models.Tunnel) error
 	Delete(id uint) error
 	UpdateStatus(id uint, status string) error
+	UpdateStatusWithReason(id uint, status, reason string) error
+	UpdateDependsOn(id uint, dependsOn models.UintList) error
+	TransferOwner(fromOwnerID, toOwnerID uint) (int64, error)
 	GetAutoStartTunnels() ([]models.Tunnel, error)
 	AddConnectionLog(log *models.ConnectionLog) error
 	GetConnectionLogs(tunnelID uint, limit int) ([]models.ConnectionLog, error)
+	QueryConnectionLogs(filter ConnectionLogFilter) ([]models.ConnectionLog, error)
+	DeleteConnectionLogsBefore(before time.Time) (int64, error)
+	TrimConnectionLogs(tunnelID uint, keep int) (int64, error)
+	GetLoggedTunnelIDs() ([]uint, error)
+}
+
+// ConnectionLogFilter 连接日志查询条件
+type ConnectionLogFilter struct {
+	TunnelID   uint       // 为0表示所有隧道
+	TunnelIDs  []uint     // 限定在这些隧道内，为nil表示不限制
+	EventTypes []string   // 事件类型
+	Since      *time.Time // 开始时间（包含）
+	Until      *time.Time // 结束时间（不包含）
+	ClientAddr string     // 客户端地址，支持部分匹配
+	Search     string     // 消息全文搜索
+	BeforeID   uint       // 分页游标，只返回ID小于该值的日志
+	Limit      int
 }
 
 // tunnelRepository 隧道数据仓库实现
@@ -76,6 +98,12 @@ func (r *tunnelRepository) Delete(id uint) error {
 	// 先删除关联的连接日志
 	r.db.Where("tunnel_id = ?", id).Delete(&models.ConnectionLog{})
 
+	// 删除隧道的共享
+	r.db.Where("resource_type = ? AND resource_id = ?", models.ResourceTypeTunnel, id).Delete(&models.ResourceShare{})
+
+	// 从隧道组中移除
+	r.db.Exec("DELETE FROM tunnel_group_members WHERE tunnel_id = ?", id)
+
 	// 删除隧道
 	return r.db.Delete(&models.Tunnel{}, id).Error
 }
@@ -85,6 +113,25 @@ func (r *tunnelRepository) UpdateStatus(id uint, status string) error {
 	return r.db.Model(&models.Tunnel{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// UpdateStatusWithReason 更新隧道状态及状态原因
+func (r *tunnelRepository) UpdateStatusWithReason(id uint, status, reason string) error {
+	return r.db.Model(&models.Tunnel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        status,
+		"status_reason": reason,
+	}).Error
+}
+
+// UpdateDependsOn 更新隧道依赖的隧道
+func (r *tunnelRepository) UpdateDependsOn(id uint, dependsOn models.UintList) error {
+	return r.db.Model(&models.Tunnel{}).Where("id = ?", id).Update("depends_on", dependsOn).Error
+}
+
+// TransferOwner 将一个用户拥有的隧道转移给另一个用户，fromOwnerID 为0时认领无主的隧道
+func (r *tunnelRepository) TransferOwner(fromOwnerID, toOwnerID uint) (int64, error) {
+	result := r.db.Model(&models.Tunnel{}).Where("owner_id = ?", fromOwnerID).Update("owner_id", toOwnerID)
+	return result.RowsAffected, result.Error
+}
+
 // GetAutoStartTunnels 获取自动启动的隧道
 func (r *tunnelRepository) GetAutoStartTunnels() ([]models.Tunnel, error) {
 	var tunnels []models.Tunnel
@@ -106,4 +153,71 @@ func (r *tunnelRepository) GetConnectionLogs(tunnelID uint, limit int) ([]models
 	}
 	err := query.Find(&logs).Error
 	return logs, err
-}
\ No newline at end of file
+}
+
+// QueryConnectionLogs 按条件查询连接日志，结果按ID倒序排列
+func (r *tunnelRepository) QueryConnectionLogs(filter ConnectionLogFilter) ([]models.ConnectionLog, error) {
+	var logs []models.ConnectionLog
+	query := r.db.Model(&models.ConnectionLog{})
+
+	if filter.TunnelID != 0 {
+		query = query.Where("tunnel_id = ?", filter.TunnelID)
+	}
+	if filter.TunnelIDs != nil {
+		query = query.Where("tunnel_id IN ?", filter.TunnelIDs)
+	}
+	if len(filter.EventTypes) > 0 {
+		query = query.Where("event_type IN ?", filter.EventTypes)
+	}
+	if filter.Since != nil {
+		query = query.Where("timestamp >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("timestamp < ?", *filter.Until)
+	}
+	if filter.ClientAddr != "" {
+		query = query.Where("client_addr LIKE ?", "%"+filter.ClientAddr+"%")
+	}
+	if filter.Search != "" {
+		query = query.Where("message LIKE ?", "%"+filter.Search+"%")
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	err := query.Order("id DESC").Find(&logs).Error
+	return logs, err
+}
+
+// DeleteConnectionLogsBefore 删除指定时间之前的连接日志
+func (r *tunnelRepository) DeleteConnectionLogsBefore(before time.Time) (int64, error) {
+	result := r.db.Where("timestamp < ?", before).Delete(&models.ConnectionLog{})
+	return result.RowsAffected, result.Error
+}
+
+// TrimConnectionLogs 只保留隧道最新的 keep 条连接日志
+func (r *tunnelRepository) TrimConnectionLogs(tunnelID uint, keep int) (int64, error) {
+	var boundary []uint
+	err := r.db.Model(&models.ConnectionLog{}).
+		Where("tunnel_id = ?", tunnelID).
+		Order("id DESC").
+		Offset(keep).
+		Limit(1).
+		Pluck("id", &boundary).Error
+	if err != nil || len(boundary) == 0 {
+		return 0, err
+	}
+
+	result := r.db.Where("tunnel_id = ? AND id <= ?", tunnelID, boundary[0]).Delete(&models.ConnectionLog{})
+	return result.RowsAffected, result.Error
+}
+
+// GetLoggedTunnelIDs 获取存在连接日志的隧道ID
+func (r *tunnelRepository) GetLoggedTunnelIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.ConnectionLog{}).Distinct("tunnel_id").Pluck("tunnel_id", &ids).Error
+	return ids, err
+}
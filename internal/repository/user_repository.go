@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"gorm.io/gorm"
+)
+
+// UserRepository 用户及登录会话数据仓库接口
+type UserRepository interface {
+	Create(user *models.User) error
+	GetByID(id uint) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	GetAll() ([]models.User, error)
+	Update(user *models.User) error
+	Delete(id uint) error
+	Count() (int64, error)
+	CreateSession(session *models.UserSession) error
+	GetSessionByTokenHash(tokenHash string) (*models.UserSession, error)
+	TouchSession(id uint, lastSeen, expiresAt time.Time) error
+	DeleteSession(id uint) error
+	DeleteUserSessions(userID uint) error
+	DeleteExpiredSessions(now time.Time) (int64, error)
+}
+
+// userRepository 用户数据仓库实现
+type userRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository 创建用户数据仓库实例
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// Create 创建用户
+func (r *userRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+// GetByID 根据ID获取用户
+func (r *userRepository) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername 根据用户名获取用户
+func (r *userRepository) GetByUsername(username string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetAll 获取所有用户
+func (r *userRepository) GetAll() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Order("id ASC").Find(&users).Error
+	return users, err
+}
+
+// Update 更新用户
+func (r *userRepository) Update(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+// Delete 删除用户及其登录会话和API令牌
+func (r *userRepository) Delete(id uint) error {
+	if err := r.DeleteUserSessions(id); err != nil {
+		return err
+	}
+	if err := r.db.Where("user_id = ?", id).Delete(&models.APIToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&models.User{}, id).Error
+}
+
+// Count 统计用户数量
+func (r *userRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Count(&count).Error
+	return count, err
+}
+
+// CreateSession 创建登录会话
+func (r *userRepository) CreateSession(session *models.UserSession) error {
+	return r.db.Create(session).Error
+}
+
+// GetSessionByTokenHash 根据令牌哈希获取登录会话及其用户
+func (r *userRepository) GetSessionByTokenHash(tokenHash string) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.db.Preload("User").Where("token_hash = ?", tokenHash).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession 更新会话的最后访问时间和过期时间
+func (r *userRepository) TouchSession(id uint, lastSeen, expiresAt time.Time) error {
+	return r.db.Model(&models.UserSession{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_seen_at": lastSeen,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// DeleteSession 删除登录会话
+func (r *userRepository) DeleteSession(id uint) error {
+	return r.db.Delete(&models.UserSession{}, id).Error
+}
+
+// DeleteUserSessions 删除用户的所有登录会话
+func (r *userRepository) DeleteUserSessions(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.UserSession{}).Error
+}
+
+// DeleteExpiredSessions 删除已过期的登录会话，返回删除的条数
+func (r *userRepository) DeleteExpiredSessions(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", now).Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,213 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// mustLocation 加载测试使用的时区
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"* * * foo *",
+		"@every",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2024-01-01 是星期一
+	tests := []struct {
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"0 1 * * *", time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), true},
+		{"0 1 * * *", time.Date(2024, 1, 1, 1, 1, 0, 0, time.UTC), false},
+		{"@daily", time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC), true},
+		{"@hourly", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC), true},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC), true},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 50, 0, 0, time.UTC), false},
+		{"10/20 * * * *", time.Date(2024, 1, 1, 10, 50, 0, 0, time.UTC), true},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC), true},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC), false},
+		{"0 0 * * mon-fri", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 * * mon-fri", time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 * * SUN", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 1 jan *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 1 feb *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), false},
+
+		// 日和周都有限制时满足其一即可（Vixie cron 规则）
+		{"0 0 13 * fri", time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC), true}, // 13日，星期六
+		{"0 0 13 * fri", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), true},  // 星期五，5日
+		{"0 0 13 * fri", time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC), false},
+		// 任一字段以 * 开头时两者都要满足
+		{"0 0 13 * *", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 * * fri", time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 */2 * fri", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), true},   // 5日，星期五
+		{"0 0 */2 * fri", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC), false}, // 12日，星期五
+		{"0 0 1-7 * */2", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), true},   // 2日，星期二
+		{"0 0 1-7 * */2", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), false},  // 1日，星期一
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) returned error: %v", tt.expr, err)
+		}
+		if got := c.Matches(tt.at); got != tt.want {
+			t.Errorf("%q.Matches(%s) = %v, want %v", tt.expr, tt.at.Format("Mon 2006-01-02 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"0 1 * * *", time.Date(2024, 1, 1, 0, 59, 59, 0, time.UTC), time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)},
+		{"0 1 * * *", time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 46, 0, 0, time.UTC), time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// 日和周满足其一：1月5日是星期五，早于13日
+		{"0 0 13 * fri", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC)},
+		// 两个条件都要满足：下一个13日是星期五的日期
+		{"0 0 13 * *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * 9 fri", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 9, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) returned error: %v", tt.expr, err)
+		}
+		got, ok := c.Next(tt.after)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, %v, want %s", tt.expr, tt.after, got, ok, tt.want)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron("0 0 30 feb *")
+	if err != nil {
+		t.Fatalf("ParseCron returned error: %v", err)
+	}
+	if got, ok := c.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Errorf("Next = %s, want no match", got)
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	loc := mustLocation(t, "America/New_York")
+	// 2024-03-10 02:00 EST 跳到 03:00 EDT；2024-11-03 02:00 EDT 回到 01:00 EST
+	edt := time.FixedZone("EDT", -4*3600)
+	est := time.FixedZone("EST", -5*3600)
+
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "skipped local time does not fire",
+			expr:  "30 2 * * *",
+			after: time.Date(2024, 3, 10, 0, 0, 0, 0, loc),
+			want:  time.Date(2024, 3, 11, 2, 30, 0, 0, edt),
+		},
+		{
+			name:  "hour after the gap",
+			expr:  "0 3 * * *",
+			after: time.Date(2024, 3, 10, 0, 0, 0, 0, loc),
+			want:  time.Date(2024, 3, 10, 3, 0, 0, 0, edt),
+		},
+		{
+			name:  "hourly across the gap",
+			expr:  "0 * * * *",
+			after: time.Date(2024, 3, 10, 1, 30, 0, 0, est),
+			want:  time.Date(2024, 3, 10, 3, 0, 0, 0, edt),
+		},
+		{
+			name:  "first of the repeated times",
+			expr:  "30 1 * * *",
+			after: time.Date(2024, 11, 3, 0, 0, 0, 0, loc),
+			want:  time.Date(2024, 11, 3, 1, 30, 0, 0, edt),
+		},
+		{
+			name:  "repeated time fires once",
+			expr:  "30 1 * * *",
+			after: time.Date(2024, 11, 3, 1, 30, 0, 0, edt),
+			want:  time.Date(2024, 11, 4, 1, 30, 0, 0, est),
+		},
+		{
+			name:  "hourly skips the repeated hour",
+			expr:  "0 * * * *",
+			after: time.Date(2024, 11, 3, 1, 0, 0, 0, edt),
+			want:  time.Date(2024, 11, 3, 2, 0, 0, 0, est),
+		},
+		{
+			name:  "after the repeated hour",
+			expr:  "0 3 * * *",
+			after: time.Date(2024, 11, 3, 1, 30, 0, 0, est),
+			want:  time.Date(2024, 11, 3, 3, 0, 0, 0, est),
+		},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) returned error: %v", tt.expr, err)
+		}
+		got, ok := c.Next(tt.after.In(loc))
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%s: %q.Next(%s) = %s, %v, want %s", tt.name, tt.expr, tt.after.In(loc), got, ok, tt.want.In(loc))
+		}
+		if ok && got.Location() != loc {
+			t.Errorf("%s: Next returned location %s, want %s", tt.name, got.Location(), loc)
+		}
+	}
+}
+
+func TestCronMatchesRepeatedHour(t *testing.T) {
+	loc := mustLocation(t, "America/New_York")
+	c, err := ParseCron("30 1 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron returned error: %v", err)
+	}
+	first := time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC).In(loc)  // 01:30 EDT
+	second := time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC).In(loc) // 01:30 EST
+	if !c.Matches(first) {
+		t.Errorf("Matches(%s) = false, want true", first)
+	}
+	if c.Matches(second) {
+		t.Errorf("Matches(%s) = true, want false", second)
+	}
+}
@@ -0,0 +1,179 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 计划触发的动作
+const (
+	ActionStart = "start"
+	ActionStop  = "stop"
+)
+
+// Transition 计划中的一次启动或停止
+type Transition struct {
+	Action string    `json:"action"`
+	At     time.Time `json:"at"`
+}
+
+// Schedule 启停计划，使用 cron 表达式在指定时刻启动和停止，或者使用每周的时间段只在时间段内运行
+type Schedule struct {
+	start    *Cron
+	stop     *Cron
+	windows  []Window
+	location *time.Location
+}
+
+// New 创建启停计划，cron 表达式和时间段不能同时使用，都为空时返回nil
+// timezone 为 IANA 时区名称，例如 Asia/Shanghai，为空时使用服务器本地时区
+func New(startCron, stopCron string, windows []string, timezone string) (*Schedule, error) {
+	startCron, stopCron = strings.TrimSpace(startCron), strings.TrimSpace(stopCron)
+	if startCron == "" && stopCron == "" && len(windows) == 0 {
+		if strings.TrimSpace(timezone) != "" {
+			return nil, errors.New("timezone requires a cron schedule or time windows")
+		}
+		return nil, nil
+	}
+	if (startCron != "" || stopCron != "") && len(windows) > 0 {
+		return nil, errors.New("cron schedule and time windows cannot be combined")
+	}
+
+	s := &Schedule{location: time.Local}
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+		}
+		s.location = loc
+	}
+
+	var err error
+	if startCron != "" {
+		if s.start, err = ParseCron(startCron); err != nil {
+			return nil, err
+		}
+	}
+	if stopCron != "" {
+		if s.stop, err = ParseCron(stopCron); err != nil {
+			return nil, err
+		}
+	}
+	for _, text := range windows {
+		window, err := ParseWindow(text)
+		if err != nil {
+			return nil, err
+		}
+		s.windows = append(s.windows, window)
+	}
+	return s, nil
+}
+
+// Windowed 检查计划是否使用时间段
+func (s *Schedule) Windowed() bool {
+	return len(s.windows) > 0
+}
+
+// Location 返回计划使用的时区
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// Active 检查时间是否在任一时间段内，使用 cron 表达式的计划总是返回false
+func (s *Schedule) Active(t time.Time) bool {
+	local := t.In(s.location)
+	for _, w := range s.windows {
+		if w.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// Due 返回在 t 所在的分钟应当执行的动作，没有动作时返回空字符串
+// 启动和停止表达式同时匹配时以停止为准；相邻的时间段首尾相接时不会停止
+func (s *Schedule) Due(t time.Time) string {
+	t = t.In(s.location).Truncate(time.Minute)
+	if s.Windowed() {
+		active, previous := s.Active(t), s.Active(t.Add(-time.Minute))
+		switch {
+		case active && !previous:
+			return ActionStart
+		case !active && previous:
+			return ActionStop
+		}
+		return ""
+	}
+
+	switch {
+	case s.stop != nil && s.stop.Matches(t):
+		return ActionStop
+	case s.start != nil && s.start.Matches(t):
+		return ActionStart
+	}
+	return ""
+}
+
+// DueBetween 返回从 from 到 to 所在分钟（都包含）中最后一个到期的动作，没有动作时返回空字符串
+// 调度延迟错过若干分钟时用于补上这段时间内的启停，只需要执行最后一个动作
+func (s *Schedule) DueBetween(from, to time.Time) string {
+	from, to = from.Truncate(time.Minute), to.Truncate(time.Minute)
+	for t := to; !t.Before(from); t = t.Add(-time.Minute) {
+		if action := s.Due(t); action != "" {
+			return action
+		}
+	}
+	return ""
+}
+
+// Next 返回 after 之后的下一次启动或停止，时间使用计划的时区；没有后续动作时返回false
+func (s *Schedule) Next(after time.Time) (Transition, bool) {
+	after = after.In(s.location)
+	if s.Windowed() {
+		return s.nextWindowTransition(after)
+	}
+
+	var next Transition
+	found := false
+	if s.stop != nil {
+		if at, ok := s.stop.Next(after); ok {
+			next, found = Transition{Action: ActionStop, At: at}, true
+		}
+	}
+	if s.start != nil {
+		if at, ok := s.start.Next(after); ok && (!found || at.Before(next.At)) {
+			next, found = Transition{Action: ActionStart, At: at}, true
+		}
+	}
+	return next, found
+}
+
+// nextWindowTransition 在未来一周多的时间段边界中查找第一个改变运行状态的时刻
+func (s *Schedule) nextWindowTransition(after time.Time) (Transition, bool) {
+	var candidates []time.Time
+	for offset := -1; offset <= 8; offset++ {
+		day := time.Date(after.Year(), after.Month(), after.Day()+offset, 0, 0, 0, 0, s.location)
+		for _, w := range s.windows {
+			start, end, ok := w.boundaries(day)
+			if !ok {
+				continue
+			}
+			for _, t := range []time.Time{start, end} {
+				if t.After(after) {
+					candidates = append(candidates, t)
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	for _, t := range candidates {
+		if action := s.Due(t); action != "" {
+			return Transition{Action: action, At: t}, true
+		}
+	}
+	return Transition{}, false
+}
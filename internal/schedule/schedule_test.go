@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		start, stop string
+		windows     []string
+		timezone    string
+	}{
+		{timezone: "UTC"},
+		{start: "0 1 * * *", windows: []string{"01:00-02:00"}},
+		{start: "0 1 * * *", timezone: "Mars/Olympus"},
+		{start: "bad"},
+		{stop: "0 25 * * *"},
+		{windows: []string{"01:00-01:00"}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.start, tt.stop, tt.windows, tt.timezone); err == nil {
+			t.Errorf("New(%q, %q, %q, %q) succeeded, want error", tt.start, tt.stop, tt.windows, tt.timezone)
+		}
+	}
+
+	s, err := New(" ", "", nil, "")
+	if err != nil || s != nil {
+		t.Errorf("New with empty schedule = %v, %v, want nil, nil", s, err)
+	}
+}
+
+func TestScheduleDue(t *testing.T) {
+	// 2024-01-05 是星期五
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name        string
+		start, stop string
+		windows     []string
+		at          time.Time
+		want        string
+	}{
+		{name: "cron start", start: "0 9 * * *", stop: "0 18 * * *", at: at(5, 9, 0), want: ActionStart},
+		{name: "cron stop", start: "0 9 * * *", stop: "0 18 * * *", at: at(5, 18, 0), want: ActionStop},
+		{name: "cron idle", start: "0 9 * * *", stop: "0 18 * * *", at: at(5, 12, 0), want: ""},
+		{name: "seconds ignored", start: "0 9 * * *", at: at(5, 9, 0).Add(45 * time.Second), want: ActionStart},
+		{name: "stop wins", start: "0 9 * * *", stop: "0 * * * *", at: at(5, 9, 0), want: ActionStop},
+		{name: "window opens", windows: []string{"fri 22:00-06:00"}, at: at(5, 22, 0), want: ActionStart},
+		{name: "window running", windows: []string{"fri 22:00-06:00"}, at: at(6, 0, 0), want: ""},
+		{name: "window closes", windows: []string{"fri 22:00-06:00"}, at: at(6, 6, 0), want: ActionStop},
+		{name: "adjacent windows", windows: []string{"fri 20:00-24:00", "sat 00:00-02:00"}, at: at(6, 0, 0), want: ""},
+		{name: "adjacent windows close", windows: []string{"fri 20:00-24:00", "sat 00:00-02:00"}, at: at(6, 2, 0), want: ActionStop},
+		{name: "all day", windows: []string{"00:00-24:00"}, at: at(6, 0, 0), want: ""},
+		{name: "all day on some days", windows: []string{"sat 00:00-24:00"}, at: at(7, 0, 0), want: ActionStop},
+	}
+	for _, tt := range tests {
+		s, err := New(tt.start, tt.stop, tt.windows, "UTC")
+		if err != nil {
+			t.Fatalf("%s: New returned error: %v", tt.name, err)
+		}
+		if got := s.Due(tt.at); got != tt.want {
+			t.Errorf("%s: Due(%s) = %q, want %q", tt.name, tt.at.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestScheduleDueBetween(t *testing.T) {
+	s, err := New("0 9 * * *", "30 9 * * *", nil, "UTC")
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 5, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		from, to time.Time
+		want     string
+	}{
+		{day(8, 55), day(9, 5), ActionStart},
+		{day(8, 55), day(9, 45), ActionStop}, // 只执行最后一个动作
+		{day(9, 0), day(9, 0), ActionStart},
+		{day(9, 1), day(9, 29), ""},
+		{day(9, 30), day(9, 29), ""},
+	}
+	for _, tt := range tests {
+		if got := s.DueBetween(tt.from, tt.to); got != tt.want {
+			t.Errorf("DueBetween(%s, %s) = %q, want %q", tt.from.Format("15:04"), tt.to.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name        string
+		start, stop string
+		windows     []string
+		after       time.Time
+		want        Transition
+		wantOK      bool
+	}{
+		{name: "next start", start: "0 9 * * *", stop: "0 18 * * *", after: at(5, 8, 0), want: Transition{ActionStart, at(5, 9, 0)}, wantOK: true},
+		{name: "next stop", start: "0 9 * * *", stop: "0 18 * * *", after: at(5, 9, 0), want: Transition{ActionStop, at(5, 18, 0)}, wantOK: true},
+		{name: "window open", windows: []string{"fri 22:00-06:00"}, after: at(5, 12, 0), want: Transition{ActionStart, at(5, 22, 0)}, wantOK: true},
+		{name: "window close", windows: []string{"fri 22:00-06:00"}, after: at(5, 23, 0), want: Transition{ActionStop, at(6, 6, 0)}, wantOK: true},
+		{name: "next week", windows: []string{"fri 22:00-06:00"}, after: at(6, 6, 0), want: Transition{ActionStart, at(12, 22, 0)}, wantOK: true},
+		{name: "adjacent windows", windows: []string{"fri 20:00-24:00", "sat 00:00-02:00"}, after: at(5, 21, 0), want: Transition{ActionStop, at(6, 2, 0)}, wantOK: true},
+		{name: "always running", windows: []string{"00:00-24:00"}, after: at(5, 12, 0)},
+	}
+	for _, tt := range tests {
+		s, err := New(tt.start, tt.stop, tt.windows, "UTC")
+		if err != nil {
+			t.Fatalf("%s: New returned error: %v", tt.name, err)
+		}
+		got, ok := s.Next(tt.after)
+		if ok != tt.wantOK || got.Action != tt.want.Action || !got.At.Equal(tt.want.At) {
+			t.Errorf("%s: Next(%s) = %+v, %v, want %+v, %v", tt.name, tt.after, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
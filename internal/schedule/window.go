@@ -0,0 +1,140 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minutesPerDay 一天的分钟数，时间段结束时间可以写作 24:00
+const minutesPerDay = 24 * 60
+
+// Window 每周重复的时间段，例如 01:00-04:00、mon-fri 09:00-18:00、sat,sun 22:00-06:00
+// 结束时间早于开始时间表示跨越午夜，星期指开始时间所在的那一天
+type Window struct {
+	text  string
+	days  [7]bool
+	start int // 开始时间，一天中的第几分钟
+	end   int // 结束时间，一天中的第几分钟
+}
+
+// ParseWindow 解析时间段，格式为 [星期] HH:MM-HH:MM，省略星期表示每天
+// 星期可以是 mon、tue 等缩写、范围 mon-fri 或逗号分隔的列表
+func ParseWindow(text string) (Window, error) {
+	text = strings.TrimSpace(text)
+	w := Window{text: text}
+
+	fields := strings.Fields(text)
+	var timeRange string
+	switch len(fields) {
+	case 1:
+		timeRange = fields[0]
+		for i := range w.days {
+			w.days[i] = true
+		}
+	case 2:
+		if err := w.parseDays(fields[0]); err != nil {
+			return Window{}, fmt.Errorf("invalid time window %q: %v", text, err)
+		}
+		timeRange = fields[1]
+	default:
+		return Window{}, fmt.Errorf("invalid time window %q: expected \"[days] HH:MM-HH:MM\"", text)
+	}
+
+	parts := strings.Split(timeRange, "-")
+	if len(parts) != 2 {
+		return Window{}, fmt.Errorf("invalid time window %q: expected HH:MM-HH:MM", text)
+	}
+	var err error
+	if w.start, err = parseClock(parts[0]); err != nil || w.start == minutesPerDay {
+		return Window{}, fmt.Errorf("invalid time window %q: invalid start time %q", text, parts[0])
+	}
+	if w.end, err = parseClock(parts[1]); err != nil {
+		return Window{}, fmt.Errorf("invalid time window %q: invalid end time %q", text, parts[1])
+	}
+	if w.start == w.end {
+		return Window{}, fmt.Errorf("invalid time window %q: start and end must differ", text)
+	}
+	return w, nil
+}
+
+// parseDays 解析星期列表
+func (w *Window) parseDays(text string) error {
+	for _, part := range strings.Split(strings.ToLower(text), ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid days %q", part)
+		}
+		first, ok := dayNames[bounds[0]]
+		if !ok {
+			return fmt.Errorf("invalid day %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = dayNames[bounds[1]]; !ok {
+				return fmt.Errorf("invalid day %q", bounds[1])
+			}
+		}
+		// 允许 fri-mon 这样跨越周末的范围
+		for day := first; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock 解析 HH:MM 格式的时间，返回一天中的第几分钟
+func parseClock(text string) (int, error) {
+	parts := strings.Split(text, ":")
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return hour*60 + minute, nil
+}
+
+// String 返回原始文本
+func (w Window) String() string {
+	return w.text
+}
+
+// overnight 检查时间段是否跨越午夜
+func (w Window) overnight() bool {
+	return w.end < w.start
+}
+
+// contains 检查本地时间是否在时间段内，包含开始时间，不包含结束时间
+func (w Window) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if !w.overnight() {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	previous := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[previous] && minute < w.end)
+}
+
+// boundaries 返回从 day 这一天开始的时间段的开始和结束时间，day 不在星期列表中时返回false
+func (w Window) boundaries(day time.Time) (time.Time, time.Time, bool) {
+	if !w.days[day.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+	end := w.end
+	if w.overnight() {
+		end += minutesPerDay
+	}
+	loc := day.Location()
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, w.start, 0, 0, loc),
+		time.Date(day.Year(), day.Month(), day.Day(), 0, end, 0, 0, loc), true
+}
@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		text  string
+		days  [7]bool // 下标为 time.Weekday
+		start int
+		end   int
+	}{
+		{"01:00-04:00", [7]bool{true, true, true, true, true, true, true}, 60, 240},
+		{"00:00-24:00", [7]bool{true, true, true, true, true, true, true}, 0, minutesPerDay},
+		{"  22:30-06:15  ", [7]bool{true, true, true, true, true, true, true}, 22*60 + 30, 6*60 + 15},
+		{"mon-fri 09:00-18:00", [7]bool{false, true, true, true, true, true, false}, 540, 1080},
+		{"sat,sun 22:00-06:00", [7]bool{true, false, false, false, false, false, true}, 1320, 360},
+		{"fri-mon 20:00-24:00", [7]bool{true, true, false, false, false, true, true}, 1200, minutesPerDay},
+		{"MON,wed-thu 00:00-01:00", [7]bool{false, true, false, true, true, false, false}, 0, 60},
+	}
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.text)
+		if err != nil {
+			t.Errorf("ParseWindow(%q) returned error: %v", tt.text, err)
+			continue
+		}
+		if w.days != tt.days || w.start != tt.start || w.end != tt.end {
+			t.Errorf("ParseWindow(%q) = days %v, %d-%d, want days %v, %d-%d",
+				tt.text, w.days, w.start, w.end, tt.days, tt.start, tt.end)
+		}
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"01:00",
+		"01:00-02:00-03:00",
+		"mon fri 01:00-02:00",
+		"funday 01:00-02:00",
+		"mon-fri-sat 01:00-02:00",
+		"mon, 01:00-02:00",
+		"1:0-02:00",
+		"25:00-02:00",
+		"01:60-02:00",
+		"24:00-02:00",
+		"01:00-24:30",
+		"01:00-01:00",
+		"00:00-00:00",
+		"24:00-24:00",
+	} {
+		if _, err := ParseWindow(text); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded, want error", text)
+		}
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 2024-01-05 是星期五
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		text string
+		at   time.Time
+		want bool
+	}{
+		{"01:00-04:00", at(5, 1, 0), true},
+		{"01:00-04:00", at(5, 3, 59), true},
+		{"01:00-04:00", at(5, 4, 0), false},
+		{"01:00-04:00", at(5, 0, 59), false},
+		{"00:00-24:00", at(5, 0, 0), true},
+		{"00:00-24:00", at(5, 23, 59), true},
+		{"mon-fri 09:00-18:00", at(5, 12, 0), true},
+		{"mon-fri 09:00-18:00", at(6, 12, 0), false},
+
+		// 跨越午夜的时间段属于开始的那一天
+		{"fri 22:00-06:00", at(5, 22, 0), true},
+		{"fri 22:00-06:00", at(5, 23, 59), true},
+		{"fri 22:00-06:00", at(6, 0, 0), true},
+		{"fri 22:00-06:00", at(6, 5, 59), true},
+		{"fri 22:00-06:00", at(6, 6, 0), false},
+		{"fri 22:00-06:00", at(5, 5, 0), false},
+		{"fri 22:00-06:00", at(6, 22, 0), false},
+		{"fri 22:00-06:00", at(5, 21, 59), false},
+		{"sun 23:00-01:00", at(8, 0, 30), true}, // 星期一凌晨，跨越周末
+		{"sun 23:00-01:00", at(1, 0, 30), true}, // 同上，上一周
+		{"sun 23:00-01:00", at(7, 0, 30), false},
+		{"22:00-06:00", at(5, 3, 0), true},
+		{"22:00-06:00", at(5, 12, 0), false},
+		{"fri-mon 20:00-24:00", at(7, 21, 0), true},
+		{"fri-mon 20:00-24:00", at(9, 21, 0), false},
+	}
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.text)
+		if err != nil {
+			t.Fatalf("ParseWindow(%q) returned error: %v", tt.text, err)
+		}
+		if got := w.contains(tt.at); got != tt.want {
+			t.Errorf("%q.contains(%s) = %v, want %v", tt.text, tt.at.Format("Mon 2006-01-02 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestWindowBoundaries(t *testing.T) {
+	tests := []struct {
+		text      string
+		day       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+		wantOK    bool
+	}{
+		{
+			text:      "fri 22:00-06:00",
+			day:       time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 1, 5, 22, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 1, 6, 6, 0, 0, 0, time.UTC),
+			wantOK:    true,
+		},
+		{
+			text:      "00:00-24:00",
+			day:       time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			wantOK:    true,
+		},
+		{
+			text: "fri 22:00-06:00",
+			day:  time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.text)
+		if err != nil {
+			t.Fatalf("ParseWindow(%q) returned error: %v", tt.text, err)
+		}
+		start, end, ok := w.boundaries(tt.day)
+		if ok != tt.wantOK || !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+			t.Errorf("%q.boundaries(%s) = %s, %s, %v, want %s, %s, %v",
+				tt.text, tt.day.Format("2006-01-02"), start, end, ok, tt.wantStart, tt.wantEnd, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrForbidden 用户对资源没有所需的权限
+var ErrForbidden = errors.New("permission denied")
+
+// permissionRank 权限等级，高等级包含低等级
+var permissionRank = map[string]int{
+	models.PermissionView:    1,
+	models.PermissionControl: 2,
+	models.PermissionEdit:    3,
+}
+
+// roleCap 各角色能获得的最高权限
+var roleCap = map[string]string{
+	models.UserRoleAdmin:    models.PermissionEdit,
+	models.UserRoleOperator: models.PermissionEdit,
+	models.UserRoleViewer:   models.PermissionView,
+}
+
+// AccessService 资源访问控制服务接口
+// user 为nil表示未启用认证，此时不做任何限制
+type AccessService interface {
+	NewScope(user *models.User) (*AccessScope, error)
+	AuthorizeHost(user *models.User, hostID uint, permission string) (*models.Host, error)
+	AuthorizeTunnel(user *models.User, tunnelID uint, permission string) (*models.Tunnel, error)
+	VisibleTunnelIDs(user *models.User) ([]uint, error)
+	CanCreate(user *models.User) bool
+	GetShares(resourceType string, resourceID uint) ([]models.ResourceShare, error)
+	ShareResource(resourceType string, resourceID uint, username, permission string) (*models.ResourceShare, error)
+	RemoveShare(resourceType string, resourceID, userID uint) error
+	ClaimUnownedResources() error
+	TransferOwnership(fromUserID, toUserID uint) error
+}
+
+// AccessScope 某个用户的访问范围，预先加载共享关系用于批量过滤
+type AccessScope struct {
+	user         *models.User
+	hostShares   map[uint]string
+	tunnelShares map[uint]string
+}
+
+// accessService 资源访问控制服务实现
+type accessService struct {
+	hostRepo   repository.HostRepository
+	tunnelRepo repository.TunnelRepository
+	shareRepo  repository.ShareRepository
+	userRepo   repository.UserRepository
+}
+
+// NewAccessService 创建资源访问控制服务实例
+func NewAccessService(hostRepo repository.HostRepository, tunnelRepo repository.TunnelRepository, shareRepo repository.ShareRepository, userRepo repository.UserRepository) AccessService {
+	return &accessService{
+		hostRepo:   hostRepo,
+		tunnelRepo: tunnelRepo,
+		shareRepo:  shareRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// NewScope 加载用户的共享关系，创建访问范围
+func (s *accessService) NewScope(user *models.User) (*AccessScope, error) {
+	scope := &AccessScope{
+		user:         user,
+		hostShares:   make(map[uint]string),
+		tunnelShares: make(map[uint]string),
+	}
+	if scope.Unrestricted() {
+		return scope, nil
+	}
+
+	shares, err := s.shareRepo.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		switch share.ResourceType {
+		case models.ResourceTypeHost:
+			scope.hostShares[share.ResourceID] = share.Permission
+		case models.ResourceTypeTunnel:
+			scope.tunnelShares[share.ResourceID] = share.Permission
+		}
+	}
+
+	return scope, nil
+}
+
+// AuthorizeHost 检查用户对主机的权限，无法查看时返回记录不存在，权限不足时返回 ErrForbidden
+func (s *accessService) AuthorizeHost(user *models.User, hostID uint, permission string) (*models.Host, error) {
+	host, err := s.hostRepo.GetByID(hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := s.NewScope(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPermission(scope.HostPermission(host), permission); err != nil {
+		return nil, err
+	}
+	return host, nil
+}
+
+// AuthorizeTunnel 检查用户对隧道的权限，无法查看时返回记录不存在，权限不足时返回 ErrForbidden
+func (s *accessService) AuthorizeTunnel(user *models.User, tunnelID uint, permission string) (*models.Tunnel, error) {
+	tunnel, err := s.tunnelRepo.GetByID(tunnelID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := s.NewScope(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPermission(scope.TunnelPermission(tunnel), permission); err != nil {
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// VisibleTunnelIDs 获取用户可以查看的隧道ID，不受限制时返回nil
+func (s *accessService) VisibleTunnelIDs(user *models.User) ([]uint, error) {
+	scope, err := s.NewScope(user)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Unrestricted() {
+		return nil, nil
+	}
+
+	tunnels, err := s.tunnelRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0)
+	for i := range tunnels {
+		if scope.CanViewTunnel(&tunnels[i]) {
+			ids = append(ids, tunnels[i].ID)
+		}
+	}
+	return ids, nil
+}
+
+// CanCreate 检查用户是否可以创建主机和隧道
+func (s *accessService) CanCreate(user *models.User) bool {
+	return user == nil || user.Role == models.UserRoleAdmin || user.Role == models.UserRoleOperator
+}
+
+// GetShares 获取资源的共享列表
+func (s *accessService) GetShares(resourceType string, resourceID uint) ([]models.ResourceShare, error) {
+	return s.shareRepo.GetByResource(resourceType, resourceID)
+}
+
+// ShareResource 将资源共享给指定用户，已共享时更新权限
+func (s *accessService) ShareResource(resourceType string, resourceID uint, username, permission string) (*models.ResourceShare, error) {
+	if _, ok := permissionRank[permission]; !ok {
+		return nil, fmt.Errorf("invalid permission: %s", permission)
+	}
+
+	user, err := s.userRepo.GetByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("user '%s' not found", username)
+	}
+
+	share := &models.ResourceShare{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		UserID:       user.ID,
+		Permission:   permission,
+	}
+	if err := s.shareRepo.Upsert(share); err != nil {
+		return nil, err
+	}
+
+	share.User = user
+	return share, nil
+}
+
+// RemoveShare 取消资源对指定用户的共享
+func (s *accessService) RemoveShare(resourceType string, resourceID, userID uint) error {
+	return s.shareRepo.Delete(resourceType, resourceID, userID)
+}
+
+// ClaimUnownedResources 将没有所有者的主机和隧道归属到第一个管理员，用于升级前创建的数据
+func (s *accessService) ClaimUnownedResources() error {
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if user.Role != models.UserRoleAdmin {
+			continue
+		}
+
+		hosts, err := s.hostRepo.TransferOwner(0, user.ID)
+		if err != nil {
+			return err
+		}
+		tunnels, err := s.tunnelRepo.TransferOwner(0, user.ID)
+		if err != nil {
+			return err
+		}
+		if hosts > 0 || tunnels > 0 {
+			log.Printf("Assigned %d unowned hosts and %d unowned tunnels to admin %q", hosts, tunnels, user.Username)
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// TransferOwnership 将用户拥有的资源转移给另一个用户，并删除共享给该用户的资源，用于删除用户前
+func (s *accessService) TransferOwnership(fromUserID, toUserID uint) error {
+	if _, err := s.hostRepo.TransferOwner(fromUserID, toUserID); err != nil {
+		return err
+	}
+	if _, err := s.tunnelRepo.TransferOwner(fromUserID, toUserID); err != nil {
+		return err
+	}
+	return s.shareRepo.DeleteByUser(fromUserID)
+}
+
+// Unrestricted 检查访问范围是否不受限制（未启用认证或管理员）
+func (a *AccessScope) Unrestricted() bool {
+	return a.user == nil || a.user.Role == models.UserRoleAdmin
+}
+
+// HostPermission 获取用户对主机的有效权限，没有任何权限时返回空字符串
+func (a *AccessScope) HostPermission(host *models.Host) string {
+	if a.Unrestricted() {
+		return models.PermissionEdit
+	}
+
+	permission := a.hostShares[host.ID]
+	if host.OwnerID == a.user.ID {
+		permission = models.PermissionEdit
+	}
+	return a.capByRole(permission)
+}
+
+// TunnelPermission 获取用户对隧道的有效权限，隧道同时继承所属主机的权限
+func (a *AccessScope) TunnelPermission(tunnel *models.Tunnel) string {
+	if a.Unrestricted() {
+		return models.PermissionEdit
+	}
+
+	permission := a.tunnelShares[tunnel.ID]
+	if tunnel.OwnerID == a.user.ID {
+		permission = models.PermissionEdit
+	}
+	if tunnel.Host != nil {
+		permission = maxPermission(permission, a.HostPermission(tunnel.Host))
+	} else {
+		permission = maxPermission(permission, a.hostShares[tunnel.HostID])
+	}
+	return a.capByRole(permission)
+}
+
+// HasTunnelPermission 检查用户对隧道的有效权限是否满足要求
+func (a *AccessScope) HasTunnelPermission(tunnel *models.Tunnel, permission string) bool {
+	return checkPermission(a.TunnelPermission(tunnel), permission) == nil
+}
+
+// CanViewHost 检查用户是否可以查看主机
+func (a *AccessScope) CanViewHost(host *models.Host) bool {
+	return a.HostPermission(host) != ""
+}
+
+// CanViewTunnel 检查用户是否可以查看隧道
+func (a *AccessScope) CanViewTunnel(tunnel *models.Tunnel) bool {
+	return a.TunnelPermission(tunnel) != ""
+}
+
+// capByRole 按用户角色限制最高权限
+func (a *AccessScope) capByRole(permission string) string {
+	if permission == "" {
+		return ""
+	}
+	limit, ok := roleCap[a.user.Role]
+	if !ok {
+		limit = models.PermissionView
+	}
+	if permissionRank[permission] > permissionRank[limit] {
+		return limit
+	}
+	return permission
+}
+
+// maxPermission 返回两个权限中较高的一个
+func maxPermission(a, b string) string {
+	if permissionRank[b] > permissionRank[a] {
+		return b
+	}
+	return a
+}
+
+// checkPermission 检查有效权限是否满足要求
+func checkPermission(have, need string) error {
+	if have == "" {
+		return gorm.ErrRecordNotFound
+	}
+	if permissionRank[have] < permissionRank[need] {
+		return ErrForbidden
+	}
+	return nil
+}
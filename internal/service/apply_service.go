@@ -0,0 +1,745 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/sshconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// ApplyService 声明式配置服务接口，使数据库中的主机和隧道与配置文档一致
+type ApplyService interface {
+	Apply(doc *ApplyDocument, opts ApplyOptions) (*ApplyResult, error)
+}
+
+// ApplyDocument 声明式配置文档，描述期望存在的主机和隧道
+type ApplyDocument struct {
+	Hosts   []ApplyHost   `yaml:"hosts" json:"hosts"`
+	Tunnels []ApplyTunnel `yaml:"tunnels" json:"tunnels"`
+}
+
+// ApplyHost 配置文档中的主机，按名称与数据库中的主机对应
+// 凭据未填写时保留数据库中的原有值，因此首次创建后可以不在文件中保留凭据
+type ApplyHost struct {
+	Name          string     `yaml:"name" json:"name"`
+	Hostname      string     `yaml:"hostname" json:"hostname"`
+	Port          int        `yaml:"port,omitempty" json:"port,omitempty"`
+	Username      string     `yaml:"username" json:"username"`
+	AuthType      string     `yaml:"auth_type" json:"auth_type"`
+	Password      *SecretRef `yaml:"password,omitempty" json:"password,omitempty"`
+	PrivateKey    *SecretRef `yaml:"private_key,omitempty" json:"private_key,omitempty"`
+	KeyPath       string     `yaml:"key_path,omitempty" json:"key_path,omitempty"`
+	Passphrase    *SecretRef `yaml:"passphrase,omitempty" json:"passphrase,omitempty"`
+	JumpHost      string     `yaml:"jump_host,omitempty" json:"jump_host,omitempty"` // 跳板机名称，需要在本主机之前声明
+	Description   string     `yaml:"description,omitempty" json:"description,omitempty"`
+	UploadLimit   int64      `yaml:"upload_limit,omitempty" json:"upload_limit,omitempty"`
+	DownloadLimit int64      `yaml:"download_limit,omitempty" json:"download_limit,omitempty"`
+
+	// key=value 标签，列表可以按标签选择器筛选
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// ApplyTunnel 配置文档中的隧道，按所属主机名称和隧道名称与数据库中的隧道对应
+type ApplyTunnel struct {
+	Name           string   `yaml:"name" json:"name"`
+	Host           string   `yaml:"host" json:"host"` // 所属主机名称
+	Type           string   `yaml:"type" json:"type"`
+	LocalAddress   string   `yaml:"local_address,omitempty" json:"local_address,omitempty"`
+	LocalPort      int      `yaml:"local_port" json:"local_port"`
+	RemoteAddress  string   `yaml:"remote_address,omitempty" json:"remote_address,omitempty"`
+	RemotePort     int      `yaml:"remote_port,omitempty" json:"remote_port,omitempty"`
+	Description    string   `yaml:"description,omitempty" json:"description,omitempty"`
+	AutoStart      bool     `yaml:"auto_start,omitempty" json:"auto_start,omitempty"`
+	UploadLimit    int64    `yaml:"upload_limit,omitempty" json:"upload_limit,omitempty"`
+	DownloadLimit  int64    `yaml:"download_limit,omitempty" json:"download_limit,omitempty"`
+	QuotaBytes     int64    `yaml:"quota_bytes,omitempty" json:"quota_bytes,omitempty"`
+	QuotaPeriod    string   `yaml:"quota_period,omitempty" json:"quota_period,omitempty"`
+	MaxConnections int      `yaml:"max_connections,omitempty" json:"max_connections,omitempty"`
+	OverflowPolicy string   `yaml:"overflow_policy,omitempty" json:"overflow_policy,omitempty"`
+	IdleTimeout    int      `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"`
+	MaxLifetime    int      `yaml:"max_lifetime,omitempty" json:"max_lifetime,omitempty"`
+	ProxyDomains   []string `yaml:"proxy_domains,omitempty" json:"proxy_domains,omitempty"`
+	Tags           []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Lazy           bool     `yaml:"lazy,omitempty" json:"lazy,omitempty"`
+	LazyTimeout    int      `yaml:"lazy_timeout,omitempty" json:"lazy_timeout,omitempty"`
+	StartCron      string   `yaml:"start_cron,omitempty" json:"start_cron,omitempty"`
+	StopCron       string   `yaml:"stop_cron,omitempty" json:"stop_cron,omitempty"`
+	TimeWindows    []string `yaml:"time_windows,omitempty" json:"time_windows,omitempty"`
+	Timezone       string   `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	HealthCheck    string   `yaml:"health_check,omitempty" json:"health_check,omitempty"`
+	HealthTarget   string   `yaml:"health_target,omitempty" json:"health_target,omitempty"`
+	HealthPath     string   `yaml:"health_path,omitempty" json:"health_path,omitempty"`
+	HealthCode     int      `yaml:"health_code,omitempty" json:"health_code,omitempty"`
+	HealthSend     string   `yaml:"health_send,omitempty" json:"health_send,omitempty"`
+	HealthExpect   string   `yaml:"health_expect,omitempty" json:"health_expect,omitempty"`
+	HealthInterval int      `yaml:"health_interval,omitempty" json:"health_interval,omitempty"`
+	HealthTimeout  int      `yaml:"health_timeout,omitempty" json:"health_timeout,omitempty"`
+	HealthFailures int      `yaml:"health_failures,omitempty" json:"health_failures,omitempty"`
+	HealthRestart  bool     `yaml:"health_restart,omitempty" json:"health_restart,omitempty"`
+	DependsOn      []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"` // 依赖的隧道，写作 主机名/隧道名，同一主机的隧道可以只写隧道名，需要在本隧道之前声明
+
+	// key=value 标签，列表和批量操作可以按标签选择器筛选
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// SecretRef 凭据引用，可以直接填写值，也可以引用环境变量或文件
+// YAML 中可以写成字符串（直接填写值）或 {env: NAME}、{file: PATH}、{value: VALUE}
+type SecretRef struct {
+	Value string `yaml:"value,omitempty" json:"value,omitempty"`
+	Env   string `yaml:"env,omitempty" json:"env,omitempty"`
+	File  string `yaml:"file,omitempty" json:"file,omitempty"`
+}
+
+// ApplyOptions 应用配置的选项
+type ApplyOptions struct {
+	DryRun  bool // 只计算变更，不修改数据库
+	Prune   bool // 删除配置文档中没有的主机和隧道
+	OwnerID uint // 新建资源的所有者
+}
+
+// ApplyChange 单个资源的变更
+type ApplyChange struct {
+	Action       string              `json:"action"` // create, update, delete, unchanged
+	ResourceType string              `json:"resource_type"`
+	Name         string              `json:"name"` // 隧道为 主机名/隧道名
+	ID           uint                `json:"id,omitempty"`
+	Changes      models.AuditChanges `json:"changes,omitempty"`
+	Applied      bool                `json:"applied,omitempty"` // 变更是否已执行
+
+	host      *models.Host
+	tunnel    *models.Tunnel
+	hostName  string   // 隧道所属主机或主机的跳板机名称，新建主机的ID在执行时才能确定
+	dependsOn []string // 隧道依赖的隧道，新建隧道的ID在执行时才能确定
+}
+
+// ApplySummary 变更统计
+type ApplySummary struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Delete    int `json:"delete"`
+	Unchanged int `json:"unchanged"`
+}
+
+// ApplyResult 应用配置的结果
+type ApplyResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Changes []ApplyChange `json:"changes"`
+	Summary ApplySummary  `json:"summary"`
+}
+
+// ApplyAction 变更类型常量
+const (
+	ApplyActionCreate    = "create"
+	ApplyActionUpdate    = "update"
+	ApplyActionDelete    = "delete"
+	ApplyActionUnchanged = "unchanged"
+)
+
+// applyService 声明式配置服务实现
+type applyService struct {
+	hostService   HostService
+	tunnelService TunnelService
+}
+
+// NewApplyService 创建声明式配置服务实例
+func NewApplyService(hostService HostService, tunnelService TunnelService) ApplyService {
+	return &applyService{
+		hostService:   hostService,
+		tunnelService: tunnelService,
+	}
+}
+
+// ParseApplyDocument 解析YAML或JSON格式的配置文档，不允许未知字段以便发现拼写错误
+func ParseApplyDocument(data []byte) (*ApplyDocument, error) {
+	var doc ApplyDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &doc, nil
+		}
+		return nil, fmt.Errorf("invalid apply document: %v", err)
+	}
+	return &doc, nil
+}
+
+// UnmarshalYAML 支持字符串和对象两种写法
+func (r *SecretRef) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Value = node.Value
+		return nil
+	}
+
+	type plain SecretRef
+	return node.Decode((*plain)(r))
+}
+
+// Resolve 读取引用的环境变量或文件，去除末尾换行
+func (r *SecretRef) Resolve() (string, error) {
+	set := 0
+	for _, value := range []string{r.Value, r.Env, r.File} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", errors.New("only one of value, env and file may be set")
+	}
+
+	switch {
+	case r.Env != "":
+		value, ok := os.LookupEnv(r.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", r.Env)
+		}
+		return strings.TrimRight(value, "\r\n"), nil
+	case r.File != "":
+		data, err := os.ReadFile(sshconfig.ExpandHome(r.File))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return r.Value, nil
+	}
+}
+
+// ResolveSecrets 将环境变量和文件引用替换为实际的值，由命令行在本地调用
+func (d *ApplyDocument) ResolveSecrets() error {
+	for i := range d.Hosts {
+		host := &d.Hosts[i]
+		if _, err := normalizeLabels(host.Labels); err != nil {
+			return fmt.Errorf("host %s: %v", host.Name, err)
+		}
+		for field, ref := range host.secretRefs() {
+			if ref == nil {
+				continue
+			}
+			value, err := ref.Resolve()
+			if err != nil {
+				return fmt.Errorf("host %s %s: %v", host.Name, field, err)
+			}
+			*ref = SecretRef{Value: value}
+		}
+	}
+	return nil
+}
+
+// secretRefs 返回主机的凭据字段
+func (h *ApplyHost) secretRefs() map[string]*SecretRef {
+	return map[string]*SecretRef{
+		"password":    h.Password,
+		"private_key": h.PrivateKey,
+		"passphrase":  h.Passphrase,
+	}
+}
+
+// validate 检查配置文档，服务端不解析环境变量和文件引用，避免通过配置读取服务器上的文件
+func (d *ApplyDocument) validate() error {
+	hostNames := make(map[string]bool)
+	for i := range d.Hosts {
+		host := &d.Hosts[i]
+		if host.Name == "" {
+			return fmt.Errorf("hosts[%d]: name is required", i)
+		}
+		if hostNames[host.Name] {
+			return fmt.Errorf("duplicate host %s", host.Name)
+		}
+		hostNames[host.Name] = true
+
+		if host.Hostname == "" || host.Username == "" {
+			return fmt.Errorf("host %s: hostname and username are required", host.Name)
+		}
+		if host.JumpHost == host.Name {
+			return fmt.Errorf("host %s: jump_host must not be the host itself", host.Name)
+		}
+		switch host.AuthType {
+		case models.AuthTypePassword, models.AuthTypeKey, models.AuthTypeKeyPassword:
+		default:
+			return fmt.Errorf("host %s: invalid auth_type %q", host.Name, host.AuthType)
+		}
+		for field, ref := range host.secretRefs() {
+			if ref != nil && (ref.Env != "" || ref.File != "") {
+				return fmt.Errorf("host %s %s: env and file references must be resolved by the apply command", host.Name, field)
+			}
+		}
+	}
+
+	tunnelKeys := make(map[string]bool)
+	for i := range d.Tunnels {
+		tunnel := &d.Tunnels[i]
+		if tunnel.Name == "" || tunnel.Host == "" {
+			return fmt.Errorf("tunnels[%d]: name and host are required", i)
+		}
+		key := tunnelKey(tunnel.Host, tunnel.Name)
+		if tunnelKeys[key] {
+			return fmt.Errorf("duplicate tunnel %s", key)
+		}
+		tunnelKeys[key] = true
+
+		switch tunnel.Type {
+		case models.TunnelTypeLocalForward, models.TunnelTypeRemoteForward, models.TunnelTypeDynamic:
+		default:
+			return fmt.Errorf("tunnel %s: invalid type %q", key, tunnel.Type)
+		}
+		if tunnel.LocalPort == 0 {
+			return fmt.Errorf("tunnel %s: local_port is required", key)
+		}
+		if _, err := normalizeProxyDomains(tunnel.ProxyDomains); err != nil {
+			return fmt.Errorf("tunnel %s: %v", key, err)
+		}
+		if _, err := normalizeTags(tunnel.Tags); err != nil {
+			return fmt.Errorf("tunnel %s: %v", key, err)
+		}
+		if _, err := normalizeLabels(tunnel.Labels); err != nil {
+			return fmt.Errorf("tunnel %s: %v", key, err)
+		}
+		for _, dep := range tunnel.dependencyKeys() {
+			if dep == key {
+				return fmt.Errorf("tunnel %s: depends_on must not include the tunnel itself", key)
+			}
+		}
+		if tunnel.Lazy && tunnel.Type == models.TunnelTypeRemoteForward {
+			return fmt.Errorf("tunnel %s: lazy mode is only supported for local forward and dynamic tunnels", key)
+		}
+		if err := normalizeSchedule(&models.Tunnel{StartCron: tunnel.StartCron, StopCron: tunnel.StopCron, TimeWindows: tunnel.TimeWindows, Timezone: tunnel.Timezone, AutoStart: tunnel.AutoStart}); err != nil {
+			return fmt.Errorf("tunnel %s: %v", key, err)
+		}
+		if err := normalizeHealthCheck(&models.Tunnel{Type: tunnel.Type, HealthCheck: tunnel.HealthCheck, HealthTarget: tunnel.HealthTarget, HealthPath: tunnel.HealthPath, HealthCode: tunnel.HealthCode, HealthSend: tunnel.HealthSend, HealthExpect: tunnel.HealthExpect, HealthInterval: tunnel.HealthInterval, HealthTimeout: tunnel.HealthTimeout, HealthFailures: tunnel.HealthFailures}); err != nil {
+			return fmt.Errorf("tunnel %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// Apply 计算配置文档与数据库的差异，非预演模式下依次创建、更新和删除资源
+func (s *applyService) Apply(doc *ApplyDocument, opts ApplyOptions) (*ApplyResult, error) {
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.plan(doc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{DryRun: opts.DryRun, Changes: changes}
+	for _, change := range changes {
+		switch change.Action {
+		case ApplyActionCreate:
+			result.Summary.Create++
+		case ApplyActionUpdate:
+			result.Summary.Update++
+		case ApplyActionDelete:
+			result.Summary.Delete++
+		default:
+			result.Summary.Unchanged++
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	return result, s.execute(result.Changes)
+}
+
+// plan 计算变更列表，顺序为主机、隧道、待删除的隧道、待删除的主机
+func (s *applyService) plan(doc *ApplyDocument, opts ApplyOptions) ([]ApplyChange, error) {
+	hosts, err := s.hostService.GetAllHosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hosts: %v", err)
+	}
+	tunnels, err := s.tunnelService.GetAllTunnels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tunnels: %v", err)
+	}
+
+	hostsByName := make(map[string]*models.Host)
+	hostNames := make(map[uint]string)
+	for i := range hosts {
+		hostsByName[hosts[i].Name] = &hosts[i]
+		hostNames[hosts[i].ID] = hosts[i].Name
+	}
+
+	tunnelsByKey := make(map[string]*models.Tunnel)
+	tunnelKeys := make(map[uint]string)
+	for i := range tunnels {
+		key := tunnelKey(hostNames[tunnels[i].HostID], tunnels[i].Name)
+		if _, exists := tunnelsByKey[key]; exists {
+			return nil, fmt.Errorf("multiple existing tunnels named %s, rename them before applying", key)
+		}
+		tunnelsByKey[key] = &tunnels[i]
+		tunnelKeys[tunnels[i].ID] = key
+	}
+
+	var changes []ApplyChange
+	declaredHosts := make(map[string]bool)
+	for i := range doc.Hosts {
+		spec := &doc.Hosts[i]
+		// 跳板机需要先创建，因此只能引用前面声明的主机或不会被删除的现有主机
+		if spec.JumpHost != "" && !declaredHosts[spec.JumpHost] && (hostsByName[spec.JumpHost] == nil || opts.Prune || declaresHost(doc, spec.JumpHost)) {
+			return nil, fmt.Errorf("host %s: jump host %s must be declared before it", spec.Name, spec.JumpHost)
+		}
+		declaredHosts[spec.Name] = true
+		changes = append(changes, planHost(spec, hostsByName[spec.Name], hostNames, opts.OwnerID))
+	}
+
+	declaredTunnels := make(map[string]bool)
+	for i := range doc.Tunnels {
+		spec := &doc.Tunnels[i]
+		if !declaredHosts[spec.Host] && (hostsByName[spec.Host] == nil || opts.Prune) {
+			return nil, fmt.Errorf("tunnel %s: host %s is not declared", tunnelKey(spec.Host, spec.Name), spec.Host)
+		}
+		key := tunnelKey(spec.Host, spec.Name)
+		// 依赖的隧道需要先创建，因此只能引用前面声明的隧道或不会被删除的现有隧道
+		for _, dep := range spec.dependencyKeys() {
+			if !declaredTunnels[dep] && (tunnelsByKey[dep] == nil || opts.Prune || declaresTunnel(doc, dep)) {
+				return nil, fmt.Errorf("tunnel %s: dependency %s must be declared before it", key, dep)
+			}
+		}
+		declaredTunnels[key] = true
+		changes = append(changes, planTunnel(spec, tunnelsByKey[key], tunnelKeys, opts.OwnerID))
+	}
+
+	if opts.Prune {
+		for i := range tunnels {
+			key := tunnelKey(hostNames[tunnels[i].HostID], tunnels[i].Name)
+			if !declaredTunnels[key] {
+				changes = append(changes, ApplyChange{
+					Action:       ApplyActionDelete,
+					ResourceType: models.ResourceTypeTunnel,
+					Name:         key,
+					ID:           tunnels[i].ID,
+					Changes:      DiffFields(&tunnels[i], nil),
+				})
+			}
+		}
+		// 先删除使用跳板机的主机，再删除跳板机
+		pruned := make([]*models.Host, 0)
+		for i := range hosts {
+			if !declaredHosts[hosts[i].Name] {
+				pruned = append(pruned, &hosts[i])
+			}
+		}
+		sort.SliceStable(pruned, func(i, j int) bool {
+			return jumpDepth(pruned[i], hosts) > jumpDepth(pruned[j], hosts)
+		})
+		for _, host := range pruned {
+			changes = append(changes, ApplyChange{
+				Action:       ApplyActionDelete,
+				ResourceType: models.ResourceTypeHost,
+				Name:         host.Name,
+				ID:           host.ID,
+				Changes:      DiffFields(host, nil),
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// planHost 计算单个主机的变更，existing 为已解密凭据的现有主机，hostNames 为现有主机ID到名称的映射
+func planHost(spec *ApplyHost, existing *models.Host, hostNames map[uint]string, ownerID uint) ApplyChange {
+	desired := &models.Host{OwnerID: ownerID}
+	if existing != nil {
+		copied := *existing
+		copied.Tunnels = nil
+		desired = &copied
+	}
+
+	desired.Name = spec.Name
+	desired.Hostname = spec.Hostname
+	desired.Port = spec.Port
+	if desired.Port == 0 {
+		desired.Port = 22
+	}
+	desired.Username = spec.Username
+	desired.AuthType = spec.AuthType
+	desired.KeyPath = spec.KeyPath
+	desired.Description = spec.Description
+	desired.UploadLimit = spec.UploadLimit
+	desired.DownloadLimit = spec.DownloadLimit
+	desired.Labels, _ = normalizeLabels(spec.Labels)
+
+	// 凭据按明文比较，只有值不同时才标记为修改
+	var changedSecrets []string
+	for field, target := range map[string]*string{
+		"password":    &desired.Password,
+		"private_key": &desired.PrivateKey,
+		"passphrase":  &desired.Passphrase,
+	} {
+		ref := spec.secretRefs()[field]
+		if ref == nil || ref.Value == "" || ref.Value == *target {
+			continue
+		}
+		*target = ref.Value
+		changedSecrets = append(changedSecrets, field)
+	}
+
+	change := ApplyChange{
+		ResourceType: models.ResourceTypeHost,
+		Name:         spec.Name,
+		host:         desired,
+		hostName:     spec.JumpHost,
+	}
+	if existing == nil {
+		change.Action = ApplyActionCreate
+		change.Changes = DiffFields(nil, desired)
+	} else {
+		change.ID = existing.ID
+		change.Changes = DiffFields(existing, desired)
+		change.Action = ApplyActionUpdate
+	}
+
+	// 跳板机按名称比较，新建的跳板机在执行时才有ID
+	delete(change.Changes, "jump_host_id")
+	previousJumpHost := ""
+	if existing != nil {
+		previousJumpHost = hostNames[existing.JumpHostID]
+	}
+	if previousJumpHost != spec.JumpHost {
+		change.Changes["jump_host"] = models.FieldChange{Before: previousJumpHost, After: spec.JumpHost}
+	}
+	for _, field := range changedSecrets {
+		change.Changes[field] = models.FieldChange{Before: models.AuditRedacted, After: models.AuditRedacted}
+	}
+	if existing != nil && len(change.Changes) == 0 {
+		change.Action = ApplyActionUnchanged
+		change.Changes = nil
+	}
+	return change
+}
+
+// planTunnel 计算单个隧道的变更，默认值与 TunnelService 保持一致以避免无意义的修改
+// tunnelKeys 为现有隧道ID到 主机名/隧道名 的映射
+func planTunnel(spec *ApplyTunnel, existing *models.Tunnel, tunnelKeys map[uint]string, ownerID uint) ApplyChange {
+	desired := &models.Tunnel{OwnerID: ownerID, Status: models.TunnelStatusInactive}
+	if existing != nil {
+		copied := *existing
+		copied.Host = nil
+		copied.ConnectionLogs = nil
+		desired = &copied
+	}
+
+	desired.Name = spec.Name
+	desired.Type = spec.Type
+	desired.LocalAddress = spec.LocalAddress
+	if desired.LocalAddress == "" {
+		desired.LocalAddress = "127.0.0.1"
+	}
+	desired.LocalPort = spec.LocalPort
+	desired.RemoteAddress = spec.RemoteAddress
+	if desired.RemoteAddress == "" && spec.Type == models.TunnelTypeRemoteForward {
+		desired.RemoteAddress = "0.0.0.0"
+	}
+	desired.RemotePort = spec.RemotePort
+	desired.Description = spec.Description
+	desired.AutoStart = spec.AutoStart
+	desired.UploadLimit = spec.UploadLimit
+	desired.DownloadLimit = spec.DownloadLimit
+	desired.QuotaBytes = spec.QuotaBytes
+	desired.QuotaPeriod = spec.QuotaPeriod
+	desired.QuotaPeriod = effectiveQuotaPeriod(desired)
+	desired.MaxConnections = spec.MaxConnections
+	desired.OverflowPolicy = spec.OverflowPolicy
+	if desired.OverflowPolicy == "" {
+		desired.OverflowPolicy = models.OverflowPolicyReject
+	}
+	desired.IdleTimeout = spec.IdleTimeout
+	desired.MaxLifetime = spec.MaxLifetime
+	desired.ProxyDomains, _ = normalizeProxyDomains(spec.ProxyDomains)
+	desired.Tags, _ = normalizeTags(spec.Tags)
+	desired.Lazy = spec.Lazy
+	desired.LazyTimeout = spec.LazyTimeout
+	desired.Labels, _ = normalizeLabels(spec.Labels)
+	desired.StartCron = spec.StartCron
+	desired.StopCron = spec.StopCron
+	desired.TimeWindows = spec.TimeWindows
+	desired.Timezone = spec.Timezone
+	_ = normalizeSchedule(desired)
+	desired.HealthCheck = spec.HealthCheck
+	desired.HealthTarget = spec.HealthTarget
+	desired.HealthPath = spec.HealthPath
+	desired.HealthCode = spec.HealthCode
+	desired.HealthSend = spec.HealthSend
+	desired.HealthExpect = spec.HealthExpect
+	desired.HealthInterval = spec.HealthInterval
+	desired.HealthTimeout = spec.HealthTimeout
+	desired.HealthFailures = spec.HealthFailures
+	desired.HealthRestart = spec.HealthRestart
+	_ = normalizeHealthCheck(desired)
+
+	change := ApplyChange{
+		ResourceType: models.ResourceTypeTunnel,
+		Name:         tunnelKey(spec.Host, spec.Name),
+		tunnel:       desired,
+		hostName:     spec.Host,
+		dependsOn:    spec.dependencyKeys(),
+	}
+	if existing == nil {
+		change.Action = ApplyActionCreate
+		change.Changes = DiffFields(nil, desired)
+		delete(change.Changes, "host_id")
+		if len(change.dependsOn) > 0 {
+			change.Changes["depends_on"] = models.FieldChange{After: change.dependsOn}
+		}
+		return change
+	}
+
+	change.ID = existing.ID
+	change.Changes = DiffFields(existing, desired)
+	change.Action = ApplyActionUpdate
+
+	// 依赖按名称比较，新建的隧道在执行时才有ID
+	var previousDeps []string
+	for _, id := range existing.DependsOn {
+		previousDeps = append(previousDeps, tunnelKeys[id])
+	}
+	if strings.Join(previousDeps, ",") != strings.Join(change.dependsOn, ",") {
+		change.Changes["depends_on"] = models.FieldChange{Before: previousDeps, After: change.dependsOn}
+	}
+	if len(change.Changes) == 0 {
+		change.Action = ApplyActionUnchanged
+		change.Changes = nil
+	}
+	return change
+}
+
+// execute 按计划顺序执行变更，遇到错误时停止，之前的变更已经生效
+func (s *applyService) execute(changes []ApplyChange) error {
+	hostIDs := make(map[string]uint)
+	hosts, err := s.hostService.GetAllHosts()
+	if err != nil {
+		return fmt.Errorf("failed to load hosts: %v", err)
+	}
+	hostNames := make(map[uint]string)
+	for _, host := range hosts {
+		hostIDs[host.Name] = host.ID
+		hostNames[host.ID] = host.Name
+	}
+	tunnels, err := s.tunnelService.GetAllTunnels()
+	if err != nil {
+		return fmt.Errorf("failed to load tunnels: %v", err)
+	}
+	tunnelIDs := make(map[string]uint)
+	for _, tunnel := range tunnels {
+		tunnelIDs[tunnelKey(hostNames[tunnel.HostID], tunnel.Name)] = tunnel.ID
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		if change.Action == ApplyActionUnchanged {
+			continue
+		}
+
+		var err error
+		switch {
+		case change.ResourceType == models.ResourceTypeHost && change.Action == ApplyActionCreate:
+			change.host.JumpHostID = hostIDs[change.hostName]
+			err = s.hostService.CreateHost(change.host)
+			hostIDs[change.Name] = change.host.ID
+			change.ID = change.host.ID
+		case change.ResourceType == models.ResourceTypeHost && change.Action == ApplyActionUpdate:
+			change.host.JumpHostID = hostIDs[change.hostName]
+			err = s.hostService.UpdateHost(change.host)
+		case change.ResourceType == models.ResourceTypeTunnel && change.Action == ApplyActionCreate:
+			change.tunnel.HostID = hostIDs[change.hostName]
+			change.tunnel.DependsOn = resolveApplyDependencies(change.dependsOn, tunnelIDs)
+			err = s.tunnelService.CreateTunnel(change.tunnel)
+			tunnelIDs[change.Name] = change.tunnel.ID
+			change.ID = change.tunnel.ID
+		case change.ResourceType == models.ResourceTypeTunnel && change.Action == ApplyActionUpdate:
+			change.tunnel.HostID = hostIDs[change.hostName]
+			change.tunnel.DependsOn = resolveApplyDependencies(change.dependsOn, tunnelIDs)
+			err = s.tunnelService.UpdateTunnel(change.tunnel)
+		case change.ResourceType == models.ResourceTypeTunnel && change.Action == ApplyActionDelete:
+			err = s.tunnelService.DeleteTunnel(change.ID)
+		case change.ResourceType == models.ResourceTypeHost && change.Action == ApplyActionDelete:
+			err = s.hostService.DeleteHost(change.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %s %s: %v", change.Action, change.ResourceType, change.Name, err)
+		}
+		change.Applied = true
+	}
+	return nil
+}
+
+// declaresHost 检查配置文档是否声明了指定名称的主机
+func declaresHost(doc *ApplyDocument, name string) bool {
+	for i := range doc.Hosts {
+		if doc.Hosts[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// declaresTunnel 检查配置文档是否声明了指定 主机名/隧道名 的隧道
+func declaresTunnel(doc *ApplyDocument, key string) bool {
+	for i := range doc.Tunnels {
+		if tunnelKey(doc.Tunnels[i].Host, doc.Tunnels[i].Name) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyKeys 返回去重后的依赖隧道，只写隧道名时补全为同一主机的隧道
+func (t *ApplyTunnel) dependencyKeys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, dep := range t.DependsOn {
+		dep = strings.TrimSpace(dep)
+		if dep == "" {
+			continue
+		}
+		if !strings.Contains(dep, "/") {
+			dep = tunnelKey(t.Host, dep)
+		}
+		if !seen[dep] {
+			seen[dep] = true
+			keys = append(keys, dep)
+		}
+	}
+	return keys
+}
+
+// resolveApplyDependencies 将依赖的 主机名/隧道名 转换为隧道ID，计划阶段已经检查过依赖存在
+func resolveApplyDependencies(keys []string, tunnelIDs map[string]uint) models.UintList {
+	var ids models.UintList
+	for _, key := range keys {
+		if id, ok := tunnelIDs[key]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// jumpDepth 计算主机经过的跳板机数量
+func jumpDepth(host *models.Host, hosts []models.Host) int {
+	depth := 0
+	for jumpHostID := host.JumpHostID; jumpHostID != 0 && depth < maxJumpHosts; depth++ {
+		next := uint(0)
+		for i := range hosts {
+			if hosts[i].ID == jumpHostID {
+				next = hosts[i].JumpHostID
+				break
+			}
+		}
+		jumpHostID = next
+	}
+	return depth
+}
+
+// tunnelKey 隧道在配置文档中的唯一标识
+func tunnelKey(hostName, tunnelName string) string {
+	return hostName + "/" + tunnelName
+}
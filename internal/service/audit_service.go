@@ -0,0 +1,202 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+)
+
+// AuditService 管理操作审计服务接口
+type AuditService interface {
+	Record(event *models.AuditEvent)
+	QueryEvents(filter repository.AuditFilter) ([]models.AuditEvent, string, error)
+	Close() error
+}
+
+// auditService 管理操作审计服务实现
+type auditService struct {
+	auditRepo repository.AuditRepository
+
+	mu   sync.Mutex
+	file *os.File // JSONL导出文件，为nil表示不导出
+}
+
+// auditIgnoredFields 不参与修改对比的字段：时间戳、运行状态及关联数据
+var auditIgnoredFields = map[string]bool{
+	"id":              true,
+	"created_at":      true,
+	"updated_at":      true,
+	"status":          true,
+	"status_reason":   true,
+	"last_check":      true,
+	"host":            true,
+	"tunnels":         true,
+	"connection_logs": true,
+}
+
+// NewAuditService 创建审计服务实例，jsonlPath 不为空时同时将审计记录追加写入该文件
+func NewAuditService(auditRepo repository.AuditRepository, jsonlPath string) (AuditService, error) {
+	s := &auditService{
+		auditRepo: auditRepo,
+	}
+
+	if jsonlPath != "" {
+		file, err := os.OpenFile(jsonlPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log file: %v", err)
+		}
+		s.file = file
+	}
+
+	return s, nil
+}
+
+// Record 保存审计记录，失败时只记录日志，不影响已完成的操作
+func (s *auditService) Record(event *models.AuditEvent) {
+	if err := s.auditRepo.Create(event); err != nil {
+		log.Printf("Failed to record audit event %s %s %d: %v", event.Action, event.ResourceType, event.ResourceID, err)
+	}
+
+	if s.file == nil {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode audit event: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write audit log file: %v", err)
+	}
+}
+
+// QueryEvents 按条件分页查询审计记录，返回下一页游标（没有更多数据时为空）
+func (s *auditService) QueryEvents(filter repository.AuditFilter) ([]models.AuditEvent, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	// 多取一条用于判断是否还有下一页
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	events, err := s.auditRepo.Query(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor = strconv.FormatUint(uint64(events[limit-1].ID), 10)
+	}
+
+	return events, nextCursor, nil
+}
+
+// Close 关闭JSONL导出文件
+func (s *auditService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// DiffFields 对比资源修改前后的字段，before 或 after 为nil分别表示创建和删除
+// 字段按JSON序列化结果对比，主机凭据在序列化时已被移除，不会出现在结果中
+func DiffFields(before, after interface{}) models.AuditChanges {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changes := models.AuditChanges{}
+	for name, value := range beforeFields {
+		if other, ok := afterFields[name]; !ok || !reflect.DeepEqual(value, other) {
+			changes[name] = models.FieldChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = models.FieldChange{After: value}
+		}
+	}
+	return changes
+}
+
+// auditFields 将资源序列化为字段表，只保留参与对比的标量字段
+func auditFields(resource interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value := reflect.ValueOf(resource); !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return fields
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return fields
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fields
+	}
+
+	for name, value := range raw {
+		if auditIgnoredFields[name] || value == nil {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			// 只对比标签这样的字符串映射，关联的资源不参与对比
+			if !isStringMap(v) {
+				continue
+			}
+		case []interface{}:
+			// 只对比字符串等标量组成的列表，关联的资源列表不参与对比
+			if !isScalarList(v) {
+				continue
+			}
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// isStringMap 检查映射是否非空且值都是字符串
+func isStringMap(m map[string]interface{}) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for _, value := range m {
+		if _, ok := value.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isScalarList 检查列表是否非空且只包含标量
+func isScalarList(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// minPasswordLength 密码最短长度
+const minPasswordLength = 8
+
+// sessionTouchInterval 会话最后访问时间的更新间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrSessionInvalid 会话不存在、已过期或用户已被禁用
+	ErrSessionInvalid = errors.New("session is invalid or expired")
+)
+
+// AuthService 认证服务接口
+type AuthService interface {
+	Login(username, password, clientIP, userAgent string) (*LoginResult, error)
+	Logout(token string) error
+	Authenticate(token string) (*models.User, *models.UserSession, error)
+	AuthenticateClientCert(commonName string) (*models.User, error)
+	EnsureAdminUser(username, password string) error
+	GetAllUsers() ([]models.User, error)
+	CreateUser(username, password, role string) (*models.User, error)
+	UpdateUser(id uint, role string, disabled bool) (*models.User, error)
+	DeleteUser(id uint) error
+	ChangePassword(userID uint, oldPassword, newPassword string) error
+}
+
+// LoginResult 登录结果，Token 只在登录时返回一次
+type LoginResult struct {
+	User      *models.User
+	Token     string
+	CSRFToken string
+	ExpiresAt time.Time
+}
+
+// authService 认证服务实现
+type authService struct {
+	userRepo   repository.UserRepository
+	sessionTTL time.Duration
+	dummyHash  []byte // 用户不存在时用于比较的哈希，使响应时间一致
+}
+
+// NewAuthService 创建认证服务实例
+func NewAuthService(userRepo repository.UserRepository, sessionTTL time.Duration) AuthService {
+	if sessionTTL <= 0 {
+		sessionTTL = 24 * time.Hour
+	}
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("drilling-dummy-password"), bcrypt.DefaultCost)
+	return &authService{
+		userRepo:   userRepo,
+		sessionTTL: sessionTTL,
+		dummyHash:  dummyHash,
+	}
+}
+
+// Login 校验用户名密码并创建登录会话
+func (s *authService) Login(username, password, clientIP, userAgent string) (*LoginResult, error) {
+	user, err := s.userRepo.GetByUsername(strings.TrimSpace(username))
+	if err != nil {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil || user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		TokenHash:  hashToken(token),
+		UserID:     user.ID,
+		CSRFToken:  csrfToken,
+		ClientIP:   clientIP,
+		UserAgent:  userAgent,
+		ExpiresAt:  now.Add(s.sessionTTL),
+		LastSeenAt: now,
+	}
+	if err := s.userRepo.CreateSession(session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	user.LastLoginAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		log.Printf("Failed to update last login time for user %d: %v", user.ID, err)
+	}
+
+	// 顺便清理过期会话
+	if _, err := s.userRepo.DeleteExpiredSessions(now); err != nil {
+		log.Printf("Failed to delete expired sessions: %v", err)
+	}
+
+	return &LoginResult{
+		User:      user,
+		Token:     token,
+		CSRFToken: csrfToken,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Logout 删除登录会话
+func (s *authService) Logout(token string) error {
+	session, err := s.userRepo.GetSessionByTokenHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.userRepo.DeleteSession(session.ID)
+}
+
+// Authenticate 根据会话令牌获取当前用户，会话有效时延长过期时间
+func (s *authService) Authenticate(token string) (*models.User, *models.UserSession, error) {
+	if token == "" {
+		return nil, nil, ErrSessionInvalid
+	}
+
+	session, err := s.userRepo.GetSessionByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, nil, ErrSessionInvalid
+	}
+
+	now := time.Now()
+	if now.After(session.ExpiresAt) || session.User == nil || session.User.Disabled {
+		s.userRepo.DeleteSession(session.ID)
+		return nil, nil, ErrSessionInvalid
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		session.LastSeenAt = now
+		session.ExpiresAt = now.Add(s.sessionTTL)
+		if err := s.userRepo.TouchSession(session.ID, session.LastSeenAt, session.ExpiresAt); err != nil {
+			log.Printf("Failed to refresh session %d: %v", session.ID, err)
+		}
+	}
+
+	return session.User, session, nil
+}
+
+// AuthenticateClientCert 根据已通过校验的客户端证书CN查找对应用户
+func (s *authService) AuthenticateClientCert(commonName string) (*models.User, error) {
+	user, err := s.userRepo.GetByUsername(commonName)
+	if err != nil || user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// EnsureAdminUser 没有任何用户时创建初始管理员，未配置密码时生成随机密码并输出到日志
+// 已有用户但没有管理员时（例如从没有角色的版本升级），将第一个用户提升为管理员
+func (s *authService) EnsureAdminUser(username, password string) error {
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		for _, user := range users {
+			if user.Role == models.UserRoleAdmin {
+				return nil
+			}
+		}
+
+		first := users[0]
+		first.Role = models.UserRoleAdmin
+		if err := s.userRepo.Update(&first); err != nil {
+			return fmt.Errorf("failed to promote user %q to admin: %v", first.Username, err)
+		}
+		log.Printf("No admin user found, promoted user %q to admin", first.Username)
+		return nil
+	}
+
+	if username == "" {
+		username = "admin"
+	}
+
+	generated := password == ""
+	if generated {
+		password, err = randomToken(12)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.CreateUser(username, password, models.UserRoleAdmin); err != nil {
+		return fmt.Errorf("failed to create initial admin user: %v", err)
+	}
+
+	if generated {
+		log.Printf("Created initial admin user %q with generated password: %s", username, password)
+		log.Println("Please log in and change this password")
+	} else {
+		log.Printf("Created initial admin user %q from configuration", username)
+	}
+
+	return nil
+}
+
+// GetAllUsers 获取所有用户
+func (s *authService) GetAllUsers() ([]models.User, error) {
+	return s.userRepo.GetAll()
+}
+
+// CreateUser 创建用户，未指定角色时为只读用户
+func (s *authService) CreateUser(username, password, role string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+
+	if role == "" {
+		role = models.UserRoleViewer
+	}
+	if !validRole(role) {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	if _, err := s.userRepo.GetByUsername(username); err == nil {
+		return nil, fmt.Errorf("user '%s' already exists", username)
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateUser 修改用户的角色和禁用状态，禁用用户会使其所有会话失效
+func (s *authService) UpdateUser(id uint, role string, disabled bool) (*models.User, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == "" {
+		role = user.Role
+	}
+	if !validRole(role) {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	// 不允许移除最后一个可用的管理员
+	if user.Role == models.UserRoleAdmin && !user.Disabled && (role != models.UserRoleAdmin || disabled) {
+		if err := s.ensureOtherAdmin(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Role = role
+	user.Disabled = disabled
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		if err := s.userRepo.DeleteUserSessions(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// DeleteUser 删除用户，不允许删除最后一个管理员
+func (s *authService) DeleteUser(id uint) error {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if user.Role == models.UserRoleAdmin && !user.Disabled {
+		if err := s.ensureOtherAdmin(user.ID); err != nil {
+			return err
+		}
+	}
+
+	return s.userRepo.Delete(id)
+}
+
+// ensureOtherAdmin 检查除指定用户外是否还有可用的管理员
+func (s *authService) ensureOtherAdmin(userID uint) error {
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if user.ID != userID && user.Role == models.UserRoleAdmin && !user.Disabled {
+			return nil
+		}
+	}
+	return errors.New("cannot remove the last admin user")
+}
+
+// ChangePassword 校验旧密码后修改密码，并使该用户的所有会话失效
+func (s *authService) ChangePassword(userID uint, oldPassword, newPassword string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.userRepo.DeleteUserSessions(user.ID)
+}
+
+// validRole 检查角色是否有效
+func validRole(role string) bool {
+	switch role {
+	case models.UserRoleAdmin, models.UserRoleOperator, models.UserRoleViewer:
+		return true
+	}
+	return false
+}
+
+// hashPassword 校验密码强度并生成bcrypt哈希
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %v", err)
+	}
+	return string(hash), nil
+}
+
+// randomToken 生成URL安全的随机令牌
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken 计算令牌的SHA-256哈希，数据库中只保存哈希值
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,777 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"golang.org/x/crypto/scrypt"
+)
+
+// BackupVersion 当前备份文件格式版本
+const BackupVersion = 1
+
+// 备份加密参数，导入时使用备份文件中记录的参数
+const (
+	backupKDF           = "scrypt"
+	backupCipher        = "aes-256-gcm"
+	backupScryptN       = 1 << 15
+	backupScryptR       = 8
+	backupScryptP       = 1
+	backupMaxScryptN    = 1 << 20 // 限制导入时的计算量
+	backupCheckValue    = "drilling-backup"
+	minBackupPassphrase = 8
+)
+
+// BackupConflict 导入时名称冲突的处理策略
+const (
+	BackupConflictSkip      = "skip"      // 保留现有资源，隧道挂到现有主机上
+	BackupConflictOverwrite = "overwrite" // 用备份中的配置覆盖现有资源
+	BackupConflictRename    = "rename"    // 以新名称创建，隧道的本地端口被占用时重新分配并以停止状态创建
+)
+
+// BackupImportAction 导入结果中的处理方式
+const (
+	BackupImportCreated = "created"
+	BackupImportUpdated = "updated"
+	BackupImportSkipped = "skipped"
+	BackupImportRenamed = "renamed"
+	BackupImportFailed  = "failed"
+)
+
+// ErrBackupPassphrase 备份口令错误
+var ErrBackupPassphrase = errors.New("incorrect backup passphrase")
+
+// BackupService 备份服务接口，导出和恢复所有主机和隧道
+type BackupService interface {
+	Export(passphrase string) (*BackupArchive, error)
+	Import(archive *BackupArchive, passphrase, strategy string, ownerID uint) (*BackupImportResult, error)
+}
+
+// BackupArchive 备份文件，凭据使用用户提供的口令重新加密，与服务端的加密密钥无关
+type BackupArchive struct {
+	Version    int              `json:"version"`
+	CreatedAt  time.Time        `json:"created_at"`
+	Encryption BackupEncryption `json:"encryption"`
+	Hosts      []BackupHost     `json:"hosts"`
+	Tunnels    []BackupTunnel   `json:"tunnels"`
+	Groups     []BackupGroup    `json:"groups,omitempty"`
+}
+
+// BackupEncryption 备份凭据的加密参数
+type BackupEncryption struct {
+	KDF    string `json:"kdf"`
+	Salt   string `json:"salt"`
+	N      int    `json:"n"`
+	R      int    `json:"r"`
+	P      int    `json:"p"`
+	Cipher string `json:"cipher"`
+	Check  string `json:"check"` // 加密的固定值，用于在修改数据前校验口令
+}
+
+// BackupHost 备份中的主机，ID仅用于关联隧道
+type BackupHost struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Hostname      string `json:"hostname"`
+	Port          int    `json:"port"`
+	Username      string `json:"username"`
+	AuthType      string `json:"auth_type"`
+	Password      string `json:"password,omitempty"`    // 加密
+	PrivateKey    string `json:"private_key,omitempty"` // 加密
+	KeyPath       string `json:"key_path,omitempty"`
+	Passphrase    string `json:"passphrase,omitempty"` // 加密
+	Description   string `json:"description"`
+	UploadLimit   int64  `json:"upload_limit"`
+	DownloadLimit int64  `json:"download_limit"`
+	JumpHostID    uint   `json:"jump_host_id,omitempty"` // 对应备份中跳板机的ID
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// BackupTunnel 备份中的隧道，HostID 对应备份中主机的ID
+type BackupTunnel struct {
+	ID             uint     `json:"id"`
+	HostID         uint     `json:"host_id"`
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`
+	LocalAddress   string   `json:"local_address"`
+	LocalPort      int      `json:"local_port"`
+	RemoteAddress  string   `json:"remote_address"`
+	RemotePort     int      `json:"remote_port"`
+	Description    string   `json:"description"`
+	AutoStart      bool     `json:"auto_start"`
+	UploadLimit    int64    `json:"upload_limit"`
+	DownloadLimit  int64    `json:"download_limit"`
+	QuotaBytes     int64    `json:"quota_bytes"`
+	QuotaPeriod    string   `json:"quota_period"`
+	MaxConnections int      `json:"max_connections"`
+	OverflowPolicy string   `json:"overflow_policy"`
+	IdleTimeout    int      `json:"idle_timeout"`
+	MaxLifetime    int      `json:"max_lifetime"`
+	ProxyDomains   []string `json:"proxy_domains,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Lazy           bool     `json:"lazy,omitempty"`
+	LazyTimeout    int      `json:"lazy_timeout,omitempty"`
+	StartCron      string   `json:"start_cron,omitempty"`
+	StopCron       string   `json:"stop_cron,omitempty"`
+	TimeWindows    []string `json:"time_windows,omitempty"`
+	Timezone       string   `json:"timezone,omitempty"`
+	HealthCheck    string   `json:"health_check,omitempty"`
+	HealthTarget   string   `json:"health_target,omitempty"`
+	HealthPath     string   `json:"health_path,omitempty"`
+	HealthCode     int      `json:"health_code,omitempty"`
+	HealthSend     string   `json:"health_send,omitempty"`
+	HealthExpect   string   `json:"health_expect,omitempty"`
+	HealthInterval int      `json:"health_interval,omitempty"`
+	HealthTimeout  int      `json:"health_timeout,omitempty"`
+	HealthFailures int      `json:"health_failures,omitempty"`
+	HealthRestart  bool     `json:"health_restart,omitempty"`
+	DependsOn      []string `json:"depends_on,omitempty"` // 依赖的隧道，格式为 备份中的主机名/隧道名
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// BackupGroup 备份中的隧道组，Tunnels 为组内隧道，格式为 备份中的主机名/隧道名
+type BackupGroup struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tunnels     []string `json:"tunnels"`
+}
+
+// BackupImportItem 单个资源的导入结果
+type BackupImportItem struct {
+	SourceID uint   `json:"source_id"`       // 备份中的ID
+	ID       uint   `json:"id,omitempty"`    // 导入后的ID
+	Name     string `json:"name"`            // 导入后的名称
+	Action   string `json:"action"`          // created, updated, skipped, renamed, failed
+	Error    string `json:"error,omitempty"` // 失败原因
+}
+
+// BackupImportResult 导入结果
+type BackupImportResult struct {
+	Hosts   []BackupImportItem `json:"hosts"`
+	Tunnels []BackupImportItem `json:"tunnels"`
+	Groups  []BackupImportItem `json:"groups"`
+	Failed  int                `json:"failed"`
+}
+
+// backupService 备份服务实现
+type backupService struct {
+	hostService   HostService
+	tunnelService TunnelService
+	groupService  TunnelGroupService
+}
+
+// NewBackupService 创建备份服务实例
+func NewBackupService(hostService HostService, tunnelService TunnelService, groupService TunnelGroupService) BackupService {
+	return &backupService{
+		hostService:   hostService,
+		tunnelService: tunnelService,
+		groupService:  groupService,
+	}
+}
+
+// Export 导出所有主机、隧道和隧道组，凭据先用服务端密钥解密，再用备份口令加密
+func (s *backupService) Export(passphrase string) (*BackupArchive, error) {
+	if len(passphrase) < minBackupPassphrase {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", minBackupPassphrase)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	encryption := BackupEncryption{
+		KDF:    backupKDF,
+		Salt:   base64.StdEncoding.EncodeToString(salt),
+		N:      backupScryptN,
+		R:      backupScryptR,
+		P:      backupScryptP,
+		Cipher: backupCipher,
+	}
+	aead, err := encryption.newAEAD(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if encryption.Check, err = sealBackupSecret(aead, backupCheckValue); err != nil {
+		return nil, err
+	}
+
+	hosts, err := s.hostService.GetAllHosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hosts: %v", err)
+	}
+	tunnels, err := s.tunnelService.GetAllTunnels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tunnels: %v", err)
+	}
+	groups, err := s.groupService.GetAllGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tunnel groups: %v", err)
+	}
+
+	// 隧道ID在恢复后会变化，依赖和隧道组按主机名和隧道名引用隧道
+	hostNames := make(map[uint]string)
+	for _, host := range hosts {
+		hostNames[host.ID] = host.Name
+	}
+	tunnelKeys := make(map[uint]string)
+	for _, tunnel := range tunnels {
+		tunnelKeys[tunnel.ID] = tunnelKey(hostNames[tunnel.HostID], tunnel.Name)
+	}
+	keysOf := func(ids []uint) []string {
+		keys := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if key, ok := tunnelKeys[id]; ok {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+
+	archive := &BackupArchive{
+		Version:    BackupVersion,
+		CreatedAt:  time.Now(),
+		Encryption: encryption,
+		Hosts:      make([]BackupHost, 0, len(hosts)),
+		Tunnels:    make([]BackupTunnel, 0, len(tunnels)),
+	}
+
+	for _, host := range hosts {
+		entry := BackupHost{
+			ID:            host.ID,
+			Name:          host.Name,
+			Hostname:      host.Hostname,
+			Port:          host.Port,
+			Username:      host.Username,
+			AuthType:      host.AuthType,
+			KeyPath:       host.KeyPath,
+			Description:   host.Description,
+			UploadLimit:   host.UploadLimit,
+			DownloadLimit: host.DownloadLimit,
+			JumpHostID:    host.JumpHostID,
+			Labels:        host.Labels,
+		}
+		for _, secret := range []struct{ from, to *string }{
+			{&host.Password, &entry.Password},
+			{&host.PrivateKey, &entry.PrivateKey},
+			{&host.Passphrase, &entry.Passphrase},
+		} {
+			if *secret.from == "" {
+				continue
+			}
+			if *secret.to, err = sealBackupSecret(aead, *secret.from); err != nil {
+				return nil, err
+			}
+		}
+		archive.Hosts = append(archive.Hosts, entry)
+	}
+
+	for _, tunnel := range tunnels {
+		archive.Tunnels = append(archive.Tunnels, BackupTunnel{
+			ID:             tunnel.ID,
+			HostID:         tunnel.HostID,
+			Name:           tunnel.Name,
+			Type:           tunnel.Type,
+			LocalAddress:   tunnel.LocalAddress,
+			LocalPort:      tunnel.LocalPort,
+			RemoteAddress:  tunnel.RemoteAddress,
+			RemotePort:     tunnel.RemotePort,
+			Description:    tunnel.Description,
+			AutoStart:      tunnel.AutoStart,
+			UploadLimit:    tunnel.UploadLimit,
+			DownloadLimit:  tunnel.DownloadLimit,
+			QuotaBytes:     tunnel.QuotaBytes,
+			QuotaPeriod:    tunnel.QuotaPeriod,
+			MaxConnections: tunnel.MaxConnections,
+			OverflowPolicy: tunnel.OverflowPolicy,
+			IdleTimeout:    tunnel.IdleTimeout,
+			MaxLifetime:    tunnel.MaxLifetime,
+			ProxyDomains:   tunnel.ProxyDomains,
+			Tags:           tunnel.Tags,
+			Lazy:           tunnel.Lazy,
+			LazyTimeout:    tunnel.LazyTimeout,
+			StartCron:      tunnel.StartCron,
+			StopCron:       tunnel.StopCron,
+			TimeWindows:    tunnel.TimeWindows,
+			Timezone:       tunnel.Timezone,
+			HealthCheck:    tunnel.HealthCheck,
+			HealthTarget:   tunnel.HealthTarget,
+			HealthPath:     tunnel.HealthPath,
+			HealthCode:     tunnel.HealthCode,
+			HealthSend:     tunnel.HealthSend,
+			HealthExpect:   tunnel.HealthExpect,
+			HealthInterval: tunnel.HealthInterval,
+			HealthTimeout:  tunnel.HealthTimeout,
+			HealthFailures: tunnel.HealthFailures,
+			HealthRestart:  tunnel.HealthRestart,
+			DependsOn:      keysOf(tunnel.DependsOn),
+			Labels:         tunnel.Labels,
+		})
+	}
+
+	for _, group := range groups {
+		archive.Groups = append(archive.Groups, BackupGroup{
+			Name:        group.Name,
+			Description: group.Description,
+			Tunnels:     keysOf(group.TunnelIDs()),
+		})
+	}
+
+	return archive, nil
+}
+
+// Import 恢复备份，先校验口令并解密所有凭据，再逐个导入
+// 单个资源导入失败不影响其他资源，主机导入失败时其隧道也标记为失败
+func (s *backupService) Import(archive *BackupArchive, passphrase, strategy string, ownerID uint) (*BackupImportResult, error) {
+	if archive.Version < 1 || archive.Version > BackupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", archive.Version)
+	}
+	switch strategy {
+	case BackupConflictSkip, BackupConflictOverwrite, BackupConflictRename:
+	default:
+		return nil, fmt.Errorf("invalid conflict strategy: %s", strategy)
+	}
+
+	aead, err := archive.Encryption.newAEAD(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := openBackupSecret(aead, archive.Encryption.Check); err != nil || subtle.ConstantTimeCompare([]byte(check), []byte(backupCheckValue)) != 1 {
+		return nil, ErrBackupPassphrase
+	}
+
+	hosts := make([]models.Host, len(archive.Hosts))
+	for i, entry := range archive.Hosts {
+		hosts[i] = models.Host{
+			Name:          entry.Name,
+			Hostname:      entry.Hostname,
+			Port:          entry.Port,
+			Username:      entry.Username,
+			AuthType:      entry.AuthType,
+			KeyPath:       entry.KeyPath,
+			Description:   entry.Description,
+			UploadLimit:   entry.UploadLimit,
+			DownloadLimit: entry.DownloadLimit,
+			Labels:        entry.Labels,
+			OwnerID:       ownerID,
+		}
+		for _, secret := range []struct{ from, to *string }{
+			{&entry.Password, &hosts[i].Password},
+			{&entry.PrivateKey, &hosts[i].PrivateKey},
+			{&entry.Passphrase, &hosts[i].Passphrase},
+		} {
+			if *secret.from == "" {
+				continue
+			}
+			if *secret.to, err = openBackupSecret(aead, *secret.from); err != nil {
+				return nil, fmt.Errorf("failed to decrypt credentials of host %s: %v", entry.Name, err)
+			}
+		}
+	}
+
+	existingHosts, err := s.hostService.GetAllHosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hosts: %v", err)
+	}
+	existingTunnels, err := s.tunnelService.GetAllTunnels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tunnels: %v", err)
+	}
+
+	hostsByName := make(map[string]*models.Host)
+	for i := range existingHosts {
+		hostsByName[existingHosts[i].Name] = &existingHosts[i]
+	}
+
+	result := &BackupImportResult{
+		Hosts:   make([]BackupImportItem, 0, len(hosts)),
+		Tunnels: make([]BackupImportItem, 0, len(archive.Tunnels)),
+		Groups:  make([]BackupImportItem, 0, len(archive.Groups)),
+	}
+
+	// 备份中的主机ID到导入后主机ID的映射
+	hostIDs := make(map[uint]uint)
+	for i := range hosts {
+		host := &hosts[i]
+		item := BackupImportItem{SourceID: archive.Hosts[i].ID, Name: host.Name}
+
+		existing := hostsByName[host.Name]
+		switch {
+		case existing == nil:
+			item.Action = BackupImportCreated
+			err = s.hostService.CreateHost(host)
+		case strategy == BackupConflictSkip:
+			item.Action = BackupImportSkipped
+			host.ID = existing.ID
+		case strategy == BackupConflictOverwrite:
+			item.Action = BackupImportUpdated
+			host.ID = existing.ID
+			host.OwnerID = existing.OwnerID
+			err = s.hostService.UpdateHost(host)
+		default:
+			item.Action = BackupImportRenamed
+			host.Name = uniqueBackupName(host.Name, func(name string) bool { return hostsByName[name] != nil })
+			item.Name = host.Name
+			err = s.hostService.CreateHost(host)
+		}
+
+		if err != nil {
+			item.Action = BackupImportFailed
+			item.Error = err.Error()
+			result.Failed++
+		} else {
+			item.ID = host.ID
+			hostIDs[item.SourceID] = host.ID
+			hostsByName[host.Name] = host
+		}
+		result.Hosts = append(result.Hosts, item)
+	}
+
+	// 所有主机导入后再关联跳板机，跳板机在备份中可能排在后面
+	for i := range result.Hosts {
+		item := &result.Hosts[i]
+		jumpSourceID := archive.Hosts[i].JumpHostID
+		if jumpSourceID == 0 || item.Action == BackupImportFailed || item.Action == BackupImportSkipped {
+			continue
+		}
+		jumpHostID, ok := hostIDs[jumpSourceID]
+		if !ok {
+			item.Error = "jump host was not restored, the host connects directly"
+			continue
+		}
+
+		host, err := s.hostService.GetHost(item.ID)
+		if err == nil {
+			host.JumpHostID = jumpHostID
+			host.Tunnels = nil
+			err = s.hostService.UpdateHost(host)
+		}
+		if err != nil {
+			item.Error = fmt.Sprintf("failed to set jump host: %v", err)
+		}
+	}
+
+	// 按导入后的主机ID和名称查找现有隧道
+	tunnelsByKey := make(map[string]*models.Tunnel)
+	for i := range existingTunnels {
+		tunnelsByKey[fmt.Sprintf("%d/%s", existingTunnels[i].HostID, existingTunnels[i].Name)] = &existingTunnels[i]
+	}
+
+	// 备份中的主机名/隧道名到导入后隧道ID的映射，用于恢复依赖和隧道组
+	backupHostNames := make(map[uint]string)
+	for _, entry := range archive.Hosts {
+		backupHostNames[entry.ID] = entry.Name
+	}
+	tunnelIDs := make(map[string]uint)
+
+	for _, entry := range archive.Tunnels {
+		item := BackupImportItem{SourceID: entry.ID, Name: entry.Name}
+
+		hostID, ok := hostIDs[entry.HostID]
+		if !ok {
+			item.Action = BackupImportFailed
+			item.Error = "host of the tunnel was not restored"
+			result.Failed++
+			result.Tunnels = append(result.Tunnels, item)
+			continue
+		}
+
+		tunnel := &models.Tunnel{
+			HostID:         hostID,
+			Name:           entry.Name,
+			Type:           entry.Type,
+			LocalAddress:   entry.LocalAddress,
+			LocalPort:      entry.LocalPort,
+			RemoteAddress:  entry.RemoteAddress,
+			RemotePort:     entry.RemotePort,
+			Description:    entry.Description,
+			AutoStart:      entry.AutoStart,
+			UploadLimit:    entry.UploadLimit,
+			DownloadLimit:  entry.DownloadLimit,
+			QuotaBytes:     entry.QuotaBytes,
+			QuotaPeriod:    entry.QuotaPeriod,
+			MaxConnections: entry.MaxConnections,
+			OverflowPolicy: entry.OverflowPolicy,
+			IdleTimeout:    entry.IdleTimeout,
+			MaxLifetime:    entry.MaxLifetime,
+			ProxyDomains:   entry.ProxyDomains,
+			Tags:           entry.Tags,
+			Lazy:           entry.Lazy,
+			LazyTimeout:    entry.LazyTimeout,
+			StartCron:      entry.StartCron,
+			StopCron:       entry.StopCron,
+			TimeWindows:    entry.TimeWindows,
+			Timezone:       entry.Timezone,
+			HealthCheck:    entry.HealthCheck,
+			HealthTarget:   entry.HealthTarget,
+			HealthPath:     entry.HealthPath,
+			HealthCode:     entry.HealthCode,
+			HealthSend:     entry.HealthSend,
+			HealthExpect:   entry.HealthExpect,
+			HealthInterval: entry.HealthInterval,
+			HealthTimeout:  entry.HealthTimeout,
+			HealthFailures: entry.HealthFailures,
+			HealthRestart:  entry.HealthRestart,
+			Labels:         entry.Labels,
+			OwnerID:        ownerID,
+		}
+
+		key := func(name string) string { return fmt.Sprintf("%d/%s", hostID, name) }
+		existing := tunnelsByKey[key(tunnel.Name)]
+		switch {
+		case existing == nil:
+			item.Action = BackupImportCreated
+			err = s.createImportedTunnel(tunnel, &item, tunnelsByKey)
+		case strategy == BackupConflictSkip:
+			item.Action = BackupImportSkipped
+			tunnel.ID = existing.ID
+		case strategy == BackupConflictOverwrite:
+			item.Action = BackupImportUpdated
+			tunnel.ID = existing.ID
+			tunnel.OwnerID = existing.OwnerID
+			tunnel.Status = existing.Status
+			tunnel.StatusReason = existing.StatusReason
+			tunnel.CreatedAt = existing.CreatedAt
+			err = s.tunnelService.UpdateTunnel(tunnel)
+		default:
+			item.Action = BackupImportRenamed
+			tunnel.Name = uniqueBackupName(tunnel.Name, func(name string) bool { return tunnelsByKey[key(name)] != nil })
+			item.Name = tunnel.Name
+			err = s.createImportedTunnel(tunnel, &item, tunnelsByKey)
+		}
+
+		if err != nil {
+			item.Action = BackupImportFailed
+			item.Error = err.Error()
+			result.Failed++
+		} else {
+			item.ID = tunnel.ID
+			tunnelsByKey[key(tunnel.Name)] = tunnel
+			tunnelIDs[tunnelKey(backupHostNames[entry.HostID], entry.Name)] = tunnel.ID
+		}
+		result.Tunnels = append(result.Tunnels, item)
+	}
+
+	// 所有隧道导入后再设置依赖，依赖的隧道在备份中可能排在后面
+	for i := range result.Tunnels {
+		item := &result.Tunnels[i]
+		dependsOn := archive.Tunnels[i].DependsOn
+		if len(dependsOn) == 0 || item.Action == BackupImportFailed || item.Action == BackupImportSkipped {
+			continue
+		}
+		deps, missing := resolveBackupTunnels(dependsOn, tunnelIDs)
+		if len(missing) > 0 {
+			item.Error = joinBackupNotes(item.Error, fmt.Sprintf("dependency %s was not restored", strings.Join(missing, ", ")))
+		}
+		if len(deps) == 0 {
+			continue
+		}
+
+		tunnel, err := s.tunnelService.GetTunnel(item.ID)
+		if err == nil {
+			tunnel.DependsOn = deps
+			tunnel.Host = nil
+			err = s.tunnelService.UpdateTunnel(tunnel)
+		}
+		if err != nil {
+			item.Error = joinBackupNotes(item.Error, fmt.Sprintf("failed to set dependencies: %v", err))
+		}
+	}
+
+	s.importGroups(archive.Groups, strategy, ownerID, tunnelIDs, result)
+	return result, nil
+}
+
+// importGroups 恢复隧道组，组内只包含成功导入的隧道
+func (s *backupService) importGroups(groups []BackupGroup, strategy string, ownerID uint, tunnelIDs map[string]uint, result *BackupImportResult) {
+	existingGroups, err := s.groupService.GetAllGroups()
+	if err != nil {
+		for _, entry := range groups {
+			result.Groups = append(result.Groups, BackupImportItem{Name: entry.Name, Action: BackupImportFailed, Error: fmt.Sprintf("failed to load tunnel groups: %v", err)})
+			result.Failed++
+		}
+		return
+	}
+	groupsByName := make(map[string]*models.TunnelGroup)
+	for i := range existingGroups {
+		groupsByName[existingGroups[i].Name] = &existingGroups[i]
+	}
+
+	for _, entry := range groups {
+		item := BackupImportItem{Name: entry.Name}
+		ids, missing := resolveBackupTunnels(entry.Tunnels, tunnelIDs)
+		req := &TunnelGroupRequest{Name: entry.Name, Description: entry.Description, TunnelIDs: ids}
+
+		var group *models.TunnelGroup
+		existing := groupsByName[entry.Name]
+		switch {
+		case existing == nil:
+			item.Action = BackupImportCreated
+			group, err = s.groupService.CreateGroup(ownerID, req)
+		case strategy == BackupConflictSkip:
+			item.Action = BackupImportSkipped
+			group = existing
+		case strategy == BackupConflictOverwrite:
+			item.Action = BackupImportUpdated
+			group, err = s.groupService.UpdateGroup(existing.ID, req)
+		default:
+			item.Action = BackupImportRenamed
+			req.Name = uniqueBackupName(entry.Name, func(name string) bool { return groupsByName[name] != nil })
+			item.Name = req.Name
+			group, err = s.groupService.CreateGroup(ownerID, req)
+		}
+
+		if err != nil {
+			item.Action = BackupImportFailed
+			item.Error = err.Error()
+			result.Failed++
+		} else {
+			item.ID = group.ID
+			groupsByName[group.Name] = group
+			if len(missing) > 0 && item.Action != BackupImportSkipped {
+				item.Error = fmt.Sprintf("tunnel %s was not restored", strings.Join(missing, ", "))
+			}
+		}
+		result.Groups = append(result.Groups, item)
+	}
+}
+
+// resolveBackupTunnels 将备份中的 主机名/隧道名 转换为导入后的隧道ID，返回没有导入的隧道
+func resolveBackupTunnels(keys []string, tunnelIDs map[string]uint) (models.UintList, []string) {
+	var ids models.UintList
+	var missing []string
+	for _, key := range keys {
+		if id, ok := tunnelIDs[key]; ok {
+			ids = append(ids, id)
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return ids, missing
+}
+
+// joinBackupNotes 合并导入结果中的多条说明
+func joinBackupNotes(notes ...string) string {
+	var parts []string
+	for _, note := range notes {
+		if note != "" {
+			parts = append(parts, note)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// createImportedTunnel 创建备份中的隧道，本地端口已被现有隧道使用时（例如以新名称导入的副本）重新分配端口
+// 更换端口的副本以停止状态创建，关闭自动启动和启停计划，避免和原隧道同时连接
+// 远程转发的本地端口是要转发的服务，不能更换，而且远程端口会和原隧道冲突，因此端口冲突时返回错误
+func (s *backupService) createImportedTunnel(tunnel *models.Tunnel, item *BackupImportItem, tunnels map[string]*models.Tunnel) error {
+	address := tunnel.LocalAddress
+	if address == "" {
+		address = "127.0.0.1"
+	}
+	used := make(map[int]bool)
+	for _, existing := range tunnels {
+		existingAddress := existing.LocalAddress
+		if existingAddress == "" {
+			existingAddress = "127.0.0.1"
+		}
+		if existingAddress == address {
+			used[existing.LocalPort] = true
+		}
+	}
+
+	if used[tunnel.LocalPort] {
+		if tunnel.Type == models.TunnelTypeRemoteForward {
+			return fmt.Errorf("local port %d is used by an existing tunnel, remote forward tunnels cannot be imported as a copy", tunnel.LocalPort)
+		}
+		port := 0
+		for candidate := tunnel.LocalPort + 1; candidate <= 65535 && port == 0; candidate++ {
+			if used[candidate] {
+				continue
+			}
+			port, _ = s.tunnelService.FindAvailablePort(candidate, candidate, address)
+		}
+		if port == 0 {
+			return fmt.Errorf("local port %d is used by an existing tunnel and no other port is available", tunnel.LocalPort)
+		}
+
+		item.Error = fmt.Sprintf("local port %d is used by an existing tunnel, changed to %d with auto start and schedule disabled", tunnel.LocalPort, port)
+		tunnel.LocalPort = port
+		tunnel.AutoStart = false
+		tunnel.StartCron = ""
+		tunnel.StopCron = ""
+		tunnel.TimeWindows = nil
+		tunnel.Timezone = ""
+	}
+	return s.tunnelService.CreateTunnel(tunnel)
+}
+
+// newAEAD 根据口令和加密参数派生密钥
+func (e *BackupEncryption) newAEAD(passphrase string) (cipher.AEAD, error) {
+	if e.KDF != backupKDF || e.Cipher != backupCipher {
+		return nil, fmt.Errorf("unsupported backup encryption %s/%s", e.KDF, e.Cipher)
+	}
+	if e.N <= 1 || e.N > backupMaxScryptN || e.R <= 0 || e.R > 32 || e.P <= 0 || e.P > 16 {
+		return nil, errors.New("invalid backup encryption parameters")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup salt: %v", err)
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, e.N, e.R, e.P, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealBackupSecret 加密凭据，结果为 base64(nonce + 密文)
+func sealBackupSecret(aead cipher.AEAD, plaintext string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openBackupSecret 解密凭据
+func openBackupSecret(aead cipher.AEAD, encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// uniqueBackupName 为重名的资源生成新名称，例如 name-restored、name-restored-2
+func uniqueBackupName(name string, taken func(string) bool) string {
+	candidate := name + "-restored"
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s-restored-%d", name, i)
+	}
+	return candidate
+}
@@ -2,7 +2,7 @@ package service
 
 import (
 	"fmt"
-	"sort"
+	"regexp"
 	"strings"
 	"time"
 
@@ -13,39 +13,51 @@ import (
 
 // ClashExportService Clash配置导出服务接口
 type ClashExportService interface {
-	GenerateClashConfig() (*ClashConfig, error)
-	ExportClashConfigYAML() ([]byte, error)
-	GetActiveSocks5Tunnels() ([]models.Tunnel, error)
+	GenerateClashConfig(profile *models.ExportProfile, opts ExportOptions) (*ClashConfig, error)
+	ExportClashConfigYAML(profile *models.ExportProfile, opts ExportOptions) ([]byte, error)
+	GetSocks5Tunnels(includeInactive bool) ([]models.Tunnel, error)
 }
 
 // clashExportService Clash配置导出服务实现
 type clashExportService struct {
-	tunnelRepo repository.TunnelRepository
-	hostRepo   repository.HostRepository
+	tunnelRepo    repository.TunnelRepository
+	hostRepo      repository.HostRepository
+	tunnelService TunnelService
 }
 
 // NewClashExportService 创建Clash导出服务实例
-func NewClashExportService(tunnelRepo repository.TunnelRepository, hostRepo repository.HostRepository) ClashExportService {
+func NewClashExportService(tunnelRepo repository.TunnelRepository, hostRepo repository.HostRepository, tunnelService TunnelService) ClashExportService {
 	return &clashExportService{
-		tunnelRepo: tunnelRepo,
-		hostRepo:   hostRepo,
+		tunnelRepo:    tunnelRepo,
+		hostRepo:      hostRepo,
+		tunnelService: tunnelService,
 	}
 }
 
 // ClashConfig Clash配置结构
 type ClashConfig struct {
-	Port          int                    `yaml:"port"`
-	SocksPort     int                    `yaml:"socks-port"`
-	AllowLan      bool                   `yaml:"allow-lan"`
-	Mode          string                 `yaml:"mode"`
-	LogLevel      string                 `yaml:"log-level"`
-	ExternalUI    string                 `yaml:"external-ui"`
-	ExternalController string            `yaml:"external-controller"`
-	Secret        string                 `yaml:"secret,omitempty"`
-	Proxies       []ClashProxy           `yaml:"proxies"`
-	ProxyGroups   []ClashProxyGroup      `yaml:"proxy-groups"`
-	Rules         []string               `yaml:"rules"`
-	DNS           ClashDNS               `yaml:"dns"`
+	Port               int                          `yaml:"port,omitempty"`
+	SocksPort          int                          `yaml:"socks-port,omitempty"`
+	MixedPort          int                          `yaml:"mixed-port,omitempty"`
+	AllowLan           bool                         `yaml:"allow-lan"`
+	BindAddress        string                       `yaml:"bind-address,omitempty"`
+	Mode               string                       `yaml:"mode"`
+	LogLevel           string                       `yaml:"log-level"`
+	IPv6               bool                         `yaml:"ipv6,omitempty"`
+	ExternalUI         string                       `yaml:"external-ui"`
+	ExternalController string                       `yaml:"external-controller,omitempty"`
+	Secret             string                       `yaml:"secret,omitempty"`
+	UnifiedDelay       bool                         `yaml:"unified-delay,omitempty"`
+	TCPConcurrent      bool                         `yaml:"tcp-concurrent,omitempty"`
+	FindProcessMode    string                       `yaml:"find-process-mode,omitempty"`
+	ClientFingerprint  string                       `yaml:"global-client-fingerprint,omitempty"`
+	GeodataMode        bool                         `yaml:"geodata-mode,omitempty"`
+	GeoxURL            map[string]string            `yaml:"geox-url,omitempty"`
+	Proxies            []ClashProxy                 `yaml:"proxies"`
+	ProxyGroups        []ClashProxyGroup            `yaml:"proxy-groups"`
+	RuleProviders      map[string]ClashRuleProvider `yaml:"rule-providers,omitempty"`
+	Rules              []string                     `yaml:"rules"`
+	DNS                *models.ClashDNS             `yaml:"dns,omitempty"`
 }
 
 // ClashProxy Clash代理配置
@@ -58,128 +70,283 @@ type ClashProxy struct {
 
 // ClashProxyGroup Clash代理组配置
 type ClashProxyGroup struct {
-	Name     string   `yaml:"name"`
-	Type     string   `yaml:"type"`
-	Proxies  []string `yaml:"proxies"`
-	URL      string   `yaml:"url,omitempty"`
-	Interval int      `yaml:"interval,omitempty"`
+	Name      string   `yaml:"name"`
+	Type      string   `yaml:"type"`
+	Proxies   []string `yaml:"proxies"`
+	URL       string   `yaml:"url,omitempty"`
+	Interval  int      `yaml:"interval,omitempty"`
+	Tolerance int      `yaml:"tolerance,omitempty"`
+	Strategy  string   `yaml:"strategy,omitempty"`
+	Hidden    bool     `yaml:"hidden,omitempty"`
+	Icon      string   `yaml:"icon,omitempty"`
 }
 
-// ClashDNS Clash DNS配置
-type ClashDNS struct {
-	Enable           bool     `yaml:"enable"`
-	Listen           string   `yaml:"listen"`
-	NameServer       []string `yaml:"nameserver"`
-	EnhancedMode     string   `yaml:"enhanced-mode"`
-	FakeIPRange      string   `yaml:"fake-ip-range"`
-	UseHosts         bool     `yaml:"use-hosts"`
-	FakeIPFilter     []string `yaml:"fake-ip-filter"`
+// ClashRuleProvider Clash规则集配置
+type ClashRuleProvider struct {
+	Type     string `yaml:"type"`
+	Behavior string `yaml:"behavior"`
+	URL      string `yaml:"url,omitempty"`
+	Path     string `yaml:"path,omitempty"`
+	Interval int    `yaml:"interval,omitempty"`
+	Format   string `yaml:"format,omitempty"`
 }
 
-// GenerateClashConfig 生成Clash配置
-func (s *clashExportService) GenerateClashConfig() (*ClashConfig, error) {
-	// 获取所有活跃的SOCKS5隧道
-	socks5Tunnels, err := s.GetActiveSocks5Tunnels()
+// GenerateClashConfig 生成Clash配置，profile 为空时使用默认模板
+func (s *clashExportService) GenerateClashConfig(profile *models.ExportProfile, opts ExportOptions) (*ClashConfig, error) {
+	nodes, err := exportNodes(s.tunnelRepo, s.hostRepo, s.tunnelService, profile, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get SOCKS5 tunnels: %v", err)
+		return nil, err
+	}
+	return (&clashExporter{}).config(nodes, profile), nil
+}
+
+// ExportClashConfigYAML 导出Clash配置为YAML格式，profile 为空时使用默认模板
+func (s *clashExportService) ExportClashConfigYAML(profile *models.ExportProfile, opts ExportOptions) ([]byte, error) {
+	nodes, err := exportNodes(s.tunnelRepo, s.hostRepo, s.tunnelService, profile, opts)
+	if err != nil {
+		return nil, err
 	}
+	return (&clashExporter{}).ExportProfile(nodes, profile)
+}
+
+// GetSocks5Tunnels 获取SOCKS5隧道，状态为隧道的实际运行状态
+// includeInactive 为false时只返回正在运行的隧道
+func (s *clashExportService) GetSocks5Tunnels(includeInactive bool) ([]models.Tunnel, error) {
+	return socks5Tunnels(s.tunnelRepo, s.tunnelService, includeInactive)
+}
 
-	if len(socks5Tunnels) == 0 {
-		return nil, fmt.Errorf("no active SOCKS5 tunnels found")
+// clashExporter Clash配置导出器
+type clashExporter struct{}
+
+// Format 返回格式名称
+func (e *clashExporter) Format() string { return "clash" }
+
+// ContentType 返回响应的 Content-Type
+func (e *clashExporter) ContentType() string { return "application/x-yaml" }
+
+// FileExtension 返回下载文件的扩展名
+func (e *clashExporter) FileExtension() string { return "yaml" }
+
+// defaultClashProfile 默认导出模板，没有指定模板时使用
+func defaultClashProfile() *models.ExportProfile {
+	return &models.ExportProfile{
+		Name: "default",
+		Settings: models.ClashSettings{
+			Port:                7890,   // HTTP代理端口
+			SocksPort:           7891,   // SOCKS5代理端口
+			AllowLan:            false,  // 默认不允许局域网访问
+			Mode:                "rule", // 规则模式
+			LogLevel:            "info",
+			ExternalController:  "127.0.0.1:9090", // Clash面板地址
+			HealthCheckURL:      "http://www.gstatic.com/generate_204",
+			HealthCheckInterval: 300,
+			DNS: &models.ClashDNS{
+				Enable:       true,
+				Listen:       "0.0.0.0:53",
+				NameServer:   []string{"223.5.5.5", "1.1.1.1"},
+				EnhancedMode: "fake-ip",
+				FakeIPRange:  "198.18.0.1/16",
+				UseHosts:     true,
+				FakeIPFilter: []string{
+					"*.lan",
+					"localhost.ptlogin2.qq.com",
+					"dns.msftncsi.com",
+					"www.msftncsi.com",
+					"www.msftconnecttest.com",
+				},
+			},
+		},
+	}
+}
+
+// config 根据代理节点和导出模板生成Clash配置
+func (e *clashExporter) config(nodes []ProxyNode, profile *models.ExportProfile) *ClashConfig {
+	if profile == nil {
+		profile = defaultClashProfile()
+	}
+	settings := profile.Settings
+	if settings.Mode == "" {
+		settings.Mode = "rule"
+	}
+	if settings.LogLevel == "" {
+		settings.LogLevel = "info"
+	}
+	if settings.HealthCheckURL == "" {
+		settings.HealthCheckURL = "http://www.gstatic.com/generate_204"
+	}
+	if settings.HealthCheckInterval == 0 {
+		settings.HealthCheckInterval = 300
 	}
 
 	// 创建基础配置
 	config := &ClashConfig{
-		Port:               7890, // HTTP代理端口
-		SocksPort:          7891, // SOCKS5代理端口
-		AllowLan:           false, // 默认不允许局域网访问
-		Mode:               "rule", // 规则模式
-		LogLevel:           "info",
+		Port:               settings.Port,
+		SocksPort:          settings.SocksPort,
+		MixedPort:          settings.MixedPort,
+		AllowLan:           settings.AllowLan,
+		BindAddress:        settings.BindAddress,
+		Mode:               settings.Mode,
+		LogLevel:           settings.LogLevel,
+		IPv6:               settings.IPv6,
 		ExternalUI:         "", // 清空外部UI配置，避免路径错误
-		ExternalController: "127.0.0.1:9090", // Clash面板地址
+		ExternalController: settings.ExternalController,
+		Secret:             settings.Secret,
 		Proxies:            []ClashProxy{},
 		ProxyGroups:        []ClashProxyGroup{},
 		Rules:              []string{},
-		DNS: ClashDNS{
-			Enable:       true,
-			Listen:       "0.0.0.0:53",
-			NameServer:   []string{"223.5.5.5", "1.1.1.1"},
-			EnhancedMode: "fake-ip",
-			FakeIPRange:  "198.18.0.1/16",
-			UseHosts:     true,
-			FakeIPFilter: []string{
-				"*.lan",
-				"localhost.ptlogin2.qq.com",
-				"dns.msftncsi.com",
-				"www.msftncsi.com",
-				"www.msftconnecttest.com",
-			},
-		},
+		DNS:                settings.DNS,
+	}
+	if profile.Mihomo {
+		config.UnifiedDelay = settings.UnifiedDelay
+		config.TCPConcurrent = settings.TCPConcurrent
+		config.FindProcessMode = settings.FindProcessMode
+		config.ClientFingerprint = settings.GlobalClientFingerprint
+		config.GeodataMode = settings.GeodataMode
+		config.GeoxURL = settings.GeoxURL
 	}
 
 	// 生成代理节点
 	proxyNames := []string{}
-	for _, tunnel := range socks5Tunnels {
-		// 获取主机信息
-		host, err := s.hostRepo.GetByID(tunnel.HostID)
-		if err != nil {
-			continue
-		}
+	for _, node := range nodes {
+		config.Proxies = append(config.Proxies, ClashProxy{
+			Name:   node.Name,
+			Type:   "socks5",
+			Server: node.Server,
+			Port:   node.Port,
+		})
+		proxyNames = append(proxyNames, node.Name)
+	}
 
-		// 生成代理节点名称
-		proxyName := fmt.Sprintf("drilling-%s-%d", sanitizeName(host.Name), tunnel.LocalPort)
+	// 生成代理组，模板没有定义分组时使用默认分组
+	finalTarget := "Proxy"
+	if len(profile.ProxyGroups) > 0 {
+		config.ProxyGroups = customClashGroups(profile, proxyNames, settings)
+		finalTarget = config.ProxyGroups[0].Name
+	} else if len(proxyNames) > 0 {
+		config.ProxyGroups = defaultClashGroups(proxyNames, settings)
+	}
 
-		// 创建SOCKS5代理配置
-		proxy := ClashProxy{
-			Name:   proxyName,
-			Type:   "socks5",
-			Server: tunnel.LocalAddress,
-			Port:   int(tunnel.LocalPort),
+	// 生成规则集，format 只有 mihomo 支持
+	if len(profile.RuleProviders) > 0 {
+		config.RuleProviders = make(map[string]ClashRuleProvider, len(profile.RuleProviders))
+		for name, provider := range profile.RuleProviders {
+			item := ClashRuleProvider{
+				Type:     provider.Type,
+				Behavior: provider.Behavior,
+				URL:      provider.URL,
+				Path:     provider.Path,
+				Interval: provider.Interval,
+			}
+			if item.Path == "" && !profile.Mihomo {
+				// Clash 要求 http 规则集指定缓存路径
+				item.Path = fmt.Sprintf("./ruleset/%s.yaml", name)
+			}
+			if profile.Mihomo {
+				item.Format = provider.Format
+			}
+			config.RuleProviders[name] = item
 		}
+	}
 
-		config.Proxies = append(config.Proxies, proxy)
-		proxyNames = append(proxyNames, proxyName)
+	// 设置了代理域名的隧道优先匹配
+	for _, node := range nodes {
+		for _, domain := range node.Domains {
+			config.Rules = append(config.Rules, fmt.Sprintf("DOMAIN-SUFFIX,%s,%s", domain, node.Name))
+		}
 	}
 
-	// 如果有多个代理，创建代理组
-	if len(proxyNames) > 0 {
-		// 自动选择组（延迟测试）
-		autoGroup := ClashProxyGroup{
-			Name:     "Auto",
-			Type:     "url-test",
+	if len(profile.Rules) > 0 {
+		config.Rules = append(config.Rules, profile.Rules...)
+	} else {
+		config.Rules = append(config.Rules, defaultClashRules(finalTarget)...)
+	}
+
+	return config
+}
+
+// defaultClashGroups 生成默认的 Auto、Proxy 和 LoadBalance 代理组
+func defaultClashGroups(proxyNames []string, settings models.ClashSettings) []ClashProxyGroup {
+	// 自动选择组（延迟测试）
+	groups := []ClashProxyGroup{{
+		Name:     "Auto",
+		Type:     models.ProxyGroupURLTest,
+		Proxies:  append([]string{}, proxyNames...),
+		URL:      settings.HealthCheckURL,
+		Interval: settings.HealthCheckInterval,
+	}}
+
+	// 手动选择组
+	selectGroup := ClashProxyGroup{
+		Name:    "Proxy",
+		Type:    models.ProxyGroupSelect,
+		Proxies: append([]string{"Auto", "DIRECT"}, proxyNames...),
+	}
+
+	// 如果有多个代理，还可以添加负载均衡组
+	if len(proxyNames) > 1 {
+		selectGroup.Proxies = append([]string{"Auto", "LoadBalance", "DIRECT"}, proxyNames...)
+		return append(groups, selectGroup, ClashProxyGroup{
+			Name:     "LoadBalance",
+			Type:     models.ProxyGroupLoadBalance,
 			Proxies:  append([]string{}, proxyNames...),
-			URL:      "http://www.gstatic.com/generate_204",
-			Interval: 300,
+			URL:      settings.HealthCheckURL,
+			Interval: settings.HealthCheckInterval,
+		})
+	}
+	return append(groups, selectGroup)
+}
+
+// customClashGroups 按导出模板生成代理组，include_nodes 的分组在 proxies 之后加入匹配 filter 的节点
+func customClashGroups(profile *models.ExportProfile, proxyNames []string, settings models.ClashSettings) []ClashProxyGroup {
+	groups := make([]ClashProxyGroup, 0, len(profile.ProxyGroups))
+	for _, group := range profile.ProxyGroups {
+		proxies := append([]string{}, group.Proxies...)
+		if group.IncludeNodes {
+			var filter *regexp.Regexp
+			if group.Filter != "" {
+				// 模板保存时已经校验过正则表达式
+				filter, _ = regexp.Compile(group.Filter)
+			}
+			for _, name := range proxyNames {
+				if filter == nil || filter.MatchString(name) {
+					proxies = append(proxies, name)
+				}
+			}
+		}
+		if len(proxies) == 0 {
+			// Clash 不接受空的代理组
+			proxies = []string{"DIRECT"}
 		}
-		config.ProxyGroups = append(config.ProxyGroups, autoGroup)
 
-		// 手动选择组
-		selectGroup := ClashProxyGroup{
-			Name:    "Proxy",
-			Type:    "select",
-			Proxies: append([]string{"Auto", "DIRECT"}, proxyNames...),
+		item := ClashProxyGroup{
+			Name:      group.Name,
+			Type:      group.Type,
+			Proxies:   proxies,
+			URL:       group.URL,
+			Interval:  group.Interval,
+			Tolerance: group.Tolerance,
+			Strategy:  group.Strategy,
 		}
-		config.ProxyGroups = append(config.ProxyGroups, selectGroup)
-
-		// 如果有多个代理，还可以添加负载均衡组
-		if len(proxyNames) > 1 {
-			loadBalanceGroup := ClashProxyGroup{
-				Name:     "LoadBalance",
-				Type:     "load-balance",
-				Proxies:  append([]string{}, proxyNames...),
-				URL:      "http://www.gstatic.com/generate_204",
-				Interval: 300,
+		if item.Type != models.ProxyGroupSelect {
+			if item.URL == "" {
+				item.URL = settings.HealthCheckURL
 			}
-			config.ProxyGroups = append(config.ProxyGroups, loadBalanceGroup)
-
-			// 更新手动选择组，添加负载均衡选项
-			selectGroup.Proxies = append([]string{"Auto", "LoadBalance", "DIRECT"}, proxyNames...)
-			config.ProxyGroups[1] = selectGroup
+			if item.Interval == 0 {
+				item.Interval = settings.HealthCheckInterval
+			}
+		}
+		if profile.Mihomo {
+			item.Hidden = group.Hidden
+			item.Icon = group.Icon
 		}
+		groups = append(groups, item)
 	}
+	return groups
+}
 
-	// 生成基础规则
-	config.Rules = []string{
+// defaultClashRules 默认规则，本地地址和国内IP直连，其余流量走 finalTarget
+func defaultClashRules(finalTarget string) []string {
+	return []string{
 		// 本地地址直连
 		"DOMAIN-SUFFIX,local,DIRECT",
 		"DOMAIN-SUFFIX,localhost,DIRECT",
@@ -194,18 +361,18 @@ func (s *clashExportService) GenerateClashConfig() (*ClashConfig, error) {
 
 		// 默认规则：国外网站使用代理，国内直连
 		"GEOIP,CN,DIRECT",
-		"MATCH,Proxy",
+		"MATCH," + finalTarget,
 	}
+}
 
-	return config, nil
+// Export 使用默认模板导出Clash配置为YAML格式
+func (e *clashExporter) Export(nodes []ProxyNode) ([]byte, error) {
+	return e.ExportProfile(nodes, nil)
 }
 
-// ExportClashConfigYAML 导出Clash配置为YAML格式
-func (s *clashExportService) ExportClashConfigYAML() ([]byte, error) {
-	config, err := s.GenerateClashConfig()
-	if err != nil {
-		return nil, err
-	}
+// ExportProfile 按导出模板导出Clash配置为YAML格式
+func (e *clashExporter) ExportProfile(nodes []ProxyNode, profile *models.ExportProfile) ([]byte, error) {
+	config := e.config(nodes, profile)
 
 	// 序列化为YAML
 	yamlData, err := yaml.Marshal(config)
@@ -213,53 +380,53 @@ func (s *clashExportService) ExportClashConfigYAML() ([]byte, error) {
 		return nil, fmt.Errorf("failed to marshal YAML: %v", err)
 	}
 
+	profileName := "default"
+	if profile != nil {
+		profileName = profile.Name
+	}
+
 	// 添加配置文件头部注释
 	header := fmt.Sprintf(`# Drilling Platform - Clash Configuration
 # Generated at: %s
+# Profile: %s
 # Total SOCKS5 proxies: %d
 #
 # This configuration file was automatically generated by Drilling Platform
-# It includes all active SOCKS5 tunnels as proxy nodes
+# It includes all active SOCKS5 tunnels selected by the export profile as proxy nodes
 #
 # Usage:
 # 1. Save this file as config.yaml in your Clash config directory
 # 2. Start Clash client and select appropriate proxy group
-# 3. Configure your system proxy to use Clash (HTTP: 7890, SOCKS5: 7891)
+# 3. Configure your system proxy to use Clash (%s)
 #
-# External Controller: http://127.0.0.1:9090 (for Clash dashboard)
+# External Controller: %s (for Clash dashboard)
 #
 # Optional: If you want to use Clash Dashboard UI, uncomment the following line:
 # external-ui: /path/to/clash-dashboard
 #
 # You can download Clash Dashboard from: https://github.com/Dreamacro/clash-dashboard
 
-`, time.Now().Format("2006-01-02 15:04:05"), len(config.Proxies))
+`, time.Now().Format("2006-01-02 15:04:05"), profileName, len(config.Proxies), clashPortSummary(config), defaultString(config.ExternalController, "disabled"))
 
 	return append([]byte(header), yamlData...), nil
 }
 
-// GetActiveSocks5Tunnels 获取所有活跃的SOCKS5隧道
-func (s *clashExportService) GetActiveSocks5Tunnels() ([]models.Tunnel, error) {
-	// 获取所有隧道
-	allTunnels, err := s.tunnelRepo.GetAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get tunnels: %v", err)
+// clashPortSummary 返回配置中开启的代理端口说明
+func clashPortSummary(config *ClashConfig) string {
+	var ports []string
+	if config.Port != 0 {
+		ports = append(ports, fmt.Sprintf("HTTP: %d", config.Port))
 	}
-
-	var socks5Tunnels []models.Tunnel
-	for _, tunnel := range allTunnels {
-		// 只包含SOCKS5类型且状态为活跃的隧道
-		if tunnel.Type == "dynamic" && tunnel.Status == "active" {
-			socks5Tunnels = append(socks5Tunnels, tunnel)
-		}
+	if config.SocksPort != 0 {
+		ports = append(ports, fmt.Sprintf("SOCKS5: %d", config.SocksPort))
 	}
-
-	// 按端口排序，确保配置文件的一致性
-	sort.Slice(socks5Tunnels, func(i, j int) bool {
-		return socks5Tunnels[i].LocalPort < socks5Tunnels[j].LocalPort
-	})
-
-	return socks5Tunnels, nil
+	if config.MixedPort != 0 {
+		ports = append(ports, fmt.Sprintf("Mixed: %d", config.MixedPort))
+	}
+	if len(ports) == 0 {
+		return "no proxy port configured"
+	}
+	return strings.Join(ports, ", ")
 }
 
 // sanitizeName 清理名称，移除特殊字符
@@ -297,4 +464,4 @@ func sanitizeName(name string) string {
 	}
 
 	return result
-}
\ No newline at end of file
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/labels"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrExportProfileNotFound 导出模板不存在
+var ErrExportProfileNotFound = errors.New("export profile not found")
+
+// ErrExportProfileInUse 导出模板仍被订阅使用
+var ErrExportProfileInUse = errors.New("export profile is used by subscriptions")
+
+// ExportProfileService 导出模板服务接口
+type ExportProfileService interface {
+	CreateProfile(profile *models.ExportProfile) error
+	GetProfile(id uint) (*models.ExportProfile, error)
+	GetAllProfiles() ([]models.ExportProfile, error)
+	UpdateProfile(profile *models.ExportProfile) error
+	DeleteProfile(id uint) error
+	ResolveProfile(ref string) (*models.ExportProfile, error)
+}
+
+// exportProfileService 导出模板服务实现
+type exportProfileService struct {
+	profileRepo      repository.ExportProfileRepository
+	subscriptionRepo repository.SubscriptionRepository
+}
+
+// NewExportProfileService 创建导出模板服务实例
+func NewExportProfileService(profileRepo repository.ExportProfileRepository, subscriptionRepo repository.SubscriptionRepository) ExportProfileService {
+	return &exportProfileService{
+		profileRepo:      profileRepo,
+		subscriptionRepo: subscriptionRepo,
+	}
+}
+
+// CreateProfile 创建导出模板
+func (s *exportProfileService) CreateProfile(profile *models.ExportProfile) error {
+	if err := validateExportProfile(profile); err != nil {
+		return err
+	}
+	if _, err := s.profileRepo.GetByName(profile.Name); err == nil {
+		return fmt.Errorf("export profile %s already exists", profile.Name)
+	}
+	return s.profileRepo.Create(profile)
+}
+
+// GetProfile 获取导出模板
+func (s *exportProfileService) GetProfile(id uint) (*models.ExportProfile, error) {
+	profile, err := s.profileRepo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportProfileNotFound
+	}
+	return profile, err
+}
+
+// GetAllProfiles 获取所有导出模板
+func (s *exportProfileService) GetAllProfiles() ([]models.ExportProfile, error) {
+	return s.profileRepo.GetAll()
+}
+
+// UpdateProfile 更新导出模板
+func (s *exportProfileService) UpdateProfile(profile *models.ExportProfile) error {
+	existing, err := s.GetProfile(profile.ID)
+	if err != nil {
+		return err
+	}
+	if err := validateExportProfile(profile); err != nil {
+		return err
+	}
+	if other, err := s.profileRepo.GetByName(profile.Name); err == nil && other.ID != profile.ID {
+		return fmt.Errorf("export profile %s already exists", profile.Name)
+	}
+	profile.CreatedAt = existing.CreatedAt
+	return s.profileRepo.Update(profile)
+}
+
+// DeleteProfile 删除导出模板，仍有订阅使用的模板不能删除
+func (s *exportProfileService) DeleteProfile(id uint) error {
+	profile, err := s.GetProfile(id)
+	if err != nil {
+		return err
+	}
+	count, err := s.subscriptionRepo.CountByProfileID(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s has %d active subscriptions, revoke them first", ErrExportProfileInUse, profile.Name, count)
+	}
+	return s.profileRepo.Delete(id)
+}
+
+// ResolveProfile 按名称或ID查找导出模板，用于导出接口的 ?profile= 参数
+func (s *exportProfileService) ResolveProfile(ref string) (*models.ExportProfile, error) {
+	profile, err := s.profileRepo.GetByName(ref)
+	if err == nil {
+		return profile, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if id, parseErr := strconv.ParseUint(ref, 10, 32); parseErr == nil {
+		return s.GetProfile(uint(id))
+	}
+	return nil, ErrExportProfileNotFound
+}
+
+// validateExportProfile 校验导出模板并规范化标签
+func validateExportProfile(profile *models.ExportProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return errors.New("profile name is required")
+	}
+
+	settings := &profile.Settings
+	for name, port := range map[string]int{"port": settings.Port, "socks_port": settings.SocksPort, "mixed_port": settings.MixedPort} {
+		if port < 0 || port > 65535 {
+			return fmt.Errorf("invalid %s %d", name, port)
+		}
+	}
+	if err := checkOneOf("mode", settings.Mode, "rule", "global", "direct"); err != nil {
+		return err
+	}
+	if err := checkOneOf("log_level", settings.LogLevel, "silent", "error", "warning", "info", "debug"); err != nil {
+		return err
+	}
+	if err := checkOneOf("find_process_mode", settings.FindProcessMode, "always", "strict", "off"); err != nil {
+		return err
+	}
+	if settings.HealthCheckInterval < 0 {
+		return errors.New("health_check_interval must not be negative")
+	}
+
+	groups := make(map[string]bool, len(profile.ProxyGroups))
+	for i := range profile.ProxyGroups {
+		group := &profile.ProxyGroups[i]
+		group.Name = strings.TrimSpace(group.Name)
+		if group.Name == "" {
+			return fmt.Errorf("proxy_groups[%d]: name is required", i)
+		}
+		if groups[group.Name] || group.Name == "DIRECT" || group.Name == "REJECT" {
+			return fmt.Errorf("proxy group %s: duplicate or reserved name", group.Name)
+		}
+		groups[group.Name] = true
+
+		switch group.Type {
+		case models.ProxyGroupSelect, models.ProxyGroupURLTest, models.ProxyGroupFallback, models.ProxyGroupLoadBalance:
+		default:
+			return fmt.Errorf("proxy group %s: invalid type %q", group.Name, group.Type)
+		}
+		if len(group.Proxies) == 0 && !group.IncludeNodes {
+			return fmt.Errorf("proxy group %s: proxies or include_nodes is required", group.Name)
+		}
+		if group.Filter != "" {
+			if _, err := regexp.Compile(group.Filter); err != nil {
+				return fmt.Errorf("proxy group %s: invalid filter: %v", group.Name, err)
+			}
+		}
+		if err := checkOneOf("strategy", group.Strategy, "consistent-hashing", "round-robin", "sticky-sessions"); err != nil {
+			return fmt.Errorf("proxy group %s: %v", group.Name, err)
+		}
+	}
+
+	for name, provider := range profile.RuleProviders {
+		if strings.TrimSpace(name) == "" || strings.Contains(name, ",") {
+			return fmt.Errorf("invalid rule provider name %q", name)
+		}
+		if err := checkOneOf("type", provider.Type, "http", "file"); err != nil || provider.Type == "" {
+			return fmt.Errorf("rule provider %s: type must be http or file", name)
+		}
+		if err := checkOneOf("behavior", provider.Behavior, "domain", "ipcidr", "classical"); err != nil || provider.Behavior == "" {
+			return fmt.Errorf("rule provider %s: behavior must be domain, ipcidr or classical", name)
+		}
+		if provider.Type == "http" && !strings.HasPrefix(provider.URL, "http://") && !strings.HasPrefix(provider.URL, "https://") {
+			return fmt.Errorf("rule provider %s: an http(s) url is required", name)
+		}
+		if provider.Type == "file" && provider.Path == "" {
+			return fmt.Errorf("rule provider %s: path is required", name)
+		}
+		if err := checkOneOf("format", provider.Format, "yaml", "text", "mrs"); err != nil {
+			return fmt.Errorf("rule provider %s: %v", name, err)
+		}
+	}
+
+	rules := profile.Rules[:0]
+	for _, rule := range profile.Rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		parts := strings.Split(rule, ",")
+		if strings.EqualFold(parts[0], "RULE-SET") {
+			if len(parts) < 3 {
+				return fmt.Errorf("invalid rule %q", rule)
+			}
+			if _, ok := profile.RuleProviders[strings.TrimSpace(parts[1])]; !ok {
+				return fmt.Errorf("rule %q references unknown rule provider %s", rule, parts[1])
+			}
+		}
+		rules = append(rules, rule)
+	}
+	profile.Rules = rules
+
+	tags, err := normalizeTags(profile.TunnelTags)
+	if err != nil {
+		return err
+	}
+	profile.TunnelTags = tags
+
+	selector, err := labels.Parse(profile.TunnelSelector)
+	if err != nil {
+		return err
+	}
+	profile.TunnelSelector = selector.String()
+	return nil
+}
+
+// checkOneOf 检查可选字段的值，为空时不检查
+func checkOneOf(field, value string, allowed ...string) error {
+	if value == "" {
+		return nil
+	}
+	for _, item := range allowed {
+		if value == item {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s %q, must be one of %s", field, value, strings.Join(allowed, ", "))
+}
+
+// selectProfileNodes 按导出模板的标签、ID和标签选择器选择节点，模板为空或没有设置选择条件时返回全部节点
+// 标签和ID满足其一即可，设置了标签选择器时还需要匹配选择器
+func selectProfileNodes(nodes []ProxyNode, profile *models.ExportProfile) ([]ProxyNode, error) {
+	if profile == nil || (len(profile.TunnelTags) == 0 && len(profile.TunnelIDs) == 0 && profile.TunnelSelector == "") {
+		return nodes, nil
+	}
+	selector, err := labels.Parse(profile.TunnelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	byTagOrID := len(profile.TunnelTags) > 0 || len(profile.TunnelIDs) > 0
+	var selected []ProxyNode
+	for _, node := range nodes {
+		if byTagOrID && !profile.TunnelIDs.Contains(node.TunnelID) && !hasAnyTag(node.Tags, profile.TunnelTags) {
+			continue
+		}
+		if selector.Matches(node.Labels) {
+			selected = append(selected, node)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w matching profile %s", ErrNoActiveSocks5Tunnels, profile.Name)
+	}
+	return selected, nil
+}
+
+// hasAnyTag 检查标签列表是否包含任一指定标签
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range wanted {
+		if models.StringList(tags).Contains(tag) {
+			return true
+		}
+	}
+	return false
+}
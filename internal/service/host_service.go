@@ -10,11 +10,15 @@ import (
 	"io"
 	"log"
 	"net"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/ratelimit"
 	"github.com/KodaTao/drilling/internal/repository"
+	"github.com/KodaTao/drilling/internal/sshconfig"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -29,16 +33,29 @@ type HostService interface {
 	CheckHostStatus(id uint) error
 	EncryptSensitiveData(host *models.Host) error
 	DecryptSensitiveData(host *models.Host) error
+	LoadPrivateKey(host *models.Host) ([]byte, error)
+	CheckKeyPath(path string) error
+	RateLimiters(host *models.Host) (upload, download *ratelimit.Bucket)
 }
 
 // hostService 主机服务实现
 type hostService struct {
-	hostRepo   repository.HostRepository
-	encryptKey []byte
+	hostRepo     repository.HostRepository
+	encryptKey   []byte
+	keyDir       string // 允许服务端读取私钥文件的目录，为空时不读取私钥文件
+	limiterMutex sync.Mutex
+	limiters     map[uint]*hostLimiter // 主机级别的聚合限速器
 }
 
-// NewHostService 创建主机服务实例
-func NewHostService(hostRepo repository.HostRepository, encryptKey string) HostService {
+// hostLimiter 主机级别的聚合限速器，由该主机的所有隧道共享
+// 令牌桶在主机的整个生命周期内保持不变，主机限速修改时只调整速率，速率为0表示不限速
+type hostLimiter struct {
+	upload   *ratelimit.Bucket
+	download *ratelimit.Bucket
+}
+
+// NewHostService 创建主机服务实例，keyDir 为允许按 key_path 读取私钥文件的目录
+func NewHostService(hostRepo repository.HostRepository, encryptKey, keyDir string) HostService {
 	key := []byte(encryptKey)
 	// 确保密钥长度为32字节（AES-256）
 	if len(key) < 32 {
@@ -50,9 +67,17 @@ func NewHostService(hostRepo repository.HostRepository, encryptKey string) HostS
 		key = key[:32]
 	}
 
+	if keyDir != "" {
+		if dir, err := filepath.Abs(sshconfig.ExpandHome(keyDir)); err == nil {
+			keyDir = dir
+		}
+	}
+
 	return &hostService{
 		hostRepo:   hostRepo,
 		encryptKey: key,
+		keyDir:     keyDir,
+		limiters:   make(map[uint]*hostLimiter),
 	}
 }
 
@@ -63,12 +88,27 @@ func (s *hostService) CreateHost(host *models.Host) error {
 	if err == nil && existingHost != nil {
 		return errors.New("host name already exists")
 	}
+	clearUnusedCredentials(host)
 
 	// 验证认证方式
 	if err := s.validateAuthConfig(host); err != nil {
 		return err
 	}
 
+	if err := s.validateJumpHost(host); err != nil {
+		return err
+	}
+
+	labels, err := normalizeLabels(host.Labels)
+	if err != nil {
+		return err
+	}
+	host.Labels = labels
+
+	if err := s.updateKeyFingerprint(host); err != nil {
+		return err
+	}
+
 	// 加密敏感数据
 	if err := s.EncryptSensitiveData(host); err != nil {
 		return fmt.Errorf("failed to encrypt sensitive data: %v", err)
@@ -91,6 +131,7 @@ func (s *hostService) GetHost(id uint) (*models.Host, error) {
 	if err := s.DecryptSensitiveData(host); err != nil {
 		return nil, fmt.Errorf("failed to decrypt sensitive data: %v", err)
 	}
+	s.backfillKeyFingerprint(host)
 
 	return host, nil
 }
@@ -108,29 +149,101 @@ func (s *hostService) GetAllHosts() ([]models.Host, error) {
 			// 记录错误但继续处理其他主机
 			continue
 		}
+		s.backfillKeyFingerprint(&hosts[i])
 	}
 
 	return hosts, nil
 }
 
-// UpdateHost 更新主机
+// UpdateHost 更新主机，密码、私钥、私钥密码留空时保留原有值
 func (s *hostService) UpdateHost(host *models.Host) error {
+	existing, err := s.hostRepo.GetByID(host.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.DecryptSensitiveData(existing); err != nil {
+		return fmt.Errorf("failed to decrypt sensitive data: %v", err)
+	}
+
+	if host.Password == "" {
+		host.Password = existing.Password
+	}
+	if host.PrivateKey == "" {
+		host.PrivateKey = existing.PrivateKey
+	}
+	if host.Passphrase == "" {
+		host.Passphrase = existing.Passphrase
+	}
+	clearUnusedCredentials(host)
+
+	// 请求中不包含的状态字段沿用原有值
+	host.Status = existing.Status
+	host.LastCheck = existing.LastCheck
+	host.CreatedAt = existing.CreatedAt
+
 	// 验证认证方式
 	if err := s.validateAuthConfig(host); err != nil {
 		return err
 	}
 
+	if err := s.validateJumpHost(host); err != nil {
+		return err
+	}
+
+	labels, err := normalizeLabels(host.Labels)
+	if err != nil {
+		return err
+	}
+	host.Labels = labels
+
+	if err := s.updateKeyFingerprint(host); err != nil {
+		return err
+	}
+
 	// 加密敏感数据
 	if err := s.EncryptSensitiveData(host); err != nil {
 		return fmt.Errorf("failed to encrypt sensitive data: %v", err)
 	}
 
-	return s.hostRepo.Update(host)
+	if err := s.hostRepo.Update(host); err != nil {
+		return err
+	}
+
+	// 运行中的隧道共享主机的令牌桶，新的限速立即生效
+	s.limiterMutex.Lock()
+	if limiter := s.limiters[host.ID]; limiter != nil {
+		limiter.upload.SetRate(host.UploadLimit)
+		limiter.download.SetRate(host.DownloadLimit)
+	}
+	s.limiterMutex.Unlock()
+	return nil
 }
 
 // DeleteHost 删除主机
 func (s *hostService) DeleteHost(id uint) error {
-	return s.hostRepo.Delete(id)
+	if err := s.hostRepo.Delete(id); err != nil {
+		return err
+	}
+	s.limiterMutex.Lock()
+	delete(s.limiters, id)
+	s.limiterMutex.Unlock()
+	return nil
+}
+
+// RateLimiters 获取主机的上行和下行聚合限速器，同一主机总是返回同一对令牌桶
+func (s *hostService) RateLimiters(host *models.Host) (*ratelimit.Bucket, *ratelimit.Bucket) {
+	s.limiterMutex.Lock()
+	defer s.limiterMutex.Unlock()
+
+	limiter := s.limiters[host.ID]
+	if limiter == nil {
+		limiter = &hostLimiter{
+			upload:   ratelimit.NewAdjustableBucket(host.UploadLimit),
+			download: ratelimit.NewAdjustableBucket(host.DownloadLimit),
+		}
+		s.limiters[host.ID] = limiter
+	}
+	return limiter.upload, limiter.download
 }
 
 // TestConnection 测试SSH连接
@@ -145,15 +258,8 @@ func (s *hostService) TestConnection(id uint) error {
 		return fmt.Errorf("failed to decrypt sensitive data: %v", err)
 	}
 
-	// 创建SSH配置
-	config, err := s.createSSHConfig(host)
-	if err != nil {
-		return fmt.Errorf("failed to create SSH config: %v", err)
-	}
-
-	// 建立连接
-	address := fmt.Sprintf("%s:%d", host.Hostname, host.Port)
-	client, err := ssh.Dial("tcp", address, config)
+	// 建立连接，配置了跳板机时经由跳板机连接
+	client, err := dialSSH(host, s.createSSHConfig, s.GetHost)
 	if err != nil {
 		// 更新主机状态为错误
 		s.hostRepo.UpdateStatus(host.ID, models.HostStatusError)
@@ -206,10 +312,16 @@ func (s *hostService) validateAuthConfig(host *models.Host) error {
 		if host.PrivateKey == "" && host.KeyPath == "" {
 			return errors.New("private key or key path is required for key authentication")
 		}
+		if err := s.checkHostKeyPath(host); err != nil {
+			return err
+		}
 	case models.AuthTypeKeyPassword:
 		if host.PrivateKey == "" && host.KeyPath == "" {
 			return errors.New("private key or key path is required for key with password authentication")
 		}
+		if err := s.checkHostKeyPath(host); err != nil {
+			return err
+		}
 		if host.Passphrase == "" {
 			return errors.New("passphrase is required for key with password authentication")
 		}
@@ -219,6 +331,110 @@ func (s *hostService) validateAuthConfig(host *models.Host) error {
 	return nil
 }
 
+// checkHostKeyPath 没有保存私钥内容时检查私钥文件路径在允许读取的目录内
+func (s *hostService) checkHostKeyPath(host *models.Host) error {
+	if host.PrivateKey != "" {
+		return nil
+	}
+	return s.CheckKeyPath(host.KeyPath)
+}
+
+// CheckKeyPath 检查服务端能否按路径读取私钥文件，只允许读取配置的私钥目录中的文件
+func (s *hostService) CheckKeyPath(path string) error {
+	_, err := resolveKeyPath(s.keyDir, path)
+	return err
+}
+
+// LoadPrivateKey 获取主机私钥，没有保存私钥内容时读取私钥目录中的私钥文件
+func (s *hostService) LoadPrivateKey(host *models.Host) ([]byte, error) {
+	return loadPrivateKey(host, s.keyDir)
+}
+
+// validateJumpHost 检查跳板机存在且不会形成循环
+func (s *hostService) validateJumpHost(host *models.Host) error {
+	jumpHostID := host.JumpHostID
+	for hops := 0; jumpHostID != 0; hops++ {
+		if host.ID != 0 && jumpHostID == host.ID {
+			return errors.New("jump host chain must not include the host itself")
+		}
+		if hops >= maxJumpHosts {
+			return fmt.Errorf("too many jump hosts, at most %d are allowed", maxJumpHosts)
+		}
+
+		jumpHost, err := s.hostRepo.GetByID(jumpHostID)
+		if err != nil {
+			return fmt.Errorf("jump host %d not found", jumpHostID)
+		}
+		jumpHostID = jumpHost.JumpHostID
+	}
+	return nil
+}
+
+// clearUnusedCredentials 清除与认证方式无关的凭据，避免切换认证方式后残留旧凭据
+func clearUnusedCredentials(host *models.Host) {
+	switch host.AuthType {
+	case models.AuthTypePassword:
+		host.PrivateKey = ""
+		host.Passphrase = ""
+	case models.AuthTypeKey:
+		host.Password = ""
+		host.Passphrase = ""
+	case models.AuthTypeKeyPassword:
+		host.Password = ""
+	}
+}
+
+// updateKeyFingerprint 根据明文私钥计算公钥指纹，私钥无法解析时返回错误
+func (s *hostService) updateKeyFingerprint(host *models.Host) error {
+	host.KeyFingerprint = ""
+	if host.PrivateKey == "" {
+		return nil
+	}
+
+	fingerprint, err := privateKeyFingerprint(host.PrivateKey, host.Passphrase)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		// 加密的私钥在设置密码后才能计算指纹
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid private key: %v", err)
+	}
+	host.KeyFingerprint = fingerprint
+	return nil
+}
+
+// backfillKeyFingerprint 为指纹功能加入前创建的主机补充计算指纹并保存
+func (s *hostService) backfillKeyFingerprint(host *models.Host) {
+	if host.KeyFingerprint != "" || host.PrivateKey == "" {
+		return
+	}
+
+	fingerprint, err := privateKeyFingerprint(host.PrivateKey, host.Passphrase)
+	if err != nil {
+		return
+	}
+	host.KeyFingerprint = fingerprint
+	if err := s.hostRepo.UpdateKeyFingerprint(host.ID, fingerprint); err != nil {
+		log.Printf("Failed to save key fingerprint for host %d: %v", host.ID, err)
+	}
+}
+
+// privateKeyFingerprint 计算私钥对应公钥的SHA256指纹
+func privateKeyFingerprint(privateKey, passphrase string) (string, error) {
+	var signer ssh.Signer
+	var err error
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(privateKey))
+	}
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(signer.PublicKey()), nil
+}
+
 // createSSHConfig 创建SSH配置
 func (s *hostService) createSSHConfig(host *models.Host) (*ssh.ClientConfig, error) {
 	config := &ssh.ClientConfig{
@@ -236,14 +452,9 @@ func (s *hostService) createSSHConfig(host *models.Host) (*ssh.ClientConfig, err
 			ssh.Password(host.Password),
 		}
 	case models.AuthTypeKey, models.AuthTypeKeyPassword:
-		var privateKey []byte
-		var err error
-
-		if host.PrivateKey != "" {
-			privateKey = []byte(host.PrivateKey)
-		} else if host.KeyPath != "" {
-			// 这里应该读取密钥文件，暂时返回错误
-			return nil, errors.New("key file reading not implemented yet")
+		privateKey, err := s.LoadPrivateKey(host)
+		if err != nil {
+			return nil, err
 		}
 
 		var signer ssh.Signer
@@ -388,4 +599,4 @@ func (s *hostService) decrypt(ciphertext string) (string, error) {
 	stream.XORKeyStream(ciphertextBytes, ciphertextBytes)
 
 	return string(ciphertextBytes), nil
-}
\ No newline at end of file
+}
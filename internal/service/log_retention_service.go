@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/repository"
+)
+
+// LogRetentionService 连接日志保留策略服务接口
+type LogRetentionService interface {
+	PruneConnectionLogs() (int64, error)
+	Run(ctx context.Context)
+}
+
+// logRetentionService 连接日志保留策略服务实现
+type logRetentionService struct {
+	tunnelRepo       repository.TunnelRepository
+	sessionRepo      repository.SessionRepository
+	maxAge           time.Duration
+	maxRowsPerTunnel int
+	interval         time.Duration
+}
+
+// NewLogRetentionService 创建连接日志保留策略服务实例
+// maxAge 为0表示不按时间清理，maxRowsPerTunnel 为0表示不按条数清理；连接会话只按时间清理
+func NewLogRetentionService(tunnelRepo repository.TunnelRepository, sessionRepo repository.SessionRepository, maxAge time.Duration, maxRowsPerTunnel int, interval time.Duration) LogRetentionService {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &logRetentionService{
+		tunnelRepo:       tunnelRepo,
+		sessionRepo:      sessionRepo,
+		maxAge:           maxAge,
+		maxRowsPerTunnel: maxRowsPerTunnel,
+		interval:         interval,
+	}
+}
+
+// PruneConnectionLogs 按保留策略清理连接日志，返回删除的条数
+func (s *logRetentionService) PruneConnectionLogs() (int64, error) {
+	var total int64
+
+	// 按保留时间清理
+	if s.maxAge > 0 {
+		before := time.Now().Add(-s.maxAge)
+		deleted, err := s.tunnelRepo.DeleteConnectionLogsBefore(before)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+
+		deleted, err = s.sessionRepo.DeleteBefore(before)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+	}
+
+	// 按每个隧道的条数清理
+	if s.maxRowsPerTunnel > 0 {
+		tunnelIDs, err := s.tunnelRepo.GetLoggedTunnelIDs()
+		if err != nil {
+			return total, err
+		}
+		for _, tunnelID := range tunnelIDs {
+			deleted, err := s.tunnelRepo.TrimConnectionLogs(tunnelID, s.maxRowsPerTunnel)
+			if err != nil {
+				return total, err
+			}
+			total += deleted
+		}
+	}
+
+	return total, nil
+}
+
+// Run 定期执行日志清理，直到上下文取消
+func (s *logRetentionService) Run(ctx context.Context) {
+	if s.maxAge <= 0 && s.maxRowsPerTunnel <= 0 {
+		log.Println("Connection log retention disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.prune()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune 执行一次清理并记录结果
+func (s *logRetentionService) prune() {
+	deleted, err := s.PruneConnectionLogs()
+	if err != nil {
+		log.Printf("Failed to prune connection logs: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d connection logs", deleted)
+	}
+}
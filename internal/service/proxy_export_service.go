@@ -0,0 +1,326 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/labels"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+)
+
+// ErrNoActiveSocks5Tunnels 没有活跃的SOCKS5隧道
+var ErrNoActiveSocks5Tunnels = errors.New("no active SOCKS5 tunnels found")
+
+// ErrUnknownExportFormat 不支持的导出格式
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+// ProxyNode 导出到代理客户端的SOCKS5代理节点，对应一条动态隧道
+type ProxyNode struct {
+	Name       string            // 节点名称，在同一份配置中唯一
+	Server     string            // 客户端连接的地址
+	Port       int               // 客户端连接的端口
+	Domains    []string          // 经由此节点访问的域名，包含子域名
+	Tags       []string          // 隧道标签，导出模板按标签选择节点
+	Labels     map[string]string // 隧道的 key=value 标签，用于标签选择器
+	TunnelID   uint
+	TunnelName string
+	Running    bool // 隧道是否正在运行
+	Started    bool // 隧道是否在本次导出时按需启动
+}
+
+// ExportOptions 导出选项，与导出模板中的同名设置合并
+type ExportOptions struct {
+	IncludeInactive bool            // 同时导出未运行的SOCKS5隧道
+	StartOnDemand   bool            // 按需启动未运行的隧道：立即监听端口，首个客户端连接时才建立SSH连接
+	NoStart         bool            // 不启动任何隧道，忽略导出模板的按需启动设置，用于只读的 GET 导出
+	Selector        labels.Selector // 只导出标签匹配的隧道，与导出模板的选择条件同时生效
+}
+
+// withProfile 合并导出模板中的选项，按需启动隐含包含未运行的隧道
+func (o ExportOptions) withProfile(profile *models.ExportProfile) ExportOptions {
+	if profile != nil {
+		o.IncludeInactive = o.IncludeInactive || profile.IncludeInactive
+		o.StartOnDemand = o.StartOnDemand || profile.StartOnDemand
+	}
+	if o.StartOnDemand {
+		o.IncludeInactive = true
+	}
+	if o.NoStart {
+		o.StartOnDemand = false
+	}
+	return o
+}
+
+// ProxyExporter 代理客户端配置导出器接口，每种客户端格式一个实现
+type ProxyExporter interface {
+	Format() string        // 格式名称，用于导出路由 /export/{format}
+	ContentType() string   // 响应的 Content-Type
+	FileExtension() string // 下载文件的扩展名
+	Export(nodes []ProxyNode) ([]byte, error)
+}
+
+// ProfileExporter 支持导出模板的导出器，按模板生成分组、规则等设置
+// 其他导出器只使用模板选择的隧道
+type ProfileExporter interface {
+	ProxyExporter
+	ExportProfile(nodes []ProxyNode, profile *models.ExportProfile) ([]byte, error)
+}
+
+// DefaultProxyExporters 返回所有内置的代理客户端导出器
+func DefaultProxyExporters() []ProxyExporter {
+	return []ProxyExporter{
+		&clashExporter{},
+		&singBoxExporter{},
+		&xrayExporter{},
+		&surgeExporter{},
+		&pacExporter{},
+	}
+}
+
+// ProxyExport 导出结果
+type ProxyExport struct {
+	Format      string
+	ContentType string
+	Filename    string
+	Data        []byte
+	NodeCount   int
+	TunnelIDs   []uint      // 导出的隧道，用于统计订阅的流量
+	Started     []ProxyNode // 本次导出按需启动的节点，用于记录审计
+}
+
+// ProxyExportService 代理客户端配置导出服务接口
+type ProxyExportService interface {
+	Formats() []string
+	Export(format string, profile *models.ExportProfile, opts ExportOptions) (*ProxyExport, error)
+}
+
+// proxyExportService 代理客户端配置导出服务实现
+type proxyExportService struct {
+	tunnelRepo    repository.TunnelRepository
+	hostRepo      repository.HostRepository
+	tunnelService TunnelService
+	exporters     map[string]ProxyExporter
+	formats       []string
+}
+
+// NewProxyExportService 创建代理客户端配置导出服务实例
+func NewProxyExportService(tunnelRepo repository.TunnelRepository, hostRepo repository.HostRepository, tunnelService TunnelService, exporters ...ProxyExporter) ProxyExportService {
+	s := &proxyExportService{
+		tunnelRepo:    tunnelRepo,
+		hostRepo:      hostRepo,
+		tunnelService: tunnelService,
+		exporters:     make(map[string]ProxyExporter, len(exporters)),
+	}
+	for _, exporter := range exporters {
+		s.exporters[exporter.Format()] = exporter
+		s.formats = append(s.formats, exporter.Format())
+	}
+	return s
+}
+
+// Formats 返回支持的导出格式
+func (s *proxyExportService) Formats() []string {
+	return append([]string(nil), s.formats...)
+}
+
+// Export 将SOCKS5隧道导出为指定格式，profile 为空时导出全部隧道并使用默认设置
+func (s *proxyExportService) Export(format string, profile *models.ExportProfile, opts ExportOptions) (*ProxyExport, error) {
+	exporter, ok := s.exporters[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExportFormat, format)
+	}
+
+	nodes, err := exportNodes(s.tunnelRepo, s.hostRepo, s.tunnelService, profile, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if profileExporter, ok := exporter.(ProfileExporter); ok && profile != nil {
+		data, err = profileExporter.ExportProfile(nodes, profile)
+	} else {
+		data, err = exporter.Export(nodes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProxyExport{
+		Format:      format,
+		ContentType: exporter.ContentType(),
+		Filename:    fmt.Sprintf("%s-config-%s.%s", format, time.Now().Format("20060102-150405"), exporter.FileExtension()),
+		Data:        data,
+		NodeCount:   len(nodes),
+		TunnelIDs:   nodeTunnelIDs(nodes),
+		Started:     startedNodes(nodes),
+	}, nil
+}
+
+// socks5Tunnels 获取SOCKS5隧道，按端口排序以保证导出结果稳定
+// 运行状态以隧道服务中正在运行的隧道为准，数据库中的状态在服务重启后可能已经过期
+// includeInactive 为true时同时返回未运行的隧道，被暂停的隧道除外
+func socks5Tunnels(tunnelRepo repository.TunnelRepository, tunnelService TunnelService, includeInactive bool) ([]models.Tunnel, error) {
+	allTunnels, err := tunnelRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tunnels: %v", err)
+	}
+
+	var socks5Tunnels []models.Tunnel
+	for _, tunnel := range allTunnels {
+		if tunnel.Type != models.TunnelTypeDynamic {
+			continue
+		}
+		running := tunnelService.IsTunnelRunning(tunnel.ID)
+		if running && !models.IsRunningStatus(tunnel.Status) {
+			tunnel.Status = models.TunnelStatusActive
+		} else if !running && models.IsRunningStatus(tunnel.Status) {
+			tunnel.Status = models.TunnelStatusInactive
+		}
+		if running || (includeInactive && tunnel.Status != models.TunnelStatusSuspended) {
+			socks5Tunnels = append(socks5Tunnels, tunnel)
+		}
+	}
+
+	sort.Slice(socks5Tunnels, func(i, j int) bool {
+		return socks5Tunnels[i].LocalPort < socks5Tunnels[j].LocalPort
+	})
+	return socks5Tunnels, nil
+}
+
+// exportNodes 加载导出模板选择的代理节点，并按选项启动未运行的隧道
+func exportNodes(tunnelRepo repository.TunnelRepository, hostRepo repository.HostRepository, tunnelService TunnelService, profile *models.ExportProfile, opts ExportOptions) ([]ProxyNode, error) {
+	opts = opts.withProfile(profile)
+
+	nodes, err := loadProxyNodes(tunnelRepo, hostRepo, tunnelService, opts.IncludeInactive)
+	if err != nil {
+		return nil, err
+	}
+	if nodes, err = selectProfileNodes(nodes, profile); err != nil {
+		return nil, err
+	}
+	if nodes, err = selectLabeledNodes(nodes, opts.Selector); err != nil {
+		return nil, err
+	}
+	if opts.StartOnDemand {
+		return startProxyNodes(tunnelService, nodes)
+	}
+	return nodes, nil
+}
+
+// startProxyNodes 按需启动未运行的节点，启动失败的节点不会导出
+func startProxyNodes(tunnelService TunnelService, nodes []ProxyNode) ([]ProxyNode, error) {
+	started := nodes[:0]
+	for _, node := range nodes {
+		if !node.Running {
+			// 并发导出时隧道可能已被其他请求启动
+			err := tunnelService.StartTunnelOnDemand(node.TunnelID)
+			if err != nil && !tunnelService.IsTunnelRunning(node.TunnelID) {
+				log.Printf("Failed to start tunnel %d on demand for export: %v", node.TunnelID, err)
+				continue
+			}
+			node.Running = true
+			node.Started = err == nil
+		}
+		started = append(started, node)
+	}
+
+	if len(started) == 0 {
+		return nil, fmt.Errorf("%w: failed to start tunnels on demand", ErrNoActiveSocks5Tunnels)
+	}
+	return started, nil
+}
+
+// loadProxyNodes 将SOCKS5隧道转换为代理节点，没有可导出的隧道时返回 ErrNoActiveSocks5Tunnels
+func loadProxyNodes(tunnelRepo repository.TunnelRepository, hostRepo repository.HostRepository, tunnelService TunnelService, includeInactive bool) ([]ProxyNode, error) {
+	tunnels, err := socks5Tunnels(tunnelRepo, tunnelService, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SOCKS5 tunnels: %v", err)
+	}
+
+	nodes := make([]ProxyNode, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		host, err := hostRepo.GetByID(tunnel.HostID)
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, ProxyNode{
+			Name:       fmt.Sprintf("drilling-%s-%d", sanitizeName(host.Name), tunnel.LocalPort),
+			Server:     proxyClientAddress(tunnel.LocalAddress),
+			Port:       tunnel.LocalPort,
+			Domains:    tunnel.ProxyDomains,
+			Tags:       tunnel.Tags,
+			Labels:     tunnel.Labels,
+			TunnelID:   tunnel.ID,
+			TunnelName: tunnel.Name,
+			Running:    models.IsRunningStatus(tunnel.Status),
+		})
+	}
+
+	if len(nodes) == 0 {
+		return nil, ErrNoActiveSocks5Tunnels
+	}
+	return nodes, nil
+}
+
+// selectLabeledNodes 选择标签匹配的节点，选择器为空时返回全部节点
+func selectLabeledNodes(nodes []ProxyNode, selector labels.Selector) ([]ProxyNode, error) {
+	if selector.Empty() {
+		return nodes, nil
+	}
+
+	var selected []ProxyNode
+	for _, node := range nodes {
+		if selector.Matches(node.Labels) {
+			selected = append(selected, node)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w matching selector %s", ErrNoActiveSocks5Tunnels, selector)
+	}
+	return selected, nil
+}
+
+// nodeTunnelIDs 返回节点对应的隧道ID
+func nodeTunnelIDs(nodes []ProxyNode) []uint {
+	ids := make([]uint, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.TunnelID)
+	}
+	return ids
+}
+
+// startedNodes 返回本次导出按需启动的节点
+func startedNodes(nodes []ProxyNode) []ProxyNode {
+	var started []ProxyNode
+	for _, node := range nodes {
+		if node.Started {
+			started = append(started, node)
+		}
+	}
+	return started
+}
+
+// proxyClientAddress 将隧道的监听地址转换为客户端可以连接的地址，监听所有接口时使用本机地址
+func proxyClientAddress(address string) string {
+	switch address {
+	case "", "0.0.0.0":
+		return "127.0.0.1"
+	case "::":
+		return "::1"
+	}
+	return address
+}
+
+// defaultProxyNode 返回没有设置代理域名的第一个节点，作为未匹配任何域名时的默认出口
+func defaultProxyNode(nodes []ProxyNode) *ProxyNode {
+	for i := range nodes {
+		if len(nodes[i].Domains) == 0 {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
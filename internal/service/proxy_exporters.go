@@ -0,0 +1,274 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// singBoxExporter sing-box 出站配置导出器
+type singBoxExporter struct{}
+
+// singBoxConfig sing-box 配置中的出站和路由部分
+type singBoxConfig struct {
+	Outbounds []singBoxOutbound `json:"outbounds"`
+	Route     singBoxRoute      `json:"route"`
+}
+
+// singBoxOutbound sing-box 出站
+type singBoxOutbound struct {
+	Type       string   `json:"type"`
+	Tag        string   `json:"tag"`
+	Server     string   `json:"server,omitempty"`
+	ServerPort int      `json:"server_port,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Outbounds  []string `json:"outbounds,omitempty"`
+	Default    string   `json:"default,omitempty"`
+}
+
+// singBoxRoute sing-box 路由
+type singBoxRoute struct {
+	Rules []singBoxRule `json:"rules,omitempty"`
+	Final string        `json:"final"`
+}
+
+// singBoxRule sing-box 路由规则
+type singBoxRule struct {
+	DomainSuffix []string `json:"domain_suffix"`
+	Outbound     string   `json:"outbound"`
+}
+
+// Format 返回格式名称
+func (e *singBoxExporter) Format() string { return "sing-box" }
+
+// ContentType 返回响应的 Content-Type
+func (e *singBoxExporter) ContentType() string { return "application/json" }
+
+// FileExtension 返回下载文件的扩展名
+func (e *singBoxExporter) FileExtension() string { return "json" }
+
+// Export 导出 sing-box 的 outbounds 和 route，可以合并到已有的 sing-box 配置中
+// 每个节点一个 socks 出站，另有一个包含全部节点的 selector，未匹配域名的流量走默认节点或直连
+func (e *singBoxExporter) Export(nodes []ProxyNode) ([]byte, error) {
+	config := singBoxConfig{Route: singBoxRoute{Final: "direct"}}
+
+	tags := make([]string, 0, len(nodes)+1)
+	for _, node := range nodes {
+		config.Outbounds = append(config.Outbounds, singBoxOutbound{
+			Type:       "socks",
+			Tag:        node.Name,
+			Server:     node.Server,
+			ServerPort: node.Port,
+			Version:    "5",
+		})
+		tags = append(tags, node.Name)
+		if len(node.Domains) > 0 {
+			config.Route.Rules = append(config.Route.Rules, singBoxRule{DomainSuffix: node.Domains, Outbound: node.Name})
+		}
+	}
+
+	selector := singBoxOutbound{Type: "selector", Tag: "drilling", Outbounds: append(tags, "direct"), Default: "direct"}
+	if node := defaultProxyNode(nodes); node != nil {
+		selector.Default = node.Name
+		config.Route.Final = "drilling"
+	}
+	config.Outbounds = append(config.Outbounds, selector, singBoxOutbound{Type: "direct", Tag: "direct"})
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sing-box config: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// xrayExporter V2Ray/Xray 出站配置导出器
+type xrayExporter struct{}
+
+// xrayConfig Xray 配置中的出站和路由部分
+type xrayConfig struct {
+	Outbounds []xrayOutbound `json:"outbounds"`
+	Routing   xrayRouting    `json:"routing"`
+}
+
+// xrayOutbound Xray 出站，第一个出站是默认出口
+type xrayOutbound struct {
+	Tag      string                 `json:"tag"`
+	Protocol string                 `json:"protocol"`
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// xrayRouting Xray 路由
+type xrayRouting struct {
+	DomainStrategy string     `json:"domainStrategy"`
+	Rules          []xrayRule `json:"rules"`
+}
+
+// xrayRule Xray 路由规则
+type xrayRule struct {
+	Type        string   `json:"type"`
+	Domain      []string `json:"domain"`
+	OutboundTag string   `json:"outboundTag"`
+}
+
+// Format 返回格式名称
+func (e *xrayExporter) Format() string { return "xray" }
+
+// ContentType 返回响应的 Content-Type
+func (e *xrayExporter) ContentType() string { return "application/json" }
+
+// FileExtension 返回下载文件的扩展名
+func (e *xrayExporter) FileExtension() string { return "json" }
+
+// Export 导出 V2Ray/Xray 的 outbounds 和 routing，默认节点排在第一个作为默认出口，没有默认节点时直连
+func (e *xrayExporter) Export(nodes []ProxyNode) ([]byte, error) {
+	config := xrayConfig{Routing: xrayRouting{DomainStrategy: "AsIs", Rules: []xrayRule{}}}
+
+	direct := xrayOutbound{Tag: "direct", Protocol: "freedom"}
+	defaultNode := defaultProxyNode(nodes)
+	if defaultNode == nil {
+		config.Outbounds = append(config.Outbounds, direct)
+	}
+	for _, node := range nodes {
+		config.Outbounds = append(config.Outbounds, xrayOutbound{
+			Tag:      node.Name,
+			Protocol: "socks",
+			Settings: map[string]interface{}{
+				"servers": []map[string]interface{}{{"address": node.Server, "port": node.Port}},
+			},
+		})
+		if len(node.Domains) > 0 {
+			domains := make([]string, 0, len(node.Domains))
+			for _, domain := range node.Domains {
+				domains = append(domains, "domain:"+domain)
+			}
+			config.Routing.Rules = append(config.Routing.Rules, xrayRule{Type: "field", Domain: domains, OutboundTag: node.Name})
+		}
+	}
+	if defaultNode != nil {
+		// 默认节点移到第一个
+		for i := range config.Outbounds {
+			if config.Outbounds[i].Tag == defaultNode.Name {
+				config.Outbounds[0], config.Outbounds[i] = config.Outbounds[i], config.Outbounds[0]
+				break
+			}
+		}
+		config.Outbounds = append(config.Outbounds, direct)
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Xray config: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// surgeExporter Surge 配置导出器，Quantumult X 等客户端也可以导入相同格式的代理行
+type surgeExporter struct{}
+
+// Format 返回格式名称
+func (e *surgeExporter) Format() string { return "surge" }
+
+// ContentType 返回响应的 Content-Type
+func (e *surgeExporter) ContentType() string { return "text/plain; charset=utf-8" }
+
+// FileExtension 返回下载文件的扩展名
+func (e *surgeExporter) FileExtension() string { return "conf" }
+
+// Export 导出 Surge 的 [Proxy]、[Proxy Group] 和 [Rule] 段
+func (e *surgeExporter) Export(nodes []ProxyNode) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Drilling Platform - Surge Configuration\n# Generated at: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	b.WriteString("[Proxy]\n")
+	for _, node := range nodes {
+		fmt.Fprintf(&b, "%s = socks5, %s, %d\n", node.Name, node.Server, node.Port)
+	}
+
+	// 选择组的第一项是默认出口
+	members := make([]string, 0, len(nodes)+1)
+	defaultNode := defaultProxyNode(nodes)
+	if defaultNode != nil {
+		members = append(members, defaultNode.Name)
+	}
+	members = append(members, "DIRECT")
+	for _, node := range nodes {
+		if defaultNode == nil || node.Name != defaultNode.Name {
+			members = append(members, node.Name)
+		}
+	}
+	fmt.Fprintf(&b, "\n[Proxy Group]\nDrilling = select, %s\n", strings.Join(members, ", "))
+
+	b.WriteString("\n[Rule]\n")
+	for _, node := range nodes {
+		for _, domain := range node.Domains {
+			fmt.Fprintf(&b, "DOMAIN-SUFFIX,%s,%s\n", domain, node.Name)
+		}
+	}
+	b.WriteString("FINAL,Drilling\n")
+
+	return []byte(b.String()), nil
+}
+
+// pacExporter 浏览器 PAC 文件导出器
+type pacExporter struct{}
+
+// Format 返回格式名称
+func (e *pacExporter) Format() string { return "pac" }
+
+// ContentType 返回响应的 Content-Type
+func (e *pacExporter) ContentType() string { return "application/x-ns-proxy-autoconfig" }
+
+// FileExtension 返回下载文件的扩展名
+func (e *pacExporter) FileExtension() string { return "pac" }
+
+// Export 导出 PAC 文件，按域名选择对应的动态隧道，未匹配的域名走默认节点或直连
+func (e *pacExporter) Export(nodes []ProxyNode) ([]byte, error) {
+	var rules [][2]string
+	for _, node := range nodes {
+		for _, domain := range node.Domains {
+			rules = append(rules, [2]string{domain, pacProxy(node)})
+		}
+	}
+	if rules == nil {
+		rules = [][2]string{}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PAC rules: %v", err)
+	}
+
+	fallback := "DIRECT"
+	if node := defaultProxyNode(nodes); node != nil {
+		fallback = pacProxy(*node)
+	}
+	fallbackJSON, _ := json.Marshal(fallback)
+
+	pac := fmt.Sprintf(`// Drilling Platform - Proxy Auto-Config
+// Generated at: %s
+
+var rules = %s;
+var fallback = %s;
+
+function FindProxyForURL(url, host) {
+  host = host.toLowerCase();
+  for (var i = 0; i < rules.length; i++) {
+    var domain = rules[i][0];
+    if (host === domain || dnsDomainIs(host, "." + domain)) {
+      return rules[i][1];
+    }
+  }
+  return fallback;
+}
+`, time.Now().Format("2006-01-02 15:04:05"), rulesJSON, fallbackJSON)
+
+	return []byte(pac), nil
+}
+
+// pacProxy 返回 PAC 中节点的代理声明，同时声明 SOCKS 以兼容不识别 SOCKS5 的浏览器
+func pacProxy(node ProxyNode) string {
+	address := net.JoinHostPort(node.Server, strconv.Itoa(node.Port))
+	return fmt.Sprintf("SOCKS5 %s; SOCKS %s", address, address)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+)
+
+// SessionService 连接会话服务接口
+type SessionService interface {
+	RecordSession(session *models.ConnectionSession)
+	GetSession(id uint) (*models.ConnectionSession, error)
+	QuerySessions(filter repository.SessionFilter) ([]models.ConnectionSession, string, error)
+}
+
+// sessionService 连接会话服务实现
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+}
+
+// NewSessionService 创建连接会话服务实例
+func NewSessionService(sessionRepo repository.SessionRepository) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+	}
+}
+
+// RecordSession 保存连接会话记录，失败时只记录日志，不影响连接处理
+func (s *sessionService) RecordSession(session *models.ConnectionSession) {
+	session.Duration = session.EndTime.Sub(session.StartTime).Milliseconds()
+	if err := s.sessionRepo.Create(session); err != nil {
+		log.Printf("Failed to record connection session for tunnel %d: %v", session.TunnelID, err)
+	}
+}
+
+// GetSession 获取连接会话
+func (s *sessionService) GetSession(id uint) (*models.ConnectionSession, error) {
+	return s.sessionRepo.GetByID(id)
+}
+
+// QuerySessions 按条件分页查询连接会话，返回下一页游标（没有更多数据时为空）
+func (s *sessionService) QuerySessions(filter repository.SessionFilter) ([]models.ConnectionSession, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	// 多取一条用于判断是否还有下一页
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	sessions, err := s.sessionRepo.Query(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(sessions) > limit {
+		sessions = sessions[:limit]
+		nextCursor = strconv.FormatUint(uint64(sessions[limit-1].ID), 10)
+	}
+
+	return sessions, nextCursor, nil
+}
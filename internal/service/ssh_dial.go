@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/sshconfig"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxJumpHosts 跳板机链的最大长度
+const maxJumpHosts = 5
+
+// sshConfigFunc 根据已解密凭据的主机创建SSH客户端配置
+type sshConfigFunc func(host *models.Host) (*ssh.ClientConfig, error)
+
+// hostLookupFunc 获取已解密凭据的主机
+type hostLookupFunc func(id uint) (*models.Host, error)
+
+// dialSSH 连接已解密凭据的主机，配置了跳板机时先依次连接跳板机，再通过跳板机连接目标主机
+func dialSSH(host *models.Host, newConfig sshConfigFunc, lookup hostLookupFunc) (*ssh.Client, error) {
+	return dialSSHHop(host, newConfig, lookup, 0)
+}
+
+// dialSSHHop 递归连接跳板机链，hops 为已经经过的跳板机数量
+func dialSSHHop(host *models.Host, newConfig sshConfigFunc, lookup hostLookupFunc, hops int) (*ssh.Client, error) {
+	config, err := newConfig(host)
+	if err != nil {
+		return nil, err
+	}
+
+	address := net.JoinHostPort(host.Hostname, strconv.Itoa(host.Port))
+	if host.JumpHostID == 0 {
+		return ssh.Dial("tcp", address, config)
+	}
+
+	if hops >= maxJumpHosts {
+		return nil, fmt.Errorf("too many jump hosts, at most %d are allowed", maxJumpHosts)
+	}
+	jumpHost, err := lookup(host.JumpHostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jump host %d: %v", host.JumpHostID, err)
+	}
+	jumpClient, err := dialSSHHop(jumpHost, newConfig, lookup, hops+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to jump host %s: %v", jumpHost.Name, err)
+	}
+
+	conn, err := jumpClient.Dial("tcp", address)
+	if err != nil {
+		jumpClient.Close()
+		return nil, fmt.Errorf("failed to reach %s via jump host %s: %v", address, jumpHost.Name, err)
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		conn.Close()
+		jumpClient.Close()
+		return nil, err
+	}
+
+	client := ssh.NewClient(clientConn, chans, reqs)
+	// 目标主机的连接断开后关闭跳板机连接
+	go func() {
+		client.Wait()
+		jumpClient.Close()
+	}()
+	return client, nil
+}
+
+// loadPrivateKey 获取主机私钥，没有保存私钥内容时读取 keyDir 中的私钥文件
+// 读取失败时不返回具体原因，避免通过错误信息探测服务端的文件
+func loadPrivateKey(host *models.Host, keyDir string) ([]byte, error) {
+	if host.PrivateKey != "" {
+		return []byte(host.PrivateKey), nil
+	}
+	if host.KeyPath == "" {
+		return nil, errors.New("private key is required")
+	}
+
+	path, err := resolveKeyPath(keyDir, host.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	// 私钥目录中的符号链接可能指向目录外的文件，按实际路径再检查一次
+	realDir, err := filepath.EvalSymlinks(keyDir)
+	if err != nil {
+		return nil, errors.New("failed to read private key file")
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil || !insideDir(realDir, realPath) {
+		return nil, errors.New("failed to read private key file")
+	}
+	data, err := os.ReadFile(realPath)
+	if err != nil {
+		return nil, errors.New("failed to read private key file")
+	}
+	return data, nil
+}
+
+// resolveKeyPath 检查私钥文件路径在 keyDir 内，返回文件的绝对路径，相对路径相对于 keyDir
+// keyDir 为空时服务端不读取任何私钥文件，只能使用保存的私钥内容
+func resolveKeyPath(keyDir, keyPath string) (string, error) {
+	if keyDir == "" {
+		return "", errors.New("key path is not allowed because ssh.key_dir is not configured, upload the private key instead")
+	}
+	path := sshconfig.ExpandHome(keyPath)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(keyDir, path)
+	}
+	path = filepath.Clean(path)
+	if !insideDir(keyDir, path) {
+		return "", errors.New("key path must be inside the configured key directory (ssh.key_dir)")
+	}
+	return path, nil
+}
+
+// insideDir 检查 path 是否在目录 dir 内，两者都必须是清理过的绝对路径
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
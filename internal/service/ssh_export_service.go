@@ -0,0 +1,413 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/labels"
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+)
+
+// ErrNoTunnelsToExport 没有可以导出的隧道
+var ErrNoTunnelsToExport = errors.New("no tunnels found to export")
+
+// SSHExportService 将主机和隧道导出为 OpenSSH 配置、ssh 命令和 autossh systemd 单元的服务接口
+// 用于在没有安装 Drilling 的机器上运行相同的隧道，导出内容不包含密码和私钥
+type SSHExportService interface {
+	ExportSSHConfig(selection ExportSelection) ([]byte, error)
+	ExportSSHCommands(selection ExportSelection) ([]byte, error)
+	ExportAutosshUnits(selection ExportSelection) ([]byte, error)
+}
+
+// ExportSelection 导出的主机和隧道，选择主机时包含该主机的所有隧道，两者都为空时导出全部
+// 设置了标签选择器时只导出标签匹配的隧道
+type ExportSelection struct {
+	HostIDs   []uint
+	TunnelIDs []uint
+	Selector  labels.Selector
+}
+
+// sshExportHost 导出的主机及其隧道，jumps 为从最外层开始的跳板机链
+type sshExportHost struct {
+	host    models.Host
+	alias   string
+	tunnels []models.Tunnel
+	jumps   []*sshExportHost
+}
+
+// sshExportService OpenSSH 导出服务实现
+type sshExportService struct {
+	tunnelRepo repository.TunnelRepository
+	hostRepo   repository.HostRepository
+}
+
+// NewSSHExportService 创建 OpenSSH 导出服务实例
+func NewSSHExportService(tunnelRepo repository.TunnelRepository, hostRepo repository.HostRepository) SSHExportService {
+	return &sshExportService{
+		tunnelRepo: tunnelRepo,
+		hostRepo:   hostRepo,
+	}
+}
+
+// ExportSSHConfig 导出为 ~/.ssh/config 片段，每个主机一个 Host 块，跳板机通过 ProxyJump 引用
+func (s *sshExportService) ExportSSHConfig(selection ExportSelection) ([]byte, error) {
+	selected, err := s.load(selection)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	writeExportHeader(&b, "OpenSSH client configuration",
+		"Append this snippet to ~/.ssh/config, then run: ssh -N <alias>")
+
+	// 选中主机的跳板机也需要 Host 块，按跳板机在前的顺序输出
+	written := make(map[uint]bool)
+	var write func(item *sshExportHost)
+	write = func(item *sshExportHost) {
+		if written[item.host.ID] {
+			return
+		}
+		written[item.host.ID] = true
+		for _, jump := range item.jumps {
+			write(jump)
+		}
+		writeSSHConfigHost(&b, item)
+	}
+	for _, item := range selected {
+		write(item)
+	}
+
+	return []byte(b.String()), nil
+}
+
+// writeSSHConfigHost 输出一个 Host 块
+func writeSSHConfigHost(b *strings.Builder, item *sshExportHost) {
+	host := item.host
+	fmt.Fprintf(b, "Host %s\n", item.alias)
+	fmt.Fprintf(b, "    HostName %s\n", host.Hostname)
+	fmt.Fprintf(b, "    User %s\n", host.Username)
+	if host.Port != 0 && host.Port != 22 {
+		fmt.Fprintf(b, "    Port %d\n", host.Port)
+	}
+	if note := identityNote(&host); note != "" {
+		fmt.Fprintf(b, "    # %s\n", note)
+	}
+	if host.KeyPath != "" {
+		fmt.Fprintf(b, "    IdentityFile %s\n", host.KeyPath)
+		b.WriteString("    IdentitiesOnly yes\n")
+	}
+	if len(item.jumps) > 0 {
+		fmt.Fprintf(b, "    ProxyJump %s\n", item.jumps[len(item.jumps)-1].alias)
+	}
+
+	if len(item.tunnels) > 0 {
+		b.WriteString("    ExitOnForwardFailure yes\n")
+		b.WriteString("    ServerAliveInterval 30\n")
+		b.WriteString("    ServerAliveCountMax 3\n")
+	}
+	for _, tunnel := range item.tunnels {
+		keyword, spec := forwardArgs(&tunnel)
+		fmt.Fprintf(b, "    # %s\n", tunnel.Name)
+		fmt.Fprintf(b, "    %s %s\n", keyword, spec)
+	}
+	b.WriteString("\n")
+}
+
+// ExportSSHCommands 导出为等价的 ssh 命令，每个主机一条命令
+func (s *sshExportService) ExportSSHCommands(selection ExportSelection) ([]byte, error) {
+	selected, err := s.loadWithTunnels(selection)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	writeExportHeader(&b, "ssh command lines",
+		"Each command keeps the tunnels of one host open until interrupted")
+	for _, item := range selected {
+		fmt.Fprintf(&b, "# %s\n", item.host.Name)
+		if note := identityNote(&item.host); note != "" {
+			fmt.Fprintf(&b, "# %s\n", note)
+		}
+		args := append([]string{"ssh"}, sshArgs(item)...)
+		for i := range args {
+			args[i] = shellQuote(args[i])
+		}
+		b.WriteString(strings.Join(args, " "))
+		b.WriteString("\n\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// ExportAutosshUnits 导出为运行 autossh 的 systemd 单元，每个主机一个单元
+func (s *sshExportService) ExportAutosshUnits(selection ExportSelection) ([]byte, error) {
+	selected, err := s.loadWithTunnels(selection)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	writeExportHeader(&b, "autossh systemd units",
+		"Save each unit under /etc/systemd/system/, set User= to the account that owns the key,\n"+
+			"# then run: systemctl daemon-reload && systemctl enable --now <unit>")
+	for _, item := range selected {
+		args := append([]string{"/usr/bin/autossh", "-M", "0"}, sshArgs(item)...)
+		for i := range args {
+			args[i] = systemdQuote(args[i])
+		}
+
+		fmt.Fprintf(&b, "# ---- drilling-%s.service ----\n", item.alias)
+		if note := identityNote(&item.host); note != "" {
+			fmt.Fprintf(&b, "# %s\n", note)
+		}
+		fmt.Fprintf(&b, `[Unit]
+Description=Drilling tunnels for %s
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Environment=AUTOSSH_GATETIME=0
+ExecStart=%s
+Restart=always
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+
+`, systemdEscape(item.host.Name), strings.Join(args, " "))
+	}
+	return []byte(b.String()), nil
+}
+
+// loadWithTunnels 读取选中的主机，只保留有隧道的主机
+func (s *sshExportService) loadWithTunnels(selection ExportSelection) ([]*sshExportHost, error) {
+	selected, err := s.load(selection)
+	if err != nil {
+		return nil, err
+	}
+
+	result := selected[:0]
+	for _, item := range selected {
+		if len(item.tunnels) > 0 {
+			result = append(result, item)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrNoTunnelsToExport
+	}
+	return result, nil
+}
+
+// load 读取选中的主机和隧道，并解析每个主机的跳板机链，返回按别名排序的选中主机
+func (s *sshExportService) load(selection ExportSelection) ([]*sshExportHost, error) {
+	hosts, err := s.hostRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hosts: %v", err)
+	}
+	tunnels, err := s.tunnelRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tunnels: %v", err)
+	}
+
+	all := make(map[uint]*sshExportHost, len(hosts))
+	aliases := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		alias := sanitizeName(host.Name)
+		for i := 2; aliases[alias]; i++ {
+			alias = fmt.Sprintf("%s-%d", sanitizeName(host.Name), i)
+		}
+		aliases[alias] = true
+		all[host.ID] = &sshExportHost{host: host, alias: alias}
+	}
+
+	selectAll := len(selection.HostIDs) == 0 && len(selection.TunnelIDs) == 0
+	hostIDs := idSet(selection.HostIDs)
+	tunnelIDs := idSet(selection.TunnelIDs)
+
+	selected := make(map[uint]*sshExportHost)
+	for _, id := range selection.HostIDs {
+		if item, ok := all[id]; ok {
+			selected[id] = item
+		}
+	}
+	for _, tunnel := range tunnels {
+		item, ok := all[tunnel.HostID]
+		if !ok || !(selectAll || hostIDs[tunnel.HostID] || tunnelIDs[tunnel.ID]) || !selection.Selector.Matches(tunnel.Labels) {
+			continue
+		}
+		item.tunnels = append(item.tunnels, tunnel)
+		selected[tunnel.HostID] = item
+	}
+	if selectAll && selection.Selector.Empty() {
+		for id, item := range all {
+			selected[id] = item
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, ErrNoTunnelsToExport
+	}
+
+	for _, item := range all {
+		jumps, err := exportJumpChain(item, all)
+		if err != nil {
+			return nil, err
+		}
+		item.jumps = jumps
+		sort.Slice(item.tunnels, func(i, j int) bool {
+			return item.tunnels[i].LocalPort < item.tunnels[j].LocalPort
+		})
+	}
+
+	result := make([]*sshExportHost, 0, len(selected))
+	for _, item := range selected {
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].alias < result[j].alias
+	})
+	return result, nil
+}
+
+// exportJumpChain 返回主机的跳板机链，从最外层的跳板机开始
+func exportJumpChain(item *sshExportHost, all map[uint]*sshExportHost) ([]*sshExportHost, error) {
+	var chain []*sshExportHost
+	current := item
+	for current.host.JumpHostID != 0 {
+		if len(chain) >= maxJumpHosts {
+			return nil, fmt.Errorf("host %s has too many jump hosts", item.host.Name)
+		}
+		jump, ok := all[current.host.JumpHostID]
+		if !ok {
+			return nil, fmt.Errorf("jump host %d of host %s not found", current.host.JumpHostID, current.host.Name)
+		}
+		chain = append([]*sshExportHost{jump}, chain...)
+		current = jump
+	}
+	return chain, nil
+}
+
+// sshArgs 生成连接主机并建立所有隧道的 ssh 参数，不包含程序名
+func sshArgs(item *sshExportHost) []string {
+	host := item.host
+	args := []string{
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=30",
+		"-o", "ServerAliveCountMax=3",
+	}
+	if host.Port != 0 && host.Port != 22 {
+		args = append(args, "-p", strconv.Itoa(host.Port))
+	}
+	if host.KeyPath != "" {
+		args = append(args, "-i", host.KeyPath, "-o", "IdentitiesOnly=yes")
+	}
+	if len(item.jumps) > 0 {
+		jumps := make([]string, 0, len(item.jumps))
+		for _, jump := range item.jumps {
+			destination := jump.host.Username + "@" + jump.host.Hostname
+			if jump.host.Port != 0 && jump.host.Port != 22 {
+				destination += ":" + strconv.Itoa(jump.host.Port)
+			}
+			jumps = append(jumps, destination)
+		}
+		args = append(args, "-J", strings.Join(jumps, ","))
+	}
+	for _, tunnel := range item.tunnels {
+		keyword, spec := forwardArgs(&tunnel)
+		flag := map[string]string{"LocalForward": "-L", "RemoteForward": "-R", "DynamicForward": "-D"}[keyword]
+		args = append(args, flag, strings.ReplaceAll(spec, " ", ":"))
+	}
+	return append(args, host.Username+"@"+host.Hostname)
+}
+
+// forwardArgs 返回隧道对应的 ssh_config 配置项和参数
+// 远程端口转发的 RemoteAddress 和 RemotePort 是远程主机上的监听地址，LocalAddress 和 LocalPort 是转发目标
+func forwardArgs(tunnel *models.Tunnel) (string, string) {
+	switch tunnel.Type {
+	case models.TunnelTypeRemoteForward:
+		return "RemoteForward", forwardAddress(tunnel.RemoteAddress, tunnel.RemotePort) + " " +
+			forwardAddress(defaultString(tunnel.LocalAddress, "127.0.0.1"), tunnel.LocalPort)
+	case models.TunnelTypeDynamic:
+		return "DynamicForward", forwardAddress(tunnel.LocalAddress, tunnel.LocalPort)
+	default:
+		return "LocalForward", forwardAddress(tunnel.LocalAddress, tunnel.LocalPort) + " " +
+			forwardAddress(defaultString(tunnel.RemoteAddress, "localhost"), tunnel.RemotePort)
+	}
+}
+
+// forwardAddress 拼接转发地址，IPv6 地址加方括号，地址为空时只有端口
+func forwardAddress(address string, port int) string {
+	if address == "" {
+		return strconv.Itoa(port)
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port))
+}
+
+// identityNote 对无法导出的认证方式给出提示
+func identityNote(host *models.Host) string {
+	switch {
+	case host.AuthType == models.AuthTypePassword:
+		return "password authentication is not exported, enter the password when prompted or configure a key"
+	case host.KeyPath == "":
+		return "the private key is stored in Drilling only, save it locally and add an IdentityFile"
+	}
+	return ""
+}
+
+// writeExportHeader 输出导出文件的头部注释
+func writeExportHeader(b *strings.Builder, title, usage string) {
+	fmt.Fprintf(b, "# Drilling Platform - %s\n", title)
+	fmt.Fprintf(b, "# Generated at: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	b.WriteString("#\n")
+	fmt.Fprintf(b, "# %s\n", usage)
+	b.WriteString("# Passwords and private keys are never exported.\n\n")
+}
+
+// shellQuote 为 POSIX shell 引用参数，只包含安全字符时原样返回
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./-_[]") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// systemdQuote 为 systemd 的 ExecStart 引用参数，~/ 开头的路径改为 %h/
+func systemdQuote(arg string) string {
+	if strings.HasPrefix(arg, "~/") {
+		arg = "%h/" + systemdEscape(arg[2:])
+	} else {
+		arg = systemdEscape(arg)
+	}
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// systemdEscape 转义 systemd 的 % 说明符
+func systemdEscape(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+// defaultString 值为空时返回默认值
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// idSet 将ID列表转换为集合
+func idSet(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,526 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/sshconfig"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultIdentityFiles 未配置 IdentityFile 时 OpenSSH 默认尝试的私钥文件
+var defaultIdentityFiles = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// SSHImport 导入计划中的处理方式常量
+const (
+	SSHImportCreate      = "create"
+	SSHImportExists      = "exists"      // 同名主机已存在，不导入
+	SSHImportUnsupported = "unsupported" // 无法用 Drilling 的主机或隧道表示
+	SSHImportFailed      = "failed"      // 执行时创建失败
+)
+
+// SSHConfigImportService 从 OpenSSH 配置导入主机和隧道的服务接口
+type SSHConfigImportService interface {
+	Plan(cfg *sshconfig.Config, opts SSHImportOptions) (*SSHImportPlan, error)
+	Import(cfg *sshconfig.Config, opts SSHImportOptions) (*SSHImportPlan, error)
+}
+
+// SSHImportOptions 导入选项
+type SSHImportOptions struct {
+	Aliases           []string               // 只导入指定的主机别名，为空时导入全部
+	ReadIdentityFiles bool                   // 读取私钥文件内容保存到主机，只在命令行本地导入时使用
+	OwnerID           uint                   // 新建资源的所有者
+	CanUseJumpHost    func(hostID uint) bool // 检查能否使用已有主机作为跳板机，为nil时不限制
+}
+
+// SSHImportHost 导入计划中的主机
+type SSHImportHost struct {
+	Alias        string            `json:"alias"`
+	Action       string            `json:"action"`
+	ID           uint              `json:"id,omitempty"`
+	Hostname     string            `json:"hostname"`
+	Port         int               `json:"port"`
+	Username     string            `json:"username"`
+	AuthType     string            `json:"auth_type,omitempty"`
+	IdentityFile string            `json:"identity_file,omitempty"`
+	JumpHost     string            `json:"jump_host,omitempty"`
+	Tunnels      []SSHImportTunnel `json:"tunnels"`
+	Issues       []string          `json:"issues,omitempty"` // 无法表示或被忽略的配置
+
+	host *models.Host
+}
+
+// SSHImportTunnel 导入计划中的隧道
+type SSHImportTunnel struct {
+	Name          string   `json:"name"`
+	Action        string   `json:"action"`
+	ID            uint     `json:"id,omitempty"`
+	Type          string   `json:"type"`
+	LocalAddress  string   `json:"local_address,omitempty"`
+	LocalPort     int      `json:"local_port,omitempty"`
+	RemoteAddress string   `json:"remote_address,omitempty"`
+	RemotePort    int      `json:"remote_port,omitempty"`
+	Source        string   `json:"source"` // 原始配置，例如 LocalForward 8080 localhost:80
+	Issues        []string `json:"issues,omitempty"`
+}
+
+// SSHImportPlan 导入计划，执行后包含创建的资源ID和失败原因
+type SSHImportPlan struct {
+	Hosts    []SSHImportHost `json:"hosts"`
+	Warnings []string        `json:"warnings,omitempty"` // 解析配置文件时的警告
+	Created  int             `json:"created"`
+	Skipped  int             `json:"skipped"`
+}
+
+// sshConfigImportService 从 OpenSSH 配置导入主机和隧道的服务实现
+type sshConfigImportService struct {
+	hostService   HostService
+	tunnelService TunnelService
+}
+
+// NewSSHConfigImportService 创建 OpenSSH 配置导入服务实例
+func NewSSHConfigImportService(hostService HostService, tunnelService TunnelService) SSHConfigImportService {
+	return &sshConfigImportService{
+		hostService:   hostService,
+		tunnelService: tunnelService,
+	}
+}
+
+// Plan 计算导入计划，不修改数据库
+func (s *sshConfigImportService) Plan(cfg *sshconfig.Config, opts SSHImportOptions) (*SSHImportPlan, error) {
+	existingHosts, err := s.hostService.GetAllHosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hosts: %v", err)
+	}
+	existingByName := make(map[string]*models.Host)
+	for i := range existingHosts {
+		existingByName[existingHosts[i].Name] = &existingHosts[i]
+	}
+
+	aliases := cfg.Aliases()
+	if len(opts.Aliases) > 0 {
+		known := make(map[string]bool)
+		for _, alias := range aliases {
+			known[alias] = true
+		}
+		for _, alias := range opts.Aliases {
+			if !known[alias] {
+				return nil, fmt.Errorf("host %s is not defined in the SSH config", alias)
+			}
+		}
+		aliases = opts.Aliases
+	}
+
+	plan := &SSHImportPlan{Hosts: make([]SSHImportHost, 0, len(aliases))}
+	for _, warning := range cfg.Warnings {
+		plan.Warnings = append(plan.Warnings, warning.String())
+	}
+
+	importing := make(map[string]bool)
+	for _, alias := range aliases {
+		importing[alias] = true
+	}
+
+	for _, alias := range aliases {
+		item := planSSHHost(cfg.Resolve(alias), opts, s.hostService.CheckKeyPath)
+		if existingByName[alias] != nil {
+			item.Action = SSHImportExists
+			item.ID = existingByName[alias].ID
+			item.Issues = append(item.Issues, "a host with this name already exists")
+		}
+
+		// 跳板机只能是本次导入的主机或已有的主机
+		if item.JumpHost != "" && item.Action == SSHImportCreate {
+			jumpHost := existingByName[item.JumpHost]
+			switch {
+			case jumpHost != nil:
+				if opts.CanUseJumpHost != nil && !opts.CanUseJumpHost(jumpHost.ID) {
+					item.Action = SSHImportUnsupported
+					item.Issues = append(item.Issues, fmt.Sprintf("permission denied for jump host %s", item.JumpHost))
+				}
+			case !importing[item.JumpHost]:
+				item.Action = SSHImportUnsupported
+				item.Issues = append(item.Issues, fmt.Sprintf("ProxyJump host %s is neither imported nor an existing host", item.JumpHost))
+			}
+		}
+
+		plan.Hosts = append(plan.Hosts, item)
+	}
+
+	// 跳板机不能被导入时，使用它的主机也无法导入
+	for changed := true; changed; {
+		changed = false
+		actions := make(map[string]string)
+		for _, item := range plan.Hosts {
+			actions[item.Alias] = item.Action
+		}
+		for i := range plan.Hosts {
+			item := &plan.Hosts[i]
+			if item.Action != SSHImportCreate || !importing[item.JumpHost] {
+				continue
+			}
+			if action := actions[item.JumpHost]; action != SSHImportCreate && existingByName[item.JumpHost] == nil {
+				item.Action = SSHImportUnsupported
+				item.Issues = append(item.Issues, fmt.Sprintf("ProxyJump host %s cannot be imported", item.JumpHost))
+				changed = true
+			}
+		}
+	}
+
+	for i := range plan.Hosts {
+		item := &plan.Hosts[i]
+		if item.Action != SSHImportCreate {
+			for j := range item.Tunnels {
+				item.Tunnels[j].Action = item.Action
+			}
+			plan.Skipped++
+		}
+	}
+
+	return plan, nil
+}
+
+// Import 按导入计划创建主机和隧道，跳板机先于使用它的主机创建，单个资源失败不影响其他资源
+func (s *sshConfigImportService) Import(cfg *sshconfig.Config, opts SSHImportOptions) (*SSHImportPlan, error) {
+	plan, err := s.Plan(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	existingHosts, err := s.hostService.GetAllHosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hosts: %v", err)
+	}
+	hostIDs := make(map[string]uint)
+	for _, host := range existingHosts {
+		hostIDs[host.Name] = host.ID
+	}
+
+	created := make(map[string]bool)
+	for pending := true; pending; {
+		pending = false
+		progressed := false
+		for i := range plan.Hosts {
+			item := &plan.Hosts[i]
+			if item.Action != SSHImportCreate || created[item.Alias] {
+				continue
+			}
+			if item.JumpHost != "" && hostIDs[item.JumpHost] == 0 {
+				pending = true
+				continue
+			}
+
+			created[item.Alias] = true
+			progressed = true
+			item.host.JumpHostID = hostIDs[item.JumpHost]
+			item.host.OwnerID = opts.OwnerID
+			if err := s.hostService.CreateHost(item.host); err != nil {
+				item.Action = SSHImportFailed
+				item.Issues = append(item.Issues, err.Error())
+				continue
+			}
+			item.ID = item.host.ID
+			hostIDs[item.Alias] = item.ID
+			plan.Created++
+			s.createTunnels(item, opts.OwnerID)
+		}
+
+		// 剩余主机的跳板机创建失败或存在循环
+		if pending && !progressed {
+			for i := range plan.Hosts {
+				item := &plan.Hosts[i]
+				if item.Action == SSHImportCreate && !created[item.Alias] {
+					item.Action = SSHImportFailed
+					item.Issues = append(item.Issues, fmt.Sprintf("jump host %s was not created", item.JumpHost))
+				}
+			}
+			break
+		}
+	}
+
+	for i := range plan.Hosts {
+		item := &plan.Hosts[i]
+		if item.Action == SSHImportFailed {
+			plan.Skipped++
+			for j := range item.Tunnels {
+				if item.Tunnels[j].Action == SSHImportCreate {
+					item.Tunnels[j].Action = SSHImportFailed
+				}
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// createTunnels 为新建的主机创建隧道
+func (s *sshConfigImportService) createTunnels(item *SSHImportHost, ownerID uint) {
+	for i := range item.Tunnels {
+		spec := &item.Tunnels[i]
+		if spec.Action != SSHImportCreate {
+			continue
+		}
+
+		tunnel := &models.Tunnel{
+			HostID:        item.ID,
+			OwnerID:       ownerID,
+			Name:          spec.Name,
+			Type:          spec.Type,
+			LocalAddress:  spec.LocalAddress,
+			LocalPort:     spec.LocalPort,
+			RemoteAddress: spec.RemoteAddress,
+			RemotePort:    spec.RemotePort,
+			Description:   spec.Source,
+		}
+		if err := s.tunnelService.CreateTunnel(tunnel); err != nil {
+			spec.Action = SSHImportFailed
+			spec.Issues = append(spec.Issues, err.Error())
+			continue
+		}
+		spec.ID = tunnel.ID
+	}
+}
+
+// planSSHHost 将主机别名的生效配置转换为主机和隧道，checkKeyPath 检查服务端能否读取私钥文件
+func planSSHHost(cfg *sshconfig.HostConfig, opts SSHImportOptions, checkKeyPath func(path string) error) SSHImportHost {
+	item := SSHImportHost{
+		Alias:    cfg.Alias,
+		Action:   SSHImportCreate,
+		Hostname: cfg.Get("hostname"),
+		Port:     22,
+		Username: cfg.Get("user"),
+		Tunnels:  make([]SSHImportTunnel, 0),
+	}
+	unsupported := func(issue string) {
+		item.Action = SSHImportUnsupported
+		item.Issues = append(item.Issues, issue)
+	}
+
+	if item.Hostname == "" {
+		item.Hostname = cfg.Alias
+	}
+	if strings.Contains(item.Hostname, "%") {
+		unsupported(fmt.Sprintf("HostName %s uses tokens", item.Hostname))
+	}
+	if port := cfg.Get("port"); port != "" {
+		parsed, err := strconv.Atoi(port)
+		if err != nil || parsed <= 0 || parsed > 65535 {
+			unsupported(fmt.Sprintf("invalid Port %s", port))
+		} else {
+			item.Port = parsed
+		}
+	}
+	if item.Username == "" && opts.ReadIdentityFiles {
+		// 与 OpenSSH 一致，未配置时使用本地用户名
+		if current, err := user.Current(); err == nil {
+			item.Username = current.Username
+		}
+	}
+	if item.Username == "" {
+		unsupported("User is not set")
+	}
+
+	if cfg.Has("proxycommand") && !strings.EqualFold(cfg.Get("proxycommand"), "none") {
+		unsupported("ProxyCommand is not supported")
+	}
+	if jump := cfg.Get("proxyjump"); jump != "" && !strings.EqualFold(jump, "none") {
+		switch {
+		case strings.Contains(jump, ","):
+			unsupported(fmt.Sprintf("ProxyJump with multiple hops (%s) is not supported, use a ProxyJump on the jump host instead", jump))
+		case strings.ContainsAny(jump, "@:"):
+			unsupported(fmt.Sprintf("ProxyJump %s must refer to a Host alias", jump))
+		default:
+			item.JumpHost = jump
+		}
+	}
+	for _, key := range []string{"certificatefile", "localcommand", "remotecommand"} {
+		if cfg.Has(key) {
+			item.Issues = append(item.Issues, fmt.Sprintf("%s is ignored", cfg.Values(key)[0].Key))
+		}
+	}
+
+	host := &models.Host{
+		Name:        cfg.Alias,
+		Hostname:    item.Hostname,
+		Port:        item.Port,
+		Username:    item.Username,
+		AuthType:    models.AuthTypeKey,
+		Description: "Imported from SSH config",
+	}
+	if err := planIdentity(cfg, opts, &item, host, checkKeyPath); err != nil {
+		unsupported(err.Error())
+	}
+	item.AuthType = host.AuthType
+	item.host = host
+
+	for _, option := range cfg.Values("localforward") {
+		item.Tunnels = append(item.Tunnels, planForward(cfg.Alias, option))
+	}
+	for _, option := range cfg.Values("remoteforward") {
+		item.Tunnels = append(item.Tunnels, planForward(cfg.Alias, option))
+	}
+	for _, option := range cfg.Values("dynamicforward") {
+		item.Tunnels = append(item.Tunnels, planForward(cfg.Alias, option))
+	}
+
+	return item
+}
+
+// planIdentity 根据 IdentityFile 设置主机的私钥，Drilling 不支持 ssh-agent，因此必须有私钥文件
+// 命令行导入时读取私钥内容保存到主机；上传导入时只保存路径，路径必须在服务端的私钥目录内
+func planIdentity(cfg *sshconfig.HostConfig, opts SSHImportOptions, item *SSHImportHost, host *models.Host, checkKeyPath func(path string) error) error {
+	var candidates []string
+	for _, option := range cfg.Values("identityfile") {
+		candidates = append(candidates, option.Value())
+	}
+	if len(candidates) > 1 {
+		item.Issues = append(item.Issues, "only the first IdentityFile is used")
+		candidates = candidates[:1]
+	}
+	if len(candidates) == 0 {
+		if !opts.ReadIdentityFiles {
+			return errors.New("IdentityFile is not set, password and ssh-agent authentication cannot be imported")
+		}
+		for _, path := range defaultIdentityFiles {
+			if _, err := os.Stat(sshconfig.ExpandHome(path)); err == nil {
+				candidates = []string{path}
+				break
+			}
+		}
+		if len(candidates) == 0 {
+			return errors.New("no IdentityFile is set and no default key exists, password and ssh-agent authentication cannot be imported")
+		}
+	}
+
+	path := candidates[0]
+	if strings.Contains(path, "%") {
+		return fmt.Errorf("IdentityFile %s uses tokens", path)
+	}
+	item.IdentityFile = path
+	host.KeyPath = path
+	if !opts.ReadIdentityFiles {
+		// 连接时由服务端读取私钥目录中的私钥文件
+		return checkKeyPath(path)
+	}
+
+	data, err := os.ReadFile(sshconfig.ExpandHome(path))
+	if err != nil {
+		return fmt.Errorf("failed to read IdentityFile: %v", err)
+	}
+	if _, err := ssh.ParsePrivateKey(data); err != nil {
+		var missing *ssh.PassphraseMissingError
+		if !errors.As(err, &missing) {
+			return fmt.Errorf("invalid IdentityFile: %v", err)
+		}
+		// 加密的私钥同样保存内容，服务端不需要再读取私钥文件
+		item.Issues = append(item.Issues, "IdentityFile is encrypted, set the passphrase in the host settings after import")
+	}
+	host.PrivateKey = string(data)
+	return nil
+}
+
+// forwardKeyNames 端口转发配置项的原始写法，用于描述隧道来源
+var forwardKeyNames = map[string]string{
+	"localforward":   "LocalForward",
+	"remoteforward":  "RemoteForward",
+	"dynamicforward": "DynamicForward",
+}
+
+// planForward 将 LocalForward、RemoteForward、DynamicForward 转换为隧道
+func planForward(alias string, option sshconfig.Option) SSHImportTunnel {
+	tunnel := SSHImportTunnel{
+		Action: SSHImportCreate,
+		Source: fmt.Sprintf("%s %s", forwardKeyNames[option.Key], option.Value()),
+	}
+	unsupported := func(issue string) SSHImportTunnel {
+		tunnel.Action = SSHImportUnsupported
+		tunnel.Issues = append(tunnel.Issues, issue)
+		return tunnel
+	}
+
+	var listen, target string
+	switch {
+	case option.Key == "dynamicforward" && len(option.Args) == 1:
+		listen = option.Args[0]
+	case option.Key != "dynamicforward" && len(option.Args) == 2:
+		listen, target = option.Args[0], option.Args[1]
+	case option.Key == "remoteforward" && len(option.Args) == 1:
+		return unsupported("dynamic RemoteForward is not supported")
+	default:
+		return unsupported("unexpected arguments")
+	}
+
+	bindAddress, bindPort, err := splitForwardAddress(listen)
+	if err != nil {
+		return unsupported(err.Error())
+	}
+	if bindAddress == "*" {
+		bindAddress = "0.0.0.0"
+	}
+
+	switch option.Key {
+	case "localforward":
+		tunnel.Type = models.TunnelTypeLocalForward
+		tunnel.Name = fmt.Sprintf("%s-L%d", alias, bindPort)
+		tunnel.LocalAddress = bindAddress
+		if tunnel.LocalAddress == "" {
+			tunnel.LocalAddress = "127.0.0.1"
+		}
+		tunnel.LocalPort = bindPort
+		if tunnel.RemoteAddress, tunnel.RemotePort, err = splitForwardAddress(target); err != nil {
+			return unsupported(err.Error())
+		}
+	case "remoteforward":
+		tunnel.Type = models.TunnelTypeRemoteForward
+		tunnel.Name = fmt.Sprintf("%s-R%d", alias, bindPort)
+		// OpenSSH 未指定绑定地址时只监听远程主机的回环地址
+		tunnel.RemoteAddress = bindAddress
+		if tunnel.RemoteAddress == "" {
+			tunnel.RemoteAddress = "127.0.0.1"
+		}
+		tunnel.RemotePort = bindPort
+		if tunnel.LocalAddress, tunnel.LocalPort, err = splitForwardAddress(target); err != nil {
+			return unsupported(err.Error())
+		}
+	default:
+		tunnel.Type = models.TunnelTypeDynamic
+		tunnel.Name = fmt.Sprintf("%s-D%d", alias, bindPort)
+		tunnel.LocalAddress = bindAddress
+		if tunnel.LocalAddress == "" {
+			tunnel.LocalAddress = "127.0.0.1"
+		}
+		tunnel.LocalPort = bindPort
+	}
+
+	return tunnel
+}
+
+// splitForwardAddress 解析 [address:]port 或 address:port，支持 [IPv6]:port 和 address/port 写法
+func splitForwardAddress(value string) (string, int, error) {
+	if strings.HasPrefix(value, "/") || strings.HasPrefix(value, "~") {
+		return "", 0, fmt.Errorf("unix socket forwarding %s is not supported", value)
+	}
+
+	address, port := "", value
+	if strings.HasPrefix(value, "[") {
+		host, portPart, err := net.SplitHostPort(value)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid address %s", value)
+		}
+		address, port = host, portPart
+	} else if i := strings.LastIndexAny(value, ":/"); i >= 0 {
+		address, port = value[:i], value[i+1:]
+	}
+
+	parsed, err := strconv.Atoi(port)
+	if err != nil || parsed <= 0 || parsed > 65535 {
+		return "", 0, fmt.Errorf("invalid port in %s", value)
+	}
+	return address, parsed, nil
+}
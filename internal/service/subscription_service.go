@@ -0,0 +1,244 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+)
+
+// subscriptionTokenPrefix 订阅令牌的固定前缀，与API令牌区分
+const subscriptionTokenPrefix = "drs_"
+
+// defaultSubscriptionUpdateInterval 默认的客户端自动更新间隔（小时）
+const defaultSubscriptionUpdateInterval = 24
+
+// ErrSubscriptionNotFound 订阅不存在
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// ErrSubscriptionInactive 订阅已吊销或已过期
+var ErrSubscriptionInactive = errors.New("subscription is revoked or expired")
+
+// SubscriptionRequest 创建订阅的参数
+type SubscriptionRequest struct {
+	Name           string     `json:"name" binding:"required"`
+	Format         string     `json:"format"`          // 导出格式，默认 clash
+	ProfileID      *uint      `json:"profile_id"`      // 导出模板，为空时使用默认模板
+	UpdateInterval int        `json:"update_interval"` // 客户端自动更新间隔（小时），默认24
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+// SubscriptionContent 订阅拉取的结果
+type SubscriptionContent struct {
+	Export   *ProxyExport
+	Upload   int64 // 本月经由订阅中隧道上传的字节数
+	Download int64 // 本月经由订阅中隧道下载的字节数
+	Total    int64 // 订阅中隧道的月流量配额之和，0表示不限，任一隧道没有月配额时为0
+	Expire   int64 // 订阅过期时间的 Unix 时间戳，0表示永不过期
+}
+
+// UserInfo 返回 subscription-userinfo 响应头的值，客户端据此显示流量和到期时间
+func (c *SubscriptionContent) UserInfo() string {
+	return fmt.Sprintf("upload=%d; download=%d; total=%d; expire=%d", c.Upload, c.Download, c.Total, c.Expire)
+}
+
+// SubscriptionService 订阅服务接口
+type SubscriptionService interface {
+	CreateSubscription(userID uint, req SubscriptionRequest) (*models.Subscription, string, error)
+	GetSubscriptions() ([]models.Subscription, error)
+	GetSubscription(id uint) (*models.Subscription, error)
+	RevokeSubscription(id uint) error
+	GetFetches(id uint, limit int) ([]models.SubscriptionFetch, error)
+	Authenticate(token string) (*models.Subscription, error)
+	Render(subscription *models.Subscription) (*SubscriptionContent, error)
+	RecordFetch(fetch *models.SubscriptionFetch)
+}
+
+// subscriptionService 订阅服务实现
+type subscriptionService struct {
+	subscriptionRepo   repository.SubscriptionRepository
+	profileRepo        repository.ExportProfileRepository
+	tunnelRepo         repository.TunnelRepository
+	proxyExportService ProxyExportService
+	trafficService     TrafficService
+}
+
+// NewSubscriptionService 创建订阅服务实例
+func NewSubscriptionService(subscriptionRepo repository.SubscriptionRepository, profileRepo repository.ExportProfileRepository, tunnelRepo repository.TunnelRepository, proxyExportService ProxyExportService, trafficService TrafficService) SubscriptionService {
+	return &subscriptionService{
+		subscriptionRepo:   subscriptionRepo,
+		profileRepo:        profileRepo,
+		tunnelRepo:         tunnelRepo,
+		proxyExportService: proxyExportService,
+		trafficService:     trafficService,
+	}
+}
+
+// CreateSubscription 创建订阅，明文令牌只在创建时返回一次
+func (s *subscriptionService) CreateSubscription(userID uint, req SubscriptionRequest) (*models.Subscription, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", errors.New("subscription name is required")
+	}
+
+	format := req.Format
+	if format == "" {
+		format = "clash"
+	}
+	if !models.StringList(s.proxyExportService.Formats()).Contains(format) {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownExportFormat, format)
+	}
+
+	if req.ProfileID != nil {
+		if _, err := s.profileRepo.GetByID(*req.ProfileID); err != nil {
+			return nil, "", ErrExportProfileNotFound
+		}
+	}
+
+	interval := req.UpdateInterval
+	if interval == 0 {
+		interval = defaultSubscriptionUpdateInterval
+	}
+	if interval < 1 || interval > 24*30 {
+		return nil, "", errors.New("update interval must be between 1 and 720 hours")
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, "", errors.New("expiration time must be in the future")
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plain := subscriptionTokenPrefix + secret
+
+	subscription := &models.Subscription{
+		Name:           name,
+		Format:         format,
+		ProfileID:      req.ProfileID,
+		Prefix:         plain[:len(subscriptionTokenPrefix)+6],
+		TokenHash:      hashToken(plain),
+		UpdateInterval: interval,
+		ExpiresAt:      req.ExpiresAt,
+		CreatedBy:      userID,
+	}
+	if err := s.subscriptionRepo.Create(subscription); err != nil {
+		return nil, "", err
+	}
+
+	created, err := s.subscriptionRepo.GetByID(subscription.ID)
+	if err != nil {
+		return subscription, plain, nil
+	}
+	return created, plain, nil
+}
+
+// GetSubscriptions 获取所有订阅
+func (s *subscriptionService) GetSubscriptions() ([]models.Subscription, error) {
+	return s.subscriptionRepo.GetAll()
+}
+
+// GetSubscription 获取订阅
+func (s *subscriptionService) GetSubscription(id uint) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetByID(id)
+	if err != nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// RevokeSubscription 吊销订阅，吊销后订阅地址不再返回配置，拉取记录保留
+func (s *subscriptionService) RevokeSubscription(id uint) error {
+	subscription, err := s.GetSubscription(id)
+	if err != nil {
+		return err
+	}
+	if subscription.RevokedAt != nil {
+		return nil
+	}
+	return s.subscriptionRepo.Revoke(id, time.Now())
+}
+
+// GetFetches 获取订阅最近的拉取记录
+func (s *subscriptionService) GetFetches(id uint, limit int) ([]models.SubscriptionFetch, error) {
+	if _, err := s.GetSubscription(id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	return s.subscriptionRepo.GetFetches(id, limit)
+}
+
+// Authenticate 根据令牌查找订阅，订阅已吊销或过期时同时返回订阅和 ErrSubscriptionInactive
+func (s *subscriptionService) Authenticate(token string) (*models.Subscription, error) {
+	if !strings.HasPrefix(token, subscriptionTokenPrefix) {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	subscription, err := s.subscriptionRepo.GetByHash(hashToken(token))
+	if err != nil {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	if subscription.RevokedAt != nil || (subscription.ExpiresAt != nil && time.Now().After(*subscription.ExpiresAt)) {
+		return subscription, ErrSubscriptionInactive
+	}
+	return subscription, nil
+}
+
+// Render 按订阅的格式和导出模板生成配置，并统计订阅中隧道本月的流量
+func (s *subscriptionService) Render(subscription *models.Subscription) (*SubscriptionContent, error) {
+	if subscription.ProfileID != nil && subscription.Profile == nil {
+		return nil, ErrExportProfileNotFound
+	}
+
+	export, err := s.proxyExportService.Export(subscription.Format, subscription.Profile, ExportOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	content := &SubscriptionContent{Export: export}
+	if subscription.ExpiresAt != nil {
+		content.Expire = subscription.ExpiresAt.Unix()
+	}
+
+	now := time.Now()
+	unlimited := false
+	for _, tunnelID := range export.TunnelIDs {
+		// 隧道的出站流量是客户端上传的数据
+		bytesIn, bytesOut, err := s.trafficService.GetPeriodTraffic(tunnelID, models.QuotaPeriodMonthly, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get traffic of tunnel %d: %v", tunnelID, err)
+		}
+		content.Upload += bytesOut
+		content.Download += bytesIn
+
+		// 只有月配额可以和本月流量对应，没有月配额的隧道不限流量，订阅的总量也不限
+		tunnel, err := s.tunnelRepo.GetByID(tunnelID)
+		if err != nil || tunnel.QuotaBytes <= 0 || effectiveQuotaPeriod(tunnel) != models.QuotaPeriodMonthly {
+			unlimited = true
+			continue
+		}
+		content.Total += tunnel.QuotaBytes
+	}
+	if unlimited {
+		content.Total = 0
+	}
+	return content, nil
+}
+
+// RecordFetch 写入拉取记录，失败时只记录日志，不影响订阅响应
+func (s *subscriptionService) RecordFetch(fetch *models.SubscriptionFetch) {
+	if fetch.FetchedAt.IsZero() {
+		fetch.FetchedAt = time.Now()
+	}
+	if err := s.subscriptionRepo.RecordFetch(fetch); err != nil {
+		log.Printf("Failed to record fetch of subscription %d: %v", fetch.SubscriptionID, err)
+	}
+}
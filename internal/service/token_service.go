@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+)
+
+// apiTokenPrefix API令牌的固定前缀，便于在日志和密钥扫描中识别
+const apiTokenPrefix = "drl_"
+
+// tokenTouchInterval 令牌最后使用时间的更新间隔
+const tokenTouchInterval = time.Minute
+
+// ErrTokenInvalid 令牌不存在、已吊销或已过期
+var ErrTokenInvalid = errors.New("api token is invalid, revoked or expired")
+
+// TokenService API令牌服务接口
+type TokenService interface {
+	CreateToken(userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error)
+	GetUserTokens(userID uint) ([]models.APIToken, error)
+	RevokeToken(userID, id uint) error
+	Authenticate(token, clientIP string) (*models.User, *models.APIToken, error)
+}
+
+// tokenService API令牌服务实现
+type tokenService struct {
+	tokenRepo repository.TokenRepository
+}
+
+// NewTokenService 创建API令牌服务实例
+func NewTokenService(tokenRepo repository.TokenRepository) TokenService {
+	return &tokenService{
+		tokenRepo: tokenRepo,
+	}
+}
+
+// CreateToken 创建API令牌，明文令牌只在创建时返回一次
+func (s *tokenService) CreateToken(userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("token name is required")
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !models.StringList(models.AllScopes).Contains(scope) {
+			return nil, "", fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return nil, "", errors.New("expiration time must be in the future")
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plain := apiTokenPrefix + secret
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plain[:len(apiTokenPrefix)+6],
+		TokenHash: hashToken(plain),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.tokenRepo.Create(token); err != nil {
+		return nil, "", err
+	}
+
+	return token, plain, nil
+}
+
+// GetUserTokens 获取用户的所有API令牌
+func (s *tokenService) GetUserTokens(userID uint) ([]models.APIToken, error) {
+	return s.tokenRepo.GetByUserID(userID)
+}
+
+// RevokeToken 吊销用户自己的API令牌
+func (s *tokenService) RevokeToken(userID, id uint) error {
+	token, err := s.tokenRepo.GetByID(id)
+	if err != nil || token.UserID != userID {
+		return errors.New("token not found")
+	}
+	return s.tokenRepo.Delete(id)
+}
+
+// Authenticate 校验Bearer令牌并记录最后使用时间
+func (s *tokenService) Authenticate(plain, clientIP string) (*models.User, *models.APIToken, error) {
+	if !strings.HasPrefix(plain, apiTokenPrefix) {
+		return nil, nil, ErrTokenInvalid
+	}
+
+	token, err := s.tokenRepo.GetByHash(hashToken(plain))
+	if err != nil {
+		return nil, nil, ErrTokenInvalid
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, ErrTokenInvalid
+	}
+	if token.User == nil || token.User.Disabled {
+		return nil, nil, ErrTokenInvalid
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= tokenTouchInterval || token.LastUsedIP != clientIP {
+		token.LastUsedAt = &now
+		token.LastUsedIP = clientIP
+		if err := s.tokenRepo.UpdateLastUsed(token.ID, now, clientIP); err != nil {
+			log.Printf("Failed to update last used time for token %d: %v", token.ID, err)
+		}
+	}
+
+	return token.User, token, nil
+}
@@ -11,6 +11,10 @@ import (
 // TrafficService 流量统计服务接口
 type TrafficService interface {
 	LogTraffic(tunnelID uint, bytesIn, bytesOut int64)
+	RecordTraffic(tunnelID uint, bytesIn, bytesOut int64)
+	FlushTraffic() error
+	GetPeriodUsage(tunnelID uint, period string, now time.Time) (int64, error)
+	GetPeriodTraffic(tunnelID uint, period string, now time.Time) (int64, int64, error)
 	GetTrafficStats(tunnelID uint, startTime, endTime time.Time) ([]models.TrafficStats, error)
 	GetRealtimeStats(tunnelID uint) (*models.RealtimeTrafficStats, error)
 	GetAllRealtimeStats() (map[uint]*models.RealtimeTrafficStats, error)
@@ -18,16 +22,27 @@ type TrafficService interface {
 
 // trafficService 流量统计服务实现
 type trafficService struct {
-	tunnelRepo    repository.TunnelRepository
+	trafficRepo   repository.TrafficRepository
 	realtimeStats map[uint]*models.RealtimeTrafficStats
+	pending       map[uint]*pendingTraffic
+	lastFlush     time.Time
 	mutex         sync.RWMutex
 }
 
+// pendingTraffic 尚未写入数据库的流量
+type pendingTraffic struct {
+	bytesIn     int64
+	bytesOut    int64
+	connections int64
+}
+
 // NewTrafficService 创建流量统计服务实例
-func NewTrafficService(tunnelRepo repository.TunnelRepository) TrafficService {
+func NewTrafficService(trafficRepo repository.TrafficRepository) TrafficService {
 	return &trafficService{
-		tunnelRepo:    tunnelRepo,
+		trafficRepo:   trafficRepo,
 		realtimeStats: make(map[uint]*models.RealtimeTrafficStats),
+		pending:       make(map[uint]*pendingTraffic),
+		lastFlush:     time.Now(),
 	}
 }
 
@@ -36,17 +51,7 @@ func (s *trafficService) LogTraffic(tunnelID uint, bytesIn, bytesOut int64) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	stats, exists := s.realtimeStats[tunnelID]
-	if !exists {
-		stats = &models.RealtimeTrafficStats{
-			TunnelID:         tunnelID,
-			CurrentBytesIn:   0,
-			CurrentBytesOut:  0,
-			ActiveConnections: 1,
-			LastUpdateTime:   time.Now(),
-		}
-		s.realtimeStats[tunnelID] = stats
-	}
+	stats := s.getOrCreateStats(tunnelID)
 
 	// 更新累计流量
 	stats.CurrentBytesIn += bytesIn
@@ -63,11 +68,96 @@ func (s *trafficService) LogTraffic(tunnelID uint, bytesIn, bytesOut int64) {
 	stats.LastUpdateTime = now
 }
 
+// RecordTraffic 记录连接实时产生的流量，累计到实时统计并等待写入数据库
+func (s *trafficService) RecordTraffic(tunnelID uint, bytesIn, bytesOut int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := s.getOrCreateStats(tunnelID)
+	stats.CurrentBytesIn += bytesIn
+	stats.CurrentBytesOut += bytesOut
+	stats.LastUpdateTime = time.Now()
+
+	pending := s.getOrCreatePending(tunnelID)
+	pending.bytesIn += bytesIn
+	pending.bytesOut += bytesOut
+}
+
+// FlushTraffic 将待写入的流量按天累加到数据库，同时更新实时速度
+func (s *trafficService) FlushTraffic() error {
+	s.mutex.Lock()
+	pending := s.pending
+	s.pending = make(map[uint]*pendingTraffic)
+
+	now := time.Now()
+	elapsed := now.Sub(s.lastFlush).Seconds()
+	s.lastFlush = now
+	for tunnelID, stats := range s.realtimeStats {
+		p := pending[tunnelID]
+		if p == nil || elapsed <= 0 {
+			stats.SpeedIn = 0
+			stats.SpeedOut = 0
+			continue
+		}
+		stats.SpeedIn = float64(p.bytesIn) / elapsed
+		stats.SpeedOut = float64(p.bytesOut) / elapsed
+	}
+	s.mutex.Unlock()
+
+	dayStart, dayEnd := periodBounds(models.QuotaPeriodDaily, now)
+
+	var firstErr error
+	for tunnelID, p := range pending {
+		if p.bytesIn == 0 && p.bytesOut == 0 && p.connections == 0 {
+			continue
+		}
+		if err := s.trafficRepo.AddTraffic(tunnelID, dayStart, dayEnd, p.bytesIn, p.bytesOut, p.connections); err != nil {
+			// 写入失败时放回待写入队列，下次重试
+			s.mutex.Lock()
+			retry := s.getOrCreatePending(tunnelID)
+			retry.bytesIn += p.bytesIn
+			retry.bytesOut += p.bytesOut
+			retry.connections += p.connections
+			s.mutex.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// GetPeriodUsage 获取隧道在当前配额周期内已使用的流量（入站+出站）
+func (s *trafficService) GetPeriodUsage(tunnelID uint, period string, now time.Time) (int64, error) {
+	bytesIn, bytesOut, err := s.GetPeriodTraffic(tunnelID, period, now)
+	if err != nil {
+		return 0, err
+	}
+	return bytesIn + bytesOut, nil
+}
+
+// GetPeriodTraffic 获取隧道在当前周期内的入站和出站流量，包括尚未写入数据库的部分
+func (s *trafficService) GetPeriodTraffic(tunnelID uint, period string, now time.Time) (int64, int64, error) {
+	startTime, endTime := periodBounds(period, now)
+	bytesIn, bytesOut, err := s.trafficRepo.SumTraffic(tunnelID, startTime, endTime)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	s.mutex.RLock()
+	if p := s.pending[tunnelID]; p != nil {
+		bytesIn += p.bytesIn
+		bytesOut += p.bytesOut
+	}
+	s.mutex.RUnlock()
+
+	return bytesIn, bytesOut, nil
+}
+
 // GetTrafficStats 获取历史流量统计
 func (s *trafficService) GetTrafficStats(tunnelID uint, startTime, endTime time.Time) ([]models.TrafficStats, error) {
-	// 这里应该实现从数据库获取历史统计数据
-	// 由于当前没有实现TrafficStats的数据库操作，返回空数组
-	return []models.TrafficStats{}, nil
+	return s.trafficRepo.GetStats(tunnelID, startTime, endTime)
 }
 
 // GetRealtimeStats 获取实时流量统计
@@ -79,13 +169,13 @@ func (s *trafficService) GetRealtimeStats(tunnelID uint) (*models.RealtimeTraffi
 	if !exists {
 		// 返回空统计
 		return &models.RealtimeTrafficStats{
-			TunnelID:         tunnelID,
-			CurrentBytesIn:   0,
-			CurrentBytesOut:  0,
+			TunnelID:          tunnelID,
+			CurrentBytesIn:    0,
+			CurrentBytesOut:   0,
 			ActiveConnections: 0,
-			SpeedIn:          0,
-			SpeedOut:         0,
-			LastUpdateTime:   time.Now(),
+			SpeedIn:           0,
+			SpeedOut:          0,
+			LastUpdateTime:    time.Now(),
 		}, nil
 	}
 
@@ -114,19 +204,10 @@ func (s *trafficService) IncrementConnection(tunnelID uint) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	stats, exists := s.realtimeStats[tunnelID]
-	if !exists {
-		stats = &models.RealtimeTrafficStats{
-			TunnelID:         tunnelID,
-			CurrentBytesIn:   0,
-			CurrentBytesOut:  0,
-			ActiveConnections: 0,
-			LastUpdateTime:   time.Now(),
-		}
-		s.realtimeStats[tunnelID] = stats
-	}
-
+	stats := s.getOrCreateStats(tunnelID)
 	stats.ActiveConnections++
+
+	s.getOrCreatePending(tunnelID).connections++
 }
 
 // DecrementConnection 减少连接数
@@ -140,6 +221,42 @@ func (s *trafficService) DecrementConnection(tunnelID uint) {
 	}
 }
 
+// getOrCreateStats 获取或创建实时统计，调用方需持有写锁
+func (s *trafficService) getOrCreateStats(tunnelID uint) *models.RealtimeTrafficStats {
+	stats, exists := s.realtimeStats[tunnelID]
+	if !exists {
+		stats = &models.RealtimeTrafficStats{
+			TunnelID:       tunnelID,
+			LastUpdateTime: time.Now(),
+		}
+		s.realtimeStats[tunnelID] = stats
+	}
+	return stats
+}
+
+// getOrCreatePending 获取或创建待写入流量，调用方需持有写锁
+func (s *trafficService) getOrCreatePending(tunnelID uint) *pendingTraffic {
+	p, exists := s.pending[tunnelID]
+	if !exists {
+		p = &pendingTraffic{}
+		s.pending[tunnelID] = p
+	}
+	return p
+}
+
+// periodBounds 计算时间所在统计周期的起止时间（本地时区）
+func periodBounds(period string, now time.Time) (time.Time, time.Time) {
+	year, month, day := now.Date()
+	switch period {
+	case models.QuotaPeriodMonthly:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 0, 1)
+	}
+}
+
 // TunnelTrafficLogger 隧道流量记录器
 type TunnelTrafficLogger struct {
 	tunnelID       uint
@@ -157,4 +274,4 @@ func NewTunnelTrafficLogger(tunnelID uint, trafficService TrafficService) *Tunne
 // LogTraffic 记录流量
 func (l *TunnelTrafficLogger) LogTraffic(bytesIn, bytesOut int64) {
 	l.trafficService.LogTraffic(l.tunnelID, bytesIn, bytesOut)
-}
\ No newline at end of file
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/ratelimit"
+)
+
+// connectionQueueTimeout 排队等待连接槽位的最长时间
+const connectionQueueTimeout = 30 * time.Second
+
+// maxConnectionQueue 排队等待连接槽位的最大连接数，队列长度为最大连接数，但不超过该值
+const maxConnectionQueue = 256
+
+// connHandler 隧道连接处理函数
+type connHandler func(ctx context.Context, at *activeTunnel, conn *trackedConn)
+
+// trackedConn 带限速和流量统计的连接包装
+// 包装的是隧道接受的客户端连接：从客户端读取的数据计为上行（出站），写给客户端的数据计为下行（入站）
+type trackedConn struct {
+	net.Conn
+	ctx            context.Context
+	tunnelID       uint
+	trafficService TrafficService
+	upload         []*ratelimit.Bucket
+	download       []*ratelimit.Bucket
+	lastActivity   int64 // 最后一次读写的时间（UnixNano）
+	bytesIn        int64 // 写给客户端的字节数
+	bytesOut       int64 // 从客户端读取的字节数
+	closeOnce      sync.Once
+	mutex          sync.Mutex
+	closeReason    string
+	targetAddr     string
+}
+
+// newTrackedConn 创建带限速和流量统计的连接
+func newTrackedConn(ctx context.Context, conn net.Conn, tunnelID uint, trafficService TrafficService, upload, download []*ratelimit.Bucket) *trackedConn {
+	return &trackedConn{
+		Conn:           conn,
+		ctx:            ctx,
+		tunnelID:       tunnelID,
+		trafficService: trafficService,
+		upload:         upload,
+		download:       download,
+		lastActivity:   time.Now().UnixNano(),
+	}
+}
+
+// Read 从客户端读取数据，按上行限速
+func (c *trackedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		atomic.StoreInt64(&c.lastActivity, time.Now().UnixNano())
+		atomic.AddInt64(&c.bytesOut, int64(n))
+		c.trafficService.RecordTraffic(c.tunnelID, 0, int64(n))
+		if waitErr := ratelimit.WaitAll(c.ctx, n, c.upload...); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// Write 向客户端写入数据，按下行限速
+func (c *trackedConn) Write(p []byte) (int, error) {
+	if err := ratelimit.WaitAll(c.ctx, len(p), c.download...); err != nil {
+		return 0, err
+	}
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		atomic.StoreInt64(&c.lastActivity, time.Now().UnixNano())
+		atomic.AddInt64(&c.bytesIn, int64(n))
+		c.trafficService.RecordTraffic(c.tunnelID, int64(n), 0)
+	}
+	return n, err
+}
+
+// idleTime 获取连接空闲的时长
+func (c *trackedConn) idleTime() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&c.lastActivity)))
+}
+
+// closeWithReason 以指定原因关闭连接，只有第一次调用生效
+func (c *trackedConn) closeWithReason(reason string) {
+	c.closeOnce.Do(func() {
+		c.setCloseReason(reason)
+		c.Conn.Close()
+	})
+}
+
+// setCloseReason 记录连接关闭原因，已有原因时不覆盖
+func (c *trackedConn) setCloseReason(reason string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closeReason == "" {
+		c.closeReason = reason
+	}
+}
+
+// setTarget 记录连接的目标地址
+func (c *trackedConn) setTarget(addr string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.targetAddr = addr
+}
+
+// session 生成连接会话记录
+func (c *trackedConn) session(startTime time.Time) *models.ConnectionSession {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return &models.ConnectionSession{
+		TunnelID:    c.tunnelID,
+		ClientAddr:  c.RemoteAddr().String(),
+		TargetAddr:  c.targetAddr,
+		StartTime:   startTime,
+		EndTime:     time.Now(),
+		BytesIn:     atomic.LoadInt64(&c.bytesIn),
+		BytesOut:    atomic.LoadInt64(&c.bytesOut),
+		CloseReason: c.closeReason,
+	}
+}
+
+// relay 在客户端连接和上游连接之间双向转发数据，并记录先关闭的一方
+func relay(ctx context.Context, conn *trackedConn, upstream net.Conn) {
+	done := make(chan string, 2)
+
+	// 客户端 -> 上游
+	go func() {
+		io.Copy(upstream, conn)
+		done <- models.SessionCloseClient
+	}()
+
+	// 上游 -> 客户端
+	go func() {
+		io.Copy(conn, upstream)
+		done <- models.SessionCloseRemote
+	}()
+
+	// 等待任一方向完成或上下文取消
+	select {
+	case reason := <-done:
+		conn.setCloseReason(reason)
+	case <-ctx.Done():
+		conn.setCloseReason(models.SessionCloseStopped)
+	}
+}
+
+// acceptLoop 隧道共享的连接接受循环，负责并发连接数限制、超时控制和连接统计
+func (s *tunnelService) acceptLoop(ctx context.Context, at *activeTunnel, name string, handler connHandler) {
+	tunnel := at.tunnel
+	listener := at.listener
+
+	defer func() {
+		log.Printf("%s goroutine exiting for tunnel %d", name, tunnel.ID)
+		listener.Close()
+		close(at.acceptDone)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Context cancelled for tunnel %d %s", tunnel.ID, name)
+			return
+		default:
+		}
+
+		// 设置accept的超时，这样可以定期检查context
+		tcpListener, isTCP := listener.(*net.TCPListener)
+		if isTCP {
+			tcpListener.SetDeadline(time.Now().Add(1 * time.Second))
+		}
+
+		conn, err := listener.Accept()
+		if err != nil {
+			// 检查是否是超时错误
+			if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
+				// 超时是正常的，继续循环检查context
+				continue
+			}
+			if ctx.Err() != nil {
+				return // 上下文已取消
+			}
+			// 监听器已关闭（例如SSH连接断开），无法继续接受连接
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+				log.Printf("%s listener closed for tunnel %d: %v", name, tunnel.ID, err)
+				return
+			}
+			log.Printf("Failed to accept %s connection for tunnel %d: %v", name, tunnel.ID, err)
+			continue
+		}
+
+		// 清除deadline
+		if isTCP {
+			tcpListener.SetDeadline(time.Time{})
+		}
+
+		// 并发数已满且策略为拒绝时直接关闭，不占用处理协程
+		acquired, accepted := s.tryAcquireSlot(at, conn)
+		if !accepted {
+			continue
+		}
+
+		// 异步处理连接
+		at.conns.Add(1)
+		go s.serveConn(ctx, at, conn, acquired, handler)
+	}
+}
+
+// tryAcquireSlot 尝试获取连接槽位
+// acquired 表示是否已占用槽位；accepted 为false表示连接已被拒绝并关闭
+func (s *tunnelService) tryAcquireSlot(at *activeTunnel, conn net.Conn) (acquired bool, accepted bool) {
+	if at.slots == nil {
+		return false, true
+	}
+
+	select {
+	case at.slots <- struct{}{}:
+		return true, true
+	default:
+	}
+
+	// 排队策略下由处理协程等待槽位，队列已满时拒绝
+	if at.queue != nil {
+		select {
+		case at.queue <- struct{}{}:
+			return false, true
+		default:
+		}
+		conn.Close()
+		s.addClientLog(at.tunnel.ID, models.LogEventReject, conn.RemoteAddr().String(), fmt.Sprintf("Connection from %s rejected: max connections (%d) reached and queue is full (%d waiting)", conn.RemoteAddr(), at.tunnel.MaxConnections, cap(at.queue)))
+		return false, false
+	}
+
+	conn.Close()
+	s.addClientLog(at.tunnel.ID, models.LogEventReject, conn.RemoteAddr().String(), fmt.Sprintf("Connection from %s rejected: max connections (%d) reached", conn.RemoteAddr(), at.tunnel.MaxConnections))
+	return false, false
+}
+
+// waitForSlot 排队等待连接槽位，超时或隧道停止时关闭连接，结束等待时让出队列位置
+func (s *tunnelService) waitForSlot(ctx context.Context, at *activeTunnel, conn net.Conn) bool {
+	defer func() { <-at.queue }()
+
+	s.addClientLog(at.tunnel.ID, models.LogEventQueue, conn.RemoteAddr().String(), fmt.Sprintf("Connection from %s queued: max connections (%d) reached", conn.RemoteAddr(), at.tunnel.MaxConnections))
+
+	timer := time.NewTimer(connectionQueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case at.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		s.addClientLog(at.tunnel.ID, models.LogEventReject, conn.RemoteAddr().String(), fmt.Sprintf("Connection from %s rejected: queued longer than %s", conn.RemoteAddr(), connectionQueueTimeout))
+	case <-ctx.Done():
+	}
+
+	conn.Close()
+	return false
+}
+
+// serveConn 处理单个连接：等待槽位、统计连接数、包装限速并监控超时
+func (s *tunnelService) serveConn(ctx context.Context, at *activeTunnel, conn net.Conn, acquired bool, handler connHandler) {
+	defer at.conns.Done()
+
+	if at.slots != nil {
+		if !acquired && !s.waitForSlot(ctx, at, conn) {
+			return
+		}
+		defer func() { <-at.slots }()
+	}
+
+	// 增加连接计数
+	if ts, ok := s.trafficService.(*trafficService); ok {
+		ts.IncrementConnection(at.tunnel.ID)
+		defer ts.DecrementConnection(at.tunnel.ID)
+	}
+
+	startTime := time.Now()
+	tracked := newTrackedConn(ctx, conn, at.tunnel.ID, s.trafficService, at.uploadLimits, at.downloadLimits)
+
+	stop := s.watchConn(ctx, at, tracked)
+	handler(ctx, at, tracked)
+	stop()
+
+	// 连接结束后记录会话
+	if ctx.Err() != nil {
+		tracked.setCloseReason(models.SessionCloseStopped)
+	}
+	tracked.setCloseReason(models.SessionCloseNormal)
+	s.sessionService.RecordSession(tracked.session(startTime))
+}
+
+// watchConn 监控连接的空闲超时和最大存活时间，超过限制时关闭连接
+// 返回的函数用于在连接结束时停止监控
+func (s *tunnelService) watchConn(ctx context.Context, at *activeTunnel, conn *trackedConn) func() {
+	tunnel := at.tunnel
+	if tunnel.IdleTimeout <= 0 && tunnel.MaxLifetime <= 0 {
+		return func() {}
+	}
+
+	idleTimeout := time.Duration(tunnel.IdleTimeout) * time.Second
+	maxLifetime := time.Duration(tunnel.MaxLifetime) * time.Second
+	startTime := time.Now()
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				var reason string
+				if idleTimeout > 0 && conn.idleTime() >= idleTimeout {
+					reason = fmt.Sprintf("idle timeout (%s)", idleTimeout)
+				} else if maxLifetime > 0 && time.Since(startTime) >= maxLifetime {
+					reason = fmt.Sprintf("max lifetime reached (%s)", maxLifetime)
+				}
+				if reason != "" {
+					s.addClientLog(tunnel.ID, models.LogEventTimeout, conn.RemoteAddr().String(), fmt.Sprintf("Connection from %s closed: %s", conn.RemoteAddr(), reason))
+					conn.closeWithReason(reason)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
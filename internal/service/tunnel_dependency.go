@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KodaTao/drilling/internal/models"
+)
+
+// ErrDependencyCycle 隧道之间存在循环依赖
+var ErrDependencyCycle = errors.New("circular tunnel dependency")
+
+// dependencyGraph 隧道依赖关系，键为隧道ID，值为其依赖的隧道ID
+type dependencyGraph map[uint][]uint
+
+// newDependencyGraph 根据隧道列表创建依赖关系
+func newDependencyGraph(tunnels []models.Tunnel) dependencyGraph {
+	graph := make(dependencyGraph, len(tunnels))
+	for _, tunnel := range tunnels {
+		graph[tunnel.ID] = tunnel.DependsOn
+	}
+	return graph
+}
+
+// order 返回指定隧道及其递归依赖的启动顺序，依赖总是排在依赖它的隧道之前
+// 没有依赖关系的隧道按ID排序，保证顺序稳定
+func (g dependencyGraph) order(ids []uint) ([]uint, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[uint]int, len(g))
+	var result []uint
+	var path []uint
+
+	var visit func(id uint) error
+	visit = func(id uint) error {
+		switch state[id] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, formatDependencyPath(append(path, id)))
+		}
+		deps, ok := g[id]
+		if !ok {
+			if len(path) == 0 {
+				return fmt.Errorf("tunnel %d not found", id)
+			}
+			return fmt.Errorf("tunnel %d depends on missing tunnel %d", path[len(path)-1], id)
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		sorted := append([]uint(nil), deps...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		for _, dep := range sorted {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		result = append(result, id)
+		return nil
+	}
+
+	roots := append([]uint(nil), ids...)
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	for _, id := range roots {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// formatDependencyPath 格式化依赖路径，例如 1 -> 2 -> 1
+func formatDependencyPath(path []uint) string {
+	parts := make([]string, len(path))
+	for i, id := range path {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// normalizeDependsOn 去掉重复的依赖，并检查隧道不能依赖自身
+func normalizeDependsOn(tunnel *models.Tunnel) error {
+	var deps models.UintList
+	for _, dep := range tunnel.DependsOn {
+		if dep == 0 || deps.Contains(dep) {
+			continue
+		}
+		if dep == tunnel.ID {
+			return errors.New("tunnel cannot depend on itself")
+		}
+		deps = append(deps, dep)
+	}
+	tunnel.DependsOn = deps
+	return nil
+}
+
+// validateDependencies 检查隧道依赖的隧道存在且没有循环依赖
+func (s *tunnelService) validateDependencies(tunnel *models.Tunnel) error {
+	if len(tunnel.DependsOn) == 0 {
+		return nil
+	}
+
+	tunnels, err := s.tunnelRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to get tunnels: %v", err)
+	}
+	graph := newDependencyGraph(tunnels)
+	for _, dep := range tunnel.DependsOn {
+		if _, ok := graph[dep]; !ok {
+			return fmt.Errorf("dependency tunnel %d not found", dep)
+		}
+	}
+
+	// 新建的隧道还没有ID，不会被其他隧道依赖，只需检查其依赖本身
+	graph[tunnel.ID] = tunnel.DependsOn
+	_, err = graph.order([]uint{tunnel.ID})
+	return err
+}
+
+// removeDependency 删除隧道时从其他隧道的依赖中移除
+func (s *tunnelService) removeDependency(id uint) error {
+	tunnels, err := s.tunnelRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, tunnel := range tunnels {
+		if !tunnel.DependsOn.Contains(id) {
+			continue
+		}
+		var deps models.UintList
+		for _, dep := range tunnel.DependsOn {
+			if dep != id {
+				deps = append(deps, dep)
+			}
+		}
+		if err := s.tunnelRepo.UpdateDependsOn(tunnel.ID, deps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartOrder 返回启动指定隧道的顺序，包含它们递归依赖的隧道
+func (s *tunnelService) StartOrder(ids []uint) ([]models.Tunnel, error) {
+	tunnels, err := s.tunnelRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tunnels: %v", err)
+	}
+
+	order, err := newDependencyGraph(tunnels).order(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]models.Tunnel, len(tunnels))
+	for _, tunnel := range tunnels {
+		byID[tunnel.ID] = tunnel
+	}
+	result := make([]models.Tunnel, 0, len(order))
+	for _, id := range order {
+		result = append(result, byID[id])
+	}
+	return result, nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrTunnelGroupNotFound 隧道组不存在
+var ErrTunnelGroupNotFound = errors.New("tunnel group not found")
+
+// TunnelGroupRequest 创建或更新隧道组的请求
+type TunnelGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	TunnelIDs   []uint `json:"tunnel_ids"`
+}
+
+// TunnelGroupResult 启动或停止隧道组的结果
+type TunnelGroupResult struct {
+	Started    []uint `json:"started,omitempty"`     // 本次启动的隧道
+	Stopped    []uint `json:"stopped,omitempty"`     // 本次停止的隧道
+	Skipped    []uint `json:"skipped,omitempty"`     // 已在运行或已停止而跳过的隧道
+	RolledBack []uint `json:"rolled_back,omitempty"` // 启动失败后重新停止的隧道
+}
+
+// TunnelGroupService 隧道组服务接口
+type TunnelGroupService interface {
+	CreateGroup(ownerID uint, req *TunnelGroupRequest) (*models.TunnelGroup, error)
+	GetGroup(id uint) (*models.TunnelGroup, error)
+	GetAllGroups() ([]models.TunnelGroup, error)
+	UpdateGroup(id uint, req *TunnelGroupRequest) (*models.TunnelGroup, error)
+	DeleteGroup(id uint) error
+	PlanGroup(id uint) ([]models.Tunnel, error)
+	StartGroup(id uint) (*TunnelGroupResult, error)
+	StopGroup(id uint) (*TunnelGroupResult, error)
+}
+
+// tunnelGroupService 隧道组服务实现
+type tunnelGroupService struct {
+	groupRepo     repository.TunnelGroupRepository
+	tunnelRepo    repository.TunnelRepository
+	tunnelService TunnelService
+	mutex         sync.Mutex // 同一时间只启动或停止一个隧道组，避免回滚时互相干扰
+}
+
+// NewTunnelGroupService 创建隧道组服务实例
+func NewTunnelGroupService(groupRepo repository.TunnelGroupRepository, tunnelRepo repository.TunnelRepository, tunnelService TunnelService) TunnelGroupService {
+	return &tunnelGroupService{
+		groupRepo:     groupRepo,
+		tunnelRepo:    tunnelRepo,
+		tunnelService: tunnelService,
+	}
+}
+
+// CreateGroup 创建隧道组
+func (s *tunnelGroupService) CreateGroup(ownerID uint, req *TunnelGroupRequest) (*models.TunnelGroup, error) {
+	group := &models.TunnelGroup{OwnerID: ownerID}
+	if err := s.applyRequest(group, req); err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(group.ID)
+}
+
+// GetGroup 获取隧道组
+func (s *tunnelGroupService) GetGroup(id uint) (*models.TunnelGroup, error) {
+	group, err := s.groupRepo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTunnelGroupNotFound
+	}
+	return group, err
+}
+
+// GetAllGroups 获取所有隧道组
+func (s *tunnelGroupService) GetAllGroups() ([]models.TunnelGroup, error) {
+	return s.groupRepo.GetAll()
+}
+
+// UpdateGroup 更新隧道组的名称、描述和组内隧道
+func (s *tunnelGroupService) UpdateGroup(id uint, req *TunnelGroupRequest) (*models.TunnelGroup, error) {
+	group, err := s.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(group, req); err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.Update(group); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(id)
+}
+
+// DeleteGroup 删除隧道组，组内的隧道不受影响
+func (s *tunnelGroupService) DeleteGroup(id uint) error {
+	if _, err := s.GetGroup(id); err != nil {
+		return err
+	}
+	return s.groupRepo.Delete(id)
+}
+
+// applyRequest 校验请求并写入隧道组
+func (s *tunnelGroupService) applyRequest(group *models.TunnelGroup, req *TunnelGroupRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("group name is required")
+	}
+	if other, err := s.groupRepo.GetByName(name); err == nil && other.ID != group.ID {
+		return fmt.Errorf("tunnel group %s already exists", name)
+	}
+
+	var tunnels []models.Tunnel
+	seen := make(map[uint]bool)
+	for _, id := range req.TunnelIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		tunnel, err := s.tunnelRepo.GetByID(id)
+		if err != nil {
+			return fmt.Errorf("tunnel %d not found", id)
+		}
+		tunnels = append(tunnels, *tunnel)
+	}
+
+	group.Name = name
+	group.Description = req.Description
+	group.Tunnels = tunnels
+	return nil
+}
+
+// PlanGroup 返回启动隧道组的顺序，包含组内隧道递归依赖的组外隧道
+func (s *tunnelGroupService) PlanGroup(id uint) ([]models.Tunnel, error) {
+	group, err := s.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.tunnelService.StartOrder(group.TunnelIDs())
+}
+
+// StartGroup 按依赖顺序启动隧道组，已在运行的隧道跳过
+// 任一隧道启动失败时按相反顺序停止本次启动的隧道，隧道组要么全部运行，要么保持启动前的状态
+func (s *tunnelGroupService) StartGroup(id uint) (*TunnelGroupResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	plan, err := s.PlanGroup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TunnelGroupResult{}
+	for _, tunnel := range plan {
+		if s.tunnelService.IsTunnelRunning(tunnel.ID) {
+			result.Skipped = append(result.Skipped, tunnel.ID)
+			continue
+		}
+
+		if err := s.tunnelService.StartTunnel(tunnel.ID); err != nil {
+			s.rollback(result)
+			return result, fmt.Errorf("failed to start tunnel %d (%s): %v", tunnel.ID, tunnel.Name, err)
+		}
+		result.Started = append(result.Started, tunnel.ID)
+	}
+	return result, nil
+}
+
+// rollback 按相反顺序停止本次启动的隧道
+func (s *tunnelGroupService) rollback(result *TunnelGroupResult) {
+	for i := len(result.Started) - 1; i >= 0; i-- {
+		id := result.Started[i]
+		if err := s.tunnelService.StopTunnel(id); err != nil {
+			log.Printf("Failed to roll back tunnel %d: %v", id, err)
+			continue
+		}
+		result.RolledBack = append(result.RolledBack, id)
+	}
+	result.Started = nil
+}
+
+// StopGroup 按依赖的相反顺序停止组内的隧道，组外的依赖隧道可能被其他隧道使用，不停止
+func (s *tunnelGroupService) StopGroup(id uint) (*TunnelGroupResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	group, err := s.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := s.tunnelService.StartOrder(group.TunnelIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[uint]bool, len(group.Tunnels))
+	for _, tunnel := range group.Tunnels {
+		members[tunnel.ID] = true
+	}
+
+	result := &TunnelGroupResult{}
+	var errs []string
+	for i := len(plan) - 1; i >= 0; i-- {
+		tunnel := plan[i]
+		if !members[tunnel.ID] {
+			continue
+		}
+		if !s.tunnelService.IsTunnelRunning(tunnel.ID) {
+			result.Skipped = append(result.Skipped, tunnel.ID)
+			continue
+		}
+		if err := s.tunnelService.StopTunnel(tunnel.ID); err != nil {
+			errs = append(errs, fmt.Sprintf("tunnel %d: %v", tunnel.ID, err))
+			continue
+		}
+		result.Stopped = append(result.Stopped, tunnel.ID)
+	}
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("failed to stop tunnels: %s", strings.Join(errs, "; "))
+	}
+	return result, nil
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// 健康检查的默认设置
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 5 * time.Second
+	defaultHealthFailures = 3
+)
+
+// 健康检查失败后自动重启的限制，第一次立即重启，之后每次重启前的等待时间翻倍
+const (
+	maxHealthRestarts       = 5
+	healthRestartBackoff    = 30 * time.Second
+	maxHealthRestartBackoff = 10 * time.Minute
+)
+
+// healthResponseLimit 检查响应内容时最多读取的字节数
+const healthResponseLimit = 64 * 1024
+
+// errHealthProbeSkipped 按需连接的隧道没有SSH连接时跳过健康检查，避免检查本身建立连接
+var errHealthProbeSkipped = errors.New("health probe skipped")
+
+// healthState 运行中隧道的健康检查状态
+type healthState struct {
+	mutex     sync.Mutex
+	failures  int // 连续失败次数
+	degraded  bool
+	lastCheck time.Time
+	lastError string
+}
+
+// HealthStatus 隧道健康检查状态
+type HealthStatus struct {
+	TunnelID            uint       `json:"tunnel_id"`
+	Enabled             bool       `json:"enabled"`
+	Running             bool       `json:"running"`
+	Healthy             bool       `json:"healthy"`
+	Degraded            bool       `json:"degraded"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastCheck           *time.Time `json:"last_check,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// normalizeHealthCheck 规范化并检查隧道的健康检查设置
+func normalizeHealthCheck(tunnel *models.Tunnel) error {
+	tunnel.HealthCheck = strings.ToLower(strings.TrimSpace(tunnel.HealthCheck))
+	tunnel.HealthTarget = strings.TrimSpace(tunnel.HealthTarget)
+	tunnel.HealthPath = strings.TrimSpace(tunnel.HealthPath)
+
+	switch tunnel.HealthCheck {
+	case "":
+		return nil
+	case models.HealthCheckTCP:
+	case models.HealthCheckHTTP:
+		if tunnel.HealthPath == "" {
+			tunnel.HealthPath = "/"
+		}
+		if !strings.HasPrefix(tunnel.HealthPath, "/") {
+			return errors.New("health check path must start with /")
+		}
+		if tunnel.HealthCode != 0 && (tunnel.HealthCode < 100 || tunnel.HealthCode > 599) {
+			return fmt.Errorf("invalid health check status code %d", tunnel.HealthCode)
+		}
+	case models.HealthCheckPayload:
+		if tunnel.HealthSend == "" && tunnel.HealthExpect == "" {
+			return errors.New("payload health check requires content to send or expect")
+		}
+	default:
+		return fmt.Errorf("invalid health check %q, expected tcp, http or payload", tunnel.HealthCheck)
+	}
+
+	if tunnel.HealthTarget != "" {
+		host, port, err := net.SplitHostPort(tunnel.HealthTarget)
+		if err != nil || host == "" {
+			return fmt.Errorf("invalid health check target %q, expected host:port", tunnel.HealthTarget)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid health check target %q, expected host:port", tunnel.HealthTarget)
+		}
+	} else if tunnel.Type == models.TunnelTypeDynamic {
+		return errors.New("health check target is required for dynamic tunnels")
+	}
+
+	if tunnel.HealthInterval < 0 || tunnel.HealthTimeout < 0 || tunnel.HealthFailures < 0 {
+		return errors.New("health check interval, timeout and failures must not be negative")
+	}
+	return nil
+}
+
+// healthTarget 返回健康检查的目标地址
+// 本地转发默认检查远程目标，远程转发默认检查本地服务，动态隧道必须指定目标
+func healthTarget(tunnel *models.Tunnel) string {
+	if tunnel.HealthTarget != "" {
+		return tunnel.HealthTarget
+	}
+	if tunnel.Type == models.TunnelTypeRemoteForward {
+		return net.JoinHostPort(tunnel.LocalAddress, strconv.Itoa(tunnel.LocalPort))
+	}
+	return net.JoinHostPort(tunnel.RemoteAddress, strconv.Itoa(tunnel.RemotePort))
+}
+
+// healthSetting 返回以秒为单位的设置，0表示使用默认值
+func healthSetting(seconds int, fallback time.Duration) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return fallback
+}
+
+// runHealthChecks 定期检查隧道目标的健康状态，直到隧道停止
+func (s *tunnelService) runHealthChecks(at *activeTunnel) {
+	ticker := time.NewTicker(healthSetting(at.tunnel.HealthInterval, defaultHealthInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-at.ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkHealth(at)
+		}
+	}
+}
+
+// checkHealth 执行一次健康检查，连续失败达到阈值时标记为 degraded，恢复后重新标记为运行中
+func (s *tunnelService) checkHealth(at *activeTunnel) {
+	tunnel := at.tunnel
+	err := s.probeTunnel(at)
+	if errors.Is(err, errHealthProbeSkipped) || at.ctx.Err() != nil {
+		return
+	}
+
+	threshold := tunnel.HealthFailures
+	if threshold <= 0 {
+		threshold = defaultHealthFailures
+	}
+
+	h := at.health
+	h.mutex.Lock()
+	h.lastCheck = time.Now()
+	if err == nil {
+		recovered := h.degraded
+		h.failures, h.degraded, h.lastError = 0, false, ""
+		h.mutex.Unlock()
+		s.resetHealthRestarts(tunnel.ID)
+
+		if recovered {
+			if at.onDemand != nil {
+				s.updateOnDemandStatus(at)
+			} else {
+				s.tunnelRepo.UpdateStatusWithReason(tunnel.ID, models.TunnelStatusActive, "")
+			}
+			s.addConnectionLog(tunnel.ID, models.LogEventRecovered, "Health check passed, tunnel recovered")
+		}
+		return
+	}
+
+	h.failures++
+	h.lastError = err.Error()
+	failures := h.failures
+	degrade := !h.degraded && failures >= threshold
+	if degrade {
+		h.degraded = true
+	}
+	h.mutex.Unlock()
+
+	if !degrade {
+		return
+	}
+
+	reason := fmt.Sprintf("Health check failed %d times in a row: %v", failures, err)
+	log.Printf("Tunnel %d degraded: %s", tunnel.ID, reason)
+	if od := at.onDemand; od != nil {
+		// 与按需连接的状态写入互斥，避免连接建立或断开时的状态覆盖 degraded
+		od.mutex.Lock()
+		if !od.closed {
+			s.tunnelRepo.UpdateStatusWithReason(tunnel.ID, models.TunnelStatusDegraded, reason)
+		}
+		od.mutex.Unlock()
+	} else {
+		s.tunnelRepo.UpdateStatusWithReason(tunnel.ID, models.TunnelStatusDegraded, reason)
+	}
+	s.addConnectionLog(tunnel.ID, models.LogEventDegraded, reason)
+
+	if tunnel.HealthRestart {
+		go s.restartUnhealthy(at)
+	}
+}
+
+// restartUnhealthy 重启健康检查连续失败的隧道，隧道已被停止或重启时不处理
+// 重启后健康检查仍未通过时按指数退避等待后再重启，连续重启 maxHealthRestarts 次后放弃，隧道保持 degraded
+func (s *tunnelService) restartUnhealthy(at *activeTunnel) {
+	id := at.tunnel.ID
+	s.mutex.Lock()
+	current := s.activeTunnels[id]
+	attempts := s.healthRestarts[id]
+	if current == at && attempts < maxHealthRestarts {
+		s.healthRestarts[id] = attempts + 1
+	}
+	s.mutex.Unlock()
+	if current != at {
+		return
+	}
+
+	if attempts >= maxHealthRestarts {
+		message := fmt.Sprintf("Giving up restarting after %d attempts, the tunnel stays degraded until health checks pass", attempts)
+		log.Printf("Tunnel %d: %s", id, message)
+		s.addConnectionLog(id, models.LogEventError, message)
+		return
+	}
+
+	if attempts > 0 {
+		delay := maxHealthRestartBackoff
+		if attempts < 16 && healthRestartBackoff<<(attempts-1) < delay {
+			delay = healthRestartBackoff << (attempts - 1)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-at.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	s.addConnectionLog(id, models.LogEventStop, fmt.Sprintf("Restarting tunnel after failed health checks (attempt %d of %d)", attempts+1, maxHealthRestarts))
+	if err := s.RestartTunnel(id); err != nil {
+		log.Printf("Failed to restart unhealthy tunnel %d: %v", id, err)
+		s.addConnectionLog(id, models.LogEventError, fmt.Sprintf("Restart after failed health checks failed: %v", err))
+	}
+}
+
+// resetHealthRestarts 清除隧道的自动重启次数
+func (s *tunnelService) resetHealthRestarts(id uint) {
+	s.mutex.Lock()
+	delete(s.healthRestarts, id)
+	s.mutex.Unlock()
+}
+
+// probeTunnel 对隧道目标执行一次健康检查
+// 本地转发和动态隧道通过隧道的SSH连接访问目标，远程转发直接访问被转发的本地服务
+func (s *tunnelService) probeTunnel(at *activeTunnel) error {
+	tunnel := at.tunnel
+	timeout := healthSetting(tunnel.HealthTimeout, defaultHealthTimeout)
+	ctx, cancel := context.WithTimeout(at.ctx, timeout)
+	defer cancel()
+
+	var client *ssh.Client
+	if tunnel.Type != models.TunnelTypeRemoteForward {
+		client = at.sshClient
+		if at.onDemand != nil {
+			// 不占用按需连接，避免健康检查让SSH连接一直保持
+			if client = at.onDemand.connected(); client == nil {
+				return errHealthProbeSkipped
+			}
+		}
+	}
+
+	target := healthTarget(tunnel)
+	dial := func(ctx context.Context) (net.Conn, error) {
+		if client != nil {
+			return client.DialContext(ctx, "tcp", target)
+		}
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", target)
+	}
+
+	var err error
+	switch tunnel.HealthCheck {
+	case models.HealthCheckHTTP:
+		err = probeHTTP(ctx, tunnel, target, dial)
+	case models.HealthCheckPayload:
+		err = probePayload(ctx, tunnel, dial)
+	default:
+		var conn net.Conn
+		if conn, err = dial(ctx); err == nil {
+			conn.Close()
+		}
+	}
+
+	// 检查期间按需连接的SSH连接被空闲断开，不计为失败
+	if err != nil && at.onDemand != nil && at.onDemand.connected() != client {
+		return errHealthProbeSkipped
+	}
+	return err
+}
+
+// probeHTTP 发送 HTTP GET 请求，检查状态码以及响应体是否包含期望的内容
+func probeHTTP(ctx context.Context, tunnel *models.Tunnel, target string, dial func(context.Context) (net.Conn, error)) error {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dial(ctx)
+			},
+			DisableKeepAlives: true,
+		},
+		// 不跟随重定向，3xx 本身视为健康
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+target+tunnel.HealthPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if tunnel.HealthCode != 0 {
+		if resp.StatusCode != tunnel.HealthCode {
+			return fmt.Errorf("unexpected HTTP status %d, expected %d", resp.StatusCode, tunnel.HealthCode)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	if tunnel.HealthExpect != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, healthResponseLimit))
+		if err != nil {
+			return fmt.Errorf("failed to read response: %v", err)
+		}
+		if !bytes.Contains(body, []byte(tunnel.HealthExpect)) {
+			return fmt.Errorf("response does not contain %q", tunnel.HealthExpect)
+		}
+	}
+	return nil
+}
+
+// probePayload 建立连接后发送自定义内容，并等待响应中出现期望的内容
+func probePayload(ctx context.Context, tunnel *models.Tunnel, dial func(context.Context) (net.Conn, error)) error {
+	conn, err := dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if tunnel.HealthSend != "" {
+		if _, err := io.WriteString(conn, tunnel.HealthSend); err != nil {
+			return fmt.Errorf("failed to send payload: %v", err)
+		}
+	}
+	if tunnel.HealthExpect == "" {
+		return nil
+	}
+
+	expect := []byte(tunnel.HealthExpect)
+	var received []byte
+	buf := make([]byte, 4096)
+	for len(received) < healthResponseLimit {
+		n, err := conn.Read(buf)
+		received = append(received, buf[:n]...)
+		if bytes.Contains(received, expect) {
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("failed to read response: %v", err)
+		}
+	}
+	return fmt.Errorf("response does not contain %q", tunnel.HealthExpect)
+}
+
+// GetHealthStatus 获取隧道的健康检查状态
+func (s *tunnelService) GetHealthStatus(id uint) (*HealthStatus, error) {
+	tunnel, err := s.tunnelRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &HealthStatus{
+		TunnelID: tunnel.ID,
+		Enabled:  tunnel.HealthCheck != "",
+	}
+
+	s.mutex.RLock()
+	at := s.activeTunnels[id]
+	s.mutex.RUnlock()
+	if at == nil {
+		return status, nil
+	}
+	status.Running = true
+	if at.health == nil {
+		return status, nil
+	}
+
+	h := at.health
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	status.Degraded = h.degraded
+	status.ConsecutiveFailures = h.failures
+	status.LastError = h.lastError
+	if !h.lastCheck.IsZero() {
+		lastCheck := h.lastCheck
+		status.LastCheck = &lastCheck
+		status.Healthy = h.failures == 0
+	}
+	return status, nil
+}
+
+// degraded 检查运行中的隧道是否因健康检查失败被标记为 degraded
+func (at *activeTunnel) degraded() bool {
+	if at.health == nil {
+		return false
+	}
+	at.health.mutex.Lock()
+	defer at.health.mutex.Unlock()
+	return at.health.degraded
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+)
+
+// defaultAutoStartConcurrency 默认同时启动的隧道数
+const defaultAutoStartConcurrency = 4
+
+// errShuttingDown 服务正在关闭，不再启动新的隧道
+var errShuttingDown = errors.New("tunnel service is shutting down")
+
+// AutoStartOptions 自动启动隧道的选项
+type AutoStartOptions struct {
+	Concurrency int           // 同时启动的隧道数
+	Retries     int           // 启动失败后的重试次数
+	RetryDelay  time.Duration // 两次重试之间的间隔
+}
+
+// RunAutoStart 按并发数启动所有自动启动的隧道，失败时按配置重试，直到上下文取消
+func (s *tunnelService) RunAutoStart(ctx context.Context, opts AutoStartOptions) error {
+	tunnels, err := s.tunnelRepo.GetAutoStartTunnels()
+	if err != nil {
+		return err
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultAutoStartConcurrency
+	}
+
+	log.Printf("Starting %d auto-start tunnels (concurrency %d, retries %d)", len(tunnels), opts.Concurrency, opts.Retries)
+
+	tunnels, deps := autoStartOrder(tunnels)
+	done := make(map[uint]chan struct{}, len(tunnels))
+	for _, tunnel := range tunnels {
+		done[tunnel.ID] = make(chan struct{})
+	}
+
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+
+	// 按依赖顺序占用并发槽位，依赖的隧道总是先占用槽位，等待依赖时不会死锁
+	for _, tunnel := range tunnels {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(tunnel models.Tunnel) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer close(done[tunnel.ID])
+
+			for _, dep := range deps[tunnel.ID] {
+				select {
+				case <-done[dep]:
+				case <-ctx.Done():
+					return
+				}
+			}
+			s.startWithRetry(ctx, tunnel, opts)
+		}(tunnel)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// autoStartOrder 将自动启动的隧道按依赖顺序排列，并返回每个隧道需要等待的自动启动隧道
+// 依赖不在自动启动列表中的隧道时不等待；存在循环依赖时按ID顺序启动，不等待依赖
+func autoStartOrder(tunnels []models.Tunnel) ([]models.Tunnel, map[uint][]uint) {
+	byID := make(map[uint]models.Tunnel, len(tunnels))
+	for _, tunnel := range tunnels {
+		byID[tunnel.ID] = tunnel
+	}
+
+	graph := make(dependencyGraph, len(tunnels))
+	ids := make([]uint, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		var deps []uint
+		for _, dep := range tunnel.DependsOn {
+			if _, ok := byID[dep]; ok {
+				deps = append(deps, dep)
+			}
+		}
+		graph[tunnel.ID] = deps
+		ids = append(ids, tunnel.ID)
+	}
+
+	order, err := graph.order(ids)
+	if err != nil {
+		log.Printf("Ignoring auto-start dependencies: %v", err)
+		return tunnels, nil
+	}
+
+	ordered := make([]models.Tunnel, 0, len(order))
+	for _, id := range order {
+		ordered = append(ordered, byID[id])
+	}
+	return ordered, graph
+}
+
+// startWithRetry 启动单个隧道，失败时按配置重试
+func (s *tunnelService) startWithRetry(ctx context.Context, tunnel models.Tunnel, opts AutoStartOptions) {
+	for attempt := 0; ; attempt++ {
+		err := s.StartTunnel(tunnel.ID)
+		if err == nil {
+			return
+		}
+
+		log.Printf("Failed to start auto tunnel %d (%s), attempt %d: %v", tunnel.ID, tunnel.Name, attempt+1, err)
+
+		// 配额耗尽、已在运行或服务关闭时重试没有意义
+		if attempt >= opts.Retries || errors.Is(err, errQuotaExhausted) || errors.Is(err, errShuttingDown) || s.IsTunnelRunning(tunnel.ID) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.RetryDelay):
+		}
+	}
+}
+
+// IsTunnelRunning 检查隧道是否在运行，以内存中的活动隧道为准
+func (s *tunnelService) IsTunnelRunning(id uint) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.activeTunnels[id] != nil
+}
+
+// Shutdown 优雅关闭所有隧道：停止接受新连接，在上下文截止前等待进行中的连接结束，然后关闭SSH连接
+func (s *tunnelService) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.shuttingDown = true
+	tunnels := make([]*activeTunnel, 0, len(s.activeTunnels))
+	for id, at := range s.activeTunnels {
+		tunnels = append(tunnels, at)
+		delete(s.activeTunnels, id)
+	}
+	s.mutex.Unlock()
+
+	log.Printf("Shutting down %d active tunnels", len(tunnels))
+
+	var wg sync.WaitGroup
+	for _, at := range tunnels {
+		wg.Add(1)
+		go func(at *activeTunnel) {
+			defer wg.Done()
+
+			id := at.tunnel.ID
+			closeListener(at)
+			if !drainConns(ctx, at) {
+				log.Printf("Drain deadline exceeded for tunnel %d, closing remaining connections", id)
+			}
+			releaseTunnel(at)
+
+			s.tunnelRepo.UpdateStatus(id, models.TunnelStatusInactive)
+			s.addConnectionLog(id, models.LogEventStop, "Tunnel stopped on shutdown")
+		}(at)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// closeListener 关闭隧道的监听器，停止接受新连接
+func closeListener(at *activeTunnel) {
+	if at.listener == nil {
+		return
+	}
+
+	// listener.Close() 可能被多次调用，忽略已关闭的错误
+	if err := at.listener.Close(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+		log.Printf("Error closing listener for tunnel %d: %v", at.tunnel.ID, err)
+	}
+}
+
+// drainConns 等待接受循环退出以及进行中的连接全部结束，超过上下文截止时间时返回false
+func drainConns(ctx context.Context, at *activeTunnel) bool {
+	drained := make(chan struct{})
+	go func() {
+		<-at.acceptDone
+		at.conns.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// releaseTunnel 取消隧道上下文并关闭SSH连接
+func releaseTunnel(at *activeTunnel) {
+	at.cancel()
+
+	if at.onDemand != nil {
+		at.onDemand.close(at.tunnel.ID)
+		return
+	}
+	if at.sshClient != nil {
+		if err := at.sshClient.Close(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			log.Printf("Error closing SSH client for tunnel %d: %v", at.tunnel.ID, err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultOnDemandIdleTimeout 按需连接的隧道在最后一个客户端连接结束后保持SSH连接的默认时间
+const defaultOnDemandIdleTimeout = 5 * time.Minute
+
+// errTunnelStopping 隧道正在停止，不再建立SSH连接
+var errTunnelStopping = errors.New("tunnel is stopping")
+
+// onDemandSSH 按需建立的SSH连接：首个客户端连接时建立，所有客户端连接结束并空闲一段时间后断开
+type onDemandSSH struct {
+	host        *models.Host
+	idleTimeout time.Duration
+	mutex       sync.Mutex
+	client      *ssh.Client
+	dialing     *onDemandDial // 正在建立的SSH连接，没有时为nil
+	users       int           // 正在使用SSH连接的客户端连接数
+	idleTimer   *time.Timer   // 空闲断开计时器，有客户端连接时为nil
+	closed      bool          // 隧道已停止
+}
+
+// onDemandDial 一次正在进行的SSH连接，同时到达的客户端连接等待同一次连接的结果
+type onDemandDial struct {
+	done chan struct{} // 连接完成后关闭
+	err  error         // 连接失败的原因，done 关闭后可读
+}
+
+// newOnDemandSSH 创建按需建立的SSH连接，idleTimeout 为空闲断开时间（秒），0表示使用默认值
+func newOnDemandSSH(host *models.Host, idleTimeout int) *onDemandSSH {
+	od := &onDemandSSH{host: host, idleTimeout: defaultOnDemandIdleTimeout}
+	if idleTimeout > 0 {
+		od.idleTimeout = time.Duration(idleTimeout) * time.Second
+	}
+	return od
+}
+
+// status 返回按需连接的隧道状态：SSH已连接为 connected，否则为 standby
+func (od *onDemandSSH) status() string {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	return od.statusLocked()
+}
+
+// connected 返回当前的SSH连接，没有连接时返回nil，不会建立新的连接
+func (od *onDemandSSH) connected() *ssh.Client {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	return od.client
+}
+
+// statusLocked 返回隧道状态，调用方需持有锁
+func (od *onDemandSSH) statusLocked() string {
+	if od.client != nil {
+		return models.TunnelStatusConnected
+	}
+	return models.TunnelStatusStandby
+}
+
+// updateOnDemandStatus 将按需连接的隧道的当前状态写入数据库
+// 持有锁写入，保证与SSH连接的建立和断开顺序一致，停止隧道后不会被覆盖
+func (s *tunnelService) updateOnDemandStatus(at *activeTunnel) {
+	od := at.onDemand
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	if !od.closed {
+		s.setOnDemandStatusLocked(at, od.statusLocked())
+	}
+}
+
+// setOnDemandStatusLocked 写入按需连接的隧道状态，调用方需持有锁
+// 健康检查标记为 degraded 时保留 degraded，检查恢复后再写入当前的连接状态
+func (s *tunnelService) setOnDemandStatusLocked(at *activeTunnel, status string) {
+	if at.degraded() {
+		return
+	}
+	s.tunnelRepo.UpdateStatusWithReason(at.tunnel.ID, status, "")
+}
+
+// StartTunnelOnDemand 按需启动隧道：立即监听端口，首个客户端连接时才建立SSH连接，空闲后断开
+// 隧道在停止前一直占用端口，SSH连接断开后下一个客户端连接会重新建立
+// 设置了按需连接（lazy）的隧道使用 StartTunnel 启动时也是这种方式
+func (s *tunnelService) StartTunnelOnDemand(id uint) error {
+	return s.startTunnel(id, true)
+}
+
+// acquireSSH 获取客户端连接使用的SSH连接，按需启动的隧道没有连接时先建立连接
+// 返回的函数在客户端连接结束时调用
+func (s *tunnelService) acquireSSH(at *activeTunnel) (*ssh.Client, func(), error) {
+	od := at.onDemand
+	if od == nil {
+		return at.sshClient, func() {}, nil
+	}
+
+	od.mutex.Lock()
+	for od.client == nil {
+		if od.closed {
+			od.mutex.Unlock()
+			return nil, nil, errTunnelStopping
+		}
+
+		// 其他客户端连接正在建立SSH连接时等待同一次连接的结果
+		if dial := od.dialing; dial != nil {
+			od.mutex.Unlock()
+			<-dial.done
+			if dial.err != nil {
+				return nil, nil, dial.err
+			}
+			od.mutex.Lock()
+			continue
+		}
+
+		if err := s.dialOnDemandSSH(at); err != nil {
+			od.mutex.Unlock()
+			return nil, nil, err
+		}
+	}
+
+	if od.idleTimer != nil {
+		od.idleTimer.Stop()
+		od.idleTimer = nil
+	}
+	od.users++
+	client := od.client
+	od.mutex.Unlock()
+	return client, func() { s.releaseSSH(at) }, nil
+}
+
+// dialOnDemandSSH 建立按需连接的SSH连接，调用方需持有锁
+// 连接期间释放锁，不阻塞状态查询和停止隧道；返回时重新持有锁
+func (s *tunnelService) dialOnDemandSSH(at *activeTunnel) error {
+	od := at.onDemand
+	dial := &onDemandDial{done: make(chan struct{})}
+	od.dialing = dial
+	od.mutex.Unlock()
+
+	client, err := s.createSSHConnection(od.host)
+
+	od.mutex.Lock()
+	od.dialing = nil
+	switch {
+	case err != nil:
+		s.addConnectionLog(at.tunnel.ID, models.LogEventError, fmt.Sprintf("On-demand SSH connection failed: %v", err))
+		err = fmt.Errorf("failed to create SSH connection: %v", err)
+	case od.closed:
+		// 连接期间隧道被停止
+		client.Close()
+		err = errTunnelStopping
+	}
+	dial.err = err
+	close(dial.done)
+	if err != nil {
+		return err
+	}
+
+	od.client = client
+	s.setOnDemandStatusLocked(at, models.TunnelStatusConnected)
+	s.addConnectionLog(at.tunnel.ID, models.LogEventConnect, "SSH connected on demand")
+	go s.watchOnDemandSSH(at, client)
+	return nil
+}
+
+// releaseSSH 客户端连接结束，最后一个连接结束后开始空闲计时
+func (s *tunnelService) releaseSSH(at *activeTunnel) {
+	od := at.onDemand
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+
+	od.users--
+	if od.users > 0 || od.client == nil || od.closed {
+		return
+	}
+	od.idleTimer = time.AfterFunc(od.idleTimeout, func() { s.closeIdleSSH(at) })
+}
+
+// closeIdleSSH 空闲计时结束时断开SSH连接，隧道继续监听端口
+func (s *tunnelService) closeIdleSSH(at *activeTunnel) {
+	od := at.onDemand
+	od.mutex.Lock()
+	if od.users > 0 || od.client == nil || od.closed {
+		od.mutex.Unlock()
+		return
+	}
+	client := od.client
+	od.client = nil
+	od.idleTimer = nil
+	s.setOnDemandStatusLocked(at, models.TunnelStatusStandby)
+	od.mutex.Unlock()
+
+	client.Close()
+	s.addConnectionLog(at.tunnel.ID, models.LogEventDisconnect, fmt.Sprintf("SSH connection closed after %s without clients", od.idleTimeout))
+}
+
+// watchOnDemandSSH 等待SSH连接断开，异常断开后由下一个客户端连接重新建立
+func (s *tunnelService) watchOnDemandSSH(at *activeTunnel, client *ssh.Client) {
+	err := client.Wait()
+
+	od := at.onDemand
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+
+	// 空闲断开和停止隧道时已经清空了连接
+	if od.client != client {
+		return
+	}
+	od.client = nil
+	s.setOnDemandStatusLocked(at, models.TunnelStatusStandby)
+	log.Printf("On-demand SSH connection for tunnel %d lost: %v", at.tunnel.ID, err)
+	s.addConnectionLog(at.tunnel.ID, models.LogEventDisconnect, "SSH connection lost, reconnecting on next client connection")
+}
+
+// close 停止空闲计时并关闭SSH连接，之后不再建立新的连接
+func (od *onDemandSSH) close(tunnelID uint) {
+	od.mutex.Lock()
+	od.closed = true
+	if od.idleTimer != nil {
+		od.idleTimer.Stop()
+		od.idleTimer = nil
+	}
+	client := od.client
+	od.client = nil
+	od.mutex.Unlock()
+
+	if client == nil {
+		return
+	}
+	if err := client.Close(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+		log.Printf("Error closing SSH client for tunnel %d: %v", tunnelID, err)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+)
+
+// quotaCheckInterval 流量写入数据库和配额检查的间隔
+const quotaCheckInterval = 10 * time.Second
+
+// errQuotaExhausted 流量配额耗尽
+var errQuotaExhausted = errors.New("traffic quota exhausted")
+
+// QuotaUsage 隧道流量配额使用情况
+type QuotaUsage struct {
+	TunnelID    uint      `json:"tunnel_id"`
+	Period      string    `json:"period"`
+	QuotaBytes  int64     `json:"quota_bytes"`
+	UsedBytes   int64     `json:"used_bytes"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Exhausted   bool      `json:"exhausted"`
+}
+
+// checkQuota 检查隧道在当前周期内是否还有可用流量
+func (s *tunnelService) checkQuota(tunnel *models.Tunnel) error {
+	if tunnel.QuotaBytes <= 0 {
+		return nil
+	}
+
+	used, err := s.trafficService.GetPeriodUsage(tunnel.ID, effectiveQuotaPeriod(tunnel), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to get traffic usage: %v", err)
+	}
+
+	if used >= tunnel.QuotaBytes {
+		reason := quotaExhaustedReason(tunnel, used)
+		s.tunnelRepo.UpdateStatusWithReason(tunnel.ID, models.TunnelStatusSuspended, reason)
+		return fmt.Errorf("%w: %s", errQuotaExhausted, reason)
+	}
+
+	return nil
+}
+
+// GetQuotaUsage 获取隧道的流量配额使用情况
+func (s *tunnelService) GetQuotaUsage(id uint) (*QuotaUsage, error) {
+	tunnel, err := s.tunnelRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	period := effectiveQuotaPeriod(tunnel)
+
+	used, err := s.trafficService.GetPeriodUsage(tunnel.ID, period, now)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := periodBounds(period, now)
+	return &QuotaUsage{
+		TunnelID:    tunnel.ID,
+		Period:      period,
+		QuotaBytes:  tunnel.QuotaBytes,
+		UsedBytes:   used,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Exhausted:   tunnel.QuotaBytes > 0 && used >= tunnel.QuotaBytes,
+	}, nil
+}
+
+// MonitorQuotas 定期写入流量统计并检查流量配额，直到上下文取消
+func (s *tunnelService) MonitorQuotas(ctx context.Context) {
+	ticker := time.NewTicker(quotaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.trafficService.FlushTraffic(); err != nil {
+				log.Printf("Failed to flush traffic stats: %v", err)
+			}
+			return
+		case <-ticker.C:
+			s.checkQuotas()
+		}
+	}
+}
+
+// checkQuotas 暂停配额耗尽的隧道，并在周期轮转后恢复被暂停的隧道
+func (s *tunnelService) checkQuotas() {
+	if err := s.trafficService.FlushTraffic(); err != nil {
+		log.Printf("Failed to flush traffic stats: %v", err)
+	}
+
+	now := time.Now()
+
+	s.mutex.RLock()
+	running := make([]*models.Tunnel, 0, len(s.activeTunnels))
+	for _, at := range s.activeTunnels {
+		running = append(running, at.tunnel)
+	}
+	s.mutex.RUnlock()
+
+	for _, tunnel := range running {
+		if tunnel.QuotaBytes <= 0 {
+			continue
+		}
+
+		used, err := s.trafficService.GetPeriodUsage(tunnel.ID, effectiveQuotaPeriod(tunnel), now)
+		if err != nil {
+			log.Printf("Failed to get traffic usage for tunnel %d: %v", tunnel.ID, err)
+			continue
+		}
+
+		if used >= tunnel.QuotaBytes {
+			s.suspendTunnel(tunnel.ID, quotaExhaustedReason(tunnel, used))
+		}
+	}
+
+	suspended, err := s.tunnelRepo.GetByStatus(models.TunnelStatusSuspended)
+	if err != nil {
+		log.Printf("Failed to get suspended tunnels: %v", err)
+		return
+	}
+
+	for _, tunnel := range suspended {
+		if tunnel.QuotaBytes > 0 {
+			used, err := s.trafficService.GetPeriodUsage(tunnel.ID, effectiveQuotaPeriod(&tunnel), now)
+			if err != nil || used >= tunnel.QuotaBytes {
+				continue
+			}
+		}
+
+		s.addConnectionLog(tunnel.ID, models.LogEventResume, "Traffic quota available again, resuming tunnel")
+		if err := s.StartTunnel(tunnel.ID); err != nil {
+			log.Printf("Failed to resume tunnel %d: %v", tunnel.ID, err)
+		}
+	}
+}
+
+// suspendTunnel 因配额耗尽停止隧道并标记为暂停
+func (s *tunnelService) suspendTunnel(id uint, reason string) {
+	log.Printf("Suspending tunnel %d: %s", id, reason)
+
+	if err := s.StopTunnel(id); err != nil {
+		log.Printf("Failed to stop tunnel %d for suspension: %v", id, err)
+		return
+	}
+
+	s.tunnelRepo.UpdateStatusWithReason(id, models.TunnelStatusSuspended, reason)
+	s.addConnectionLog(id, models.LogEventSuspend, reason)
+}
+
+// quotaExhaustedReason 生成配额耗尽的原因描述
+func quotaExhaustedReason(tunnel *models.Tunnel, used int64) string {
+	return fmt.Sprintf("%s traffic quota exhausted: used %d of %d bytes", effectiveQuotaPeriod(tunnel), used, tunnel.QuotaBytes)
+}
+
+// effectiveQuotaPeriod 返回隧道实际使用的配额周期，未设置时按天统计
+// 创建和更新隧道时会写入该值，早期创建的隧道可能为空
+func effectiveQuotaPeriod(tunnel *models.Tunnel) string {
+	if tunnel.QuotaPeriod == "" {
+		return models.QuotaPeriodDaily
+	}
+	return tunnel.QuotaPeriod
+}
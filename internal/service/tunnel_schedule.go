@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/schedule"
+)
+
+// ScheduleStatus 隧道启停计划的状态
+type ScheduleStatus struct {
+	TunnelID  uint                 `json:"tunnel_id"`
+	Scheduled bool                 `json:"scheduled"`
+	Timezone  string               `json:"timezone,omitempty"`  // 计划实际使用的时区
+	InWindow  *bool                `json:"in_window,omitempty"` // 当前是否在时间段内，仅使用时间段的计划返回
+	Next      *schedule.Transition `json:"next,omitempty"`      // 下一次计划的启动或停止
+}
+
+// tunnelSchedule 根据隧道配置创建启停计划，隧道没有设置计划时返回nil
+func tunnelSchedule(tunnel *models.Tunnel) (*schedule.Schedule, error) {
+	return schedule.New(tunnel.StartCron, tunnel.StopCron, tunnel.TimeWindows, tunnel.Timezone)
+}
+
+// normalizeSchedule 规范化并检查隧道的启停计划
+func normalizeSchedule(tunnel *models.Tunnel) error {
+	tunnel.StartCron = strings.TrimSpace(tunnel.StartCron)
+	tunnel.StopCron = strings.TrimSpace(tunnel.StopCron)
+	tunnel.Timezone = strings.TrimSpace(tunnel.Timezone)
+
+	var windows models.StringList
+	for _, window := range tunnel.TimeWindows {
+		if window = strings.Join(strings.Fields(window), " "); window != "" {
+			windows = append(windows, window)
+		}
+	}
+	tunnel.TimeWindows = windows
+
+	sched, err := tunnelSchedule(tunnel)
+	if err != nil {
+		return err
+	}
+	// 时间段计划在服务启动时会按当前时间决定是否启动，自动启动会在时间段外启动隧道
+	if sched != nil && sched.Windowed() && tunnel.AutoStart {
+		return errors.New("auto start cannot be combined with time windows, the scheduler starts the tunnel inside its windows")
+	}
+	return nil
+}
+
+// GetScheduleStatus 获取隧道启停计划的状态和下一次计划的动作
+func (s *tunnelService) GetScheduleStatus(id uint) (*ScheduleStatus, error) {
+	tunnel, err := s.tunnelRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ScheduleStatus{TunnelID: tunnel.ID}
+	sched, err := tunnelSchedule(tunnel)
+	if err != nil {
+		return nil, err
+	}
+	if sched == nil {
+		return status, nil
+	}
+
+	now := time.Now()
+	status.Scheduled = true
+	status.Timezone = sched.Location().String()
+	if sched.Windowed() {
+		inWindow := sched.Active(now)
+		status.InWindow = &inWindow
+	}
+	if next, ok := sched.Next(now); ok {
+		status.Next = &next
+	}
+	return status, nil
+}
+
+// maxScheduleCatchUp 调度延迟或系统休眠后最多补算的时间
+const maxScheduleCatchUp = 24 * time.Hour
+
+// RunScheduler 在每分钟开始时执行到期的计划启停，直到上下文取消
+// 启动时会先启动当前处于时间段内的隧道；cron 计划只在触发时刻执行
+// 记录已经处理到的分钟，一次执行超过一分钟时下一次会补上错过的分钟
+func (s *tunnelService) RunScheduler(ctx context.Context) {
+	last := time.Now().Truncate(time.Minute)
+	s.runSchedules(last, last, true)
+
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		current := time.Now().Truncate(time.Minute)
+		from := last.Add(time.Minute)
+		switch {
+		case current.Before(from):
+			// 系统时间被调回时只处理当前分钟
+			from = current
+		case current.Sub(from) > maxScheduleCatchUp:
+			from = current.Add(-maxScheduleCatchUp)
+		}
+		if missed := int(current.Sub(from) / time.Minute); missed > 0 {
+			log.Printf("Scheduler is catching up %d missed minutes", missed)
+		}
+		s.runSchedules(from, current, false)
+		last = current
+	}
+}
+
+// runSchedules 执行 from 到 to 所在分钟内到期的计划动作，每个隧道只执行最后一个动作
+// reconcile 为true时启动所有处于时间段内但未运行的隧道
+func (s *tunnelService) runSchedules(from, to time.Time, reconcile bool) {
+	tunnels, err := s.tunnelRepo.GetAll()
+	if err != nil {
+		log.Printf("Failed to get tunnels for schedules: %v", err)
+		return
+	}
+
+	for i := range tunnels {
+		tunnel := &tunnels[i]
+		sched, err := tunnelSchedule(tunnel)
+		if err != nil {
+			log.Printf("Invalid schedule for tunnel %d: %v", tunnel.ID, err)
+			continue
+		}
+		if sched == nil {
+			continue
+		}
+
+		action := sched.DueBetween(from, to)
+		if reconcile && sched.Windowed() && sched.Active(to) {
+			action = schedule.ActionStart
+		}
+
+		switch action {
+		case schedule.ActionStart:
+			s.scheduledStart(tunnel, describeSchedule(tunnel))
+		case schedule.ActionStop:
+			s.scheduledStop(tunnel, describeSchedule(tunnel))
+		}
+	}
+}
+
+// scheduledStart 按计划启动隧道，先启动未运行的依赖隧道
+func (s *tunnelService) scheduledStart(tunnel *models.Tunnel, reason string) {
+	if s.IsTunnelRunning(tunnel.ID) {
+		return
+	}
+
+	plan, err := s.StartOrder([]uint{tunnel.ID})
+	if err != nil {
+		s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("Scheduled start failed (%s): %v", reason, err))
+		return
+	}
+	for _, t := range plan {
+		if s.IsTunnelRunning(t.ID) {
+			continue
+		}
+		if err := s.StartTunnel(t.ID); err != nil {
+			if errors.Is(err, errShuttingDown) {
+				return
+			}
+			message := fmt.Sprintf("Scheduled start failed (%s): %v", reason, err)
+			if t.ID != tunnel.ID {
+				message = fmt.Sprintf("Scheduled start failed (%s): dependency tunnel %d: %v", reason, t.ID, err)
+			}
+			s.addConnectionLog(tunnel.ID, models.LogEventError, message)
+			log.Printf("Scheduled start of tunnel %d failed: %v", tunnel.ID, err)
+			return
+		}
+	}
+
+	s.addConnectionLog(tunnel.ID, models.LogEventStart, fmt.Sprintf("Tunnel started by schedule (%s)", reason))
+	log.Printf("Tunnel %d started by schedule", tunnel.ID)
+}
+
+// scheduledStop 按计划停止隧道，依赖隧道可能被其他隧道使用，不停止
+func (s *tunnelService) scheduledStop(tunnel *models.Tunnel, reason string) {
+	if !s.IsTunnelRunning(tunnel.ID) {
+		return
+	}
+
+	if err := s.StopTunnel(tunnel.ID); err != nil {
+		s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("Scheduled stop failed (%s): %v", reason, err))
+		log.Printf("Scheduled stop of tunnel %d failed: %v", tunnel.ID, err)
+		return
+	}
+
+	s.addConnectionLog(tunnel.ID, models.LogEventStop, fmt.Sprintf("Tunnel stopped by schedule (%s)", reason))
+	log.Printf("Tunnel %d stopped by schedule", tunnel.ID)
+}
+
+// describeSchedule 返回连接日志中使用的计划描述
+func describeSchedule(tunnel *models.Tunnel) string {
+	var parts []string
+	if len(tunnel.TimeWindows) > 0 {
+		parts = append(parts, "windows "+strings.Join(tunnel.TimeWindows, "; "))
+	}
+	if tunnel.StartCron != "" {
+		parts = append(parts, fmt.Sprintf("start %q", tunnel.StartCron))
+	}
+	if tunnel.StopCron != "" {
+		parts = append(parts, fmt.Sprintf("stop %q", tunnel.StopCron))
+	}
+	if tunnel.Timezone != "" {
+		parts = append(parts, tunnel.Timezone)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/KodaTao/drilling/internal/labels"
 	"github.com/KodaTao/drilling/internal/models"
+	"github.com/KodaTao/drilling/internal/ratelimit"
 	"github.com/KodaTao/drilling/internal/repository"
 	"github.com/KodaTao/drilling/internal/socks5"
 	"golang.org/x/crypto/ssh"
@@ -20,8 +23,8 @@ import (
 // TunnelService 隧道服务接口
 type TunnelService interface {
 	CreateTunnel(tunnel *models.Tunnel) error
-	CreateMultipleLocalForwards(hostID uint, localServices []LocalServiceMapping) ([]models.Tunnel, error)
-	CreateDynamicSOCKS5Tunnel(hostID uint, name, description string, autoStart bool) (*models.Tunnel, error)
+	CreateMultipleLocalForwards(hostID, ownerID uint, localServices []LocalServiceMapping) ([]models.Tunnel, error)
+	CreateDynamicSOCKS5Tunnel(hostID, ownerID uint, name, description string, autoStart bool) (*models.Tunnel, error)
 	FindAvailablePort(startPort, endPort int, address string) (int, error)
 	GetTunnel(id uint) (*models.Tunnel, error)
 	GetAllTunnels() ([]models.Tunnel, error)
@@ -29,13 +32,24 @@ type TunnelService interface {
 	UpdateTunnel(tunnel *models.Tunnel) error
 	DeleteTunnel(id uint) error
 	StartTunnel(id uint) error
+	StartTunnelOnDemand(id uint) error
 	StopTunnel(id uint) error
 	RestartTunnel(id uint) error
+	StartOrder(ids []uint) ([]models.Tunnel, error)
 	GetTunnelStatus(id uint) (string, error)
+	IsTunnelRunning(id uint) bool
 	StartAutoTunnels() error
+	RunAutoStart(ctx context.Context, opts AutoStartOptions) error
+	Shutdown(ctx context.Context) error
 	StopAllTunnels() error
 	GetConnectionLogs(tunnelID uint, limit int) ([]models.ConnectionLog, error)
+	QueryConnectionLogs(filter repository.ConnectionLogFilter) ([]models.ConnectionLog, string, error)
 	CheckServiceHealth(localAddress string, localPort int) error
+	GetQuotaUsage(id uint) (*QuotaUsage, error)
+	MonitorQuotas(ctx context.Context)
+	GetScheduleStatus(id uint) (*ScheduleStatus, error)
+	RunScheduler(ctx context.Context)
+	GetHealthStatus(id uint) (*HealthStatus, error)
 }
 
 // tunnelService 隧道服务实现
@@ -43,28 +57,41 @@ type tunnelService struct {
 	tunnelRepo     repository.TunnelRepository
 	hostService    HostService
 	trafficService TrafficService
+	sessionService SessionService
 	activeTunnels  map[uint]*activeTunnel
+	healthRestarts map[uint]int // 健康检查失败后连续自动重启的次数，检查通过后清零
+	shuttingDown   bool
 	mutex          sync.RWMutex
 }
 
 // activeTunnel 活动隧道信息
 type activeTunnel struct {
-	tunnel     *models.Tunnel
-	sshClient  *ssh.Client
-	listener   net.Listener
-	ctx        context.Context
-	cancel     context.CancelFunc
-	startTime  time.Time
+	tunnel         *models.Tunnel
+	sshClient      *ssh.Client
+	listener       net.Listener
+	ctx            context.Context
+	cancel         context.CancelFunc
+	startTime      time.Time
+	uploadLimits   []*ratelimit.Bucket // 隧道及主机的上行限速器
+	downloadLimits []*ratelimit.Bucket // 隧道及主机的下行限速器
+	slots          chan struct{}       // 并发连接槽位，为nil表示不限制
+	queue          chan struct{}       // 排队等待槽位的连接，为nil表示不排队
+	conns          sync.WaitGroup      // 进行中的连接
+	acceptDone     chan struct{}       // 接受循环退出时关闭
+	onDemand       *onDemandSSH        // 按需建立的SSH连接，为nil表示启动时已建立连接
+	startOnDemand  bool                // 通过 StartTunnelOnDemand 启动，重启时保持按需方式
+	health         *healthState        // 健康检查状态，为nil表示不检查
 }
 
 // NewTunnelService 创建隧道服务实例
-func NewTunnelService(tunnelRepo repository.TunnelRepository, hostService HostService) TunnelService {
-	trafficService := NewTrafficService(tunnelRepo)
+func NewTunnelService(tunnelRepo repository.TunnelRepository, hostService HostService, trafficService TrafficService, sessionService SessionService) TunnelService {
 	return &tunnelService{
 		tunnelRepo:     tunnelRepo,
 		hostService:    hostService,
 		trafficService: trafficService,
+		sessionService: sessionService,
 		activeTunnels:  make(map[uint]*activeTunnel),
+		healthRestarts: make(map[uint]int),
 	}
 }
 
@@ -74,6 +101,9 @@ func (s *tunnelService) CreateTunnel(tunnel *models.Tunnel) error {
 	if err := s.validateTunnelConfig(tunnel); err != nil {
 		return err
 	}
+	if err := s.validateDependencies(tunnel); err != nil {
+		return err
+	}
 
 	// 检查端口是否已被占用
 	if err := s.checkPortAvailability(tunnel); err != nil {
@@ -87,7 +117,7 @@ func (s *tunnelService) CreateTunnel(tunnel *models.Tunnel) error {
 }
 
 // CreateMultipleLocalForwards 创建多个本地服务的远程端口映射
-func (s *tunnelService) CreateMultipleLocalForwards(hostID uint, localServices []LocalServiceMapping) ([]models.Tunnel, error) {
+func (s *tunnelService) CreateMultipleLocalForwards(hostID, ownerID uint, localServices []LocalServiceMapping) ([]models.Tunnel, error) {
 	var createdTunnels []models.Tunnel
 	var errors []string
 
@@ -96,6 +126,7 @@ func (s *tunnelService) CreateMultipleLocalForwards(hostID uint, localServices [
 			Name:          service.Name,
 			Type:          models.TunnelTypeRemoteForward,
 			HostID:        hostID,
+			OwnerID:       ownerID,
 			LocalAddress:  service.LocalAddress,
 			LocalPort:     service.LocalPort,
 			RemoteAddress: service.RemoteAddress,
@@ -151,12 +182,12 @@ func (s *tunnelService) UpdateTunnel(tunnel *models.Tunnel) error {
 	if err := s.validateTunnelConfig(tunnel); err != nil {
 		return err
 	}
+	if err := s.validateDependencies(tunnel); err != nil {
+		return err
+	}
 
-	// 如果隧道正在运行，需要重启
-	s.mutex.RLock()
-	isActive := s.activeTunnels[tunnel.ID] != nil
-	s.mutex.RUnlock()
-
+	// 如果隧道正在运行，需要以原来的启动方式重启
+	isActive, onDemand := s.runningMode(tunnel.ID)
 	if isActive {
 		// 停止隧道
 		if err := s.StopTunnel(tunnel.ID); err != nil {
@@ -169,7 +200,7 @@ func (s *tunnelService) UpdateTunnel(tunnel *models.Tunnel) error {
 		}
 
 		// 重新启动隧道
-		return s.StartTunnel(tunnel.ID)
+		return s.startTunnel(tunnel.ID, onDemand)
 	}
 
 	return s.tunnelRepo.Update(tunnel)
@@ -180,11 +211,30 @@ func (s *tunnelService) DeleteTunnel(id uint) error {
 	// 先停止隧道
 	s.StopTunnel(id)
 
-	return s.tunnelRepo.Delete(id)
+	if err := s.tunnelRepo.Delete(id); err != nil {
+		return err
+	}
+	s.resetHealthRestarts(id)
+
+	// 其他隧道不再依赖已删除的隧道
+	return s.removeDependency(id)
 }
 
 // StartTunnel 启动隧道
 func (s *tunnelService) StartTunnel(id uint) error {
+	return s.startTunnel(id, false)
+}
+
+// runningMode 返回隧道是否正在运行，以及是否通过 StartTunnelOnDemand 启动
+func (s *tunnelService) runningMode(id uint) (bool, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	at := s.activeTunnels[id]
+	return at != nil, at != nil && at.startOnDemand
+}
+
+// startTunnel 启动隧道，onDemand 为true或隧道设置了按需连接时只监听端口，SSH连接在首个客户端连接时建立
+func (s *tunnelService) startTunnel(id uint, onDemand bool) error {
 	tunnel, err := s.tunnelRepo.GetByID(id)
 	if err != nil {
 		return fmt.Errorf("failed to get tunnel: %v", err)
@@ -192,342 +242,251 @@ func (s *tunnelService) StartTunnel(id uint) error {
 
 	// 检查隧道是否已经在运行
 	s.mutex.RLock()
+	if s.shuttingDown {
+		s.mutex.RUnlock()
+		return errShuttingDown
+	}
 	if s.activeTunnels[id] != nil {
 		s.mutex.RUnlock()
 		return errors.New("tunnel is already running")
 	}
 	s.mutex.RUnlock()
 
+	startOnDemand := onDemand
+	onDemand = onDemand || tunnel.Lazy
+	if onDemand && tunnel.Type == models.TunnelTypeRemoteForward {
+		return errors.New("remote forward tunnels cannot be started on demand")
+	}
+
+	// 检查流量配额
+	if err := s.checkQuota(tunnel); err != nil {
+		return err
+	}
+
 	// 获取主机信息
 	host, err := s.hostService.GetHost(tunnel.HostID)
 	if err != nil {
 		return fmt.Errorf("failed to get host: %v", err)
 	}
 
-	// 创建SSH连接
-	sshClient, err := s.createSSHConnection(host)
-	if err != nil {
-		s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("SSH connection failed: %v", err))
-		s.tunnelRepo.UpdateStatus(id, models.TunnelStatusError)
-		return fmt.Errorf("failed to create SSH connection: %v", err)
+	// 创建SSH连接，按需启动的隧道推迟到首个客户端连接时
+	var sshClient *ssh.Client
+	if !onDemand {
+		sshClient, err = s.createSSHConnection(host)
+		if err != nil {
+			s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("SSH connection failed: %v", err))
+			s.tunnelRepo.UpdateStatus(id, models.TunnelStatusError)
+			return fmt.Errorf("failed to create SSH connection: %v", err)
+		}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// 活动隧道信息需要在启动转发前创建，转发协程通过它获取监听器和限速器
+	hostUpload, hostDownload := s.hostService.RateLimiters(host)
+	activeTunnel := &activeTunnel{
+		tunnel:         tunnel,
+		sshClient:      sshClient,
+		ctx:            ctx,
+		cancel:         cancel,
+		startTime:      time.Now(),
+		uploadLimits:   []*ratelimit.Bucket{ratelimit.NewBucket(tunnel.UploadLimit), hostUpload},
+		downloadLimits: []*ratelimit.Bucket{ratelimit.NewBucket(tunnel.DownloadLimit), hostDownload},
+		acceptDone:     make(chan struct{}),
+		startOnDemand:  startOnDemand,
+	}
+	if tunnel.MaxConnections > 0 {
+		activeTunnel.slots = make(chan struct{}, tunnel.MaxConnections)
+		if tunnel.OverflowPolicy == models.OverflowPolicyQueue {
+			activeTunnel.queue = make(chan struct{}, min(tunnel.MaxConnections, maxConnectionQueue))
+		}
+	}
+	if onDemand {
+		activeTunnel.onDemand = newOnDemandSSH(host, tunnel.LazyTimeout)
+	}
+	if tunnel.HealthCheck != "" {
+		activeTunnel.health = &healthState{}
+	}
+
 	// 根据隧道类型启动相应的转发
 	switch tunnel.Type {
 	case models.TunnelTypeLocalForward:
-		err = s.startLocalForward(ctx, tunnel, sshClient)
+		err = s.startLocalForward(ctx, activeTunnel)
 	case models.TunnelTypeRemoteForward:
-		err = s.startRemoteForward(ctx, tunnel, sshClient)
+		err = s.startRemoteForward(ctx, activeTunnel)
 	case models.TunnelTypeDynamic:
-		err = s.startDynamicForward(ctx, tunnel, sshClient)
+		err = s.startDynamicForward(ctx, activeTunnel)
 	default:
-		cancel()
-		sshClient.Close()
+		releaseTunnel(activeTunnel)
 		return errors.New("unsupported tunnel type")
 	}
 
 	if err != nil {
-		cancel()
-		sshClient.Close()
+		releaseTunnel(activeTunnel)
 		s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("Failed to start tunnel: %v", err))
 		s.tunnelRepo.UpdateStatus(id, models.TunnelStatusError)
 		return fmt.Errorf("failed to start tunnel: %v", err)
 	}
 
 	// 保存活动隧道信息
-	activeTunnel := &activeTunnel{
-		tunnel:    tunnel,
-		sshClient: sshClient,
-		ctx:       ctx,
-		cancel:    cancel,
-		startTime: time.Now(),
-	}
-
 	s.mutex.Lock()
 	s.activeTunnels[id] = activeTunnel
 	s.mutex.Unlock()
 
+	if activeTunnel.health != nil {
+		go s.runHealthChecks(activeTunnel)
+	}
+
 	// 更新隧道状态
-	s.tunnelRepo.UpdateStatus(id, models.TunnelStatusActive)
-	s.addConnectionLog(tunnel.ID, models.LogEventStart, "Tunnel started successfully")
+	if onDemand {
+		s.updateOnDemandStatus(activeTunnel)
+		s.addConnectionLog(tunnel.ID, models.LogEventStart, "Tunnel started on demand, SSH connects on first client connection")
+	} else {
+		s.tunnelRepo.UpdateStatusWithReason(id, models.TunnelStatusActive, "")
+		s.addConnectionLog(tunnel.ID, models.LogEventStart, "Tunnel started successfully")
+	}
 
 	return nil
 }
 
 // startLocalForward 启动本地转发（远程服务映射到本地）
-func (s *tunnelService) startLocalForward(ctx context.Context, tunnel *models.Tunnel, sshClient *ssh.Client) error {
+func (s *tunnelService) startLocalForward(ctx context.Context, at *activeTunnel) error {
+	tunnel := at.tunnel
+
 	// 监听本地端口
 	localAddr := fmt.Sprintf("%s:%d", tunnel.LocalAddress, tunnel.LocalPort)
 	listener, err := net.Listen("tcp", localAddr)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %v", localAddr, err)
 	}
+	at.listener = listener
 
-	// 保存listener到activeTunnel（需要在调用处设置）
-	s.mutex.Lock()
-	if activeTunnel := s.activeTunnels[tunnel.ID]; activeTunnel != nil {
-		activeTunnel.listener = listener
-	}
-	s.mutex.Unlock()
-
-	go func() {
-		defer func() {
-			log.Printf("Local forward goroutine exiting for tunnel %d", tunnel.ID)
-			listener.Close()
-		}()
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Printf("Context cancelled for tunnel %d local forward", tunnel.ID)
-				return
-			default:
-				// 设置accept的超时，这样可以定期检查context
-				if tcpListener, ok := listener.(*net.TCPListener); ok {
-					tcpListener.SetDeadline(time.Now().Add(1 * time.Second))
-				}
-
-				// 接受本地连接
-				localConn, err := listener.Accept()
-				if err != nil {
-					// 检查是否是超时错误
-					if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
-						// 超时是正常的，继续循环检查context
-						continue
-					}
-					if ctx.Err() != nil {
-						return // 上下文已取消
-					}
-					log.Printf("Failed to accept local connection for tunnel %d: %v", tunnel.ID, err)
-					continue
-				}
-
-				// 清除deadline
-				if tcpListener, ok := listener.(*net.TCPListener); ok {
-					tcpListener.SetDeadline(time.Time{})
-				}
-
-				// 异步处理连接
-				go s.handleLocalForward(ctx, tunnel, sshClient, localConn)
-			}
-		}
-	}()
+	go s.acceptLoop(ctx, at, "Local forward", s.handleLocalForward)
 
 	return nil
 }
 
 // handleLocalForward 处理本地转发连接
-func (s *tunnelService) handleLocalForward(ctx context.Context, tunnel *models.Tunnel, sshClient *ssh.Client, localConn net.Conn) {
+func (s *tunnelService) handleLocalForward(ctx context.Context, at *activeTunnel, localConn *trackedConn) {
 	defer localConn.Close()
+	tunnel := at.tunnel
 
 	// 连接远程地址
-	remoteAddr := fmt.Sprintf("%s:%d", tunnel.RemoteAddress, tunnel.RemotePort)
+	remoteAddr := net.JoinHostPort(tunnel.RemoteAddress, strconv.Itoa(tunnel.RemotePort))
+	localConn.setTarget(remoteAddr)
+
+	sshClient, release, err := s.acquireSSH(at)
+	if err != nil {
+		s.addClientLog(tunnel.ID, models.LogEventError, localConn.RemoteAddr().String(), fmt.Sprintf("SSH connection unavailable: %v", err))
+		localConn.setCloseReason(err.Error())
+		return
+	}
+	defer release()
+
 	remoteConn, err := sshClient.Dial("tcp", remoteAddr)
 	if err != nil {
 		log.Printf("Failed to dial remote address %s for tunnel %d: %v", remoteAddr, tunnel.ID, err)
-		s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("Failed to connect to %s: %v", remoteAddr, err))
+		s.addClientLog(tunnel.ID, models.LogEventError, localConn.RemoteAddr().String(), fmt.Sprintf("Failed to connect to %s: %v", remoteAddr, err))
+		localConn.setCloseReason(fmt.Sprintf("dial failed: %v", err))
 		return
 	}
 	defer remoteConn.Close()
 
-	s.addConnectionLog(tunnel.ID, models.LogEventConnect, fmt.Sprintf("Connection established: %s -> %s", localConn.RemoteAddr(), remoteAddr))
+	s.addClientLog(tunnel.ID, models.LogEventConnect, localConn.RemoteAddr().String(), fmt.Sprintf("Connection established: %s -> %s", localConn.RemoteAddr(), remoteAddr))
 
 	// 双向数据转发
-	done := make(chan struct{}, 2)
-
-	// 本地 -> 远程
-	go func() {
-		defer func() { done <- struct{}{} }()
-		io.Copy(remoteConn, localConn)
-	}()
+	relay(ctx, localConn, remoteConn)
 
-	// 远程 -> 本地
-	go func() {
-		defer func() { done <- struct{}{} }()
-		io.Copy(localConn, remoteConn)
-	}()
-
-	// 等待任一方向完成或上下文取消
-	select {
-	case <-done:
-	case <-ctx.Done():
-	}
-
-	s.addConnectionLog(tunnel.ID, models.LogEventDisconnect, "Connection closed")
+	s.addClientLog(tunnel.ID, models.LogEventDisconnect, localConn.RemoteAddr().String(), "Connection closed")
 }
 
 // startRemoteForward 启动远程转发（本地服务映射到远程）
-func (s *tunnelService) startRemoteForward(ctx context.Context, tunnel *models.Tunnel, sshClient *ssh.Client) error {
+func (s *tunnelService) startRemoteForward(ctx context.Context, at *activeTunnel) error {
+	tunnel := at.tunnel
+
 	// 在远程主机上监听端口
 	remoteAddr := fmt.Sprintf("%s:%d", tunnel.RemoteAddress, tunnel.RemotePort)
-	listener, err := sshClient.Listen("tcp", remoteAddr)
+	listener, err := at.sshClient.Listen("tcp", remoteAddr)
 	if err != nil {
 		return fmt.Errorf("failed to listen on remote %s: %v", remoteAddr, err)
 	}
+	at.listener = listener
 
-	s.mutex.Lock()
-	if activeTunnel := s.activeTunnels[tunnel.ID]; activeTunnel != nil {
-		activeTunnel.listener = listener
-	}
-	s.mutex.Unlock()
-
-	go func() {
-		defer func() {
-			log.Printf("Remote forward goroutine exiting for tunnel %d", tunnel.ID)
-			listener.Close()
-		}()
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Printf("Context cancelled for tunnel %d remote forward", tunnel.ID)
-				return
-			default:
-				// 接受远程连接
-				remoteConn, err := listener.Accept()
-				if err != nil {
-					if ctx.Err() != nil {
-						return
-					}
-					log.Printf("Failed to accept remote connection for tunnel %d: %v", tunnel.ID, err)
-					continue
-				}
-
-				// 异步处理连接
-				go s.handleRemoteForward(ctx, tunnel, remoteConn)
-			}
-		}
-	}()
+	go s.acceptLoop(ctx, at, "Remote forward", s.handleRemoteForward)
 
 	return nil
 }
 
 // handleRemoteForward 处理远程转发连接
-func (s *tunnelService) handleRemoteForward(ctx context.Context, tunnel *models.Tunnel, remoteConn net.Conn) {
+func (s *tunnelService) handleRemoteForward(ctx context.Context, at *activeTunnel, remoteConn *trackedConn) {
 	defer remoteConn.Close()
+	tunnel := at.tunnel
 
 	// 连接本地地址
-	localAddr := fmt.Sprintf("%s:%d", tunnel.LocalAddress, tunnel.LocalPort)
+	localAddr := net.JoinHostPort(tunnel.LocalAddress, strconv.Itoa(tunnel.LocalPort))
+	remoteConn.setTarget(localAddr)
 	localConn, err := net.Dial("tcp", localAddr)
 	if err != nil {
 		log.Printf("Failed to dial local address %s for tunnel %d: %v", localAddr, tunnel.ID, err)
-		s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("Failed to connect to %s: %v", localAddr, err))
+		s.addClientLog(tunnel.ID, models.LogEventError, remoteConn.RemoteAddr().String(), fmt.Sprintf("Failed to connect to %s: %v", localAddr, err))
+		remoteConn.setCloseReason(fmt.Sprintf("dial failed: %v", err))
 		return
 	}
 	defer localConn.Close()
 
-	s.addConnectionLog(tunnel.ID, models.LogEventConnect, fmt.Sprintf("Connection established: %s -> %s", remoteConn.RemoteAddr(), localAddr))
+	s.addClientLog(tunnel.ID, models.LogEventConnect, remoteConn.RemoteAddr().String(), fmt.Sprintf("Connection established: %s -> %s", remoteConn.RemoteAddr(), localAddr))
 
 	// 双向数据转发
-	done := make(chan struct{}, 2)
-
-	// 远程 -> 本地
-	go func() {
-		defer func() { done <- struct{}{} }()
-		io.Copy(localConn, remoteConn)
-	}()
-
-	// 本地 -> 远程
-	go func() {
-		defer func() { done <- struct{}{} }()
-		io.Copy(remoteConn, localConn)
-	}()
-
-	select {
-	case <-done:
-	case <-ctx.Done():
-	}
+	relay(ctx, remoteConn, localConn)
 
-	s.addConnectionLog(tunnel.ID, models.LogEventDisconnect, "Connection closed")
+	s.addClientLog(tunnel.ID, models.LogEventDisconnect, remoteConn.RemoteAddr().String(), "Connection closed")
 }
 
 // startDynamicForward 启动动态转发（SOCKS5代理）
-func (s *tunnelService) startDynamicForward(ctx context.Context, tunnel *models.Tunnel, sshClient *ssh.Client) error {
+func (s *tunnelService) startDynamicForward(ctx context.Context, at *activeTunnel) error {
+	tunnel := at.tunnel
+
 	// 监听本地端口作为SOCKS5代理
 	localAddr := fmt.Sprintf("%s:%d", tunnel.LocalAddress, tunnel.LocalPort)
 	listener, err := net.Listen("tcp", localAddr)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %v", localAddr, err)
 	}
+	at.listener = listener
 
-	s.mutex.Lock()
-	if activeTunnel := s.activeTunnels[tunnel.ID]; activeTunnel != nil {
-		activeTunnel.listener = listener
-	}
-	s.mutex.Unlock()
-
-	go func() {
-		defer func() {
-			log.Printf("SOCKS5 goroutine exiting for tunnel %d", tunnel.ID)
-			listener.Close()
-		}()
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Printf("Context cancelled for tunnel %d SOCKS5", tunnel.ID)
-				return
-			default:
-				// 设置accept的超时，这样可以定期检查context
-				if tcpListener, ok := listener.(*net.TCPListener); ok {
-					tcpListener.SetDeadline(time.Now().Add(1 * time.Second))
-				}
-
-				conn, err := listener.Accept()
-				if err != nil {
-					// 检查是否是超时错误
-					if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
-						// 超时是正常的，继续循环检查context
-						continue
-					}
-					if ctx.Err() != nil {
-						return
-					}
-					log.Printf("Failed to accept SOCKS connection for tunnel %d: %v", tunnel.ID, err)
-					continue
-				}
-
-				// 清除deadline
-				if tcpListener, ok := listener.(*net.TCPListener); ok {
-					tcpListener.SetDeadline(time.Time{})
-				}
-
-				// 异步处理SOCKS5连接
-				go s.handleSOCKS5(ctx, tunnel, sshClient, conn)
-			}
-		}
-	}()
+	go s.acceptLoop(ctx, at, "SOCKS5", s.handleSOCKS5)
 
 	return nil
 }
 
 // handleSOCKS5 处理SOCKS5连接
-func (s *tunnelService) handleSOCKS5(ctx context.Context, tunnel *models.Tunnel, sshClient *ssh.Client, conn net.Conn) {
+func (s *tunnelService) handleSOCKS5(ctx context.Context, at *activeTunnel, conn *trackedConn) {
 	defer conn.Close()
+	tunnel := at.tunnel
 
-	s.addConnectionLog(tunnel.ID, models.LogEventConnect, fmt.Sprintf("SOCKS5 connection from %s", conn.RemoteAddr()))
+	clientAddr := conn.RemoteAddr().String()
+	s.addClientLog(tunnel.ID, models.LogEventConnect, clientAddr, fmt.Sprintf("SOCKS5 connection from %s", clientAddr))
 
-	// 增加连接计数
-	if ts, ok := s.trafficService.(*trafficService); ok {
-		ts.IncrementConnection(tunnel.ID)
-		defer ts.DecrementConnection(tunnel.ID)
+	sshClient, release, err := s.acquireSSH(at)
+	if err != nil {
+		conn.setCloseReason(err.Error())
+		s.addClientLog(tunnel.ID, models.LogEventError, clientAddr, fmt.Sprintf("SSH connection unavailable: %v", err))
+		return
 	}
+	defer release()
 
-	// 创建流量记录器
-	trafficLogger := NewTunnelTrafficLogger(tunnel.ID, s.trafficService)
-
-	// 创建带流量统计的SOCKS5服务器实例
-	socksServer := socks5.NewSOCKS5ServerWithTrafficLogger(sshClient, trafficLogger)
+	// 流量统计和限速由包装后的连接完成
+	socksServer := socks5.NewSOCKS5Server(sshClient)
+	socksServer.SetTargetCallback(conn.setTarget)
 
 	// 处理SOCKS5连接
 	if err := socksServer.HandleConnection(ctx, conn); err != nil {
-		s.addConnectionLog(tunnel.ID, models.LogEventError, fmt.Sprintf("SOCKS5 connection error: %v", err))
+		conn.setCloseReason(err.Error())
+		s.addClientLog(tunnel.ID, models.LogEventError, clientAddr, fmt.Sprintf("SOCKS5 connection error: %v", err))
 		log.Printf("SOCKS5 connection error for tunnel %d: %v", tunnel.ID, err)
 	}
 
-	s.addConnectionLog(tunnel.ID, models.LogEventDisconnect, "SOCKS5 connection closed")
+	s.addClientLog(tunnel.ID, models.LogEventDisconnect, clientAddr, "SOCKS5 connection closed")
 }
 
 // StopTunnel 停止隧道
@@ -540,38 +499,24 @@ func (s *tunnelService) StopTunnel(id uint) error {
 	s.mutex.Unlock()
 
 	if activeTunnel == nil {
+		// 停止被暂停的隧道会取消配额周期轮转后的自动恢复
+		if tunnel, err := s.tunnelRepo.GetByID(id); err == nil && tunnel.Status == models.TunnelStatusSuspended {
+			s.tunnelRepo.UpdateStatusWithReason(id, models.TunnelStatusInactive, "")
+			s.addConnectionLog(id, models.LogEventStop, "Suspended tunnel stopped, automatic resume cancelled")
+			return nil
+		}
 		return errors.New("tunnel is not running")
 	}
 
 	log.Printf("Stopping tunnel %d", id)
 
 	// 第一步：立即关闭监听器以释放端口
-	if activeTunnel.listener != nil {
-		log.Printf("Closing listener for tunnel %d", id)
-		if err := activeTunnel.listener.Close(); err != nil {
-			// listener.Close() 可能被多次调用，忽略已关闭的错误
-			if !strings.Contains(err.Error(), "use of closed network connection") {
-				log.Printf("Error closing listener for tunnel %d: %v", id, err)
-			}
-		} else {
-			log.Printf("Successfully closed listener for tunnel %d", id)
-		}
-	}
-
-	// 第二步：取消上下文，通知goroutines退出
-	activeTunnel.cancel()
+	closeListener(activeTunnel)
 
-	// 第三步：关闭SSH连接
-	if activeTunnel.sshClient != nil {
-		log.Printf("Closing SSH client for tunnel %d", id)
-		if err := activeTunnel.sshClient.Close(); err != nil {
-			if !strings.Contains(err.Error(), "use of closed network connection") {
-				log.Printf("Error closing SSH client for tunnel %d: %v", id, err)
-			}
-		}
-	}
+	// 第二步：取消上下文通知goroutines退出，并关闭SSH连接
+	releaseTunnel(activeTunnel)
 
-	// 第四步：等待一小段时间确保资源完全释放
+	// 第三步：等待一小段时间确保资源完全释放
 	time.Sleep(200 * time.Millisecond)
 
 	// 更新状态
@@ -582,15 +527,16 @@ func (s *tunnelService) StopTunnel(id uint) error {
 	return nil
 }
 
-// RestartTunnel 重启隧道
+// RestartTunnel 重启隧道，按需启动的隧道重启后仍然按需连接
 func (s *tunnelService) RestartTunnel(id uint) error {
+	_, onDemand := s.runningMode(id)
 	if err := s.StopTunnel(id); err != nil && err.Error() != "tunnel is not running" {
 		return err
 	}
 
 	time.Sleep(1 * time.Second) // 短暂延迟
 
-	return s.StartTunnel(id)
+	return s.startTunnel(id, onDemand)
 }
 
 // GetTunnelStatus 获取隧道状态
@@ -600,6 +546,12 @@ func (s *tunnelService) GetTunnelStatus(id uint) (string, error) {
 	s.mutex.RUnlock()
 
 	if activeTunnel != nil {
+		if activeTunnel.degraded() {
+			return models.TunnelStatusDegraded, nil
+		}
+		if activeTunnel.onDemand != nil {
+			return activeTunnel.onDemand.status(), nil
+		}
 		return models.TunnelStatusActive, nil
 	}
 
@@ -611,20 +563,9 @@ func (s *tunnelService) GetTunnelStatus(id uint) (string, error) {
 	return tunnel.Status, nil
 }
 
-// StartAutoTunnels 启动自动启动的隧道
+// StartAutoTunnels 启动自动启动的隧道，不重试
 func (s *tunnelService) StartAutoTunnels() error {
-	tunnels, err := s.tunnelRepo.GetAutoStartTunnels()
-	if err != nil {
-		return err
-	}
-
-	for _, tunnel := range tunnels {
-		if err := s.StartTunnel(tunnel.ID); err != nil {
-			log.Printf("Failed to start auto tunnel %d (%s): %v", tunnel.ID, tunnel.Name, err)
-		}
-	}
-
-	return nil
+	return s.RunAutoStart(context.Background(), AutoStartOptions{Concurrency: defaultAutoStartConcurrency})
 }
 
 // StopAllTunnels 停止所有隧道
@@ -650,6 +591,30 @@ func (s *tunnelService) GetConnectionLogs(tunnelID uint, limit int) ([]models.Co
 	return s.tunnelRepo.GetConnectionLogs(tunnelID, limit)
 }
 
+// QueryConnectionLogs 按条件分页查询连接日志，返回下一页游标（没有更多数据时为空）
+func (s *tunnelService) QueryConnectionLogs(filter repository.ConnectionLogFilter) ([]models.ConnectionLog, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	// 多取一条用于判断是否还有下一页
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	logs, err := s.tunnelRepo.QueryConnectionLogs(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(logs) > limit {
+		logs = logs[:limit]
+		nextCursor = strconv.FormatUint(uint64(logs[limit-1].ID), 10)
+	}
+
+	return logs, nextCursor, nil
+}
+
 // validateTunnelConfig 验证隧道配置
 func (s *tunnelService) validateTunnelConfig(tunnel *models.Tunnel) error {
 	if tunnel.Name == "" {
@@ -664,6 +629,14 @@ func (s *tunnelService) validateTunnelConfig(tunnel *models.Tunnel) error {
 		return errors.New("local port is required")
 	}
 
+	if tunnel.MaxConnections < 0 || tunnel.IdleTimeout < 0 || tunnel.MaxLifetime < 0 {
+		return errors.New("connection limits must not be negative")
+	}
+	if tunnel.OverflowPolicy == "" {
+		tunnel.OverflowPolicy = models.OverflowPolicyReject
+	}
+	tunnel.QuotaPeriod = effectiveQuotaPeriod(tunnel)
+
 	switch tunnel.Type {
 	case models.TunnelTypeLocalForward:
 		if tunnel.RemoteAddress == "" {
@@ -685,7 +658,93 @@ func (s *tunnelService) validateTunnelConfig(tunnel *models.Tunnel) error {
 		return errors.New("invalid tunnel type")
 	}
 
-	return nil
+	domains, err := normalizeProxyDomains(tunnel.ProxyDomains)
+	if err != nil {
+		return err
+	}
+	if len(domains) > 0 && tunnel.Type != models.TunnelTypeDynamic {
+		return errors.New("proxy domains are only supported for dynamic tunnels")
+	}
+	tunnel.ProxyDomains = domains
+
+	if tunnel.Lazy && tunnel.Type == models.TunnelTypeRemoteForward {
+		return errors.New("lazy mode is only supported for local forward and dynamic tunnels")
+	}
+	if tunnel.LazyTimeout < 0 {
+		return errors.New("lazy timeout must not be negative")
+	}
+
+	tags, err := normalizeTags(tunnel.Tags)
+	if err != nil {
+		return err
+	}
+	tunnel.Tags = tags
+
+	if tunnel.Labels, err = normalizeLabels(tunnel.Labels); err != nil {
+		return err
+	}
+
+	if err := normalizeSchedule(tunnel); err != nil {
+		return err
+	}
+
+	if err := normalizeHealthCheck(tunnel); err != nil {
+		return err
+	}
+
+	return normalizeDependsOn(tunnel)
+}
+
+// proxyDomainPattern 代理域名的格式，只允许小写字母、数字、连字符和点
+var proxyDomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// normalizeProxyDomains 规范化代理域名，转为小写，去掉 *. 和 . 前缀及重复项
+func normalizeProxyDomains(domains models.StringList) (models.StringList, error) {
+	var result models.StringList
+	seen := make(map[string]bool)
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		domain = strings.TrimPrefix(strings.TrimPrefix(domain, "*"), ".")
+		if domain == "" || seen[domain] {
+			continue
+		}
+		if !proxyDomainPattern.MatchString(domain) {
+			return nil, fmt.Errorf("invalid proxy domain %q", domain)
+		}
+		seen[domain] = true
+		result = append(result, domain)
+	}
+	return result, nil
+}
+
+// tagPattern 标签的格式，以字母或数字开头，最长64个字符
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// normalizeTags 规范化标签，去掉空白和重复项
+func normalizeTags(tags models.StringList) (models.StringList, error) {
+	var result models.StringList
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// normalizeLabels 规范化主机和隧道的标签，检查键和值的格式
+func normalizeLabels(l models.Labels) (models.Labels, error) {
+	normalized, err := labels.Normalize(l)
+	if err != nil {
+		return nil, err
+	}
+	return normalized, nil
 }
 
 // checkPortAvailability 检查端口可用性
@@ -728,20 +787,8 @@ func (s *tunnelService) createSSHConnection(host *models.Host) (*ssh.Client, err
 		return nil, fmt.Errorf("failed to decrypt host data: %v", err)
 	}
 
-	// 创建SSH配置
-	config, err := s.createSSHConfig(host)
-	if err != nil {
-		return nil, err
-	}
-
-	// 建立连接
-	address := fmt.Sprintf("%s:%d", host.Hostname, host.Port)
-	client, err := ssh.Dial("tcp", address, config)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
+	// 建立连接，配置了跳板机时经由跳板机连接
+	return dialSSH(host, s.createSSHConfig, s.hostService.GetHost)
 }
 
 // createSSHConfig 创建SSH配置
@@ -760,17 +807,16 @@ func (s *tunnelService) createSSHConfig(host *models.Host) (*ssh.ClientConfig, e
 			ssh.Password(host.Password),
 		}
 	case models.AuthTypeKey, models.AuthTypeKeyPassword:
-		if host.PrivateKey == "" {
-			return nil, errors.New("private key is required")
+		privateKey, err := s.hostService.LoadPrivateKey(host)
+		if err != nil {
+			return nil, err
 		}
 
 		var signer ssh.Signer
-		var err error
-
 		if host.AuthType == models.AuthTypeKeyPassword && host.Passphrase != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(host.PrivateKey), []byte(host.Passphrase))
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(host.Passphrase))
 		} else {
-			signer, err = ssh.ParsePrivateKey([]byte(host.PrivateKey))
+			signer, err = ssh.ParsePrivateKey(privateKey)
 		}
 
 		if err != nil {
@@ -787,11 +833,17 @@ func (s *tunnelService) createSSHConfig(host *models.Host) (*ssh.ClientConfig, e
 
 // addConnectionLog 添加连接日志
 func (s *tunnelService) addConnectionLog(tunnelID uint, eventType, message string) {
+	s.addClientLog(tunnelID, eventType, "", message)
+}
+
+// addClientLog 添加带客户端地址的连接日志
+func (s *tunnelService) addClientLog(tunnelID uint, eventType, clientAddr, message string) {
 	connectionLog := &models.ConnectionLog{
-		TunnelID:  tunnelID,
-		EventType: eventType,
-		Message:   message,
-		Timestamp: time.Now(),
+		TunnelID:   tunnelID,
+		EventType:  eventType,
+		ClientAddr: clientAddr,
+		Message:    message,
+		Timestamp:  time.Now(),
 	}
 
 	if err := s.tunnelRepo.AddConnectionLog(connectionLog); err != nil {
@@ -801,7 +853,7 @@ func (s *tunnelService) addConnectionLog(tunnelID uint, eventType, message strin
 
 // CheckServiceHealth 检查本地服务健康状态
 func (s *tunnelService) CheckServiceHealth(localAddress string, localPort int) error {
-	address := fmt.Sprintf("%s:%d", localAddress, localPort)
+	address := net.JoinHostPort(localAddress, strconv.Itoa(localPort))
 
 	// 设置连接超时
 	timeout := 5 * time.Second
@@ -833,7 +885,7 @@ func (s *tunnelService) FindAvailablePort(startPort, endPort int, address string
 }
 
 // CreateDynamicSOCKS5Tunnel 创建动态SOCKS5隧道，自动分配端口
-func (s *tunnelService) CreateDynamicSOCKS5Tunnel(hostID uint, name, description string, autoStart bool) (*models.Tunnel, error) {
+func (s *tunnelService) CreateDynamicSOCKS5Tunnel(hostID, ownerID uint, name, description string, autoStart bool) (*models.Tunnel, error) {
 	// 查找可用端口（1080-1090是常见的SOCKS代理端口范围）
 	localPort, err := s.FindAvailablePort(1080, 1090, "localhost")
 	if err != nil {
@@ -848,6 +900,7 @@ func (s *tunnelService) CreateDynamicSOCKS5Tunnel(hostID uint, name, description
 		Name:         name,
 		Type:         models.TunnelTypeDynamic,
 		HostID:       hostID,
+		OwnerID:      ownerID,
 		LocalAddress: "localhost",
 		LocalPort:    localPort,
 		AutoStart:    autoStart,
@@ -859,4 +912,4 @@ func (s *tunnelService) CreateDynamicSOCKS5Tunnel(hostID uint, name, description
 	}
 
 	return tunnel, nil
-}
\ No newline at end of file
+}
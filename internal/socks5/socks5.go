@@ -40,10 +40,14 @@ const (
 
 // SOCKS5Server SOCKS5代理服务器
 type SOCKS5Server struct {
-	sshClient     *ssh.Client
-	trafficLogger TrafficLogger
+	sshClient      *ssh.Client
+	trafficLogger  TrafficLogger
+	targetCallback TargetCallback
 }
 
+// TargetCallback 解析出客户端请求的目标地址后的回调
+type TargetCallback func(target string)
+
 // TrafficLogger 流量记录接口
 type TrafficLogger interface {
 	LogTraffic(bytesIn, bytesOut int64)
@@ -64,6 +68,11 @@ func NewSOCKS5ServerWithTrafficLogger(sshClient *ssh.Client, logger TrafficLogge
 	}
 }
 
+// SetTargetCallback 设置目标地址回调，用于记录连接的目的地址
+func (s *SOCKS5Server) SetTargetCallback(callback TargetCallback) {
+	s.targetCallback = callback
+}
+
 // HandleConnection 处理SOCKS5连接
 func (s *SOCKS5Server) HandleConnection(ctx context.Context, conn net.Conn) error {
 	defer conn.Close()
@@ -188,6 +197,9 @@ func (s *SOCKS5Server) handleRequest(ctx context.Context, conn net.Conn) error {
 
 	// 通过SSH连接到目标地址
 	target := net.JoinHostPort(destAddr, strconv.Itoa(destPort))
+	if s.targetCallback != nil {
+		s.targetCallback(target)
+	}
 	remoteConn, err := s.sshClient.Dial("tcp", target)
 	if err != nil {
 		response := []byte{Socks5Version, RepHostUnreachable, 0x00, AtypIPV4, 0, 0, 0, 0, 0, 0}
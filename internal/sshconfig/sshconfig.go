@@ -0,0 +1,388 @@
+// Package sshconfig 解析 OpenSSH 客户端配置文件（~/.ssh/config）
+package sshconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxIncludeDepth Include 的最大嵌套层数，与 OpenSSH 一致
+const maxIncludeDepth = 16
+
+// multiValueKeys 可以出现多次并全部生效的配置项，其余配置项以第一次出现的值为准
+var multiValueKeys = map[string]bool{
+	"identityfile":   true,
+	"localforward":   true,
+	"remoteforward":  true,
+	"dynamicforward": true,
+}
+
+// Option 配置项，Key 统一为小写
+type Option struct {
+	Key  string
+	Args []string
+	File string
+	Line int
+}
+
+// Value 返回参数拼接后的值
+func (o Option) Value() string {
+	return strings.Join(o.Args, " ")
+}
+
+// Block Host 或 Match 块，配置文件开头不属于任何块的配置项放在匹配所有主机的块中
+type Block struct {
+	Patterns          []string // Host 的模式，或 Match host 的模式
+	Match             bool     // 是否为 Match 块
+	MatchOriginalHost []string // Match originalhost 的模式
+	MatchUser         []string // Match user 的模式
+	MatchAll          bool     // Match all
+	Unsupported       string   // 不支持的 Match 条件，非空时整个块被忽略
+	Options           []Option
+	File              string
+	Line              int
+}
+
+// Warning 解析时发现的无法处理的内容
+type Warning struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// String 返回带位置的警告信息
+func (w Warning) String() string {
+	if w.File == "" {
+		return w.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", w.File, w.Line, w.Message)
+}
+
+// Config 解析后的配置文件
+type Config struct {
+	Blocks   []*Block
+	Warnings []Warning
+}
+
+// parser 配置文件解析器
+type parser struct {
+	config   *Config
+	current  *Block
+	baseDir  string // Include 相对路径的基准目录
+	readFile func(path string) ([]byte, error)
+}
+
+// ParseFile 解析配置文件，Include 的文件从磁盘读取，相对路径以配置文件所在目录为基准
+func ParseFile(path string) (*Config, error) {
+	path = ExpandHome(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser(filepath.Dir(path), os.ReadFile)
+	if err := p.parse(bytes.NewReader(data), path, 0); err != nil {
+		return nil, err
+	}
+	return p.config, nil
+}
+
+// Parse 解析上传的配置内容，无法读取 Include 的文件
+func Parse(r io.Reader, name string) (*Config, error) {
+	p := newParser("", nil)
+	if err := p.parse(r, name, 0); err != nil {
+		return nil, err
+	}
+	return p.config, nil
+}
+
+// newParser 创建解析器，开头的全局配置项放在匹配所有主机的块中
+func newParser(baseDir string, readFile func(string) ([]byte, error)) *parser {
+	global := &Block{Patterns: []string{"*"}}
+	return &parser{
+		config:   &Config{Blocks: []*Block{global}},
+		current:  global,
+		baseDir:  baseDir,
+		readFile: readFile,
+	}
+}
+
+// parse 逐行解析配置
+func (p *parser) parse(r io.Reader, file string, depth int) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, args, err := splitLine(text)
+		if err != nil {
+			p.warn(file, line, err.Error())
+			continue
+		}
+
+		switch key {
+		case "host":
+			p.current = &Block{Patterns: args, File: file, Line: line}
+			p.config.Blocks = append(p.config.Blocks, p.current)
+		case "match":
+			p.current = parseMatch(args)
+			p.current.File, p.current.Line = file, line
+			if p.current.Unsupported != "" {
+				p.warn(file, line, fmt.Sprintf("Match %s is not supported, the block is ignored", p.current.Unsupported))
+			}
+			p.config.Blocks = append(p.config.Blocks, p.current)
+		case "include":
+			if err := p.include(args, file, line, depth); err != nil {
+				return err
+			}
+		default:
+			p.current.Options = append(p.current.Options, Option{Key: key, Args: args, File: file, Line: line})
+		}
+	}
+	return scanner.Err()
+}
+
+// include 读取 Include 的文件，内容属于当前所在的块
+func (p *parser) include(patterns []string, file string, line, depth int) error {
+	if p.readFile == nil {
+		p.warn(file, line, "Include is not supported for uploaded configuration")
+		return nil
+	}
+	if depth >= maxIncludeDepth {
+		return fmt.Errorf("%s:%d: too many nested includes", file, line)
+	}
+
+	for _, pattern := range patterns {
+		pattern = ExpandHome(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(p.baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			p.warn(file, line, fmt.Sprintf("invalid Include pattern %s", pattern))
+			continue
+		}
+		for _, match := range matches {
+			data, err := p.readFile(match)
+			if err != nil {
+				p.warn(file, line, fmt.Sprintf("failed to read included file: %v", err))
+				continue
+			}
+			if err := p.parse(bytes.NewReader(data), match, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// warn 记录警告
+func (p *parser) warn(file string, line int, message string) {
+	p.config.Warnings = append(p.config.Warnings, Warning{File: file, Line: line, Message: message})
+}
+
+// parseMatch 解析 Match 条件，只支持 all、host、originalhost 和 user
+func parseMatch(args []string) *Block {
+	block := &Block{Match: true}
+	for i := 0; i < len(args); i++ {
+		criterion := strings.ToLower(args[i])
+		switch criterion {
+		case "all":
+			block.MatchAll = true
+		case "host", "originalhost", "user":
+			if i+1 >= len(args) {
+				block.Unsupported = criterion + " without patterns"
+				return block
+			}
+			i++
+			patterns := strings.Split(args[i], ",")
+			switch criterion {
+			case "host":
+				block.Patterns = append(block.Patterns, patterns...)
+			case "originalhost":
+				block.MatchOriginalHost = append(block.MatchOriginalHost, patterns...)
+			case "user":
+				block.MatchUser = append(block.MatchUser, patterns...)
+			}
+		default:
+			block.Unsupported = args[i]
+			return block
+		}
+	}
+	return block
+}
+
+// splitLine 拆分配置行为小写的配置名和参数，支持 Key=Value 和双引号
+func splitLine(text string) (string, []string, error) {
+	end := strings.IndexAny(text, " \t=")
+	if end < 0 {
+		return strings.ToLower(text), nil, nil
+	}
+	key := strings.ToLower(text[:end])
+	rest := strings.TrimSpace(text[end:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+
+	var args []string
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if inQuotes {
+		return "", nil, fmt.Errorf("unterminated quote in %s", key)
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	return key, args, nil
+}
+
+// Aliases 返回 Host 行中声明的具体主机别名，不包含通配符和排除模式
+func (c *Config) Aliases() []string {
+	seen := make(map[string]bool)
+	var aliases []string
+	for _, block := range c.Blocks {
+		if block.Match || block.File == "" {
+			continue
+		}
+		for _, pattern := range block.Patterns {
+			if strings.ContainsAny(pattern, "*?!") || seen[pattern] {
+				continue
+			}
+			seen[pattern] = true
+			aliases = append(aliases, pattern)
+		}
+	}
+	return aliases
+}
+
+// HostConfig 某个主机别名的生效配置
+type HostConfig struct {
+	Alias   string
+	options map[string][]Option
+}
+
+// Get 返回配置项的值，未设置时返回空字符串
+func (h *HostConfig) Get(key string) string {
+	if options := h.options[key]; len(options) > 0 {
+		return options[0].Value()
+	}
+	return ""
+}
+
+// Values 返回可以多次出现的配置项的所有值
+func (h *HostConfig) Values(key string) []Option {
+	return h.options[key]
+}
+
+// Has 检查是否设置了配置项
+func (h *HostConfig) Has(key string) bool {
+	return len(h.options[key]) > 0
+}
+
+// Resolve 按 OpenSSH 的规则计算主机别名的生效配置，依次应用匹配的块，单值配置项以第一次出现的值为准
+func (c *Config) Resolve(alias string) *HostConfig {
+	host := &HostConfig{Alias: alias, options: make(map[string][]Option)}
+	for _, block := range c.Blocks {
+		if !block.matches(host) {
+			continue
+		}
+		for _, option := range block.Options {
+			if multiValueKeys[option.Key] || !host.Has(option.Key) {
+				host.options[option.Key] = append(host.options[option.Key], option)
+			}
+		}
+	}
+	return host
+}
+
+// matches 检查块是否适用于主机，Match host 按目前已确定的 HostName 匹配，Match originalhost 按主机别名匹配
+func (b *Block) matches(host *HostConfig) bool {
+	if !b.Match {
+		return matchPatterns(b.Patterns, host.Alias)
+	}
+	if b.Unsupported != "" {
+		return false
+	}
+	if b.MatchAll {
+		return true
+	}
+
+	if len(b.Patterns) > 0 {
+		hostname := host.Get("hostname")
+		if hostname == "" {
+			hostname = host.Alias
+		}
+		if !matchPatterns(b.Patterns, hostname) {
+			return false
+		}
+	}
+	if len(b.MatchOriginalHost) > 0 && !matchPatterns(b.MatchOriginalHost, host.Alias) {
+		return false
+	}
+	if len(b.MatchUser) > 0 && !matchPatterns(b.MatchUser, host.Get("user")) {
+		return false
+	}
+	return len(b.Patterns) > 0 || len(b.MatchOriginalHost) > 0 || len(b.MatchUser) > 0
+}
+
+// matchPatterns 检查名称是否匹配模式列表，以 ! 开头的模式匹配时直接排除
+func matchPatterns(patterns []string, name string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			if matchPattern(pattern[1:], name) {
+				return false
+			}
+			continue
+		}
+		if matchPattern(pattern, name) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern 匹配支持 * 和 ? 通配符的模式，不区分大小写
+func matchPattern(pattern, name string) bool {
+	expr := regexp.QuoteMeta(strings.ToLower(pattern))
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, err := regexp.MatchString("^"+expr+"$", strings.ToLower(name))
+	return err == nil && matched
+}
+
+// ExpandHome 将以 ~/ 开头的路径展开为用户主目录下的路径
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
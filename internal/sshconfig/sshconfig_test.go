@@ -0,0 +1,291 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustParse 解析测试用的配置内容
+func mustParse(t *testing.T, text string) *Config {
+	t.Helper()
+	cfg, err := Parse(strings.NewReader(text), "config")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	return cfg
+}
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		text string
+		key  string
+		args []string
+	}{
+		{"Host db", "host", []string{"db"}},
+		{"HostName\t10.0.0.1", "hostname", []string{"10.0.0.1"}},
+		{"Port=2222", "port", []string{"2222"}},
+		{"Port = 2222", "port", []string{"2222"}},
+		{"Host  web  db\tcache", "host", []string{"web", "db", "cache"}},
+		{`IdentityFile "~/.ssh/my key"`, "identityfile", []string{"~/.ssh/my key"}},
+		{`ProxyCommand ssh -W "%h:%p" jump`, "proxycommand", []string{"ssh", "-W", "%h:%p", "jump"}},
+		{`User ""`, "user", []string{""}},
+		{"Compression", "compression", nil},
+	}
+	for _, tt := range tests {
+		key, args, err := splitLine(tt.text)
+		if err != nil {
+			t.Errorf("splitLine(%q) returned error: %v", tt.text, err)
+			continue
+		}
+		if key != tt.key || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("splitLine(%q) = %q, %q, want %q, %q", tt.text, key, args, tt.key, tt.args)
+		}
+	}
+
+	if _, _, err := splitLine(`IdentityFile "~/.ssh/key`); err == nil {
+		t.Error("splitLine with unterminated quote succeeded, want error")
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{[]string{"*"}, "db", true},
+		{[]string{"db"}, "DB", true},
+		{[]string{"db"}, "db2", false},
+		{[]string{"db?"}, "db2", true},
+		{[]string{"*.example.com"}, "web.example.com", true},
+		{[]string{"*.example.com"}, "example.com", false},
+		{[]string{"web", "db"}, "db", true},
+		{[]string{"*", "!db"}, "db", false},
+		{[]string{"!db", "*"}, "db", false},
+		{[]string{"*", "!db"}, "web", true},
+		{[]string{"!db"}, "web", false}, // 只有排除模式时不匹配任何主机
+		{[]string{"a.b"}, "axb", false},
+	}
+	for _, tt := range tests {
+		if got := matchPatterns(tt.patterns, tt.name); got != tt.want {
+			t.Errorf("matchPatterns(%q, %q) = %v, want %v", tt.patterns, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveFirstValueWins(t *testing.T) {
+	cfg := mustParse(t, `
+# 全局配置
+User global
+ServerAliveInterval 30
+
+Host db
+  HostName 10.0.0.5
+  IdentityFile ~/.ssh/db
+  LocalForward 5432 localhost:5432
+
+Host db web
+  Port 2222
+  User ops
+  HostName 10.0.0.6
+  IdentityFile ~/.ssh/shared
+
+Host *
+  Port 22
+  User root
+  IdentityFile ~/.ssh/id_ed25519
+  LocalForward 8080 localhost:80
+`)
+
+	tests := []struct {
+		alias      string
+		get        map[string]string
+		identities []string
+		forwards   int
+	}{
+		{
+			alias:      "db",
+			get:        map[string]string{"hostname": "10.0.0.5", "port": "2222", "user": "global", "serveraliveinterval": "30"},
+			identities: []string{"~/.ssh/db", "~/.ssh/shared", "~/.ssh/id_ed25519"},
+			forwards:   2,
+		},
+		{
+			alias:      "web",
+			get:        map[string]string{"hostname": "10.0.0.6", "port": "2222", "user": "global"},
+			identities: []string{"~/.ssh/shared", "~/.ssh/id_ed25519"},
+			forwards:   1,
+		},
+		{
+			alias:      "other",
+			get:        map[string]string{"hostname": "", "port": "22", "user": "global"},
+			identities: []string{"~/.ssh/id_ed25519"},
+			forwards:   1,
+		},
+	}
+	for _, tt := range tests {
+		host := cfg.Resolve(tt.alias)
+		for key, want := range tt.get {
+			if got := host.Get(key); got != want {
+				t.Errorf("Resolve(%q).Get(%q) = %q, want %q", tt.alias, key, got, want)
+			}
+		}
+		var identities []string
+		for _, option := range host.Values("identityfile") {
+			identities = append(identities, option.Value())
+		}
+		if !reflect.DeepEqual(identities, tt.identities) {
+			t.Errorf("Resolve(%q) identity files = %q, want %q", tt.alias, identities, tt.identities)
+		}
+		if got := len(host.Values("localforward")); got != tt.forwards {
+			t.Errorf("Resolve(%q) has %d local forwards, want %d", tt.alias, got, tt.forwards)
+		}
+	}
+}
+
+func TestResolveMatch(t *testing.T) {
+	cfg := mustParse(t, `
+Host db
+  HostName db.internal
+
+Host cache
+  HostName 10.0.0.9
+
+Host web
+  User deploy
+
+Match host *.internal
+  ProxyJump bastion
+
+Match host cache
+  Port 1111
+
+Match originalhost cache
+  Port 6380
+
+Match user deploy
+  IdentityFile ~/.ssh/deploy
+
+Match host web user admin
+  Port 2200
+
+Match exec "test -f /tmp/flag"
+  Port 9999
+
+Match all
+  Port 22
+  User nobody
+`)
+
+	tests := []struct {
+		alias string
+		get   map[string]string
+	}{
+		// Match host 按 HostName 匹配
+		{"db", map[string]string{"proxyjump": "bastion", "port": "22", "user": "nobody"}},
+		// Match originalhost 按别名匹配，Match host 不再按别名匹配
+		{"cache", map[string]string{"proxyjump": "", "port": "6380"}},
+		// Match user 按已确定的 User 匹配，两个条件需要同时满足
+		{"web", map[string]string{"identityfile": "~/.ssh/deploy", "port": "22", "user": "deploy"}},
+		// 没有 HostName 时 Match host 按别名匹配
+		{"app.internal", map[string]string{"proxyjump": "bastion", "port": "22"}},
+	}
+	for _, tt := range tests {
+		host := cfg.Resolve(tt.alias)
+		for key, want := range tt.get {
+			if got := host.Get(key); got != want {
+				t.Errorf("Resolve(%q).Get(%q) = %q, want %q", tt.alias, key, got, want)
+			}
+		}
+	}
+
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0].Message, "exec") {
+		t.Errorf("Warnings = %v, want one warning about Match exec", cfg.Warnings)
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	tests := []struct {
+		args []string
+		want Block
+	}{
+		{[]string{"all"}, Block{Match: true, MatchAll: true}},
+		{[]string{"host", "a,b"}, Block{Match: true, Patterns: []string{"a", "b"}}},
+		{[]string{"Host", "a", "User", "root"}, Block{Match: true, Patterns: []string{"a"}, MatchUser: []string{"root"}}},
+		{[]string{"originalhost", "db"}, Block{Match: true, MatchOriginalHost: []string{"db"}}},
+		{[]string{"host"}, Block{Match: true, Unsupported: "host without patterns"}},
+		{[]string{"canonical"}, Block{Match: true, Unsupported: "canonical"}},
+		{[]string{"!host", "db"}, Block{Match: true, Unsupported: "!host"}},
+	}
+	for _, tt := range tests {
+		if got := parseMatch(tt.args); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("parseMatch(%q) = %+v, want %+v", tt.args, *got, tt.want)
+		}
+	}
+}
+
+func TestAliases(t *testing.T) {
+	cfg := mustParse(t, `
+Host web db
+Host *.example.com !bad.example.com
+Host db cache?
+Match host api
+Host cache
+`)
+	want := []string{"web", "db", "cache"}
+	if got := cfg.Aliases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Aliases() = %q, want %q", got, want)
+	}
+}
+
+func TestParseIncludeUnsupported(t *testing.T) {
+	cfg := mustParse(t, "Include other.conf\nHost db\n")
+	if len(cfg.Warnings) != 1 || cfg.Warnings[0].Line != 1 {
+		t.Errorf("Warnings = %v, want one warning for line 1", cfg.Warnings)
+	}
+}
+
+func TestParseFileInclude(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config":        "Include conf.d/*.conf\nHost *\n  User root\n",
+		"conf.d/a.conf": "Host db\n  HostName 10.0.0.5\n  User dba\n",
+		"conf.d/b.conf": "Host web\n  HostName 10.0.0.6\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := ParseFile(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if got, want := cfg.Aliases(), []string{"db", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Aliases() = %q, want %q", got, want)
+	}
+	if got := cfg.Resolve("db").Get("user"); got != "dba" {
+		t.Errorf("Resolve(db).Get(user) = %q, want dba", got)
+	}
+	if got := cfg.Resolve("web").Get("user"); got != "root" {
+		t.Errorf("Resolve(web).Get(user) = %q, want root", got)
+	}
+}
+
+func TestParseFileIncludeLoop(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte("Include config\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseFile(path); err == nil {
+		t.Error("ParseFile with recursive Include succeeded, want error")
+	}
+}
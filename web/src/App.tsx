@@ -1,12 +1,42 @@
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
 import Header from './components/Header'
+import Login from './pages/Login'
 import Dashboard from './pages/Dashboard'
 import HostManagement from './pages/HostManagement'
 import TunnelManagement from './components/TunnelManagement'
 import ClashExport from './components/ClashExport'
+import { authApi, User } from './api/authApi'
+import { UNAUTHORIZED_EVENT } from './api/client'
 
 function App() {
   const [currentView, setCurrentView] = useState('dashboard')
+  // undefined 表示还在检查登录状态，null 表示未登录
+  const [user, setUser] = useState<User | null | undefined>(undefined)
+  const [authRequired, setAuthRequired] = useState(true)
+
+  useEffect(() => {
+    authApi.getCurrentUser()
+      .then((data) => setUser(data.user))
+      .catch((err) => {
+        // 服务端关闭认证时 /auth/me 不存在，直接进入管理界面
+        if (err instanceof Error && err.message.includes('not found')) {
+          setAuthRequired(false)
+        }
+        setUser(null)
+      })
+
+    const handleUnauthorized = () => setUser(null)
+    window.addEventListener(UNAUTHORIZED_EVENT, handleUnauthorized)
+    return () => window.removeEventListener(UNAUTHORIZED_EVENT, handleUnauthorized)
+  }, [])
+
+  const handleLogout = async () => {
+    try {
+      await authApi.logout()
+    } finally {
+      setUser(null)
+    }
+  }
 
   const renderCurrentView = () => {
     switch (currentView) {
@@ -28,9 +58,22 @@ function App() {
     }
   }
 
+  if (user === undefined) {
+    return null
+  }
+
+  if (authRequired && user === null) {
+    return <Login onLogin={setUser} />
+  }
+
   return (
     <div style={{ minHeight: '100vh', backgroundColor: '#f8fafc' }}>
-      <Header currentView={currentView} onNavigate={setCurrentView} />
+      <Header
+        currentView={currentView}
+        onNavigate={setCurrentView}
+        username={user?.username}
+        onLogout={authRequired ? handleLogout : undefined}
+      />
       <main>
         {renderCurrentView()}
       </main>
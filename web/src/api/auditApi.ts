@@ -0,0 +1,46 @@
+import { apiClient } from './client';
+
+export interface FieldChange {
+  before: unknown;
+  after: unknown;
+}
+
+export interface AuditEvent {
+  id: number;
+  user_id: number;
+  username: string;
+  token_prefix?: string;
+  source_ip: string;
+  action: 'create' | 'update' | 'delete' | 'start' | 'stop' | 'restart' | 'share' | 'unshare' | 'export';
+  resource_type: 'host' | 'tunnel' | 'export';
+  resource_id: number;
+  resource_name: string;
+  changes?: Record<string, FieldChange>;
+  created_at: string;
+}
+
+export interface AuditQuery {
+  user?: string;
+  action?: string;
+  resource_type?: string;
+  resource_id?: number;
+  since?: string;
+  until?: string;
+  cursor?: string;
+  limit?: number;
+}
+
+export interface AuditPage {
+  events: AuditEvent[];
+  count: number;
+  next_cursor: string;
+}
+
+class AuditApi {
+  async queryEvents(query: AuditQuery = {}): Promise<AuditPage> {
+    const response = await apiClient.get('/audit', { params: query });
+    return response.data;
+  }
+}
+
+export const auditApi = new AuditApi();
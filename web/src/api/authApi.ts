@@ -0,0 +1,124 @@
+import { apiClient } from './client';
+
+export type UserRole = 'admin' | 'operator' | 'viewer';
+
+export type SharePermission = 'view' | 'control' | 'edit';
+
+export interface User {
+  id: number;
+  username: string;
+  role: UserRole;
+  disabled: boolean;
+  last_login_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface APIToken {
+  id: number;
+  user_id: number;
+  name: string;
+  prefix: string;
+  scopes: string[];
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+  created_at: string;
+}
+
+export interface ResourceShare {
+  id: number;
+  resource_type: 'host' | 'tunnel';
+  resource_id: number;
+  user_id: number;
+  permission: SharePermission;
+  created_at: string;
+  user?: User;
+}
+
+export interface CreateTokenResponse {
+  token: string;
+  info: APIToken;
+}
+
+export interface CurrentUserResponse {
+  user: User;
+  csrf_token: string;
+  expires_at: string;
+}
+
+class AuthApi {
+  async login(username: string, password: string): Promise<User> {
+    const response = await apiClient.post('/auth/login', { username, password });
+    return response.data.user;
+  }
+
+  async logout(): Promise<void> {
+    await apiClient.post('/auth/logout');
+  }
+
+  async getCurrentUser(): Promise<CurrentUserResponse> {
+    const response = await apiClient.get('/auth/me');
+    return response.data;
+  }
+
+  async changePassword(oldPassword: string, newPassword: string): Promise<void> {
+    await apiClient.put('/auth/password', { old_password: oldPassword, new_password: newPassword });
+  }
+
+  async getUsers(): Promise<User[]> {
+    const response = await apiClient.get('/users');
+    return response.data.users || [];
+  }
+
+  async createUser(username: string, password: string, role: UserRole = 'viewer'): Promise<User> {
+    const response = await apiClient.post('/users', { username, password, role });
+    return response.data.user;
+  }
+
+  async updateUser(id: number, role: UserRole, disabled: boolean): Promise<User> {
+    const response = await apiClient.put(`/users/${id}`, { role, disabled });
+    return response.data.user;
+  }
+
+  async deleteUser(id: number): Promise<void> {
+    await apiClient.delete(`/users/${id}`);
+  }
+
+  // 资源共享
+  async getShares(resource: 'hosts' | 'tunnels', id: number): Promise<ResourceShare[]> {
+    const response = await apiClient.get(`/${resource}/${id}/shares`);
+    return response.data.shares || [];
+  }
+
+  async shareResource(resource: 'hosts' | 'tunnels', id: number, username: string, permission: SharePermission): Promise<ResourceShare> {
+    const response = await apiClient.post(`/${resource}/${id}/shares`, { username, permission });
+    return response.data.share;
+  }
+
+  async removeShare(resource: 'hosts' | 'tunnels', id: number, userId: number): Promise<void> {
+    await apiClient.delete(`/${resource}/${id}/shares/${userId}`);
+  }
+
+  // API 令牌
+  async getTokens(): Promise<APIToken[]> {
+    const response = await apiClient.get('/tokens');
+    return response.data.tokens || [];
+  }
+
+  async getTokenScopes(): Promise<string[]> {
+    const response = await apiClient.get('/tokens/scopes');
+    return response.data.scopes || [];
+  }
+
+  async createToken(name: string, scopes: string[], expiresInDays = 0): Promise<CreateTokenResponse> {
+    const response = await apiClient.post('/tokens', { name, scopes, expires_in_days: expiresInDays });
+    return response.data;
+  }
+
+  async revokeToken(id: number): Promise<void> {
+    await apiClient.delete(`/tokens/${id}`);
+  }
+}
+
+export const authApi = new AuthApi();
@@ -1,9 +1,34 @@
 import axios from 'axios';
 
+// 认证失效时派发的事件，App 监听后切换到登录页
+export const UNAUTHORIZED_EVENT = 'drilling:unauthorized';
+
+// 读取 CSRF Cookie，非只读请求需要放入 X-CSRF-Token 请求头
+// 同时携带 X-Requested-With，使用客户端证书认证时服务端以此防止 CSRF
+export function csrfHeaders(): Record<string, string> {
+  const headers: Record<string, string> = { 'X-Requested-With': 'XMLHttpRequest' };
+  const match = document.cookie.match(/(?:^|;\s*)drilling_csrf=([^;]*)/);
+  if (match) {
+    headers['X-CSRF-Token'] = decodeURIComponent(match[1]);
+  }
+  return headers;
+}
+
+// 处理 fetch 请求的 401 响应
+export function handleUnauthorized(response: Response): void {
+  if (response.status === 401) {
+    window.dispatchEvent(new Event(UNAUTHORIZED_EVENT));
+  }
+}
+
+// API 路径，相对于服务端注入的 <base href> 计算，支持通过反向代理子路径访问
+export const API_BASE = new URL('api/v1', document.baseURI).pathname;
+
 // 创建 axios 实例
 export const apiClient = axios.create({
-  baseURL: '/api/v1',
+  baseURL: API_BASE,
   timeout: 10000,
+  withCredentials: true,
   headers: {
     'Content-Type': 'application/json',
   },
@@ -12,6 +37,10 @@ export const apiClient = axios.create({
 // 请求拦截器
 apiClient.interceptors.request.use(
   (config: any) => {
+    const method = (config.method || 'get').toLowerCase();
+    if (!['get', 'head', 'options'].includes(method)) {
+      config.headers = { ...config.headers, ...csrfHeaders() };
+    }
     return config;
   },
   (error: any) => {
@@ -26,6 +55,9 @@ apiClient.interceptors.response.use(
   },
   (error: any) => {
     if (error.response) {
+      if (error.response.status === 401) {
+        window.dispatchEvent(new Event(UNAUTHORIZED_EVENT));
+      }
       // 服务器响应了错误状态码
       const message = error.response.data?.error || error.response.data?.message || 'Request failed';
       return Promise.reject(new Error(message));
@@ -1,10 +1,92 @@
-import { ClashExportResponse, Socks5StatusResponse } from '../types'
+import { API_BASE, apiClient, csrfHeaders, handleUnauthorized } from './client'
+import { ClashExportResponse, ExportProfile, Socks5StatusResponse, Subscription, SubscriptionFetch } from '../types'
 
-const API_BASE = '/api/v1'
+// 恢复备份时名称冲突的处理策略
+export type BackupConflictStrategy = 'skip' | 'overwrite' | 'rename'
+
+export interface BackupImportItem {
+  source_id: number
+  id?: number
+  name: string
+  action: 'created' | 'updated' | 'skipped' | 'renamed' | 'failed'
+  error?: string
+}
+
+export interface CreateSubscriptionRequest {
+  name: string
+  format?: ProxyExportFormat
+  profile_id?: number
+  update_interval?: number
+  expires_at?: string
+}
+
+export interface BackupImportResult {
+  hosts: BackupImportItem[]
+  tunnels: BackupImportItem[]
+  groups: BackupImportItem[]
+  failed: number
+}
+
+// 可以导出的代理客户端格式，导出全部活跃的动态隧道
+export type ProxyExportFormat = 'clash' | 'sing-box' | 'xray' | 'surge' | 'pac'
+
+// 可以导出的 OpenSSH 格式：ssh_config 片段、ssh 命令、autossh systemd 单元
+export type SshExportFormat = 'ssh-config' | 'ssh-commands' | 'autossh'
+
+// 导出的主机和隧道，选择主机时包含该主机的所有隧道，都为空时导出全部
+export interface ExportSelection {
+  hostIds?: number[]
+  tunnelIds?: number[]
+}
+
+// 导出选项，与导出模板中的同名设置合并
+export interface ExportOptions {
+  // 同时导出未运行的动态隧道
+  includeInactive?: boolean
+  // 按需启动未运行的隧道，首个客户端连接时才建立SSH连接
+  startOnDemand?: boolean
+}
+
+// 导出模板和导出选项的查询参数，未选择模板时使用默认设置导出全部隧道
+function exportQuery(profile?: string, options: ExportOptions = {}): string {
+  const params = new URLSearchParams()
+  if (profile) params.set('profile', profile)
+  if (options.includeInactive) params.set('include_inactive', 'true')
+  const query = params.toString()
+  return query ? `?${query}` : ''
+}
+
+// 导出请求，按需启动隧道需要使用 POST，GET 导出不会启动任何隧道
+function exportRequest(path: string, profile?: string, options: ExportOptions = {}): Promise<Response> {
+  const url = `${API_BASE}${path}${exportQuery(profile, options)}`
+  if (options.startOnDemand) {
+    return fetch(url, { method: 'POST', headers: csrfHeaders() })
+  }
+  return fetch(url)
+}
+
+// 将响应内容保存为文件，文件名优先使用 Content-Disposition
+async function saveResponseAsFile(response: Response, fallback: string): Promise<void> {
+  const contentDisposition = response.headers.get('Content-Disposition')
+  const filenameMatch = contentDisposition?.match(/filename=([^;]+)/)
+  const filename = filenameMatch ? filenameMatch[1].replace(/['"]/g, '') : fallback
+
+  const blob = await response.blob()
+  const url = window.URL.createObjectURL(blob)
+  const a = document.createElement('a')
+  a.style.display = 'none'
+  a.href = url
+  a.download = filename
+  document.body.appendChild(a)
+  a.click()
+  window.URL.revokeObjectURL(url)
+  document.body.removeChild(a)
+}
 
 class ExportApi {
-  async getSocks5Status(): Promise<Socks5StatusResponse> {
-    const response = await fetch(`${API_BASE}/export/socks5/status`)
+  async getSocks5Status(includeInactive = false): Promise<Socks5StatusResponse> {
+    const response = await fetch(`${API_BASE}/export/socks5/status${includeInactive ? '?include_inactive=true' : ''}`)
+    handleUnauthorized(response)
     if (!response.ok) {
       const error = await response.json().catch(() => ({ error: 'Failed to get SOCKS5 status' }))
       throw new Error(error.details || error.error || 'Failed to get SOCKS5 status')
@@ -12,8 +94,9 @@ class ExportApi {
     return response.json()
   }
 
-  async getClashConfigPreview(): Promise<ClashExportResponse> {
-    const response = await fetch(`${API_BASE}/export/clash/preview`)
+  async getClashConfigPreview(profile?: string, options: ExportOptions = {}): Promise<ClashExportResponse> {
+    const response = await fetch(`${API_BASE}/export/clash/preview${exportQuery(profile, { includeInactive: options.includeInactive })}`)
+    handleUnauthorized(response)
     if (!response.ok) {
       const error = await response.json().catch(() => ({ error: 'Failed to get Clash config preview' }))
       throw new Error(error.details || error.error || 'Failed to get Clash config preview')
@@ -21,8 +104,9 @@ class ExportApi {
     return response.json()
   }
 
-  async downloadClashConfig(): Promise<void> {
-    const response = await fetch(`${API_BASE}/export/clash`)
+  async downloadClashConfig(profile?: string, options: ExportOptions = {}): Promise<void> {
+    const response = await exportRequest('/export/clash', profile, options)
+    handleUnauthorized(response)
     if (!response.ok) {
       const error = await response.json().catch(() => ({ error: 'Failed to download Clash config' }))
       throw new Error(error.details || error.error || 'Failed to download Clash config')
@@ -50,6 +134,115 @@ class ExportApi {
     window.URL.revokeObjectURL(url)
     document.body.removeChild(a)
   }
+
+  // 下载包含所有主机和隧道的备份文件，凭据使用 passphrase 加密
+  async downloadBackup(passphrase: string): Promise<void> {
+    const response = await fetch(`${API_BASE}/export/backup`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', ...csrfHeaders() },
+      body: JSON.stringify({ passphrase })
+    })
+    handleUnauthorized(response)
+    if (!response.ok) {
+      const error = await response.json().catch(() => ({ error: 'Failed to export backup' }))
+      throw new Error(error.details || error.error || 'Failed to export backup')
+    }
+
+    const contentDisposition = response.headers.get('Content-Disposition')
+    const filenameMatch = contentDisposition?.match(/filename=([^;]+)/)
+    const filename = filenameMatch ? filenameMatch[1].replace(/['"]/g, '') : 'drilling-backup.json'
+
+    const blob = await response.blob()
+    const url = window.URL.createObjectURL(blob)
+    const a = document.createElement('a')
+    a.style.display = 'none'
+    a.href = url
+    a.download = filename
+    document.body.appendChild(a)
+    a.click()
+    window.URL.revokeObjectURL(url)
+    document.body.removeChild(a)
+  }
+
+  // 下载代理客户端配置，设置了代理域名的隧道只用于匹配的域名
+  // 指定导出模板时只导出模板选择的隧道，Clash 还会使用模板中的分组和规则
+  async downloadProxyConfig(format: ProxyExportFormat, profile?: string, options: ExportOptions = {}): Promise<void> {
+    const response = await exportRequest(`/export/${format}`, profile, options)
+    handleUnauthorized(response)
+    if (!response.ok) {
+      const error = await response.json().catch(() => ({ error: 'Failed to export proxy config' }))
+      throw new Error(error.details || error.error || 'Failed to export proxy config')
+    }
+
+    await saveResponseAsFile(response, `drilling-${format}.txt`)
+  }
+
+  // 下载 OpenSSH 格式的导出文件，用于在没有安装 Drilling 的机器上运行隧道
+  async downloadSshExport(format: SshExportFormat, selection: ExportSelection = {}): Promise<void> {
+    const params = new URLSearchParams()
+    if (selection.hostIds?.length) {
+      params.set('hosts', selection.hostIds.join(','))
+    }
+    if (selection.tunnelIds?.length) {
+      params.set('tunnels', selection.tunnelIds.join(','))
+    }
+    const query = params.toString()
+    const response = await fetch(`${API_BASE}/export/${format}${query ? `?${query}` : ''}`)
+    handleUnauthorized(response)
+    if (!response.ok) {
+      const error = await response.json().catch(() => ({ error: 'Failed to export tunnels' }))
+      throw new Error(error.details || error.error || 'Failed to export tunnels')
+    }
+
+    await saveResponseAsFile(response, `drilling-${format}.txt`)
+  }
+
+  // 获取所有导出模板
+  async getExportProfiles(): Promise<ExportProfile[]> {
+    const response = await apiClient.get('/export-profiles')
+    return response.data.profiles || []
+  }
+
+  async createExportProfile(profile: Partial<ExportProfile>): Promise<ExportProfile> {
+    const response = await apiClient.post('/export-profiles', profile)
+    return response.data.profile
+  }
+
+  async updateExportProfile(id: number, profile: Partial<ExportProfile>): Promise<ExportProfile> {
+    const response = await apiClient.put(`/export-profiles/${id}`, profile)
+    return response.data.profile
+  }
+
+  async deleteExportProfile(id: number): Promise<void> {
+    await apiClient.delete(`/export-profiles/${id}`)
+  }
+
+  // 获取所有订阅
+  async getSubscriptions(): Promise<Subscription[]> {
+    const response = await apiClient.get('/subscriptions')
+    return response.data.subscriptions || []
+  }
+
+  // 创建订阅，返回的订阅地址只显示一次
+  async createSubscription(data: CreateSubscriptionRequest): Promise<{ url: string; subscription: Subscription }> {
+    const response = await apiClient.post('/subscriptions', data)
+    return response.data
+  }
+
+  async revokeSubscription(id: number): Promise<void> {
+    await apiClient.delete(`/subscriptions/${id}`)
+  }
+
+  async getSubscriptionFetches(id: number, limit = 100): Promise<SubscriptionFetch[]> {
+    const response = await apiClient.get(`/subscriptions/${id}/fetches`, { params: { limit } })
+    return response.data.fetches || []
+  }
+
+  // 恢复备份文件
+  async importBackup(archive: unknown, passphrase: string, strategy: BackupConflictStrategy = 'skip'): Promise<BackupImportResult> {
+    const response = await apiClient.post('/import/backup', { archive, passphrase, strategy })
+    return response.data
+  }
 }
 
 export const exportApi = new ExportApi()
\ No newline at end of file
@@ -1,12 +1,48 @@
+import { API_BASE, csrfHeaders, handleUnauthorized } from './client'
 import { Host, ConnectionTestResponse, StatusCheckResponse } from '../types'
 
 export type { Host } from '../types'
 
-const API_BASE = '/api/v1'
+export type SSHImportAction = 'create' | 'exists' | 'unsupported' | 'failed'
+
+export interface SSHImportTunnel {
+  name: string
+  action: SSHImportAction
+  id?: number
+  type: string
+  local_address?: string
+  local_port?: number
+  remote_address?: string
+  remote_port?: number
+  source: string
+  issues?: string[]
+}
+
+export interface SSHImportHost {
+  alias: string
+  action: SSHImportAction
+  id?: number
+  hostname: string
+  port: number
+  username: string
+  auth_type?: string
+  identity_file?: string
+  jump_host?: string
+  tunnels: SSHImportTunnel[]
+  issues?: string[]
+}
+
+export interface SSHImportPlan {
+  hosts: SSHImportHost[]
+  warnings?: string[]
+  created: number
+  skipped: number
+}
 
 class HostApi {
   async getAllHosts(): Promise<Host[]> {
     const response = await fetch(`${API_BASE}/hosts`)
+    handleUnauthorized(response)
     if (!response.ok) {
       throw new Error('Failed to fetch hosts')
     }
@@ -17,6 +53,7 @@ class HostApi {
 
   async getHost(id: number): Promise<Host> {
     const response = await fetch(`${API_BASE}/hosts/${id}`)
+    handleUnauthorized(response)
     if (!response.ok) {
       throw new Error('Failed to fetch host')
     }
@@ -30,9 +67,11 @@ class HostApi {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        ...csrfHeaders(),
       },
       body: JSON.stringify(host),
     })
+    handleUnauthorized(response)
 
     if (!response.ok) {
       const error = await response.json()
@@ -49,9 +88,11 @@ class HostApi {
       method: 'PUT',
       headers: {
         'Content-Type': 'application/json',
+        ...csrfHeaders(),
       },
       body: JSON.stringify(host),
     })
+    handleUnauthorized(response)
 
     if (!response.ok) {
       const error = await response.json()
@@ -66,7 +107,9 @@ class HostApi {
   async deleteHost(id: number): Promise<void> {
     const response = await fetch(`${API_BASE}/hosts/${id}`, {
       method: 'DELETE',
+      headers: csrfHeaders(),
     })
+    handleUnauthorized(response)
 
     if (!response.ok) {
       const error = await response.json()
@@ -77,7 +120,9 @@ class HostApi {
   async testConnection(id: number): Promise<ConnectionTestResponse> {
     const response = await fetch(`${API_BASE}/hosts/${id}/test`, {
       method: 'POST',
+      headers: csrfHeaders(),
     })
+    handleUnauthorized(response)
 
     const data: ConnectionTestResponse = await response.json()
 
@@ -91,7 +136,9 @@ class HostApi {
   async checkStatus(id: number): Promise<StatusCheckResponse> {
     const response = await fetch(`${API_BASE}/hosts/${id}/status`, {
       method: 'POST',
+      headers: csrfHeaders(),
     })
+    handleUnauthorized(response)
 
     const data: StatusCheckResponse = await response.json()
 
@@ -101,6 +148,32 @@ class HostApi {
 
     return data
   }
+
+  // 从 OpenSSH 客户端配置导入主机和隧道，dryRun 时只返回导入计划
+  async importSshConfig(text: string, dryRun = true, aliases: string[] = []): Promise<SSHImportPlan> {
+    const params = new URLSearchParams({ dry_run: String(dryRun) })
+    if (aliases.length > 0) {
+      params.set('hosts', aliases.join(','))
+    }
+    const response = await fetch(`${API_BASE}/import/ssh-config?${params}`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'text/plain',
+        ...csrfHeaders(),
+      },
+      body: text,
+    })
+    handleUnauthorized(response)
+
+    if (!response.ok) {
+      const error = await response.json()
+      throw new Error(error.details || error.error || 'Failed to import SSH config')
+    }
+
+    const data = await response.json()
+    // 后端返回格式：{ "dry_run": true, "plan": {...} }
+    return data.plan
+  }
 }
 
 export const hostApi = new HostApi()
\ No newline at end of file
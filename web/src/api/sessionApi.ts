@@ -0,0 +1,49 @@
+import { apiClient } from './client';
+
+export interface ConnectionSession {
+  id: number;
+  tunnel_id: number;
+  client_addr: string;
+  target_addr: string;
+  start_time: string;
+  end_time: string;
+  duration: number;
+  bytes_in: number;
+  bytes_out: number;
+  close_reason: string;
+}
+
+export interface SessionQuery {
+  tunnel_id?: number;
+  client?: string;
+  target?: string;
+  since?: string;
+  until?: string;
+  cursor?: string;
+  limit?: number;
+}
+
+export interface SessionPage {
+  sessions: ConnectionSession[];
+  count: number;
+  next_cursor: string;
+}
+
+class SessionApi {
+  async querySessions(query: SessionQuery = {}): Promise<SessionPage> {
+    const response = await apiClient.get('/sessions', { params: query });
+    return response.data;
+  }
+
+  async getTunnelSessions(tunnelId: number, query: SessionQuery = {}): Promise<SessionPage> {
+    const response = await apiClient.get(`/tunnels/${tunnelId}/sessions`, { params: query });
+    return response.data;
+  }
+
+  async getSession(id: number): Promise<ConnectionSession> {
+    const response = await apiClient.get(`/sessions/${id}`);
+    return response.data.session;
+  }
+}
+
+export const sessionApi = new SessionApi();
@@ -10,8 +10,38 @@ export interface Tunnel {
   remote_address?: string;
   remote_port?: number;
   description?: string;
-  status: 'active' | 'inactive' | 'error';
+  status: 'active' | 'inactive' | 'error' | 'suspended' | 'standby' | 'connected' | 'degraded';
+  status_reason?: string;
   auto_start: boolean;
+  upload_limit?: number;
+  download_limit?: number;
+  quota_bytes?: number;
+  quota_period?: '' | 'daily' | 'monthly';
+  max_connections?: number;
+  overflow_policy?: 'reject' | 'queue';
+  idle_timeout?: number;
+  max_lifetime?: number;
+  proxy_domains?: string[];
+  tags?: string[];
+  lazy?: boolean;
+  lazy_timeout?: number;
+  depends_on?: number[];
+  labels?: Record<string, string>;
+  start_cron?: string;
+  stop_cron?: string;
+  time_windows?: string[];
+  timezone?: string;
+  health_check?: '' | 'tcp' | 'http' | 'payload';
+  health_target?: string;
+  health_path?: string;
+  health_code?: number;
+  health_send?: string;
+  health_expect?: string;
+  health_interval?: number;
+  health_timeout?: number;
+  health_failures?: number;
+  health_restart?: boolean;
+  owner_id?: number;
   created_at: string;
   updated_at: string;
   host?: {
@@ -24,6 +54,44 @@ export interface Tunnel {
   };
 }
 
+// isTunnelRunning 隧道是否正在运行，按需连接的隧道在等待连接时也在运行
+export const isTunnelRunning = (status: string): boolean =>
+  status === 'active' || status === 'standby' || status === 'connected' || status === 'degraded';
+
+// parseLabels 解析逗号分隔的 key=value 标签
+export const parseLabels = (text: string): Record<string, string> => {
+  const labels: Record<string, string> = {};
+  text.split(',').map(part => part.trim()).filter(Boolean).forEach(part => {
+    const index = part.indexOf('=');
+    if (index < 0) {
+      labels[part] = '';
+    } else {
+      labels[part.slice(0, index).trim()] = part.slice(index + 1).trim();
+    }
+  });
+  return labels;
+};
+
+// formatLabels 将标签格式化为逗号分隔的 key=value
+export const formatLabels = (labels?: Record<string, string>): string =>
+  Object.entries(labels || {}).map(([key, value]) => `${key}=${value}`).join(', ');
+
+export interface BulkTunnelResult {
+  tunnel_id: number;
+  name: string;
+  success: boolean;
+  skipped?: boolean;
+  error?: string;
+}
+
+export interface BulkTunnelResponse {
+  selector: string;
+  matched: number;
+  succeeded: number;
+  failed: number;
+  results: BulkTunnelResult[];
+}
+
 export interface CreateTunnelRequest {
   host_id: number;
   name: string;
@@ -34,6 +102,50 @@ export interface CreateTunnelRequest {
   remote_port?: number;
   description?: string;
   auto_start?: boolean;
+  proxy_domains?: string[];
+  tags?: string[];
+  lazy?: boolean;
+  lazy_timeout?: number;
+  depends_on?: number[];
+  labels?: Record<string, string>;
+  start_cron?: string;
+  stop_cron?: string;
+  time_windows?: string[];
+  timezone?: string;
+  health_check?: '' | 'tcp' | 'http' | 'payload';
+  health_target?: string;
+  health_path?: string;
+  health_code?: number;
+  health_send?: string;
+  health_expect?: string;
+  health_interval?: number;
+  health_timeout?: number;
+  health_failures?: number;
+  health_restart?: boolean;
+}
+
+// HealthStatus 隧道健康检查的状态
+export interface HealthStatus {
+  tunnel_id: number;
+  enabled: boolean;
+  running: boolean;
+  healthy: boolean;
+  degraded: boolean;
+  consecutive_failures: number;
+  last_check?: string;
+  last_error?: string;
+}
+
+// ScheduleStatus 隧道启停计划的状态
+export interface ScheduleStatus {
+  tunnel_id: number;
+  scheduled: boolean;
+  timezone?: string;
+  in_window?: boolean;
+  next?: {
+    action: 'start' | 'stop';
+    at: string;
+  };
 }
 
 export interface LocalServiceMapping {
@@ -81,10 +193,50 @@ export interface ConnectionLog {
   id: number;
   tunnel_id: number;
   event_type: string;
+  client_addr?: string;
   message: string;
   timestamp: string;
 }
 
+export interface ConnectionLogQuery {
+  event_type?: string;
+  since?: string;
+  until?: string;
+  client?: string;
+  q?: string;
+  cursor?: string;
+  limit?: number;
+}
+
+export interface ConnectionLogPage {
+  logs: ConnectionLog[];
+  count: number;
+  next_cursor: string;
+}
+
+export interface TunnelGroup {
+  id: number;
+  name: string;
+  description?: string;
+  owner_id?: number;
+  tunnels: Tunnel[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TunnelGroupRequest {
+  name: string;
+  description?: string;
+  tunnel_ids: number[];
+}
+
+export interface TunnelGroupResult {
+  started?: number[];
+  stopped?: number[];
+  skipped?: number[];
+  rolled_back?: number[];
+}
+
 export interface TunnelStatus {
   status: string;
 }
@@ -96,8 +248,10 @@ class TunnelApi {
     return response.data.tunnel;
   }
 
-  async getAllTunnels(): Promise<Tunnel[]> {
-    const response = await apiClient.get('/tunnels');
+  async getAllTunnels(selector?: string): Promise<Tunnel[]> {
+    const response = await apiClient.get('/tunnels', {
+      params: selector ? { selector } : undefined
+    });
     return response.data.tunnels || [];
   }
 
@@ -138,6 +292,16 @@ class TunnelApi {
     return response.data;
   }
 
+  async getScheduleStatus(id: number): Promise<ScheduleStatus> {
+    const response = await apiClient.get(`/tunnels/${id}/schedule`);
+    return response.data.schedule;
+  }
+
+  async getHealthStatus(id: number): Promise<HealthStatus> {
+    const response = await apiClient.get(`/tunnels/${id}/health`);
+    return response.data.health;
+  }
+
   async getConnectionLogs(id: number, limit = 100): Promise<ConnectionLog[]> {
     const response = await apiClient.get(`/tunnels/${id}/logs`, {
       params: { limit }
@@ -145,6 +309,11 @@ class TunnelApi {
     return response.data.logs || [];
   }
 
+  async queryConnectionLogs(id: number, query: ConnectionLogQuery = {}): Promise<ConnectionLogPage> {
+    const response = await apiClient.get(`/tunnels/${id}/logs`, { params: query });
+    return response.data;
+  }
+
   // 批量操作
   async createMultipleLocalForwards(
     hostId: number,
@@ -162,6 +331,14 @@ class TunnelApi {
     return response.data.tunnel;
   }
 
+  // 按标签选择器批量操作，例如 env=prod,team in (a,b)
+  async bulkTunnels(action: 'start' | 'stop' | 'delete', selector: string): Promise<BulkTunnelResponse> {
+    const response = await apiClient.post(`/tunnels/bulk/${action}`, null, {
+      params: { selector }
+    });
+    return response.data;
+  }
+
   async startAutoTunnels(): Promise<void> {
     await apiClient.post('/tunnels/auto-start');
   }
@@ -170,6 +347,36 @@ class TunnelApi {
     await apiClient.post('/tunnels/stop-all');
   }
 
+  // 隧道组
+  async getTunnelGroups(): Promise<TunnelGroup[]> {
+    const response = await apiClient.get('/tunnel-groups');
+    return response.data.groups || [];
+  }
+
+  async createTunnelGroup(group: TunnelGroupRequest): Promise<TunnelGroup> {
+    const response = await apiClient.post('/tunnel-groups', group);
+    return response.data.group;
+  }
+
+  async updateTunnelGroup(id: number, group: TunnelGroupRequest): Promise<TunnelGroup> {
+    const response = await apiClient.put(`/tunnel-groups/${id}`, group);
+    return response.data.group;
+  }
+
+  async deleteTunnelGroup(id: number): Promise<void> {
+    await apiClient.delete(`/tunnel-groups/${id}`);
+  }
+
+  async startTunnelGroup(id: number): Promise<TunnelGroupResult> {
+    const response = await apiClient.post(`/tunnel-groups/${id}/start`);
+    return response.data.result;
+  }
+
+  async stopTunnelGroup(id: number): Promise<TunnelGroupResult> {
+    const response = await apiClient.post(`/tunnel-groups/${id}/stop`);
+    return response.data.result;
+  }
+
   // 服务健康检查
   async checkServiceHealth(request: HealthCheckRequest): Promise<HealthCheckResponse> {
     const response = await apiClient.post('/service/health-check', request);
@@ -1,6 +1,6 @@
 import React, { useState, useEffect } from 'react'
-import { exportApi } from '../api/exportApi'
-import { ClashExportResponse, Socks5StatusResponse } from '../types'
+import { exportApi, ProxyExportFormat } from '../api/exportApi'
+import { ClashExportResponse, ExportProfile, Socks5StatusResponse, Subscription } from '../types'
 
 const ClashExport: React.FC = () => {
   const [socks5Status, setSocks5Status] = useState<Socks5StatusResponse | null>(null)
@@ -9,6 +9,16 @@ const ClashExport: React.FC = () => {
   const [downloading, setDownloading] = useState(false)
   const [error, setError] = useState<string | null>(null)
   const [showPreview, setShowPreview] = useState(false)
+  const [format, setFormat] = useState<ProxyExportFormat>('clash')
+  // 导出模板，空字符串表示使用默认设置导出全部隧道
+  const [profiles, setProfiles] = useState<ExportProfile[]>([])
+  const [profile, setProfile] = useState('')
+  // 包含未运行的隧道，以及导出时按需启动这些隧道
+  const [includeInactive, setIncludeInactive] = useState(false)
+  const [startOnDemand, setStartOnDemand] = useState(false)
+  // 订阅列表，以及刚创建的订阅地址（只显示一次）
+  const [subscriptions, setSubscriptions] = useState<Subscription[]>([])
+  const [subscriptionUrl, setSubscriptionUrl] = useState<string | null>(null)
 
   // 样式定义
   const cardStyle: React.CSSProperties = {
@@ -69,7 +79,7 @@ const ClashExport: React.FC = () => {
     try {
       setLoading(true)
       setError(null)
-      const status = await exportApi.getSocks5Status()
+      const status = await exportApi.getSocks5Status(includeInactive)
       setSocks5Status(status)
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to fetch SOCKS5 status')
@@ -84,7 +94,7 @@ const ClashExport: React.FC = () => {
     try {
       setLoading(true)
       setError(null)
-      const preview = await exportApi.getClashConfigPreview()
+      const preview = await exportApi.getClashConfigPreview(profile || undefined, { includeInactive })
       setClashPreview(preview)
       setShowPreview(true)
     } catch (err) {
@@ -95,19 +105,65 @@ const ClashExport: React.FC = () => {
     }
   }
 
-  // 下载Clash配置
+  // 下载所选格式的代理客户端配置
   const handleDownloadConfig = async () => {
     try {
       setDownloading(true)
       setError(null)
-      await exportApi.downloadClashConfig()
+      const options = { includeInactive, startOnDemand }
+      if (format === 'clash') {
+        await exportApi.downloadClashConfig(profile || undefined, options)
+      } else {
+        await exportApi.downloadProxyConfig(format, profile || undefined, options)
+      }
+      // 按需启动会改变隧道状态
+      if (startOnDemand) {
+        fetchSocks5Status()
+      }
     } catch (err) {
-      setError(err instanceof Error ? err.message : 'Failed to download Clash config')
+      setError(err instanceof Error ? err.message : 'Failed to download proxy config')
     } finally {
       setDownloading(false)
     }
   }
 
+  const fetchSubscriptions = async () => {
+    try {
+      setSubscriptions(await exportApi.getSubscriptions())
+    } catch {
+      setSubscriptions([])
+    }
+  }
+
+  // 使用当前选择的格式和模板创建订阅
+  const handleCreateSubscription = async () => {
+    const name = window.prompt('订阅名称（客户端中显示的配置名称）')
+    if (!name?.trim()) {
+      return
+    }
+    try {
+      setError(null)
+      const selected = profiles.find(item => item.name === profile)
+      const result = await exportApi.createSubscription({ name: name.trim(), format, profile_id: selected?.id })
+      setSubscriptionUrl(result.url)
+      fetchSubscriptions()
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to create subscription')
+    }
+  }
+
+  const handleRevokeSubscription = async (subscription: Subscription) => {
+    if (!window.confirm(`吊销订阅 ${subscription.name}？使用此订阅地址的客户端将无法再更新配置。`)) {
+      return
+    }
+    try {
+      await exportApi.revokeSubscription(subscription.id)
+      fetchSubscriptions()
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to revoke subscription')
+    }
+  }
+
   // 刷新数据
   const handleRefresh = () => {
     fetchSocks5Status()
@@ -118,9 +174,15 @@ const ClashExport: React.FC = () => {
 
   // 初始化加载
   useEffect(() => {
-    fetchSocks5Status()
+    // 没有模板时不显示模板选择
+    exportApi.getExportProfiles().then(setProfiles).catch(() => setProfiles([]))
+    fetchSubscriptions()
   }, [])
 
+  useEffect(() => {
+    fetchSocks5Status()
+  }, [includeInactive])
+
   return (
     <div>
       <div style={cardStyle}>
@@ -165,7 +227,7 @@ const ClashExport: React.FC = () => {
             {socks5Status.tunnels.length > 0 && (
               <div style={{ marginBottom: '1.5rem' }}>
                 <h4 style={{ margin: '0 0 0.75rem 0', display: 'flex', alignItems: 'center', gap: '0.5rem' }}>
-                  ⚡ 活跃的 SOCKS5 隧道
+                  ⚡ {includeInactive ? 'SOCKS5 隧道' : '活跃的 SOCKS5 隧道'}
                 </h4>
                 <div style={{ display: 'flex', flexDirection: 'column', gap: '0.5rem' }}>
                   {socks5Status.tunnels.map((tunnel) => (
@@ -192,6 +254,53 @@ const ClashExport: React.FC = () => {
               >
                 👁️ 预览配置
               </button>
+              <select
+                style={buttonStyle}
+                value={format}
+                onChange={(e) => setFormat(e.target.value as ProxyExportFormat)}
+              >
+                <option value="clash">Clash</option>
+                <option value="sing-box">sing-box</option>
+                <option value="xray">V2Ray / Xray</option>
+                <option value="surge">Surge</option>
+                <option value="pac">PAC</option>
+              </select>
+              {profiles.length > 0 && (
+                <select
+                  style={buttonStyle}
+                  value={profile}
+                  onChange={(e) => setProfile(e.target.value)}
+                  title="导出模板"
+                >
+                  <option value="">默认模板</option>
+                  {profiles.map(item => (
+                    <option key={item.id} value={item.name}>{item.name}</option>
+                  ))}
+                </select>
+              )}
+              <label style={{ display: 'inline-flex', alignItems: 'center', gap: '0.25rem', fontSize: '0.875rem' }}>
+                <input
+                  type="checkbox"
+                  checked={includeInactive}
+                  onChange={(e) => {
+                    setIncludeInactive(e.target.checked)
+                    if (!e.target.checked) setStartOnDemand(false)
+                  }}
+                />
+                包含未运行的隧道
+              </label>
+              <label
+                style={{ display: 'inline-flex', alignItems: 'center', gap: '0.25rem', fontSize: '0.875rem' }}
+                title="导出时启动未运行的隧道：立即监听端口，首个客户端连接时才建立SSH连接，空闲后断开"
+              >
+                <input
+                  type="checkbox"
+                  checked={startOnDemand}
+                  disabled={!includeInactive}
+                  onChange={(e) => setStartOnDemand(e.target.checked)}
+                />
+                按需启动
+              </label>
               <button
                 style={socks5Status.can_export && !downloading ? primaryButtonStyle : { ...buttonStyle, opacity: 0.5 }}
                 onClick={handleDownloadConfig}
@@ -204,7 +313,7 @@ const ClashExport: React.FC = () => {
             {/* 提示信息 */}
             {!socks5Status.can_export ? (
               <div style={alertStyle}>
-                ⚠️ 没有找到活跃的 SOCKS5 隧道。请先启动至少一个 SOCKS5 类型的隧道，然后再导出 Clash 配置。
+                ⚠️ 没有找到活跃的 SOCKS5 隧道。请先启动至少一个 SOCKS5 类型的隧道，或勾选“包含未运行的隧道”后再导出 Clash 配置。
               </div>
             ) : (
               <div style={alertStyle}>
@@ -226,6 +335,54 @@ const ClashExport: React.FC = () => {
         )}
       </div>
 
+      {/* 订阅 */}
+      <div style={cardStyle}>
+        <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', marginBottom: '1rem' }}>
+          <h3 style={{ margin: 0, fontSize: '1.125rem', fontWeight: '600', color: '#1e293b' }}>
+            🔗 订阅地址
+          </h3>
+          <button style={buttonStyle} onClick={handleCreateSubscription}>
+            ＋ 按当前格式和模板创建
+          </button>
+        </div>
+
+        {subscriptionUrl && (
+          <div style={alertStyle}>
+            <div style={{ marginBottom: '0.5rem' }}>订阅地址只显示一次，请复制到代理客户端中：</div>
+            <code style={{ wordBreak: 'break-all' }}>{subscriptionUrl}</code>
+          </div>
+        )}
+
+        {subscriptions.length === 0 ? (
+          <div style={{ fontSize: '0.875rem', color: '#64748b' }}>
+            还没有订阅。客户端通过订阅地址定期拉取最新配置，并显示本月流量。
+          </div>
+        ) : (
+          <div style={{ display: 'flex', flexDirection: 'column', gap: '0.5rem' }}>
+            {subscriptions.map(subscription => (
+              <div key={subscription.id} style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', padding: '0.75rem', backgroundColor: '#f8fafc', borderRadius: '6px', opacity: subscription.revoked_at ? 0.5 : 1 }}>
+                <div>
+                  <div style={{ fontWeight: '500' }}>
+                    {subscription.name} <span style={badgeStyle}>{subscription.format}</span>{' '}
+                    {subscription.profile && <span style={badgeStyle}>{subscription.profile.name}</span>}
+                  </div>
+                  <div style={{ fontSize: '0.875rem', color: '#64748b' }}>
+                    {subscription.prefix}… · 拉取 {subscription.fetch_count} 次
+                    {subscription.last_fetched_at && ` · 最后拉取 ${new Date(subscription.last_fetched_at).toLocaleString()}`}
+                    {subscription.revoked_at && ' · 已吊销'}
+                  </div>
+                </div>
+                {!subscription.revoked_at && (
+                  <button style={buttonStyle} onClick={() => handleRevokeSubscription(subscription)}>
+                    吊销
+                  </button>
+                )}
+              </div>
+            ))}
+          </div>
+        )}
+      </div>
+
       {/* Clash配置预览 */}
       {showPreview && clashPreview && (
         <div style={cardStyle}>
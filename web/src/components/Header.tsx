@@ -1,9 +1,11 @@
 interface HeaderProps {
   currentView: string
   onNavigate: (view: string) => void
+  username?: string
+  onLogout?: () => void
 }
 
-const Header = ({ currentView, onNavigate }: HeaderProps) => {
+const Header = ({ currentView, onNavigate, username, onLogout }: HeaderProps) => {
   const linkStyle = (isActive: boolean): React.CSSProperties => ({
     marginRight: '1rem',
     padding: '0.5rem 1rem',
@@ -59,6 +61,16 @@ const Header = ({ currentView, onNavigate }: HeaderProps) => {
           >
             Settings
           </button>
+          {onLogout && (
+            <>
+              <span style={{ marginLeft: '1rem', marginRight: '0.5rem', fontSize: '0.875rem', color: '#64748b' }}>
+                {username}
+              </span>
+              <button onClick={onLogout} style={linkStyle(false)}>
+                Logout
+              </button>
+            </>
+          )}
         </nav>
       </div>
     </header>
@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react'
 import { Host } from '../types'
+import { parseLabels, formatLabels } from '../api/tunnelApi'
 
 interface HostFormProps {
   host?: Host | null
@@ -22,6 +23,8 @@ const HostForm = ({ host, onSave, onCancel, loading = false }: HostFormProps) =>
     description: '',
   })
   const [errors, setErrors] = useState<Record<string, string>>({})
+  // 标签以逗号分隔的 key=value 输入
+  const [labels, setLabels] = useState('')
 
   useEffect(() => {
     if (host) {
@@ -37,6 +40,7 @@ const HostForm = ({ host, onSave, onCancel, loading = false }: HostFormProps) =>
         passphrase: '', // Don't populate passphrase for security
         description: host.description,
       })
+      setLabels(formatLabels(host.labels))
     }
   }, [host])
 
@@ -108,6 +112,7 @@ const HostForm = ({ host, onSave, onCancel, loading = false }: HostFormProps) =>
         password: formData.password || undefined,
         private_key: formData.private_key || undefined,
         passphrase: formData.passphrase || undefined,
+        labels: parseLabels(labels),
       }
 
       await onSave(submitData)
@@ -252,7 +257,7 @@ const HostForm = ({ host, onSave, onCancel, loading = false }: HostFormProps) =>
                 value={formData.key_path}
                 onChange={handleInputChange}
                 style={inputStyle}
-                placeholder="/path/to/private/key (optional if private key provided below)"
+                placeholder="File in the server key directory (ssh.key_dir), optional if private key provided below"
               />
             </div>
 
@@ -273,6 +278,11 @@ const HostForm = ({ host, onSave, onCancel, loading = false }: HostFormProps) =>
                 }}
                 placeholder={host ? "Leave empty to keep current key" : "Paste private key content here (alternative to key path)"}
               />
+              {host?.key_fingerprint && (
+                <div style={{ fontSize: '0.75rem', color: '#6b7280', marginTop: '0.25rem', fontFamily: 'monospace' }}>
+                  Current key: {host.key_fingerprint}
+                </div>
+              )}
               {errors.private_key && <div style={errorStyle}>{errors.private_key}</div>}
             </div>
           </>
@@ -295,6 +305,18 @@ const HostForm = ({ host, onSave, onCancel, loading = false }: HostFormProps) =>
           </div>
         )}
 
+        <div style={{ marginBottom: '1rem' }}>
+          <label style={labelStyle}>Labels</label>
+          <input
+            type="text"
+            name="labels"
+            value={labels}
+            onChange={(e) => setLabels(e.target.value)}
+            style={inputStyle}
+            placeholder="env=prod, region=eu"
+          />
+        </div>
+
         <div style={{ marginBottom: '2rem' }}>
           <label style={labelStyle}>Description</label>
           <textarea
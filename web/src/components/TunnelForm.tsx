@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { Tunnel, CreateTunnelRequest, tunnelApi } from '../api/tunnelApi';
+import { Tunnel, CreateTunnelRequest, tunnelApi, parseLabels, formatLabels } from '../api/tunnelApi';
 import { Host } from '../api/hostApi';
 
 interface TunnelFormProps {
@@ -9,6 +9,18 @@ interface TunnelFormProps {
   onCancel: () => void;
 }
 
+// escapePayload 将健康检查内容中的换行等控制字符转义，便于在单行输入框中编辑
+const escapePayload = (value?: string): string => (value ? JSON.stringify(value).slice(1, -1) : '');
+
+// unescapePayload 还原转义的健康检查内容，无法解析时按原样使用
+const unescapePayload = (value: string): string => {
+  try {
+    return JSON.parse(`"${value}"`);
+  } catch {
+    return value;
+  }
+};
+
 const TunnelForm: React.FC<TunnelFormProps> = ({ hosts, tunnel, onSubmit, onCancel }) => {
   const [formData, setFormData] = useState<CreateTunnelRequest>({
     host_id: hosts.length > 0 ? hosts[0].id : 0,
@@ -19,13 +31,39 @@ const TunnelForm: React.FC<TunnelFormProps> = ({ hosts, tunnel, onSubmit, onCanc
     remote_address: '',
     remote_port: 80,
     description: '',
-    auto_start: false
+    auto_start: false,
+    lazy: false,
+    lazy_timeout: 0,
+    start_cron: '',
+    stop_cron: '',
+    timezone: '',
+    health_check: '',
+    health_target: '',
+    health_path: '',
+    health_code: 0,
+    health_interval: 0,
+    health_timeout: 0,
+    health_failures: 0,
+    health_restart: false
   });
 
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string>('');
   const [checking, setChecking] = useState(false);
   const [portAvailable, setPortAvailable] = useState<boolean | null>(null);
+  // 代理域名以逗号分隔输入，提交时拆分为列表
+  const [proxyDomains, setProxyDomains] = useState('');
+  // 标签同样以逗号分隔输入
+  const [tags, setTags] = useState('');
+  // 依赖的隧道ID同样以逗号分隔输入
+  const [dependsOn, setDependsOn] = useState('');
+  // 标签以逗号分隔的 key=value 输入
+  const [labels, setLabels] = useState('');
+  // 时间段以分号分隔输入，星期列表本身包含逗号
+  const [timeWindows, setTimeWindows] = useState('');
+  // 健康检查发送和期望的内容以转义形式输入，例如 PING\r\n
+  const [healthSend, setHealthSend] = useState('');
+  const [healthExpect, setHealthExpect] = useState('');
 
   // 如果是编辑模式，填充表单数据
   useEffect(() => {
@@ -39,8 +77,28 @@ const TunnelForm: React.FC<TunnelFormProps> = ({ hosts, tunnel, onSubmit, onCanc
         remote_address: tunnel.remote_address || '',
         remote_port: tunnel.remote_port || 80,
         description: tunnel.description || '',
-        auto_start: tunnel.auto_start
+        auto_start: tunnel.auto_start,
+        lazy: tunnel.lazy || false,
+        lazy_timeout: tunnel.lazy_timeout || 0,
+        start_cron: tunnel.start_cron || '',
+        stop_cron: tunnel.stop_cron || '',
+        timezone: tunnel.timezone || '',
+        health_check: tunnel.health_check || '',
+        health_target: tunnel.health_target || '',
+        health_path: tunnel.health_path || '',
+        health_code: tunnel.health_code || 0,
+        health_interval: tunnel.health_interval || 0,
+        health_timeout: tunnel.health_timeout || 0,
+        health_failures: tunnel.health_failures || 0,
+        health_restart: tunnel.health_restart || false
       });
+      setProxyDomains((tunnel.proxy_domains || []).join(', '));
+      setTags((tunnel.tags || []).join(', '));
+      setDependsOn((tunnel.depends_on || []).join(', '));
+      setLabels(formatLabels(tunnel.labels));
+      setTimeWindows((tunnel.time_windows || []).join('; '));
+      setHealthSend(escapePayload(tunnel.health_send));
+      setHealthExpect(escapePayload(tunnel.health_expect));
     }
   }, [tunnel]);
 
@@ -123,10 +181,24 @@ const TunnelForm: React.FC<TunnelFormProps> = ({ hosts, tunnel, onSubmit, onCanc
         description: formData.description?.trim()
       };
 
+      submitData.tags = tags.split(',').map(tag => tag.trim()).filter(Boolean);
+      submitData.labels = parseLabels(labels);
+      submitData.depends_on = dependsOn.split(',').map(id => parseInt(id.trim(), 10)).filter(id => id > 0);
+      submitData.time_windows = timeWindows.split(';').map(window => window.trim()).filter(Boolean);
+      submitData.health_send = unescapePayload(healthSend);
+      submitData.health_expect = unescapePayload(healthExpect);
+
+      // 远程转发由远程主机监听端口，不支持按需连接
+      if (formData.type === 'remote_forward') {
+        submitData.lazy = false;
+        submitData.lazy_timeout = 0;
+      }
+
       // 对于动态隧道，不需要远程地址和端口
       if (formData.type === 'dynamic') {
         delete submitData.remote_address;
         delete submitData.remote_port;
+        submitData.proxy_domains = proxyDomains.split(',').map(domain => domain.trim()).filter(Boolean);
       }
 
       await onSubmit(submitData);
@@ -276,6 +348,119 @@ const TunnelForm: React.FC<TunnelFormProps> = ({ hosts, tunnel, onSubmit, onCanc
             </>
           )}
 
+          {formData.type === 'dynamic' && (
+            <div className="form-group">
+              <label htmlFor="proxy_domains">Proxy Domains (Optional)</label>
+              <input
+                type="text"
+                id="proxy_domains"
+                name="proxy_domains"
+                value={proxyDomains}
+                onChange={(e) => setProxyDomains(e.target.value)}
+                placeholder="corp.example.com, intranet.test"
+              />
+              <small className="form-hint">
+                Exported PAC, Clash, sing-box, Xray and Surge configs route these domains and their subdomains through this tunnel
+              </small>
+            </div>
+          )}
+
+          <div className="form-group">
+            <label htmlFor="tags">Tags (Optional)</label>
+            <input
+              type="text"
+              id="tags"
+              name="tags"
+              value={tags}
+              onChange={(e) => setTags(e.target.value)}
+              placeholder="work, eu"
+            />
+            <small className="form-hint">
+              Export profiles can select tunnels by tag
+            </small>
+          </div>
+
+          <div className="form-group">
+            <label htmlFor="labels">Labels (Optional)</label>
+            <input
+              type="text"
+              id="labels"
+              name="labels"
+              value={labels}
+              onChange={(e) => setLabels(e.target.value)}
+              placeholder="env=prod, team=infra"
+            />
+            <small className="form-hint">
+              Tunnel lists and bulk operations can select tunnels by label selector
+            </small>
+          </div>
+
+          <div className="form-group">
+            <label htmlFor="depends_on">Depends On (Optional)</label>
+            <input
+              type="text"
+              id="depends_on"
+              name="depends_on"
+              value={dependsOn}
+              onChange={(e) => setDependsOn(e.target.value)}
+              placeholder="Tunnel IDs, e.g. 1, 2"
+            />
+            <small className="form-hint">
+              These tunnels are started first when starting a group or auto-starting
+            </small>
+          </div>
+
+          <div className="form-group">
+            <label htmlFor="start_cron">Start Cron (Optional)</label>
+            <input
+              type="text"
+              id="start_cron"
+              name="start_cron"
+              value={formData.start_cron}
+              onChange={handleInputChange}
+              placeholder="0 1 * * *"
+            />
+          </div>
+
+          <div className="form-group">
+            <label htmlFor="stop_cron">Stop Cron (Optional)</label>
+            <input
+              type="text"
+              id="stop_cron"
+              name="stop_cron"
+              value={formData.stop_cron}
+              onChange={handleInputChange}
+              placeholder="0 4 * * *"
+            />
+          </div>
+
+          <div className="form-group">
+            <label htmlFor="time_windows">Time Windows (Optional)</label>
+            <input
+              type="text"
+              id="time_windows"
+              name="time_windows"
+              value={timeWindows}
+              onChange={(e) => setTimeWindows(e.target.value)}
+              placeholder="01:00-04:00; sat,sun 22:00-06:00"
+            />
+            <small className="form-hint">
+              The tunnel only runs inside these windows; cannot be combined with cron or auto start
+            </small>
+          </div>
+
+          <div className="form-group">
+            <label htmlFor="timezone">Timezone (Optional)</label>
+            <input
+              type="text"
+              id="timezone"
+              name="timezone"
+              value={formData.timezone}
+              onChange={handleInputChange}
+              placeholder="Server local time, e.g. Asia/Shanghai"
+            />
+          </div>
+
           <div className="form-group">
             <label htmlFor="description">Description (Optional)</label>
             <textarea
@@ -300,6 +485,158 @@ const TunnelForm: React.FC<TunnelFormProps> = ({ hosts, tunnel, onSubmit, onCanc
             </label>
           </div>
 
+          {formData.type !== 'remote_forward' && (
+            <div className="form-group">
+              <label className="checkbox-label">
+                <input
+                  type="checkbox"
+                  name="lazy"
+                  checked={formData.lazy || false}
+                  onChange={handleInputChange}
+                />
+                Connect SSH on demand
+              </label>
+              <small className="form-hint">
+                The local port stays open, SSH connects on the first client connection and disconnects when idle
+              </small>
+              {formData.lazy && (
+                <input
+                  type="number"
+                  name="lazy_timeout"
+                  value={formData.lazy_timeout || ''}
+                  onChange={handleInputChange}
+                  placeholder="Idle disconnect after seconds (default 300)"
+                  min={0}
+                />
+              )}
+            </div>
+          )}
+
+          <div className="form-group">
+            <label htmlFor="health_check">Health Check (Optional)</label>
+            <select
+              id="health_check"
+              name="health_check"
+              value={formData.health_check}
+              onChange={handleInputChange}
+            >
+              <option value="">Disabled</option>
+              <option value="tcp">TCP connect</option>
+              <option value="http">HTTP GET</option>
+              <option value="payload">Send payload and expect response</option>
+            </select>
+            <small className="form-hint">
+              Probes run through the tunnel: the remote target for local forwards, the local service for remote forwards
+            </small>
+          </div>
+
+          {formData.health_check && (
+            <>
+              <div className="form-group">
+                <label htmlFor="health_target">Health Check Target</label>
+                <input
+                  type="text"
+                  id="health_target"
+                  name="health_target"
+                  value={formData.health_target}
+                  onChange={handleInputChange}
+                  placeholder={formData.type === 'dynamic' ? 'host:port (required)' : 'host:port (defaults to the tunnel target)'}
+                />
+              </div>
+
+              {formData.health_check === 'http' && (
+                <div className="form-group">
+                  <label htmlFor="health_path">HTTP Path and Expected Status</label>
+                  <input
+                    type="text"
+                    id="health_path"
+                    name="health_path"
+                    value={formData.health_path}
+                    onChange={handleInputChange}
+                    placeholder="/healthz"
+                  />
+                  <input
+                    type="number"
+                    name="health_code"
+                    value={formData.health_code || ''}
+                    onChange={handleInputChange}
+                    placeholder="Expected status (default any 2xx or 3xx)"
+                    min={0}
+                  />
+                </div>
+              )}
+
+              {formData.health_check === 'payload' && (
+                <div className="form-group">
+                  <label htmlFor="health_send">Payload to Send</label>
+                  <input
+                    type="text"
+                    id="health_send"
+                    name="health_send"
+                    value={healthSend}
+                    onChange={(e) => setHealthSend(e.target.value)}
+                    placeholder="PING\r\n"
+                  />
+                </div>
+              )}
+
+              {formData.health_check !== 'tcp' && (
+                <div className="form-group">
+                  <label htmlFor="health_expect">Expected Response (Optional)</label>
+                  <input
+                    type="text"
+                    id="health_expect"
+                    name="health_expect"
+                    value={healthExpect}
+                    onChange={(e) => setHealthExpect(e.target.value)}
+                    placeholder="+PONG"
+                  />
+                </div>
+              )}
+
+              <div className="form-group">
+                <label htmlFor="health_interval">Interval, Timeout and Failure Threshold</label>
+                <input
+                  type="number"
+                  id="health_interval"
+                  name="health_interval"
+                  value={formData.health_interval || ''}
+                  onChange={handleInputChange}
+                  placeholder="Interval in seconds (default 30)"
+                  min={0}
+                />
+                <input
+                  type="number"
+                  name="health_timeout"
+                  value={formData.health_timeout || ''}
+                  onChange={handleInputChange}
+                  placeholder="Timeout in seconds (default 5)"
+                  min={0}
+                />
+                <input
+                  type="number"
+                  name="health_failures"
+                  value={formData.health_failures || ''}
+                  onChange={handleInputChange}
+                  placeholder="Consecutive failures before degraded (default 3)"
+                  min={0}
+                />
+              </div>
+
+              <div className="form-group">
+                <label className="checkbox-label">
+                  <input
+                    type="checkbox"
+                    name="health_restart"
+                    checked={formData.health_restart || false}
+                    onChange={handleInputChange}
+                  />
+                  Restart the tunnel when it becomes degraded
+                </label>
+              </div>
+            </>
+          )}
+
           <div className="tunnel-form-footer">
             <button
               type="button"
@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { Tunnel } from '../api/tunnelApi';
+import { Tunnel, isTunnelRunning } from '../api/tunnelApi';
 import { Host } from '../api/hostApi';
 import TunnelStatusBadge from './TunnelStatusBadge';
 import TunnelLogs from './TunnelLogs';
@@ -95,7 +95,7 @@ const TunnelList: React.FC<TunnelListProps> = ({
               )}
             </div>
             <div className="tunnel-actions">
-              {tunnel.status === 'active' ? (
+              {isTunnelRunning(tunnel.status) ? (
                 <>
                   <button
                     onClick={(e) => {
@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { tunnelApi, Tunnel, CreateTunnelRequest } from '../api/tunnelApi';
+import { tunnelApi, Tunnel, TunnelGroup, CreateTunnelRequest } from '../api/tunnelApi';
 import { hostApi, Host } from '../api/hostApi';
 import TunnelList from './TunnelList';
 import TunnelForm from './TunnelForm';
@@ -8,11 +8,14 @@ import './TunnelManagement.css';
 const TunnelManagement: React.FC = () => {
   const [tunnels, setTunnels] = useState<Tunnel[]>([]);
   const [hosts, setHosts] = useState<Host[]>([]);
+  const [groups, setGroups] = useState<TunnelGroup[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string>('');
   const [showCreateForm, setShowCreateForm] = useState(false);
   const [editingTunnel, setEditingTunnel] = useState<Tunnel | null>(null);
   const [selectedHost, setSelectedHost] = useState<string>('all');
+  // 标签选择器，例如 env=prod,team in (a,b)，为空时显示所有隧道
+  const [selector, setSelector] = useState('');
   const [autoRefresh, setAutoRefresh] = useState(false);
   const [refreshInterval, setRefreshInterval] = useState<number | null>(null);
 
@@ -20,12 +23,14 @@ const TunnelManagement: React.FC = () => {
   const loadData = async () => {
     try {
       setLoading(true);
-      const [tunnelsData, hostsData] = await Promise.all([
-        tunnelApi.getAllTunnels(),
-        hostApi.getAllHosts()
+      const [tunnelsData, hostsData, groupsData] = await Promise.all([
+        tunnelApi.getAllTunnels(selector.trim()),
+        hostApi.getAllHosts(),
+        tunnelApi.getTunnelGroups()
       ]);
       setTunnels(tunnelsData);
       setHosts(hostsData);
+      setGroups(groupsData);
       setError('');
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to load data');
@@ -153,6 +158,84 @@ const TunnelManagement: React.FC = () => {
     }
   };
 
+  // 按标签选择器批量操作
+  const handleBulk = async (action: 'start' | 'stop' | 'delete') => {
+    const value = selector.trim();
+    if (!value) {
+      setError('Enter a label selector first');
+      return;
+    }
+    if (action === 'delete' && !window.confirm(`Delete all tunnels matching "${value}"?`)) {
+      return;
+    }
+    try {
+      const result = await tunnelApi.bulkTunnels(action, value);
+      await loadData();
+      const failures = result.results.filter(item => !item.success);
+      setError(failures.length > 0
+        ? failures.map(item => `${item.name}: ${item.error}`).join('; ')
+        : '');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : `Failed to ${action} tunnels`);
+    }
+  };
+
+  // 创建隧道组
+  const handleCreateGroup = async () => {
+    const name = window.prompt('Group name');
+    if (!name) {
+      return;
+    }
+    const ids = window.prompt('Tunnel IDs in this group, e.g. 1, 2, 3') || '';
+    try {
+      await tunnelApi.createTunnelGroup({
+        name,
+        tunnel_ids: ids.split(',').map(id => parseInt(id.trim(), 10)).filter(id => id > 0)
+      });
+      await loadData();
+      setError('');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to create tunnel group');
+    }
+  };
+
+  // 删除隧道组，组内的隧道保留
+  const handleDeleteGroup = async (id: number) => {
+    if (!window.confirm('Are you sure you want to delete this group? Its tunnels are kept.')) {
+      return;
+    }
+    try {
+      await tunnelApi.deleteTunnelGroup(id);
+      await loadData();
+      setError('');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to delete tunnel group');
+    }
+  };
+
+  // 按依赖顺序启动隧道组，任一隧道失败时整组回滚
+  const handleStartGroup = async (id: number) => {
+    try {
+      await tunnelApi.startTunnelGroup(id);
+      await loadData();
+      setError('');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to start tunnel group');
+      await loadData();
+    }
+  };
+
+  // 停止隧道组
+  const handleStopGroup = async (id: number) => {
+    try {
+      await tunnelApi.stopTunnelGroup(id);
+      await loadData();
+      setError('');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to stop tunnel group');
+    }
+  };
+
   // 过滤隧道
   const filteredTunnels = selectedHost === 'all'
     ? tunnels
@@ -179,6 +262,27 @@ const TunnelManagement: React.FC = () => {
               </option>
             ))}
           </select>
+          <input
+            type="text"
+            value={selector}
+            onChange={(e) => setSelector(e.target.value)}
+            onKeyDown={(e) => e.key === 'Enter' && loadData()}
+            placeholder="Label selector, e.g. env=prod"
+            className="host-filter"
+          />
+          {selector.trim() && (
+            <>
+              <button onClick={() => handleBulk('start')} className="btn btn-success">
+                Start Matching
+              </button>
+              <button onClick={() => handleBulk('stop')} className="btn btn-warning">
+                Stop Matching
+              </button>
+              <button onClick={() => handleBulk('delete')} className="btn btn-danger">
+                Delete Matching
+              </button>
+            </>
+          )}
           <button
             onClick={() => setShowCreateForm(true)}
             className="btn btn-primary"
@@ -218,6 +322,32 @@ const TunnelManagement: React.FC = () => {
         </div>
       )}
 
+      <div className="tunnel-groups">
+        <div className="tunnel-groups-header">
+          <h3>Groups</h3>
+          <button onClick={handleCreateGroup} className="btn btn-secondary btn-sm">
+            Create Group
+          </button>
+        </div>
+        {groups.map(group => (
+          <div key={group.id} className="tunnel-group-item">
+            <span className="tunnel-group-name">{group.name}</span>
+            <span className="tunnel-group-members">
+              {group.tunnels.map(tunnel => tunnel.name).join(', ') || 'No tunnels'}
+            </span>
+            <button onClick={() => handleStartGroup(group.id)} className="btn btn-success btn-sm">
+              Start
+            </button>
+            <button onClick={() => handleStopGroup(group.id)} className="btn btn-warning btn-sm">
+              Stop
+            </button>
+            <button onClick={() => handleDeleteGroup(group.id)} className="btn btn-danger btn-sm">
+              Delete
+            </button>
+          </div>
+        ))}
+      </div>
+
       <TunnelList
         tunnels={filteredTunnels}
         hosts={hosts}
@@ -26,6 +26,30 @@ const TunnelStatusBadge: React.FC<TunnelStatusBadgeProps> = ({ status, size = 'm
           label: 'Error',
           color: '#dc3545'
         };
+      case 'standby':
+        return {
+          className: 'status-standby',
+          label: 'Standby',
+          color: '#17a2b8'
+        };
+      case 'connected':
+        return {
+          className: 'status-connected',
+          label: 'Connected',
+          color: '#28a745'
+        };
+      case 'degraded':
+        return {
+          className: 'status-degraded',
+          label: 'Degraded',
+          color: '#e0a800'
+        };
+      case 'suspended':
+        return {
+          className: 'status-suspended',
+          label: 'Suspended',
+          color: '#fd7e14'
+        };
       default:
         return {
           className: 'status-unknown',
@@ -0,0 +1,106 @@
+import { useState } from 'react'
+import { authApi, User } from '../api/authApi'
+
+interface LoginProps {
+  onLogin: (user: User) => void
+}
+
+const Login = ({ onLogin }: LoginProps) => {
+  const [username, setUsername] = useState('')
+  const [password, setPassword] = useState('')
+  const [error, setError] = useState<string | null>(null)
+  const [loading, setLoading] = useState(false)
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      setLoading(true)
+      setError(null)
+      const user = await authApi.login(username, password)
+      onLogin(user)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Login failed')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const inputStyle: React.CSSProperties = {
+    width: '100%',
+    padding: '0.5rem',
+    border: '1px solid #cbd5e1',
+    borderRadius: '4px',
+    fontSize: '0.875rem',
+    boxSizing: 'border-box',
+  }
+
+  return (
+    <div style={{ minHeight: '100vh', display: 'flex', alignItems: 'center', justifyContent: 'center', backgroundColor: '#f8fafc' }}>
+      <form
+        onSubmit={handleSubmit}
+        style={{
+          width: '320px',
+          padding: '2rem',
+          backgroundColor: 'white',
+          borderRadius: '8px',
+          boxShadow: '0 1px 3px rgba(0, 0, 0, 0.1)',
+        }}
+      >
+        <h1 style={{ margin: '0 0 1.5rem', fontSize: '1.5rem', fontWeight: '700', color: '#1e293b' }}>
+          Drilling Platform
+        </h1>
+
+        {error && (
+          <div style={{ marginBottom: '1rem', padding: '0.5rem', color: '#dc2626', backgroundColor: '#fef2f2', borderRadius: '4px', fontSize: '0.875rem' }}>
+            {error}
+          </div>
+        )}
+
+        <label style={{ display: 'block', marginBottom: '1rem', fontSize: '0.875rem', color: '#475569' }}>
+          Username
+          <input
+            type="text"
+            value={username}
+            onChange={(e) => setUsername(e.target.value)}
+            autoComplete="username"
+            autoFocus
+            required
+            style={inputStyle}
+          />
+        </label>
+
+        <label style={{ display: 'block', marginBottom: '1.5rem', fontSize: '0.875rem', color: '#475569' }}>
+          Password
+          <input
+            type="password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            autoComplete="current-password"
+            required
+            style={inputStyle}
+          />
+        </label>
+
+        <button
+          type="submit"
+          disabled={loading}
+          style={{
+            width: '100%',
+            padding: '0.5rem',
+            color: 'white',
+            backgroundColor: '#3b82f6',
+            border: 'none',
+            borderRadius: '4px',
+            fontSize: '0.875rem',
+            fontWeight: '600',
+            cursor: loading ? 'not-allowed' : 'pointer',
+          }}
+        >
+          {loading ? 'Signing in...' : 'Sign in'}
+        </button>
+      </form>
+    </div>
+  )
+}
+
+export default Login
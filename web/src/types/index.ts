@@ -9,9 +9,21 @@ export interface Host {
   private_key?: string
   key_path?: string
   passphrase?: string
+  // 凭据只在提交时使用，响应中只返回是否已设置及私钥指纹
+  has_password?: boolean
+  has_private_key?: boolean
+  has_passphrase?: boolean
+  key_fingerprint?: string
   description: string
+  upload_limit?: number
+  download_limit?: number
   status: 'active' | 'inactive' | 'error'
   last_check?: string
+  owner_id?: number
+  // 经由跳板机连接时为跳板机的主机ID
+  jump_host_id?: number
+  // key=value 标签，列表可以按标签选择器筛选
+  labels?: Record<string, string>
   created_at: string
   updated_at: string
   tunnels?: Tunnel[]
@@ -27,8 +39,45 @@ export interface Tunnel {
   remote_address?: string
   remote_port?: number
   description: string
-  status: 'active' | 'inactive' | 'error'
+  status: 'active' | 'inactive' | 'error' | 'suspended' | 'standby' | 'connected' | 'degraded'
+  status_reason?: string
   auto_start: boolean
+  upload_limit?: number
+  download_limit?: number
+  quota_bytes?: number
+  quota_period?: '' | 'daily' | 'monthly'
+  max_connections?: number
+  overflow_policy?: 'reject' | 'queue'
+  idle_timeout?: number
+  max_lifetime?: number
+  // 导出代理客户端配置时经由此动态隧道访问的域名，包含子域名
+  proxy_domains?: string[]
+  // 标签，导出模板可以按标签选择隧道
+  tags?: string[]
+  // 按需连接：只监听端口，首个客户端连接时才建立SSH连接，空闲 lazy_timeout 秒后断开
+  lazy?: boolean
+  lazy_timeout?: number
+  // 启动前需要先运行的隧道ID
+  depends_on?: number[]
+  // key=value 标签，列表和批量操作可以按标签选择器筛选
+  labels?: Record<string, string>
+  // 启停计划：cron 表达式或每周的时间段，timezone 为空时使用服务器本地时区
+  start_cron?: string
+  stop_cron?: string
+  time_windows?: string[]
+  timezone?: string
+  // 健康检查：通过隧道定期检查目标，连续失败 health_failures 次后标记为 degraded
+  health_check?: '' | 'tcp' | 'http' | 'payload'
+  health_target?: string
+  health_path?: string
+  health_code?: number
+  health_send?: string
+  health_expect?: string
+  health_interval?: number
+  health_timeout?: number
+  health_failures?: number
+  health_restart?: boolean
+  owner_id?: number
   created_at: string
   updated_at: string
 }
@@ -36,7 +85,8 @@ export interface Tunnel {
 export interface ConnectionLog {
   id: number
   tunnel_id: number
-  event_type: 'connect' | 'disconnect' | 'error' | 'start' | 'stop'
+  event_type: 'connect' | 'disconnect' | 'error' | 'start' | 'stop' | 'suspend' | 'resume' | 'reject' | 'queue' | 'timeout'
+  client_addr?: string
   message: string
   timestamp: string
 }
@@ -89,6 +139,107 @@ export interface ClashConfig {
   dns: ClashDNS
 }
 
+// Clash/mihomo 导出模板，分组和规则为空时使用默认设置
+export interface ExportProfileSettings {
+  port?: number
+  socks_port?: number
+  mixed_port?: number
+  allow_lan?: boolean
+  bind_address?: string
+  mode?: '' | 'rule' | 'global' | 'direct'
+  log_level?: string
+  ipv6?: boolean
+  external_controller?: string
+  secret?: string
+  health_check_url?: string
+  health_check_interval?: number
+  dns?: {
+    enable: boolean
+    listen?: string
+    nameserver: string[]
+    fallback?: string[]
+    enhanced_mode?: string
+    fake_ip_range?: string
+    fake_ip_filter?: string[]
+  } | null
+  // 以下字段只在 mihomo 模板中输出
+  unified_delay?: boolean
+  tcp_concurrent?: boolean
+  find_process_mode?: '' | 'always' | 'strict' | 'off'
+  global_client_fingerprint?: string
+  geodata_mode?: boolean
+  geox_url?: Record<string, string>
+}
+
+export interface ExportProxyGroup {
+  name: string
+  type: 'select' | 'url-test' | 'fallback' | 'load-balance'
+  proxies?: string[]
+  include_nodes?: boolean
+  filter?: string
+  url?: string
+  interval?: number
+  tolerance?: number
+  strategy?: string
+  hidden?: boolean
+  icon?: string
+}
+
+export interface ExportRuleProvider {
+  type: 'http' | 'file'
+  behavior: 'domain' | 'ipcidr' | 'classical'
+  url?: string
+  path?: string
+  interval?: number
+  format?: '' | 'yaml' | 'text' | 'mrs'
+}
+
+export interface ExportProfile {
+  id: number
+  name: string
+  description?: string
+  mihomo: boolean
+  settings: ExportProfileSettings
+  proxy_groups: ExportProxyGroup[] | null
+  rules: string[] | null
+  rule_providers: Record<string, ExportRuleProvider> | null
+  tunnel_tags: string[] | null
+  tunnel_ids: number[] | null
+  // 标签选择器，例如 env=prod,team in (a,b)
+  tunnel_selector?: string
+  include_inactive: boolean
+  start_on_demand: boolean
+  created_at: string
+  updated_at: string
+}
+
+// 代理客户端订阅，令牌只在创建时返回一次
+export interface Subscription {
+  id: number
+  name: string
+  format: string
+  profile_id: number | null
+  prefix: string
+  update_interval: number
+  expires_at: string | null
+  revoked_at: string | null
+  last_fetched_at: string | null
+  fetch_count: number
+  created_at: string
+  profile?: ExportProfile
+}
+
+export interface SubscriptionFetch {
+  id: number
+  subscription_id: number
+  source_ip: string
+  user_agent: string
+  status_code: number
+  error?: string
+  bytes: number
+  fetched_at: string
+}
+
 export interface ClashExportResponse {
   message: string
   config: ClashConfig
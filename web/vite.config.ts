@@ -4,6 +4,8 @@ import react from '@vitejs/plugin-react'
 export default defineConfig({
   plugins: [react()],
   root: '.',
+  // 使用相对路径引用资源，配合服务端注入的 <base href> 支持子路径部署
+  base: './',
   publicDir: 'public',
   server: {
     port: 3000,